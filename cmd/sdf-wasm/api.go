@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/graph"
+	"github.com/rebornplusplus/chisel-tools/internal/plan"
+)
+
+// A function of the "sdf" object. It takes its arguments and returns its
+// result as JSON, which the bindings convert from and to JavaScript values,
+// so that it runs the same outside of a browser.
+type function struct {
+	// Number of arguments, the last ones optional.
+	min, max int
+	call     func(args []json.RawMessage) (any, error)
+}
+
+var functions = map[string]*function{
+	"parseSlices": {1, 1, parseSlices},
+	"parseConfig": {1, 1, parseConfig},
+	"prune":       {1, 2, prune},
+	"group":       {1, 2, group},
+	"deps":        {2, 2, deps},
+	"rdeps":       {2, 2, rdeps},
+	"affected":    {2, 2, affected},
+}
+
+type slice struct {
+	Name      string   `json:"name"`
+	Package   string   `json:"package"`
+	Essential []string `json:"essential"`
+}
+
+type groupOptions struct {
+	Combine   bool `json:"combine"`
+	ByPackage bool `json:"byPackage"`
+	Size      int  `json:"size"`
+}
+
+// call calls the function with the JSON arguments and returns the JSON of
+// an object with either the "result" or the "error" of the call.
+func call(name string, args []json.RawMessage) []byte {
+	res, err := callResult(name, args)
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(map[string]any{"result": res})
+	}
+	if err != nil {
+		data, _ = json.Marshal(map[string]any{"error": err.Error()})
+	}
+	return data
+}
+
+func callResult(name string, args []json.RawMessage) (any, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if len(args) < fn.min || len(args) > fn.max {
+		switch {
+		case fn.min != fn.max:
+			return nil, fmt.Errorf("%s expects %d or %d arguments", name, fn.min, fn.max)
+		case fn.min == 1:
+			return nil, fmt.Errorf("%s expects 1 argument", name)
+		}
+		return nil, fmt.Errorf("%s expects %d arguments", name, fn.min)
+	}
+	return fn.call(args)
+}
+
+func parseSlices(args []json.RawMessage) (any, error) {
+	var data string
+	if err := json.Unmarshal(args[0], &data); err != nil {
+		return nil, err
+	}
+	slices, err := chisel.DecodeSlices([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	return fromSlices(slices), nil
+}
+
+func parseConfig(args []json.RawMessage) (any, error) {
+	var data string
+	if err := json.Unmarshal(args[0], &data); err != nil {
+		return nil, err
+	}
+	cfg, err := chisel.DecodeConfig([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	archives := make(map[string]any)
+	for name, a := range cfg.Archives {
+		archives[name] = map[string]any{
+			"suites":     a.Suites,
+			"components": a.Components,
+		}
+	}
+	return map[string]any{
+		"format":   cfg.Format,
+		"archives": archives,
+	}, nil
+}
+
+func prune(args []json.RawMessage) (any, error) {
+	var slices []*slice
+	if err := json.Unmarshal(args[0], &slices); err != nil {
+		return nil, err
+	}
+	opts := &plan.PruneOptions{}
+	if len(args) == 2 {
+		if err := json.Unmarshal(args[1], &opts.Keep); err != nil {
+			return nil, err
+		}
+	}
+	return fromSlices(plan.Prune(toSlices(slices), opts)), nil
+}
+
+func group(args []json.RawMessage) (any, error) {
+	var slices []*slice
+	if err := json.Unmarshal(args[0], &slices); err != nil {
+		return nil, err
+	}
+	var opts groupOptions
+	if len(args) == 2 {
+		if err := json.Unmarshal(args[1], &opts); err != nil {
+			return nil, err
+		}
+	}
+	groups := plan.Group(toSlices(slices), &plan.GroupOptions{
+		Combine:   opts.Combine,
+		ByPackage: opts.ByPackage,
+		Size:      opts.Size,
+	})
+	return groups, nil
+}
+
+func deps(args []json.RawMessage) (any, error) {
+	_, g, names, err := decodeGraph(args)
+	if err != nil {
+		return nil, err
+	}
+	return nonNil(g.Deps(names...)), nil
+}
+
+func rdeps(args []json.RawMessage) (any, error) {
+	_, g, names, err := decodeGraph(args)
+	if err != nil {
+		return nil, err
+	}
+	return nonNil(g.RDeps(names...)), nil
+}
+
+// affected returns the names of the slices affected by changes to the
+// named ones: themselves and the slices they are essential to, in the
+// order of the slices.
+func affected(args []json.RawMessage) (any, error) {
+	slices, g, names, err := decodeGraph(args)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool)
+	for _, name := range append(names, g.RDeps(names...)...) {
+		found[name] = true
+	}
+	res := []string{}
+	for _, s := range slices {
+		if found[s.Name] {
+			res = append(res, s.Name)
+		}
+	}
+	return res, nil
+}
+
+// decodeGraph returns the slices of the first argument and their graph, and
+// the names of the second one, which must be those of some of the slices.
+func decodeGraph(args []json.RawMessage) ([]*slice, *graph.Graph, []string, error) {
+	var slices []*slice
+	if err := json.Unmarshal(args[0], &slices); err != nil {
+		return nil, nil, nil, err
+	}
+	var names []string
+	if err := json.Unmarshal(args[1], &names); err != nil {
+		return nil, nil, nil, err
+	}
+	g := &graph.Graph{}
+	for _, s := range slices {
+		g.Add(s.Name, s.Essential...)
+	}
+	for _, name := range names {
+		if !g.Has(name) {
+			return nil, nil, nil, fmt.Errorf("slice %s not found", name)
+		}
+	}
+	return slices, g, names, nil
+}
+
+// nonNil returns the names, or an empty list if none, so that JavaScript
+// gets an array rather than null.
+func nonNil(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
+
+func fromSlices(slices []*chisel.Slice) []*slice {
+	res := make([]*slice, 0, len(slices))
+	for _, s := range slices {
+		res = append(res, &slice{
+			Name:      s.Name,
+			Package:   s.Package,
+			Essential: s.Essential,
+		})
+	}
+	return res
+}
+
+func toSlices(slices []*slice) []*chisel.Slice {
+	res := make([]*chisel.Slice, 0, len(slices))
+	for _, s := range slices {
+		res = append(res, &chisel.Slice{
+			Name:      s.Name,
+			Package:   s.Package,
+			Essential: s.Essential,
+		})
+	}
+	return res
+}
@@ -0,0 +1,108 @@
+package main_test
+
+import (
+	"testing"
+
+	wasm "github.com/rebornplusplus/chisel-tools/cmd/sdf-wasm"
+)
+
+// The slices of the tests: a_bins and b_libs need a_libs, which needs
+// c_copyright.
+const testSlices = `[
+	{"name": "a_bins", "package": "a", "essential": ["a_libs"]},
+	{"name": "a_libs", "package": "a", "essential": ["c_copyright"]},
+	{"name": "b_libs", "package": "b", "essential": ["a_libs"]},
+	{"name": "c_copyright", "package": "c", "essential": null}
+]`
+
+var callTests = []struct {
+	summary string
+	name    string
+	args    []string
+	json    string
+}{{
+	summary: "Parse slices",
+	name:    "parseSlices",
+	args:    []string{`"package: a\nslices:\n  bins:\n    essential: [a_libs]\n  libs: {}\n"`},
+	json:    `{"result":[{"name":"a_bins","package":"a","essential":["a_libs"]},{"name":"a_libs","package":"a","essential":null}]}`,
+}, {
+	summary: "Invalid slice definitions",
+	name:    "parseSlices",
+	args:    []string{`"slices: ["`},
+	json:    `{"error":"yaml: line 1: did not find expected node content"}`,
+}, {
+	summary: "Parse the configuration",
+	name:    "parseConfig",
+	args:    []string{`"format: v1\narchives:\n  ubuntu:\n    version: \"24.04\"\n    suites: [noble]\n    components: [main]\n"`},
+	json:    `{"result":{"archives":{"ubuntu":{"components":["main"],"suites":["noble"]}},"format":"v1"}}`,
+}, {
+	summary: "Prune",
+	name:    "prune",
+	args:    []string{testSlices},
+	json:    `{"result":[{"name":"a_bins","package":"a","essential":["a_libs"]},{"name":"b_libs","package":"b","essential":["a_libs"]}]}`,
+}, {
+	summary: "Prune keeping slices",
+	name:    "prune",
+	args:    []string{testSlices, `["c_copyright"]`},
+	json:    `{"result":[{"name":"a_bins","package":"a","essential":["a_libs"]},{"name":"b_libs","package":"b","essential":["a_libs"]},{"name":"c_copyright","package":"c","essential":null}]}`,
+}, {
+	summary: "Group by package",
+	name:    "group",
+	args:    []string{testSlices, `{"byPackage": true}`},
+	json:    `{"result":[["a_bins","a_libs"],["b_libs"],["c_copyright"]]}`,
+}, {
+	summary: "Dependencies",
+	name:    "deps",
+	args:    []string{testSlices, `["a_bins"]`},
+	json:    `{"result":["a_libs","c_copyright"]}`,
+}, {
+	summary: "No dependencies",
+	name:    "deps",
+	args:    []string{testSlices, `["c_copyright"]`},
+	json:    `{"result":[]}`,
+}, {
+	summary: "Reverse dependencies",
+	name:    "rdeps",
+	args:    []string{testSlices, `["a_libs"]`},
+	json:    `{"result":["a_bins","b_libs"]}`,
+}, {
+	summary: "Affected slices, in their order",
+	name:    "affected",
+	args:    []string{testSlices, `["c_copyright"]`},
+	json:    `{"result":["a_bins","a_libs","b_libs","c_copyright"]}`,
+}, {
+	summary: "Unknown slice",
+	name:    "rdeps",
+	args:    []string{testSlices, `["d_bins"]`},
+	json:    `{"error":"slice d_bins not found"}`,
+}, {
+	summary: "Invalid slices",
+	name:    "deps",
+	args:    []string{`{"name": "a_bins"}`, `["a_bins"]`},
+	json:    `{"error":"json: cannot unmarshal object into Go value of type []*main.slice"}`,
+}, {
+	summary: "Missing argument",
+	name:    "affected",
+	args:    []string{testSlices},
+	json:    `{"error":"affected expects 2 arguments"}`,
+}, {
+	summary: "Too many arguments",
+	name:    "group",
+	args:    []string{testSlices, `{}`, `{}`},
+	json:    `{"error":"group expects 1 or 2 arguments"}`,
+}, {
+	summary: "Argument of the wrong type",
+	name:    "parseSlices",
+	args:    []string{`42`},
+	json:    `{"error":"json: cannot unmarshal number into Go value of type string"}`,
+}}
+
+func TestCall(t *testing.T) {
+	for _, test := range callTests {
+		t.Logf("Summary: %s", test.summary)
+		have := wasm.Call(test.name, test.args...)
+		if have != test.json {
+			t.Fatalf("have %s, want %s", have, test.json)
+		}
+	}
+}
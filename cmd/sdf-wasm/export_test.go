@@ -0,0 +1,13 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+func Call(name string, args ...string) string {
+	raw := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		raw[i] = json.RawMessage(arg)
+	}
+	return string(call(name, raw))
+}
@@ -0,0 +1,56 @@
+//go:build js && wasm
+
+// Command sdf-wasm exposes the slice definition parsing and planning logic
+// of sdf to JavaScript, so that browser based tools reuse the exact same
+// code as the CLI.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o sdf.wasm ./cmd/sdf-wasm
+//
+// Once loaded with wasm_exec.js, the module registers a global "sdf" object
+// with the following functions. All of them return an object with either a
+// "result" or an "error" field:
+//
+//	sdf.parseSlices(yaml)          // Slices of a slice definition file.
+//	sdf.parseConfig(yaml)          // A chisel.yaml file.
+//	sdf.prune(slices, keep)        // Top level slices, see plan.Prune.
+//	sdf.group(slices, options)     // Installation groups, see plan.Group.
+//	sdf.deps(slices, names)        // Slices installed along, see graph.Deps.
+//	sdf.rdeps(slices, names)       // Slices installing them, see graph.RDeps.
+//	sdf.affected(slices, names)    // The slices and those installing them.
+//
+// The slices passed to the other functions are the ones returned by
+// parseSlices.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+func main() {
+	sdf := make(map[string]any)
+	for name := range functions {
+		sdf[name] = js.FuncOf(func(this js.Value, args []js.Value) any {
+			return bind(name, args)
+		})
+	}
+	js.Global().Set("sdf", js.ValueOf(sdf))
+	select {} // Keep the functions alive.
+}
+
+// bind calls the function with the JavaScript arguments by round-tripping
+// through JSON, which is simpler and safer than walking the values by hand,
+// and as [js.ValueOf] only handles a few basic types.
+func bind(name string, args []js.Value) any {
+	JSON := js.Global().Get("JSON")
+	raw := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		raw[i] = json.RawMessage("null")
+		if !arg.IsUndefined() {
+			raw[i] = json.RawMessage(JSON.Call("stringify", arg).String())
+		}
+	}
+	return JSON.Call("parse", string(call(name, raw)))
+}
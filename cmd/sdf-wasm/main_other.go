@@ -0,0 +1,15 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// The functions of the module are the same outside of a browser, so that
+// they can be tested, but there is nothing to run them.
+func main() {
+	fmt.Fprintln(os.Stderr, "sdf-wasm only runs in JavaScript, build it with GOOS=js GOARCH=wasm")
+	os.Exit(1)
+}
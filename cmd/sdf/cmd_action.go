@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/ghactions"
+	"github.com/rebornplusplus/chisel-tools/internal/pipeline"
+)
+
+type cmdAction struct{}
+
+func init() {
+	parser.AddCommand(
+		"action",
+		"Run as the step of the GitHub Action",
+		"The action command runs the stages of the GitHub Action: lint, install and test, for the slice definition files that changed. Its inputs come from the INPUT_* variables set by the runner: release (default .), arch (default amd64), stages (default lint,install,test), changed-since (default the base branch of pull requests or the commit before pushes, everything if none), workers (default 4) and backend (default unshare). It writes the outputs result, changed-files, lint-issues, install-tasks, install-failed, tests and tests-failed, and a summary of the stages in the step summary",
+		&cmdAction{},
+	)
+}
+
+// actionStages are the stages of the action, in the order they run.
+var actionStages = []string{"lint", "install", "test"}
+
+func (c *cmdAction) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	p := &stageRunner{
+		release:  ghactions.Input("release", "."),
+		arch:     ghactions.Input("arch", "amd64"),
+		annotate: true,
+		outputs:  make(map[string]string),
+	}
+	workers, err := strconv.Atoi(ghactions.Input("workers", "4"))
+	if err != nil || workers <= 0 {
+		return exitErrorf(exitUsage, "invalid value for input workers: %q", ghactions.Input("workers", "4"))
+	}
+	p.workers = workers
+	backend := ghactions.Input("backend", "unshare")
+	if backend != "unshare" && backend != "chroot" {
+		return exitErrorf(exitUsage, "invalid value for input backend: %q, want unshare or chroot", backend)
+	}
+	run := make(map[string]bool)
+	for _, s := range strings.Split(ghactions.Input("stages", strings.Join(actionStages, ",")), ",") {
+		s = strings.TrimSpace(s)
+		if !slices.Contains(actionStages, s) {
+			return exitErrorf(exitUsage, "unknown stage %q in input stages, want %s", s, strings.Join(actionStages, ", "))
+		}
+		run[s] = true
+	}
+
+	if ref := actionBase(ghactions.Input("changed-since", "")); ref != "" {
+		if err := p.setChanged(ref); err != nil {
+			log.Printf("Checking the whole release: %v", err)
+		} else {
+			p.outputs["changed-files"] = strings.Join(p.changedNames(), "\n")
+		}
+	}
+	if run["install"] || run["test"] {
+		if err := checkChisel(p.release); err != nil {
+			return err
+		}
+	}
+
+	var results []*pipeline.Result
+	var failed []error
+	for _, name := range actionStages {
+		if !run[name] {
+			continue
+		}
+		result, err := p.run(&pipeline.Stage{Name: name, Backend: backend})
+		results = append(results, result)
+		if err != nil {
+			log.Print(err)
+			failed = append(failed, err)
+		}
+	}
+
+	p.outputs["result"] = "success"
+	if len(failed) > 0 {
+		p.outputs["result"] = "failure"
+	}
+	if err := ghactions.SetOutputs(p.outputs); err != nil {
+		return fmt.Errorf("cannot write outputs: %w", err)
+	}
+	if err := ghactions.AppendSummary(pipeline.Markdown("Slice definitions", results)); err != nil {
+		return fmt.Errorf("cannot write step summary: %w", err)
+	}
+	if len(failed) > 0 {
+		// The stage errors were already logged, exit with the code of the
+		// first one.
+		return withExitCode(exitCode(failed[0]), fmt.Errorf("%c %d of %d stage(s) failed", cross, len(failed), len(results)))
+	}
+	log.Printf("%c All stages passed", tick)
+	return nil
+}
+
+// actionBase returns the ref to find the changes since: the given one, else
+// the base branch of a pull request or the commit before a push. It returns
+// an empty string if there is none.
+func actionBase(ref string) string {
+	if ref != "" {
+		return ref
+	}
+	if base := os.Getenv("GITHUB_BASE_REF"); base != "" {
+		return "origin/" + base
+	}
+	data, err := os.ReadFile(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return ""
+	}
+	var event struct {
+		Before string `json:"before"`
+	}
+	// Pushes creating a branch have no commit before.
+	if json.Unmarshal(data, &event) != nil || strings.Trim(event.Before, "0") == "" {
+		return ""
+	}
+	return event.Before
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/rebornplusplus/chisel-tools/internal/attest"
+)
+
+type cmdAttest struct {
+	Release       string      `short:"r" long:"release" description:"Chisel release path" path:"yes"`
+	Arch          string      `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Slices        []sliceName `short:"s" long:"slice" description:"Installed slice (can be repeated)" required:"true"`
+	SubjectFile   string      `long:"subject-file" description:"Root tarball to attest" path:"yes"`
+	SubjectDigest string      `long:"subject-digest" description:"Digest to attest, e.g. the sha256:... of an OCI image"`
+	SubjectName   string      `long:"subject-name" description:"Subject name (default: base name of the subject file)"`
+	Results       string      `long:"results" description:"JSON file with the install results to include" path:"yes"`
+	Key           string      `long:"key" description:"PEM private key to sign the attestation with"`
+	Output        string      `short:"o" long:"output" description:"Output file (default: stdout)" path:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"attest",
+		"Create an attestation of installed slices",
+		"The attest command creates an in-toto statement about a root tarball or an OCI image, with the installed slices, the chisel version and the release commit as predicate. With --key, it is signed in a DSSE envelope",
+		&cmdAttest{},
+	)
+}
+
+func (c *cmdAttest) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	subject, err := c.subject()
+	if err != nil {
+		return err
+	}
+
+	predicate := &attest.Install{Slices: names(c.Slices), Arch: c.Arch}
+	if predicate.ChiselVersion, err = chiselVersion(); err != nil {
+		log.Printf("Leaving out the chisel version: %s", err)
+	}
+	if c.Release != "" {
+		predicate.Release = &attest.Release{Path: c.Release}
+		if predicate.Release.Commit, err = releaseCommit(c.Release); err != nil {
+			log.Printf("Leaving out the release commit: %s", err)
+		}
+	}
+	if c.Results != "" {
+		data, err := os.ReadFile(c.Results)
+		if err != nil {
+			return fmt.Errorf("cannot read results: %w", err)
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("cannot read results: %s is not valid JSON", c.Results)
+		}
+		predicate.Results = data
+	}
+	st := attest.NewStatement(attest.InstallPredicateType, predicate, subject)
+
+	if c.Key == "" {
+		return writeJSON(c.Output, st)
+	}
+	data, err := os.ReadFile(c.Key)
+	if err != nil {
+		return fmt.Errorf("cannot read key: %w", err)
+	}
+	key, err := attest.ParsePrivateKey(data)
+	if err != nil {
+		return fmt.Errorf("cannot parse key %s: %w", c.Key, err)
+	}
+	env, err := attest.Sign(st, key)
+	if err != nil {
+		return err
+	}
+	return writeJSON(c.Output, env)
+}
+
+func (c *cmdAttest) subject() (*attest.Subject, error) {
+	switch {
+	case c.SubjectFile != "" && c.SubjectDigest != "":
+		return nil, fmt.Errorf("cannot use both --subject-file and --subject-digest")
+	case c.SubjectFile != "":
+		name := c.SubjectName
+		if name == "" {
+			name = filepath.Base(c.SubjectFile)
+		}
+		return attest.FileSubject(name, c.SubjectFile)
+	case c.SubjectDigest != "":
+		if c.SubjectName == "" {
+			return nil, fmt.Errorf("--subject-digest requires --subject-name")
+		}
+		return attest.DigestSubject(c.SubjectName, c.SubjectDigest)
+	default:
+		return nil, fmt.Errorf("either --subject-file or --subject-digest must be specified")
+	}
+}
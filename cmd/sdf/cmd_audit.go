@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/audit"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+)
+
+type cmdAudit struct {
+	Root     string   `long:"root" description:"Root directory chisel installed slices into" required:"true" path:"yes"`
+	Manifest string   `long:"manifest" description:"Chisel manifest path, instead of the one in the root" path:"yes"`
+	Checks   []string `long:"check" description:"Check to run (can be repeated, default: all)" choice:"secrets" choice:"permissions"`
+	Ignore   []string `long:"ignore" description:"Path pattern to ignore findings for, e.g. /etc/ssl/private/* (can be repeated)"`
+	Output   string   `short:"o" long:"output" description:"Write the findings as JSON to this file" path:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"audit",
+		"Audit a root for secrets and unsafe permissions",
+		"The audit command checks a root for accidentally shipped secrets, such as private keys, tokens and password-bearing configuration files, and for setuid, setgid, world-writable and special files. It reports each finding with the slices providing the path",
+		&cmdAudit{},
+	)
+}
+
+// auditChecks maps the names of the checks to their implementation.
+var auditChecks = map[string]func(root string) ([]*audit.Finding, error){
+	"secrets":     audit.Secrets,
+	"permissions": audit.Permissions,
+}
+
+func (c *cmdAudit) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	for _, pattern := range c.Ignore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+	}
+	checks := c.Checks
+	if len(checks) == 0 {
+		for name := range auditChecks {
+			checks = append(checks, name)
+		}
+	}
+
+	var findings []*audit.Finding
+	for _, name := range checks {
+		found, err := auditChecks[name](c.Root)
+		if err != nil {
+			return fmt.Errorf("cannot run %s check: %w", name, err)
+		}
+		for _, f := range found {
+			if !ignored(f.Path, c.Ignore) {
+				findings = append(findings, f)
+			}
+		}
+	}
+	audit.Sort(findings)
+
+	m, err := readManifest(c.Root, c.Manifest)
+	if err == nil {
+		audit.Attribute(findings, m)
+	} else if !errors.Is(err, manifest.ErrNotFound) {
+		return err
+	} else {
+		log.Print("No manifest found, findings are not attributed to slices")
+	}
+
+	if c.Output != "" {
+		if err := writeJSON(c.Output, findings); err != nil {
+			return fmt.Errorf("cannot write findings: %w", err)
+		}
+	}
+	if len(findings) > 0 || opts.Format != "table" {
+		t := &table{Header: []string{"PATH", "CHECK", "SLICES", "MESSAGE"}}
+		for _, f := range findings {
+			slices := strings.Join(f.Slices, ", ")
+			if slices == "" {
+				slices = "-"
+			}
+			t.Rows = append(t.Rows, []string{f.Path, f.Check, slices, f.Message})
+		}
+		if findings == nil {
+			findings = []*audit.Finding{}
+		}
+		if err := printOutput(findings, t); err != nil {
+			return err
+		}
+	}
+	if len(findings) == 0 {
+		log.Printf("%c No findings", tick)
+		return nil
+	}
+	return exitErrorf(exitFindings, "%c %d finding(s)", cross, len(findings))
+}
+
+func ignored(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
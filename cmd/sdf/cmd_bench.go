@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/bench"
+	"github.com/rebornplusplus/chisel-tools/internal/chiselbin"
+)
+
+type cmdBench struct {
+	Release   string        `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Arch      string        `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Runs      int           `short:"n" long:"runs" description:"Number of times to cut the slices" default:"5"`
+	Each      bool          `long:"each" description:"Benchmark every slice on its own instead of all of them in one cut"`
+	Cached    bool          `long:"cached" description:"Keep the chisel cache between runs, after a first cut to fill it"`
+	Version   string        `long:"chisel-version" description:"Chisel version to run, e.g. v1.0.0, instead of chisel from the PATH"`
+	CacheDir  string        `long:"cache-dir" description:"Directory to keep the chisel binaries in (default: sdf/chisel in the user cache directory)" path:"yes"`
+	Compare   string        `long:"compare" description:"Fail if the median of a phase is slower than in this report"`
+	Threshold float64       `long:"threshold" description:"Percentage a phase may be slower by with --compare" default:"10"`
+	MinDiff   time.Duration `long:"min-diff" description:"Difference a phase may be slower by with --compare regardless of the percentage" default:"50ms"`
+	Output    string        `short:"o" long:"output" description:"Write the report as JSON to this file" path:"yes"`
+
+	Positional struct {
+		Slices []sliceName `positional-arg-name:"slices"`
+	} `positional-args:"yes" required:"true"`
+}
+
+func init() {
+	parser.AddCommand(
+		"bench",
+		"Measure how long chisel takes to cut slices",
+		"The bench command cuts the slices, by default in one go, several times into fresh roots and reports the median time of every phase: setup, fetching, extraction and mutation, as told apart by the chisel output. Every run starts with an empty chisel cache unless --cached is set. With --compare, it fails if a phase is slower than in a report written by -o before, e.g. with another chisel version",
+		&cmdBench{},
+	)
+}
+
+func (c *cmdBench) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Runs <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --runs: %d", c.Runs)
+	}
+	if c.Threshold < 0 {
+		return exitErrorf(exitUsage, "invalid value for --threshold: %v", c.Threshold)
+	}
+	var base *bench.Report
+	if c.Compare != "" {
+		data, err := os.ReadFile(c.Compare)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &base); err != nil {
+			return fmt.Errorf("cannot parse report %s: %w", c.Compare, err)
+		}
+	}
+
+	report := &bench.Report{Arch: c.Arch, Cached: c.Cached}
+	bin := ""
+	if c.Version != "" {
+		d := &chiselbin.Downloader{CacheDir: c.CacheDir}
+		if d.CacheDir == "" {
+			var err error
+			if d.CacheDir, err = chiselbin.DefaultCacheDir(); err != nil {
+				return err
+			}
+		}
+		report.Chisel = "v" + strings.TrimPrefix(c.Version, "v")
+		progressf("Getting chisel %s...", report.Chisel)
+		var err error
+		if bin, err = d.Path(report.Chisel); err != nil {
+			return err
+		}
+	} else {
+		if err := checkChisel(c.Release); err != nil {
+			return err
+		}
+		report.Chisel, _ = detectChisel()
+	}
+
+	groups := [][]string{names(c.Positional.Slices)}
+	if c.Each {
+		groups = nil
+		for _, s := range c.Positional.Slices {
+			groups = append(groups, []string{string(s)})
+		}
+	}
+	for _, slices := range groups {
+		b, err := c.bench(slices, bin)
+		if err != nil {
+			return exitErrorf(exitInstall, "%c Cannot benchmark %s: %w", cross, strings.Join(slices, " "), err)
+		}
+		report.Benchmarks = append(report.Benchmarks, b)
+	}
+
+	if err := printBench(report); err != nil {
+		return err
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, report); err != nil {
+			return fmt.Errorf("cannot write report: %w", err)
+		}
+	}
+	if base == nil {
+		return nil
+	}
+	regressions := bench.Compare(base, report, c.Threshold, c.MinDiff)
+	for _, r := range regressions {
+		log.Printf("%c %s", cross, r)
+	}
+	if len(regressions) > 0 {
+		return fmt.Errorf("%c %d phase(s) slower than in %s", cross, len(regressions), c.Compare)
+	}
+	log.Printf("%c No phase slower than in %s", tick, c.Compare)
+	return nil
+}
+
+// bench cuts the slices c.Runs times, each into a fresh root.
+func (c *cmdBench) bench(slices []string, bin string) (*bench.Benchmark, error) {
+	cacheDir := ""
+	if c.Cached {
+		var err error
+		if cacheDir, err = os.MkdirTemp("", "sdf-cache-"); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(cacheDir)
+		progressf("Filling the cache for %s...", strings.Join(slices, " "))
+		if _, err := c.run(slices, bin, cacheDir); err != nil {
+			return nil, err
+		}
+	}
+	var runs []*bench.Timings
+	for i := range c.Runs {
+		t, err := c.run(slices, bin, cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		progressf("%c %s run %d: %s", tick, strings.Join(slices, " "), i+1, t.Total.Round(time.Millisecond))
+		runs = append(runs, t)
+	}
+	return bench.NewBenchmark(slices, runs), nil
+}
+
+// run cuts the slices once and returns the timings of the run. The cache is
+// a fresh one if cacheDir is empty.
+func (c *cmdBench) run(slices []string, bin, cacheDir string) (*bench.Timings, error) {
+	if cacheDir == "" {
+		dir, err := os.MkdirTemp("", "sdf-cache-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		cacheDir = dir
+	}
+	root, err := os.MkdirTemp("", "sdf-bench-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+
+	rec := &bench.Recorder{}
+	start := time.Now()
+	err = cut(context.Background(), &cutOptions{
+		Release:  c.Release,
+		Arch:     c.Arch,
+		Root:     root,
+		CacheDir: cacheDir,
+		Slices:   slices,
+		Chisel:   bin,
+		Output:   rec,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bench.Breakdown(rec.Lines(), start, time.Now()), nil
+}
+
+func printBench(report *bench.Report) error {
+	t := &table{Header: []string{"SLICES", "RUNS", "SETUP", "FETCH", "EXTRACT", "MUTATE", "TOTAL"}}
+	for _, b := range report.Benchmarks {
+		row := []string{strings.Join(b.Slices, " "), strconv.Itoa(len(b.Runs))}
+		for _, p := range b.Median.Phases() {
+			row = append(row, p.Duration.Round(time.Millisecond).String())
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return printOutput(report, t)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rebornplusplus/chisel-tools/internal/bisect"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type cmdBisect struct {
+	Release string      `short:"r" long:"release" description:"Chisel release path, a git repository" required:"true" path:"yes"`
+	Arch    string      `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Slices  []sliceName `long:"slice" description:"Slice to install at each step (can be repeated)" required:"true"`
+	Good    string      `long:"good" description:"Git ref the slices install at" required:"true"`
+	Bad     string      `long:"bad" description:"Git ref the slices fail to install at" default:"HEAD"`
+}
+
+func init() {
+	parser.AddCommand(
+		"bisect",
+		"Find the release commit that broke slices",
+		"The bisect command runs git bisect over the release repository between the good and bad refs, installing the slices at each step, to find the first commit they fail to install at. Commits where a slice is not defined are skipped. The bisection runs in a worktree of its own, leaving the checkout of the release alone",
+		&cmdBisect{},
+	)
+}
+
+func (c *cmdBisect) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	cacheDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(cacheDir)
+
+	b, err := bisect.Start(c.Release, c.Good, c.Bad)
+	if err != nil {
+		return fmt.Errorf("cannot start bisection: %w", err)
+	}
+	defer b.Close()
+	for {
+		commit, err := b.Commit()
+		if err != nil {
+			return err
+		}
+		desc, err := b.Describe(commit)
+		if err != nil {
+			return err
+		}
+		v, reason := c.test(b.Dir, cacheDir)
+		switch v {
+		case bisect.Good:
+			progressf("%c %s", tick, desc)
+		case bisect.Bad:
+			progressf("%c %s", cross, desc)
+		default:
+			progressf("%c %s: skipped, %s", skip, desc, reason)
+		}
+		first, err := b.Mark(v)
+		if err != nil {
+			return err
+		}
+		if first != "" {
+			desc, err := b.Describe(first)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("First bad commit: %s\n", desc)
+			return nil
+		}
+	}
+}
+
+// test installs the slices from the release at dir.
+func (c *cmdBisect) test(dir, cacheDir string) (bisect.Verdict, string) {
+	r, err := chisel.ReadRelease(dir)
+	if err != nil {
+		return bisect.Skip, err.Error()
+	}
+	for _, s := range names(c.Slices) {
+		if r.Slice(s) == nil {
+			return bisect.Skip, fmt.Sprintf("slice %s not defined", s)
+		}
+	}
+	root, err := os.MkdirTemp("", "sdf-bisect-root-")
+	if err != nil {
+		return bisect.Skip, err.Error()
+	}
+	defer os.RemoveAll(root)
+	err = cut(context.Background(), &cutOptions{
+		Release:  dir,
+		Arch:     c.Arch,
+		Root:     root,
+		CacheDir: cacheDir,
+		Slices:   names(c.Slices),
+	})
+	if err != nil {
+		return bisect.Bad, err.Error()
+	}
+	return bisect.Good, ""
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/cache"
+)
+
+type cmdCache struct {
+	Kinds     []string `long:"kind" description:"Kind of entries to consider (can be repeated)" choice:"deb" choice:"index" choice:"chisel" choice:"parse" choice:"temp"`
+	OlderThan string   `long:"older-than" description:"Consider only the entries last used before this age, e.g. 12h, 7d or 2w"`
+	DryRun    bool     `long:"dry-run" description:"Show what clean would remove without removing it"`
+
+	Positional struct {
+		Action string `positional-arg-name:"action" choice:"ls" choice:"size" choice:"clean"`
+	} `positional-args:"yes" required:"true"`
+}
+
+func init() {
+	parser.AddCommand(
+		"cache",
+		"Inspect and prune the caches",
+		"The cache command lists (ls), sums up (size) or removes (clean) what builds up on disk: the debs and archive indexes in the chisel cache, the chisel binaries downloaded by the matrix and bench commands, the parse cache of the slice definition files and the temporary directories of interrupted runs. Temporary directories count as used when anything in them last changed, so that the ones of running commands can be kept with --older-than",
+		&cmdCache{},
+	)
+}
+
+// cacheSize is the output of "cache size", per kind.
+type cacheSize struct {
+	Kind    string `json:"kind"`
+	Entries int    `json:"entries"`
+	Size    int64  `json:"size"`
+}
+
+func (c *cmdCache) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	switch c.Positional.Action {
+	case "ls", "size", "clean":
+	default:
+		return exitErrorf(exitUsage, "unknown action %q, want ls, size or clean", c.Positional.Action)
+	}
+	var before time.Time
+	if c.OlderThan != "" {
+		age, err := cache.ParseAge(c.OlderThan)
+		if err != nil {
+			return exitErrorf(exitUsage, "invalid value for --older-than: %w", err)
+		}
+		before = time.Now().Add(-age)
+	}
+	var kinds []cache.Kind
+	for _, k := range c.Kinds {
+		kinds = append(kinds, cache.Kind(k))
+	}
+	dirs, err := cache.DefaultDirs()
+	if err != nil {
+		return err
+	}
+	entries, err := cache.Find(dirs)
+	if err != nil {
+		return fmt.Errorf("cannot read caches: %w", err)
+	}
+	entries = cache.Filter(entries, kinds, before)
+
+	switch c.Positional.Action {
+	case "ls":
+		t := &table{Header: []string{"KIND", "SIZE", "USED", "PATH"}}
+		for _, e := range entries {
+			t.Rows = append(t.Rows, []string{string(e.Kind), cache.FormatSize(e.Size), e.Used.Format(time.DateTime), e.Path})
+		}
+		if entries == nil {
+			entries = []*cache.Entry{}
+		}
+		return printOutput(entries, t)
+	case "size":
+		shown := kinds
+		if len(shown) == 0 {
+			shown = cache.Kinds
+		}
+		sizes := make(map[cache.Kind]*cacheSize)
+		var summary []*cacheSize
+		for _, k := range shown {
+			sizes[k] = &cacheSize{Kind: string(k)}
+			summary = append(summary, sizes[k])
+		}
+		total := &cacheSize{Kind: "total"}
+		for _, e := range entries {
+			sizes[e.Kind].Entries++
+			sizes[e.Kind].Size += e.Size
+			total.Entries++
+			total.Size += e.Size
+		}
+		summary = append(summary, total)
+		t := &table{Header: []string{"KIND", "ENTRIES", "SIZE"}}
+		for _, s := range summary {
+			t.Rows = append(t.Rows, []string{s.Kind, strconv.Itoa(s.Entries), cache.FormatSize(s.Size)})
+		}
+		return printOutput(summary, t)
+	case "clean":
+		if c.DryRun {
+			var total int64
+			for _, e := range entries {
+				progressf("Would remove %s (%s)", e.Path, cache.FormatSize(e.Size))
+				total += e.Size
+			}
+			log.Printf("%c Would free %s", tick, cache.FormatSize(total))
+			return nil
+		}
+		freed, err := cache.Remove(entries)
+		if err != nil {
+			return fmt.Errorf("%c Cannot clean cache, %s freed: %w", cross, cache.FormatSize(freed), err)
+		}
+		log.Printf("%c Removed %d entries, %s freed", tick, len(entries), cache.FormatSize(freed))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/lint"
+)
+
+type cmdCheck struct {
+	Release string `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	NoCache bool   `long:"no-cache" description:"Parse all slice definition files again instead of using the parse cache"`
+	Format  string `long:"format" description:"Output format of the issues, the global --format if not given" choice:"text" choice:"json"`
+
+	hookOptions
+}
+
+func init() {
+	parser.AddCommand(
+		"check",
+		"Validate the slice definitions without chisel",
+		"The check command validates the release before anything is installed, without running chisel: it reports the slice definition files that do not parse, along with the issues of all the lint checks in the others, such as undefined essential slices or packages, dependency cycles, slices defined twice and paths the slices disagree on. Every issue is one chisel would fail on, so the command fails if there are any. Use --format json for a report CI can parse",
+		&cmdCheck{},
+	)
+}
+
+func (c *cmdCheck) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	switch c.Format {
+	case "text":
+		opts.Format = "table"
+	case "json":
+		opts.Format = "json"
+	}
+	bus, closeBus := c.newBus()
+	defer closeBus()
+	start := time.Now()
+	issues, files, err := checkRelease(newParseCache(c.NoCache), c.Release)
+	if err != nil {
+		return err
+	}
+	publishFindings(bus, "check", c.Release, issues, start)
+	if err := printIssues(c.Release, issues); err != nil {
+		return err
+	}
+	log.Printf("%c No issues in %d slice definition file(s)", tick, files)
+	return nil
+}
+
+// checkRelease returns the issues of the release, those of the files that
+// do not parse included, and the number of slice definition files.
+func checkRelease(pc *chisel.ParseCache, dir string) ([]*lint.Issue, int, error) {
+	cfgPath := filepath.Join(dir, "chisel.yaml")
+	if _, err := chisel.ParseConfig(cfgPath); err != nil {
+		return []*lint.Issue{{Check: lint.ParseCheck, File: cfgPath, Message: err.Error()}}, 0, nil
+	}
+	var issues []*lint.Issue
+	files := 0
+	r, err := chisel.ReadReleaseFunc(dir, func(path string) ([]*chisel.Slice, error) {
+		files++
+		slices, err := parseSlices(pc, path)
+		if err != nil {
+			issues = append(issues, &lint.Issue{Check: lint.ParseCheck, File: path, Message: err.Error()})
+			return nil, nil
+		}
+		return slices, nil
+	})
+	if err != nil {
+		return nil, 0, exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	issues = append(issues, lint.Run(r)...)
+	lint.Sort(issues)
+	return issues, files, nil
+}
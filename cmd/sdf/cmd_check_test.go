@@ -0,0 +1,93 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+	"github.com/rebornplusplus/chisel-tools/internal/fixtures"
+)
+
+var checkReleaseTests = []struct {
+	summary string
+	files   map[string]string
+	issues  []string
+	count   int
+}{{
+	summary: "Clean release",
+	count:   2,
+}, {
+	summary: "Files that do not parse along with issues of the others",
+	files: map[string]string{
+		"slices/broken.yaml": "package: broken\n",
+		"slices/extra.yaml": `
+package: extra
+slices:
+  bins:
+    essential: [missing_libs]
+    contents:
+      /usr/lib/libhello.so.1:
+`,
+	},
+	issues: []string{
+		"slices/broken.yaml: missing 'slices' field (parse)",
+		"slices/extra.yaml: extra_bins: essential slice missing_libs is of undefined package missing (undefined-essential)",
+		"slices/libhello1.yaml: libhello1_libs: path /usr/lib/libhello.so.1 is also copied from package extra by slice extra_bins (path-conflict)",
+	},
+	count: 4,
+}, {
+	summary: "Package essentials",
+	files: map[string]string{
+		"slices/hello.yaml": `
+package: hello
+essential:
+  - hello_copyright
+slices:
+  bins:
+    essential:
+      - libhello1_libs
+    contents:
+      /usr/bin/hello:
+      /usr/bin/hi:
+  copyright:
+    contents:
+      /usr/share/doc/hello/copyright:
+`,
+	},
+	count: 2,
+}, {
+	summary: "Invalid chisel.yaml",
+	files: map[string]string{
+		"chisel.yaml": "format: v1\n",
+	},
+	issues: []string{"chisel.yaml: no 'archives' specified (parse)"},
+}}
+
+func TestCheckRelease(t *testing.T) {
+	for _, test := range checkReleaseTests {
+		t.Logf("Summary: %s", test.summary)
+		f, err := fixtures.Write(t.TempDir(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, data := range test.files {
+			if err := os.WriteFile(filepath.Join(f.Release, name), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		issues, count, err := sdf.CheckRelease(nil, f.Release)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var have []string
+		for _, i := range issues {
+			i.File, _ = filepath.Rel(f.Release, i.File)
+			have = append(have, i.String())
+		}
+		if !reflect.DeepEqual(have, test.issues) || count != test.count {
+			t.Fatalf("have issues %q in %d file(s), want %q in %d", have, count, test.issues, test.count)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/pipeline"
+)
+
+type cmdCI struct {
+	Config       string `short:"c" long:"config" description:"Pipeline file" default:"pipeline.yaml" path:"yes"`
+	ChangedSince string `long:"changed-since" description:"Check only what changed since this git ref, instead of the one of the pipeline file"`
+}
+
+func init() {
+	parser.AddCommand(
+		"ci",
+		"Run the pipeline of a release",
+		"The ci command runs the stages declared in the pipeline file, in order: lint, install, test and coverage, any number of times and with their own options. When changed-since is set, the stages only check the slice definition files, packages and tests affected by the files changed since the ref, unless they are given all. The stages share their chisel caches, kept in cache-dir if set, so that every package is fetched once. A failed stage does not stop the next ones unless fail-fast is set; the command fails with the exit code of the first failed stage once they are all done, after writing the reports. See the documentation of the pipeline package for the format of the file",
+		&cmdCI{},
+	)
+}
+
+func (c *cmdCI) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	cfg, err := pipeline.ReadFile(c.Config)
+	if err != nil {
+		return exitErrorf(exitUsage, "cannot read pipeline: %w", err)
+	}
+	needChisel := false
+	for _, s := range cfg.Stages {
+		if err := checkStage(s); err != nil {
+			return err
+		}
+		needChisel = needChisel || s.Name != "lint"
+	}
+	start := time.Now()
+	p := &stageRunner{release: cfg.Release, arch: cfg.Arch, workers: cfg.Workers}
+	ref := cfg.ChangedSince
+	if c.ChangedSince != "" {
+		ref = c.ChangedSince
+	}
+	if ref != "" {
+		if err := p.setChanged(ref); err != nil {
+			return err
+		}
+	}
+	if needChisel {
+		if err := checkChisel(cfg.Release); err != nil {
+			return err
+		}
+	}
+	cacheDir := cfg.CacheDir
+	if cacheDir == "" {
+		if cacheDir, err = os.MkdirTemp("", "sdf-ci-cache-"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(cacheDir)
+	}
+	if err := workerCaches.keep(cacheDir); err != nil {
+		return fmt.Errorf("cannot create cache directory: %w", err)
+	}
+
+	report := &pipeline.Report{Release: cfg.Release, Changed: p.changedNames()}
+	var failed []error
+	for _, s := range cfg.Stages {
+		if len(failed) > 0 && (cfg.FailFast || errors.Is(failed[len(failed)-1], context.Canceled)) {
+			report.Results = append(report.Results, &pipeline.Result{Stage: s.Name, Status: pipeline.Skipped, Summary: "a previous stage failed"})
+			continue
+		}
+		result, err := p.run(s)
+		report.Results = append(report.Results, result)
+		if err != nil {
+			log.Print(err)
+			failed = append(failed, err)
+		}
+	}
+	report.Passed = len(failed) == 0
+	report.Duration = time.Since(start)
+
+	if cfg.Reports.Markdown != "" {
+		md := pipeline.Markdown("Pipeline", report.Results)
+		if err := os.WriteFile(cfg.Reports.Markdown, []byte(md), 0644); err != nil {
+			return fmt.Errorf("cannot write report: %w", err)
+		}
+	}
+	if cfg.Reports.JSON != "" {
+		if err := writeJSON(cfg.Reports.JSON, report); err != nil {
+			return fmt.Errorf("cannot write report: %w", err)
+		}
+	}
+	t := &table{Header: []string{"STAGE", "STATUS", "DURATION", "SUMMARY"}}
+	for _, r := range report.Results {
+		t.Rows = append(t.Rows, []string{r.Stage, string(r.Status), r.Duration.Round(100 * time.Millisecond).String(), r.Summary})
+	}
+	if err := printOutput(report, t); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		// The stage errors were already logged, exit with the code of the
+		// first one.
+		return withExitCode(exitCode(failed[0]), fmt.Errorf("%c %d of %d stage(s) failed", cross, len(failed), len(cfg.Stages)))
+	}
+	log.Printf("%c Pipeline passed in %s", tick, report.Duration.Round(100*time.Millisecond))
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type cmdCompletion struct {
+	Positional struct {
+		Shell string `positional-arg-name:"shell" choice:"bash" choice:"zsh" choice:"fish"`
+	} `positional-args:"yes" required:"true"`
+}
+
+func init() {
+	parser.AddCommand(
+		"completion",
+		"Print a shell completion script",
+		"The completion command prints the completion script for bash, zsh or fish. Besides commands and flags, it completes slice names and slice definition files from the release given with --release. For example, add this to ~/.bashrc:\n\n    source <(sdf completion bash)",
+		&cmdCompletion{},
+	)
+}
+
+// The scripts run the program with GO_FLAGS_COMPLETION set, so that it prints
+// the completions of the command line instead of running it. %[1]s is the
+// program name and %[2]s the name of the completion function.
+var completionScripts = map[string]string{
+	"bash": `%[2]s() {
+	local IFS=$'\n'
+	COMPREPLY=($(GO_FLAGS_COMPLETION=1 "${COMP_WORDS[0]}" "${COMP_WORDS[@]:1:$COMP_CWORD}"))
+	if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == */ ]]; then
+		compopt -o nospace
+	fi
+	return 0
+}
+complete -F %[2]s %[1]s
+`,
+	"zsh": `#compdef %[1]s
+%[2]s() {
+	local -a items
+	items=("${(@f)$(GO_FLAGS_COMPLETION=1 "${words[1]}" "${(@)words[2,$CURRENT]}")}")
+	compadd -Q -- "${items[@]}"
+}
+compdef %[2]s %[1]s
+`,
+	"fish": `function %[2]s
+	set -l args (commandline -opc)[2..-1] (commandline -ct)
+	env GO_FLAGS_COMPLETION=1 %[1]s $args
+end
+complete -c %[1]s -f -a '(%[2]s)'
+`,
+}
+
+func (c *cmdCompletion) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	script, ok := completionScripts[c.Positional.Shell]
+	if !ok {
+		return exitErrorf(exitUsage, "unsupported shell %q, want bash, zsh or fish", c.Positional.Shell)
+	}
+	prog := filepath.Base(os.Args[0])
+	fmt.Printf(script, prog, "__"+prog+"_complete")
+	return nil
+}
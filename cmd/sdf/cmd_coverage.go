@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/coverage"
+)
+
+type cmdCoverage struct {
+	Release string   `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Arch    string   `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Workers int      `short:"w" long:"workers" description:"Number of concurrent workers" default:"4"`
+	Min     []string `long:"min" description:"Minimum coverage, N% for the release or <package>=N% for a package (can be repeated)"`
+	Missing bool     `long:"missing" description:"List the files no slice installs"`
+	Output  string   `short:"o" long:"output" description:"Write the report as JSON to this file" path:"yes"`
+
+	Positional struct {
+		Packages []string `positional-arg-name:"packages"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"coverage",
+		"Report how much of the packages their slices install",
+		"The coverage command cuts all the slices of every package, by default all packages of the release, into a fresh root and computes the percentage of the files shipped in the deb that the slices install. With --min, it fails if the coverage of the release or of a package is below the threshold",
+		&cmdCoverage{},
+	)
+}
+
+type coverageResult struct {
+	pkg    string
+	slices []string
+	result *coverage.Package
+	err    error
+}
+
+func (c *cmdCoverage) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Workers <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --workers: %d", c.Workers)
+	}
+	var thresholds []*coverage.Threshold
+	for _, s := range c.Min {
+		t, err := coverage.ParseThreshold(s)
+		if err != nil {
+			return err
+		}
+		thresholds = append(thresholds, t)
+	}
+	if err := checkChisel(c.Release); err != nil {
+		return err
+	}
+	_, err := c.run(thresholds)
+	return err
+}
+
+// run computes the coverage of the packages and checks it against the
+// thresholds. The report is nil if the coverage could not be computed.
+func (c *cmdCoverage) run(thresholds []*coverage.Threshold) (*coverage.Report, error) {
+	r, err := chisel.ReadRelease(c.Release)
+	if err != nil {
+		return nil, withExitCode(exitFindings, err)
+	}
+	slices := make(map[string][]string)
+	for _, s := range r.Slices {
+		slices[s.Package] = append(slices[s.Package], s.Name)
+	}
+	pkgs := c.Positional.Packages
+	if len(pkgs) == 0 {
+		for pkg := range slices {
+			pkgs = append(pkgs, pkg)
+		}
+		sort.Strings(pkgs)
+	}
+	var results []*coverageResult
+	for _, pkg := range pkgs {
+		if len(slices[pkg]) == 0 {
+			return nil, fmt.Errorf("package %s has no slices in the release", pkg)
+		}
+		results = append(results, &coverageResult{pkg: pkg, slices: slices[pkg]})
+	}
+	forEachCut(c.Workers, results, func(r *coverageResult, cacheDir string, err error) {
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.result, r.err = c.compute(r, cacheDir)
+	})
+
+	var computed []*coverage.Package
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			log.Printf("%c %s: %s", cross, r.pkg, r.err)
+			continue
+		}
+		computed = append(computed, r.result)
+	}
+	report := coverage.NewReport(computed)
+	if err := printCoverage(report, c.Missing); err != nil {
+		return nil, err
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, report); err != nil {
+			return nil, fmt.Errorf("cannot write report: %w", err)
+		}
+	}
+	if failed > 0 {
+		return report, exitErrorf(exitInstall, "%c cannot compute the coverage of %d package(s)", cross, failed)
+	}
+	if below := report.Check(thresholds); len(below) > 0 {
+		for _, msg := range below {
+			log.Printf("%c %s", cross, msg)
+		}
+		return report, exitErrorf(exitFindings, "%c coverage is below %d threshold(s)", cross, len(below))
+	}
+	return report, nil
+}
+
+// compute installs all the slices of the package and compares the root
+// with the files of the deb chisel fetched.
+func (c *cmdCoverage) compute(r *coverageResult, cacheDir string) (*coverage.Package, error) {
+	root, err := os.MkdirTemp("", "sdf-coverage-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+	err = cut(context.Background(), &cutOptions{
+		Release:  c.Release,
+		Arch:     c.Arch,
+		Root:     root,
+		CacheDir: cacheDir,
+		Slices:   r.slices,
+	})
+	if err != nil {
+		return nil, err
+	}
+	debs, err := coverage.FindDebs(cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find debs: %w", err)
+	}
+	deb, ok := debs[r.pkg]
+	if !ok {
+		return nil, fmt.Errorf("cannot find the deb of %s in the chisel cache", r.pkg)
+	}
+	files, err := coverage.DebFiles(deb)
+	if err != nil {
+		return nil, err
+	}
+	return coverage.Compute(r.pkg, files, root)
+}
+
+// printCoverage prints the report, with a table of the paths missing from
+// the slices if missing is set.
+func printCoverage(r *coverage.Report, missing bool) error {
+	t := &table{Header: []string{"PACKAGE", "FILES", "COVERED", "COVERAGE"}}
+	for _, p := range r.Packages {
+		t.Rows = append(t.Rows, []string{p.Name, strconv.Itoa(p.Files), strconv.Itoa(p.Covered), fmt.Sprintf("%.1f%%", p.Percent())})
+	}
+	t.Rows = append(t.Rows, []string{"TOTAL", strconv.Itoa(r.Files), strconv.Itoa(r.Covered), fmt.Sprintf("%.1f%%", r.Percent())})
+	tables := []*table{t}
+	if missing {
+		m := &table{Header: []string{"PACKAGE", "MISSING"}}
+		for _, p := range r.Packages {
+			for _, path := range p.Missing {
+				m.Rows = append(m.Rows, []string{p.Name, path})
+			}
+		}
+		tables = append(tables, m)
+	}
+	return printOutput(r, tables...)
+}
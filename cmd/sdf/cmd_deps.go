@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type cmdDeps struct {
+	Release string `short:"r" long:"release" description:"Chisel release directory" required:"true" path:"yes"`
+	Reverse bool   `long:"reverse" description:"Show the slices installing the slice instead"`
+
+	Positional struct {
+		Slice sliceName `positional-arg-name:"slice"`
+	} `positional-args:"yes" required:"true"`
+}
+
+func init() {
+	parser.AddCommand(
+		"deps",
+		"Show the dependencies of a slice",
+		"The deps command shows the slices chisel installs along with the slice, following its essential slices. With --reverse, it shows the slices whose installation includes the slice",
+		&cmdDeps{},
+	)
+}
+
+// depInfo is a slice in the output of the deps command.
+type depInfo struct {
+	Slice   string `json:"slice"`
+	Defined bool   `json:"defined"`
+}
+
+func (c *cmdDeps) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	r, err := chisel.ReadRelease(c.Release)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	deps, t, err := depsOutput(r, string(c.Positional.Slice), c.Reverse)
+	if err != nil {
+		return err
+	}
+	return printOutput(deps, t)
+}
+
+// depsOutput returns the output of the deps command for the slice.
+func depsOutput(r *chisel.Release, name string, reverse bool) ([]*depInfo, *table, error) {
+	if r.Slice(name) == nil {
+		return nil, nil, fmt.Errorf("slice %s not found", name)
+	}
+	found := r.Deps(name)
+	if reverse {
+		found = r.RDeps(name)
+	}
+	deps := []*depInfo{}
+	t := &table{Header: []string{"SLICE", "DEFINED"}}
+	for _, dep := range found {
+		defined := r.Slice(dep) != nil
+		deps = append(deps, &depInfo{Slice: dep, Defined: defined})
+		mark := tick
+		if !defined {
+			mark = cross
+		}
+		t.Rows = append(t.Rows, []string{dep, string(mark)})
+	}
+	return deps, t, nil
+}
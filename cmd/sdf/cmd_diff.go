@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type cmdDiff struct {
+	Positional struct {
+		Old string `positional-arg-name:"old release"`
+		New string `positional-arg-name:"new release"`
+	} `positional-args:"yes" required:"true"`
+}
+
+func init() {
+	parser.AddCommand(
+		"diff",
+		"Show the slices that differ between releases",
+		"The diff command compares the slices of two release directories: it shows the slices added and removed, and for the slices of both, the essential slices and paths added or removed and the paths whose entries changed",
+		&cmdDiff{},
+	)
+}
+
+// sliceDiff is a slice in the output of the diff command.
+type sliceDiff struct {
+	Slice string `json:"slice"`
+	// Added, removed or changed.
+	Status           string   `json:"status"`
+	AddedEssential   []string `json:"added_essential,omitempty"`
+	RemovedEssential []string `json:"removed_essential,omitempty"`
+	AddedPaths       []string `json:"added_paths,omitempty"`
+	RemovedPaths     []string `json:"removed_paths,omitempty"`
+	// Paths of both with different entries.
+	ChangedPaths []string `json:"changed_paths,omitempty"`
+}
+
+func (c *cmdDiff) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	old, err := chisel.ReadRelease(c.Positional.Old)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release %s: %w", c.Positional.Old, err)
+	}
+	cur, err := chisel.ReadRelease(c.Positional.New)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release %s: %w", c.Positional.New, err)
+	}
+	diffs, t := diffOutput(old, cur)
+	return printOutput(diffs, t)
+}
+
+// diffOutput returns the output of the diff command, sorted by slice.
+func diffOutput(old, cur *chisel.Release) ([]*sliceDiff, *table) {
+	var sliceNames []string
+	for _, r := range []*chisel.Release{old, cur} {
+		for _, s := range r.Slices {
+			if !slices.Contains(sliceNames, s.Name) {
+				sliceNames = append(sliceNames, s.Name)
+			}
+		}
+	}
+	slices.Sort(sliceNames)
+
+	diffs := []*sliceDiff{}
+	t := &table{Header: []string{"SLICE", "STATUS", "CHANGES"}}
+	for _, name := range sliceNames {
+		a, b := old.Slice(name), cur.Slice(name)
+		d := &sliceDiff{Slice: name}
+		switch {
+		case a == nil:
+			d.Status = "added"
+		case b == nil:
+			d.Status = "removed"
+		default:
+			d.AddedEssential, d.RemovedEssential = difference(b.Essential, a.Essential), difference(a.Essential, b.Essential)
+			d.AddedPaths, d.RemovedPaths = difference(b.Contents, a.Contents), difference(a.Contents, b.Contents)
+			for _, p := range a.Contents {
+				if info, ok := b.Paths[p]; ok && !reflect.DeepEqual(a.Paths[p], info) {
+					d.ChangedPaths = append(d.ChangedPaths, p)
+				}
+			}
+			if d.AddedEssential == nil && d.RemovedEssential == nil && d.AddedPaths == nil && d.RemovedPaths == nil && d.ChangedPaths == nil {
+				continue
+			}
+			d.Status = "changed"
+		}
+		diffs = append(diffs, d)
+
+		var changes []string
+		for _, c := range []struct {
+			format string
+			values []string
+		}{
+			{"+essential %s", d.AddedEssential},
+			{"-essential %s", d.RemovedEssential},
+			{"+%s", d.AddedPaths},
+			{"-%s", d.RemovedPaths},
+			{"~%s", d.ChangedPaths},
+		} {
+			for _, v := range c.values {
+				changes = append(changes, fmt.Sprintf(c.format, v))
+			}
+		}
+		if len(changes) == 0 {
+			changes = []string{"-"}
+		}
+		t.Rows = append(t.Rows, []string{name, d.Status, strings.Join(changes, ", ")})
+	}
+	return diffs, t
+}
+
+// difference returns the values of a that are not in b, in their order.
+func difference(a, b []string) []string {
+	var found []string
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			found = append(found, v)
+		}
+	}
+	return found
+}
@@ -0,0 +1,95 @@
+package main_test
+
+import (
+	"reflect"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+func testRelease(slices ...*chisel.Slice) *chisel.Release {
+	files := make(map[string][]*chisel.Slice)
+	for _, s := range slices {
+		s.File = "/release/slices/" + s.Package + ".yaml"
+		files[s.File] = append(files[s.File], s)
+	}
+	return chisel.NewRelease("/release", &chisel.Config{}, files)
+}
+
+func TestDiffOutput(t *testing.T) {
+	old := testRelease(&chisel.Slice{
+		Name:      "hello_bins",
+		Package:   "hello",
+		Essential: []string{"hello_copyright"},
+		Contents:  []string{"/usr/bin/hello", "/usr/bin/hi"},
+		Paths: map[string]*chisel.PathInfo{
+			"/usr/bin/hello": {Kind: chisel.CopyPath},
+			"/usr/bin/hi":    {Kind: chisel.SymlinkPath, Info: "hello"},
+		},
+	}, &chisel.Slice{
+		Name:    "hello_copyright",
+		Package: "hello",
+	}, &chisel.Slice{
+		Name:    "hello_config",
+		Package: "hello",
+	})
+	cur := testRelease(&chisel.Slice{
+		Name:      "hello_bins",
+		Package:   "hello",
+		Essential: []string{"hello_libs"},
+		Contents:  []string{"/usr/bin/hello", "/usr/bin/hola"},
+		Paths: map[string]*chisel.PathInfo{
+			"/usr/bin/hello": {Kind: chisel.CopyPath, Mode: 0755},
+			"/usr/bin/hola":  {Kind: chisel.SymlinkPath, Info: "hello"},
+		},
+	}, &chisel.Slice{
+		Name:    "hello_libs",
+		Package: "hello",
+	}, &chisel.Slice{
+		Name:    "hello_config",
+		Package: "hello",
+	})
+	_, table := sdf.DiffOutput(old, cur)
+	want := [][]string{
+		{"hello_bins", "changed", "+essential hello_libs, -essential hello_copyright, +/usr/bin/hola, -/usr/bin/hi, ~/usr/bin/hello"},
+		{"hello_copyright", "removed", "-"},
+		{"hello_libs", "added", "-"},
+	}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Fatalf("have %q, want %q", table.Rows, want)
+	}
+	if diffs, _ := sdf.DiffOutput(old, old); len(diffs) != 0 {
+		t.Fatalf("have %d difference(s) with itself, want none", len(diffs))
+	}
+}
+
+func TestStatsOutput(t *testing.T) {
+	r := testRelease(&chisel.Slice{
+		Name:      "hello_bins",
+		Package:   "hello",
+		Essential: []string{"hello_copyright", "libc6_libs"},
+		Contents:  []string{"/usr/bin/hello", "/usr/bin/hi"},
+		Paths: map[string]*chisel.PathInfo{
+			"/usr/bin/hello": {Kind: chisel.CopyPath},
+			"/usr/bin/hi":    {Kind: chisel.SymlinkPath, Info: "hello"},
+		},
+	}, &chisel.Slice{
+		Name:     "hello_copyright",
+		Package:  "hello",
+		Contents: []string{"/usr/share/doc/hello/copyright"},
+		Paths:    map[string]*chisel.PathInfo{"/usr/share/doc/hello/copyright": {Kind: chisel.CopyPath}},
+	}, &chisel.Slice{
+		Name:    "libc6_libs",
+		Package: "libc6",
+	})
+	_, tables := sdf.StatsOutput(r)
+	var have [][]string
+	for _, t := range tables {
+		have = append(have, t.Rows...)
+	}
+	want := [][]string{{"2", "2", "3", "2", "3"}, {"copy", "2"}, {"symlink", "1"}}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/archive"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/doctor"
+)
+
+type cmdDoctor struct {
+	Release string        `short:"r" long:"release" description:"Also check chisel and the archives against this release" path:"yes"`
+	Arch    string        `short:"a" long:"arch" description:"Package architecture to check the archives for" default:"amd64"`
+	MinFree uint64        `long:"min-free" description:"Free space wanted in the temporary directory, in MiB" default:"2048"`
+	Offline bool          `long:"offline" description:"Do not check the access to the archives"`
+	Timeout time.Duration `long:"timeout" description:"How long to wait for every archive" default:"10s"`
+	Output  string        `short:"o" long:"output" description:"Write the results as JSON to this file" path:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"doctor",
+		"Check the environment",
+		"The doctor command checks what the other commands need: chisel on the PATH and, with --release, that its version supports the release and the archives of the release can be reached, the free space in the temporary directory, a container engine for service tests and the tools some commands run. It suggests a fix for every problem found, and fails if a problem prevents installing slices",
+		&cmdDoctor{},
+	)
+}
+
+func (c *cmdDoctor) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	ctx := context.Background()
+	var cfg *chisel.Config
+	if c.Release != "" {
+		var err error
+		if cfg, err = chisel.ParseConfig(filepath.Join(c.Release, "chisel.yaml")); err != nil {
+			return fmt.Errorf("cannot parse chisel.yaml: %w", err)
+		}
+	}
+
+	var results []*doctor.Result
+	format := ""
+	if cfg != nil {
+		format = cfg.Format
+	}
+	results = append(results, doctor.Chisel(ctx, format))
+	results = append(results, doctor.FreeSpace(os.TempDir(), c.MinFree<<20))
+	results = append(results, doctor.ContainerEngine(ctx))
+	for _, tool := range []struct {
+		name, purpose, fix string
+	}{
+		{"git", "to bisect and record provenance", `install git with "apt install git"`},
+		{"unshare", "to run smoke tests with the unshare backend", `install util-linux, or use --backend=chroot as root`},
+		{"dpkg-deb", "to compute coverage", `install dpkg with "apt install dpkg"`},
+		{"gpgv", "to verify the archive signatures", `install gpgv with "apt install gpgv"`},
+		{"zstd", "to read compressed manifests", `install zstd with "apt install zstd"`},
+	} {
+		results = append(results, doctor.Tool(tool.name, tool.purpose, doctor.Warning, tool.fix))
+	}
+	if cfg != nil && !c.Offline {
+		results = append(results, c.archives(ctx, cfg)...)
+	}
+
+	failed := false
+	for _, r := range results {
+		if r.Status == doctor.OK {
+			progressf("%c %s: %s", tick, r.Name, r.Message)
+			continue
+		}
+		mark := cross
+		switch r.Status {
+		case doctor.Warning:
+			mark = warn
+		case doctor.Failure:
+			failed = true
+		}
+		log.Printf("%c %s: %s", mark, r.Name, r.Message)
+		if r.Fix != "" {
+			log.Printf("    Fix: %s", r.Fix)
+		}
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, results); err != nil {
+			return fmt.Errorf("cannot write results: %w", err)
+		}
+	}
+	if failed {
+		return exitErrorf(exitEnvironment, "%c Problems found, see the fixes above", cross)
+	}
+	return nil
+}
+
+// archives checks the access to every suite of the archives of the release.
+func (c *cmdDoctor) archives(ctx context.Context, cfg *chisel.Config) []*doctor.Result {
+	var names []string
+	for name := range cfg.Archives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	client := &http.Client{Timeout: c.Timeout}
+	var results []*doctor.Result
+	for _, name := range names {
+		a := cfg.Archives[name]
+		if a.Pro != "" {
+			results = append(results, &doctor.Result{
+				Name:    fmt.Sprintf("archive %s", name),
+				Message: fmt.Sprintf("not checked, Ubuntu Pro archive %s needs credentials", a.Pro),
+			})
+			continue
+		}
+		for _, suite := range a.Suites {
+			results = append(results, doctor.Archive(ctx, client, name, archive.UbuntuURL(c.Arch), suite))
+		}
+	}
+	return results
+}
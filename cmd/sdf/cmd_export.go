@@ -0,0 +1,100 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+type cmdExport struct {
+	Root        string `long:"root" description:"Root directory chisel installed slices into" required:"true" path:"yes"`
+	Output      string `short:"o" long:"output" description:"Output file" required:"true" path:"yes"`
+	Format      string `long:"format" description:"Archive format (default: from the output extension)" choice:"tar" choice:"tar.gz" choice:"squashfs"`
+	ModTime     int64  `long:"mtime" description:"Modification time of all entries, in seconds since the epoch" env:"SOURCE_DATE_EPOCH"`
+	Compression string `long:"squashfs-comp" description:"Compression algorithm of squashfs images, e.g. zstd"`
+}
+
+func init() {
+	parser.AddCommand(
+		"export",
+		"Export a root as a reproducible archive",
+		"The export command writes a root as a tar archive or squashfs image with sorted entries, fixed modification times and root ownership, so that the same root always yields a byte-identical archive",
+		&cmdExport{},
+	)
+}
+
+func (c *cmdExport) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	format := c.Format
+	if format == "" {
+		switch {
+		case strings.HasSuffix(c.Output, ".tar.gz"), strings.HasSuffix(c.Output, ".tgz"):
+			format = "tar.gz"
+		case strings.HasSuffix(c.Output, ".squashfs"), strings.HasSuffix(c.Output, ".sqfs"):
+			format = "squashfs"
+		default:
+			format = "tar"
+		}
+	}
+	mtime := time.Unix(c.ModTime, 0)
+
+	var err error
+	switch format {
+	case "squashfs":
+		err = rootfs.WriteSquashfs(c.Output, c.Root, &rootfs.SquashfsOptions{
+			ModTime:     mtime,
+			Compression: c.Compression,
+		})
+	default:
+		err = writeTar(c.Output, c.Root, format == "tar.gz", mtime)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot export root: %w", err)
+	}
+
+	f, err := os.Open(c.Output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	log.Printf("%c Exported %s (sha256:%x)", tick, c.Output, h.Sum(nil))
+	return nil
+}
+
+// writeTar writes the reproducible tar archive of root to path, gzipped if
+// requested. The gzip header holds no name or time.
+func writeTar(path, root string, compress bool, mtime time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	if err := rootfs.WriteTar(w, root, &rootfs.TarOptions{ModTime: mtime}); err != nil {
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/fixtures"
+)
+
+type cmdFixtures struct {
+	Output        string   `short:"o" long:"output" description:"Directory to write the fixture into" required:"true" path:"yes"`
+	Arch          string   `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Suite         string   `long:"suite" description:"Suite of the archive" default:"noble"`
+	ChiselVersion string   `long:"chisel-version" description:"Version the stub chisel reports" default:"v1.1.0"`
+	Fail          []string `long:"fail" description:"Slice the stub chisel fails to install (can be repeated)"`
+	Serve         string   `long:"serve" description:"Serve the archive over HTTP on this address until interrupted"`
+}
+
+func init() {
+	parser.AddCommand(
+		"fixtures",
+		"Generate a hermetic test environment",
+		"The fixtures command writes a release slicing tiny generated packages, a local archive holding their debs and a stub chisel that installs the slices from the fixture without network access. Put the stub first in the PATH to run other commands, or CI pipelines, against the fixture release",
+		&cmdFixtures{},
+	)
+}
+
+func (c *cmdFixtures) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	f, err := fixtures.Write(c.Output, &fixtures.Options{
+		Suite:         c.Suite,
+		Arch:          c.Arch,
+		ChiselVersion: c.ChiselVersion,
+		Fail:          c.Fail,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot write fixture: %w", err)
+	}
+	log.Printf("%c Fixture written to %s", tick, f.Dir)
+	fmt.Printf("Release: %s\n", f.Release)
+	fmt.Printf("Archive: %s\n", f.Archive)
+	fmt.Printf("Chisel:  %s\n", f.Chisel)
+	fmt.Printf("\nexport PATH=%s:$PATH\n", filepath.Dir(f.Chisel))
+	if c.Serve == "" {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	l, err := net.Listen("tcp", c.Serve)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           http.FileServer(http.Dir(f.Archive)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	log.Printf("Serving the archive on http://%s/, interrupt to stop...", l.Addr())
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
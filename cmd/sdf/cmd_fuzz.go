@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/plan"
+)
+
+type cmdFuzz struct {
+	Release string `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Arch    string `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Workers int    `short:"w" long:"workers" description:"Number of concurrent workers" default:"4"`
+	Seed    uint64 `long:"seed" description:"Seed of the random combinations (default: a random one)"`
+	Runs    int    `short:"n" long:"runs" description:"Number of combinations to install" default:"100"`
+	MinSize int    `long:"min-size" description:"Minimum number of slices per combination" default:"2"`
+	MaxSize int    `long:"max-size" description:"Maximum number of slices per combination" default:"5"`
+	// Shrinking cuts the failing combination once per slice at least.
+	NoShrink bool   `long:"no-shrink" description:"Do not reduce the failing combinations to minimal ones"`
+	Output   string `short:"o" long:"output" description:"Write the failures as JSON to this file" path:"yes"`
+
+	Positional struct {
+		Slices []sliceName `positional-arg-name:"slices"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"fuzz",
+		"Install random combinations of slices",
+		"The fuzz command installs random combinations of slices, by default of all slices of the release, each into a fresh root, to find the conflicts and ordering bugs that only appear when specific slices are installed together. The combinations only depend on the seed, so that a run can be reproduced. Failing combinations are reduced to minimal ones that still fail",
+		&cmdFuzz{},
+	)
+}
+
+type fuzzFailure struct {
+	Run     int      `json:"run"`
+	Slices  []string `json:"slices"`
+	Minimal []string `json:"minimal,omitempty"`
+	Error   string   `json:"error"`
+}
+
+type fuzzReport struct {
+	Seed     uint64         `json:"seed"`
+	Runs     int            `json:"runs"`
+	Failures []*fuzzFailure `json:"failures"`
+}
+
+func (c *cmdFuzz) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Workers <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --workers: %d", c.Workers)
+	}
+	if c.Runs <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --runs: %d", c.Runs)
+	}
+	if c.MinSize <= 0 || c.MaxSize < c.MinSize {
+		return fmt.Errorf("invalid combination sizes: %d to %d", c.MinSize, c.MaxSize)
+	}
+	if err := checkChisel(c.Release); err != nil {
+		return err
+	}
+
+	r, err := chisel.ReadRelease(c.Release)
+	if err != nil {
+		return withExitCode(exitFindings, err)
+	}
+	slices := r.Slices
+	if len(c.Positional.Slices) > 0 {
+		slices = nil
+		for _, name := range names(c.Positional.Slices) {
+			s := r.Slice(name)
+			if s == nil {
+				return fmt.Errorf("slice %s not found in the release", name)
+			}
+			slices = append(slices, s)
+		}
+	}
+	seed := c.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	progressf("Installing %d combinations of %d slices with seed %d...", c.Runs, len(slices), seed)
+	groups := plan.Random(slices, &plan.RandomOptions{
+		Seed:  seed,
+		Count: c.Runs,
+		Min:   c.MinSize,
+		Max:   c.MaxSize,
+	})
+
+	failures := make([]*fuzzFailure, len(groups))
+	runs := make([]int, len(groups))
+	for i := range runs {
+		runs[i] = i
+	}
+	forEachCut(c.Workers, runs, func(i int, cacheDir string, err error) {
+		if err == nil {
+			err = c.cut(groups[i], cacheDir)
+		}
+		if err != nil {
+			failures[i] = &fuzzFailure{Run: i + 1, Slices: groups[i], Error: err.Error()}
+		}
+	})
+	report := &fuzzReport{Seed: seed, Runs: len(groups), Failures: []*fuzzFailure{}}
+	for _, f := range failures {
+		if f != nil {
+			report.Failures = append(report.Failures, f)
+		}
+	}
+
+	if !c.NoShrink {
+		forEachCut(c.Workers, report.Failures, func(f *fuzzFailure, cacheDir string, err error) {
+			if err != nil {
+				return
+			}
+			f.Minimal = plan.Shrink(f.Slices, func(group []string) bool {
+				return c.cut(group, cacheDir) != nil
+			})
+		})
+	}
+	for _, f := range report.Failures {
+		log.Printf("%c run %d: %s", cross, f.Run, strings.Join(f.Slices, " "))
+		if f.Minimal != nil {
+			log.Printf("    minimal: %s", strings.Join(f.Minimal, " "))
+		}
+		log.Printf("    %s", strings.ReplaceAll(f.Error, "\n", "\n    "))
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, report); err != nil {
+			return fmt.Errorf("cannot write failures: %w", err)
+		}
+	}
+	if n := len(report.Failures); n > 0 {
+		return exitErrorf(exitInstall, "%c %d of %d combination(s) failed, reproduce with --seed %d", cross, n, len(groups), seed)
+	}
+	log.Printf("%c all %d combinations installed", tick, len(groups))
+	return nil
+}
+
+// cut installs the slices into a fresh root.
+func (c *cmdFuzz) cut(slices []string, cacheDir string) error {
+	root, err := os.MkdirTemp("", "sdf-fuzz-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(root)
+	return cut(context.Background(), &cutOptions{
+		Release:  c.Release,
+		Arch:     c.Arch,
+		Root:     root,
+		CacheDir: cacheDir,
+		Slices:   slices,
+	})
+}
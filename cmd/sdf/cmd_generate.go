@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/buildfile"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/plan"
+)
+
+type cmdGenerate struct {
+	Format        string   `long:"format" description:"Build file to generate" choice:"dockerfile" choice:"rockcraft" default:"dockerfile"`
+	Release       string   `short:"r" long:"release" description:"Chisel release name, or release directory in the build context (default: ubuntu-<version>)"`
+	Ubuntu        string   `long:"ubuntu" description:"Ubuntu version of the build stage" default:"24.04"`
+	ChiselVersion string   `long:"chisel-version" description:"Chisel version to download in the build stage" default:"v1.1.0"`
+	Part          string   `long:"part" description:"Name of the rockcraft part" default:"slices"`
+	Prune         bool     `long:"prune" description:"Install only the top level slices"`
+	Keep          []string `long:"keep" description:"Slice to install even if pruned (can be repeated)"`
+	Output        string   `short:"o" long:"output" description:"Output file (default: stdout)" path:"yes"`
+	Check         bool     `long:"check" description:"Fail if the output file is not up to date instead of writing it"`
+
+	// The digests not given are found by downloading chisel, which is then
+	// trusted on first use.
+	ChiselSHA256 map[string]string `long:"chisel-sha256" description:"SHA-256 digest of the chisel tarball for a docker target architecture, as arch:digest (can be repeated; default: downloaded for every --target-arch)"`
+	TargetArches []string          `long:"target-arch" description:"Docker target architecture the Dockerfile builds for (can be repeated)" default:"amd64" default:"arm64"`
+	ChiselURL    string            `long:"chisel-url" description:"Base URL to download the chisel releases from" default:"https://github.com/canonical/chisel/releases/download"`
+
+	Positional struct {
+		Files []sliceFile `positional-arg-name:"slice definition files"`
+	} `positional-args:"yes" required:"true"`
+}
+
+func init() {
+	parser.AddCommand(
+		"generate",
+		"Generate build files installing slices",
+		"The generate command writes a multi-stage Dockerfile, or the parts of a rockcraft.yaml, that install all slices from the specified files. The Dockerfile verifies the chisel it downloads against its SHA-256 digest for the target architecture, given with --chisel-sha256 or found by downloading chisel for every --target-arch",
+		&cmdGenerate{},
+	)
+}
+
+func (c *cmdGenerate) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Check && c.Output == "" {
+		return fmt.Errorf("--check requires --output")
+	}
+	var slices []*chisel.Slice
+	for _, f := range names(c.Positional.Files) {
+		s, err := chisel.ParseSlices(f)
+		if err != nil {
+			return exitErrorf(exitFindings, "cannot parse slices from file %s: %w", f, err)
+		}
+		slices = append(slices, s...)
+	}
+	if c.Prune {
+		slices = plan.Prune(slices, &plan.PruneOptions{Keep: c.Keep})
+	}
+	opts := &buildfile.Options{
+		Release:       c.Release,
+		Ubuntu:        c.Ubuntu,
+		ChiselVersion: c.ChiselVersion,
+		Part:          c.Part,
+	}
+	if c.Format == "dockerfile" {
+		digests, err := c.chiselDigests()
+		if err != nil {
+			return err
+		}
+		opts.ChiselSHA256 = digests
+		opts.ChiselURL = c.ChiselURL
+	}
+	for _, s := range slices {
+		opts.Slices = append(opts.Slices, s.Name)
+	}
+	if opts.Release == "" {
+		opts.Release = "ubuntu-" + c.Ubuntu
+	} else if fi, err := os.Stat(opts.Release); err == nil && fi.IsDir() {
+		opts.LocalRelease = true
+	}
+
+	var data []byte
+	var err error
+	switch c.Format {
+	case "rockcraft":
+		data, err = buildfile.Rockcraft(opts)
+	default:
+		data, err = buildfile.Dockerfile(opts)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot generate %s: %w", c.Format, err)
+	}
+
+	switch {
+	case c.Check:
+		old, err := os.ReadFile(c.Output)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(old, data) {
+			return exitErrorf(exitFindings, "%c %s is out of date, run sdf generate to update it", cross, c.Output)
+		}
+		log.Printf("%c %s is up to date", tick, c.Output)
+		return nil
+	case c.Output != "":
+		return os.WriteFile(c.Output, data, 0644)
+	default:
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+}
+
+// chiselDigests returns the digests of the chisel tarballs of the target
+// architectures, downloading those that were not given.
+func (c *cmdGenerate) chiselDigests() (map[string]string, error) {
+	digests := maps.Clone(c.ChiselSHA256)
+	if digests == nil {
+		digests = make(map[string]string)
+	}
+	client := &http.Client{Timeout: 2 * time.Minute}
+	for _, arch := range c.TargetArches {
+		if digests[arch] != "" {
+			continue
+		}
+		progressf("Downloading chisel %s for %s...", c.ChiselVersion, arch)
+		digest, err := buildfile.ChiselSHA256(client, c.ChiselURL, c.ChiselVersion, arch)
+		if err != nil {
+			return nil, exitErrorf(exitEnvironment, "cannot find SHA-256 digest of chisel: %w", err)
+		}
+		digests[arch] = digest
+	}
+	return digests, nil
+}
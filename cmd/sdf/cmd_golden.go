@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+type cmdGolden struct {
+	Release string `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Arch    string `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Workers int    `short:"w" long:"workers" description:"Number of concurrent workers" default:"4"`
+	Dir     string `long:"dir" description:"Directory of the golden files (default: tests/golden in the release)" path:"yes"`
+	Update  bool   `long:"update" description:"Write the golden files instead of comparing with them"`
+
+	Positional struct {
+		Slices []sliceName `positional-arg-name:"slices"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"golden",
+		"Compare slices with their golden file lists",
+		"The golden command cuts every slice, by default all slices of the release, alone into a fresh root and compares the resulting files with the golden file of the slice at <dir>/<arch>/<slice>.golden. With --update, it writes the golden files instead, and removes those of slices that are gone",
+		&cmdGolden{},
+	)
+}
+
+type goldenResult struct {
+	slice string
+	have  []byte
+	err   error
+}
+
+func (c *cmdGolden) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Workers <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --workers: %d", c.Workers)
+	}
+	if err := checkChisel(c.Release); err != nil {
+		return err
+	}
+	dir := c.Dir
+	if dir == "" {
+		dir = filepath.Join(c.Release, "tests", "golden")
+	}
+	dir = filepath.Join(dir, c.Arch)
+
+	slices := names(c.Positional.Slices)
+	if len(slices) == 0 {
+		r, err := chisel.ReadRelease(c.Release)
+		if err != nil {
+			return withExitCode(exitFindings, err)
+		}
+		for _, s := range r.Slices {
+			slices = append(slices, s.Name)
+		}
+	}
+	var results []*goldenResult
+	for _, s := range slices {
+		results = append(results, &goldenResult{slice: s})
+	}
+	forEachCut(c.Workers, results, func(r *goldenResult, cacheDir string, err error) {
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.have, r.err = c.cut(r.slice, cacheDir)
+	})
+
+	if c.Update {
+		return c.update(dir, results)
+	}
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			log.Printf("%c %s: %s", cross, r.slice, r.err)
+			continue
+		}
+		want, err := os.ReadFile(filepath.Join(dir, r.slice+".golden"))
+		if errors.Is(err, fs.ErrNotExist) {
+			failed++
+			log.Printf("%c %s: no golden file, run with --update", cross, r.slice)
+			continue
+		} else if err != nil {
+			return err
+		}
+		if diff := slicetest.CompareGolden(r.have, want); len(diff) > 0 {
+			failed++
+			log.Printf("%c %s:\n    %s", cross, r.slice, strings.Join(colorDiff(diff), "\n    "))
+			continue
+		}
+		progressf("%c %s", tick, r.slice)
+	}
+	if failed > 0 {
+		return exitErrorf(exitFindings, "%c %d of %d slice(s) differ from their golden files", cross, failed, len(results))
+	}
+	return nil
+}
+
+// cut installs the slice alone and returns its golden file content.
+func (c *cmdGolden) cut(slice, cacheDir string) ([]byte, error) {
+	root, err := os.MkdirTemp("", "sdf-golden-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+	err = cut(context.Background(), &cutOptions{
+		Release:  c.Release,
+		Arch:     c.Arch,
+		Root:     root,
+		CacheDir: cacheDir,
+		Slices:   []string{slice},
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := rootfs.List(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list root: %w", err)
+	}
+	return slicetest.Golden(entries), nil
+}
+
+func (c *cmdGolden) update(dir string, results []*goldenResult) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var errs error
+	keep := make(map[string]bool)
+	for _, r := range results {
+		p := filepath.Join(dir, r.slice+".golden")
+		keep[p] = true
+		if r.err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%c %s: %w", cross, r.slice, r.err))
+			continue
+		}
+		old, err := os.ReadFile(p)
+		if err == nil && bytes.Equal(old, r.have) {
+			continue
+		}
+		if err := os.WriteFile(p, r.have, 0644); err != nil {
+			return err
+		}
+		progressf("%c Updated %s", tick, p)
+	}
+	if len(c.Positional.Slices) > 0 {
+		return errs
+	}
+	// All slices were cut, the remaining golden files are stale.
+	stale, err := filepath.Glob(filepath.Join(dir, "*.golden"))
+	if err != nil {
+		return err
+	}
+	for _, p := range stale {
+		if !keep[p] {
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+			progressf("%c Removed %s", tick, p)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/oci"
+)
+
+type cmdImage struct {
+	Root       string   `long:"root" description:"Root directory chisel installed slices into" required:"true" path:"yes"`
+	Arch       string   `short:"a" long:"arch" description:"Package architecture of the root" default:"amd64"`
+	Entrypoint []string `long:"entrypoint" description:"Entrypoint argument (can be repeated)"`
+	Cmd        []string `long:"cmd" description:"Command argument (can be repeated)"`
+	User       string   `long:"user" description:"User to run the image as"`
+	Env        []string `long:"env" description:"Environment variable as KEY=VALUE (can be repeated)"`
+	Labels     []string `long:"label" description:"Label as KEY=VALUE (can be repeated)"`
+	WorkingDir string   `long:"workdir" description:"Working directory of the image"`
+	Tag        string   `long:"tag" description:"Tag of the image in the layout or archive" default:"latest"`
+	Output     string   `short:"o" long:"output" description:"OCI layout directory, or archive if it ends in .tar" path:"yes"`
+	Push       string   `long:"push" description:"Push the image to this reference, e.g. ghcr.io/org/name:tag"`
+	Username   string   `long:"username" description:"Registry username"`
+	Password   string   `long:"password" description:"Registry password or token" env:"SDF_REGISTRY_PASSWORD"`
+	Insecure   bool     `long:"insecure" description:"Use plain HTTP for the registry"`
+}
+
+func init() {
+	parser.AddCommand(
+		"image",
+		"Export a root as an OCI image",
+		"The image command packs a root into a reproducible single-layer OCI image, written as a layout directory or archive, or pushed to a registry",
+		&cmdImage{},
+	)
+}
+
+func (c *cmdImage) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Output == "" && c.Push == "" {
+		return fmt.Errorf("either --output or --push is required")
+	}
+	for _, env := range c.Env {
+		if !strings.Contains(env, "=") {
+			return fmt.Errorf("invalid environment variable %q, want KEY=VALUE", env)
+		}
+	}
+	labels := make(map[string]string)
+	for _, label := range c.Labels {
+		k, v, ok := strings.Cut(label, "=")
+		if !ok {
+			return fmt.Errorf("invalid label %q, want KEY=VALUE", label)
+		}
+		labels[k] = v
+	}
+	img, err := oci.Build(c.Root, &oci.Options{
+		Arch:       c.Arch,
+		User:       c.User,
+		Env:        c.Env,
+		Entrypoint: c.Entrypoint,
+		Cmd:        c.Cmd,
+		WorkingDir: c.WorkingDir,
+		Labels:     labels,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot build image: %w", err)
+	}
+	log.Printf("%c Built image %s", tick, img.Manifest.Digest)
+
+	if c.Output != "" {
+		if err := c.write(img); err != nil {
+			return err
+		}
+		log.Printf("%c Wrote %s", tick, c.Output)
+	}
+	if c.Push != "" {
+		ref, err := oci.ParseReference(c.Push)
+		if err != nil {
+			return err
+		}
+		err = img.Push(ref, &oci.PushOptions{
+			Username: c.Username,
+			Password: c.Password,
+			Insecure: c.Insecure,
+		})
+		if err != nil {
+			return fmt.Errorf("%c Could not push %s: %w", cross, ref, err)
+		}
+		log.Printf("%c Pushed %s", tick, ref)
+	}
+	return nil
+}
+
+func (c *cmdImage) write(img *oci.Image) error {
+	if !strings.HasSuffix(c.Output, ".tar") {
+		if err := img.WriteLayout(c.Output, c.Tag); err != nil {
+			return fmt.Errorf("cannot write image layout: %w", err)
+		}
+		return nil
+	}
+	f, err := os.Create(c.Output)
+	if err != nil {
+		return err
+	}
+	if err := img.WriteArchive(f, c.Tag); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot write image archive: %w", err)
+	}
+	return f.Close()
+}
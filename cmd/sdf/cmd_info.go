@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type cmdInfo struct {
+	Release string `short:"r" long:"release" description:"Chisel release directory" required:"true" path:"yes"`
+
+	Positional struct {
+		Slices []sliceName `positional-arg-name:"slices"`
+	} `positional-args:"yes" required:"true"`
+}
+
+func init() {
+	parser.AddCommand(
+		"info",
+		"Show the details of slices",
+		"The info command shows the definition file, essential slices and contents of every slice given, along with the slices installed with it (deps) and the slices installing it (rdeps)",
+		&cmdInfo{},
+	)
+}
+
+func (c *cmdInfo) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	r, err := chisel.ReadRelease(c.Release)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	slices, tables, err := infoOutput(r, names(c.Positional.Slices))
+	if err != nil {
+		return err
+	}
+	return printOutput(slices, tables...)
+}
+
+// infoOutput returns the output of the info command for the slices.
+func infoOutput(r *chisel.Release, sliceNames []string) ([]*sliceInfo, []*table, error) {
+	var slices []*sliceInfo
+	var tables []*table
+	for _, name := range sliceNames {
+		s := r.Slice(name)
+		if s == nil {
+			return nil, nil, fmt.Errorf("slice %s not found", name)
+		}
+		info := newSliceInfo(r, s)
+		info.Deps = r.Deps(name)
+		info.RDeps = r.RDeps(name)
+		slices = append(slices, info)
+
+		t := &table{Header: []string{"FIELD", "VALUE"}}
+		t.Rows = append(t.Rows, []string{"name", info.Name}, []string{"package", info.Package}, []string{"file", info.File})
+		for _, field := range []struct {
+			name   string
+			values []string
+		}{
+			{"essential", info.Essential},
+			{"deps", info.Deps},
+			{"rdeps", info.RDeps},
+			{"contents", info.Contents},
+		} {
+			if len(field.values) == 0 {
+				t.Rows = append(t.Rows, []string{field.name, "-"})
+			}
+			for i, v := range field.values {
+				label := field.name
+				if i > 0 {
+					label = ""
+				}
+				t.Rows = append(t.Rows, []string{label, v})
+			}
+		}
+		tables = append(tables, t)
+	}
+	return slices, tables, nil
+}
@@ -5,46 +5,76 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/rebornplusplus/chisel-tools/internal/archive"
 	"github.com/rebornplusplus/chisel-tools/internal/chisel"
-	"github.com/rebornplusplus/chisel-tools/internal/rmadison"
-)
-
-const (
-	tick  = '\u2713'
-	cross = '\u2717'
+	"github.com/rebornplusplus/chisel-tools/internal/events"
+	"github.com/rebornplusplus/chisel-tools/internal/graph"
+	"github.com/rebornplusplus/chisel-tools/internal/plan"
+	"github.com/rebornplusplus/chisel-tools/internal/results"
 )
 
 type cmdInstall struct {
-	Release string `short:"r" long:"release" description:"Chisel release path" required:"true"`
-	Arch    string `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Release string `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Arch    string `short:"a" long:"arch" description:"Package architectures, comma-separated, or all for all of those chisel supports" default:"amd64"`
 	Workers int    `short:"w" long:"workers" description:"Number of concurrent workers" default:"10"`
 
 	// You may use [Combine] and [Prune] together. The slices will be pruned
 	// first and then combined to install only the top level slices in one go.
-	Combine bool `long:"combine" description:"Install all slices in one go"`
-	Prune   bool `long:"prune" description:"Install only the top level slices"`
+	Combine   bool     `long:"combine" description:"Install all slices in one go"`
+	Prune     bool     `long:"prune" description:"Install only the top level slices"`
+	Keep      []string `long:"keep" description:"Slice to install even if pruned (can be repeated)"`
+	ByPackage bool     `long:"group-by-package" description:"Install the slices of a package in one go"`
+	GroupSize int      `long:"group-size" description:"Maximum number of slices to install in one go"`
+
+	// The slices affected by the changes are selected first, and may then
+	// be pruned and combined.
+	ChangedSince string `long:"changed-since" description:"Install only the slices of the files changed since this git ref and those depending on them"`
 
 	Continue bool `short:"c" long:"continue-on-error" description:"Continue on installation errors"`
 	Ignore   bool `long:"ignore-missing" description:"Ignore missing packages for an arch"`
 	Ensure   bool `long:"ensure-existence" description:"Ensure package existence for at least one arch"`
+	// Base URL of the archive to find the packages in, for tests and
+	// mirrors.
+	ArchiveURL string `long:"archive-url" description:"Archive to find the packages in for --ignore-missing and --ensure-existence, instead of the Ubuntu archive of each arch"`
+
+	Provenance string `long:"provenance" description:"Directory to write the SLSA provenance of each install into" path:"yes"`
+	Verify     string `long:"verify-archives" description:"Verify the signatures and hashes of everything chisel fetched and write the report to this file" path:"yes"`
+
+	Report       string `long:"report" description:"Write the results of the tasks to this file" path:"yes"`
+	ReportFormat string `long:"report-format" description:"Format of the report (default: junit if the file ends in .xml, json otherwise)" choice:"json" choice:"junit"`
+
+	watchOptions
+	hookOptions
 
 	Positional struct {
-		Files []string `positional-arg-name:"slice definition files"`
-	} `positional-args:"yes" required:"true"`
+		Files []sliceFile `positional-arg-name:"slice definition files"`
+	} `positional-args:"yes"`
+
+	// Handlers to subscribe to the events of the run, besides the hooks.
+	handlers []events.Handler
+	// Files changed in the release, to install only the slices affected by
+	// them if not nil.
+	changed []string
+	// Slices of the changed files before the changes.
+	base []*chisel.Slice
 }
 
 func init() {
 	parser.AddCommand(
 		"install",
 		"Install slices",
-		"The install command installs all slices from the specified files",
+		"The install command installs all slices from the specified files, for every architecture of --arch. With --ignore-missing, the slices of the packages not in the Packages indexes of the archives for an architecture are not installed for it, and with --ensure-existence, the command fails if a package is in the indexes of no architecture. With --watch, it keeps running and installs the slices of the files again whenever they change in the release. With --changed-since, it installs only the slices of the files changed since the git ref, of all slice definition files of the release if none are given, along with every slice depending on them, or on the slices they defined at the merge base of the ref, through its essential slices, or all slices if chisel.yaml changed. Once done, it shows the result of every task and, with --report, writes them along with the output of chisel as JSON or JUnit XML. It fails if any task failed, going on with the others first with --continue-on-error",
 		&cmdInstall{},
 	)
 }
@@ -54,121 +84,341 @@ func (c *cmdInstall) Execute(args []string) error {
 		return ErrExtraArgs
 	}
 	if c.Workers <= 0 {
-		return fmt.Errorf("invalid value for --workers: %d", c.Workers)
+		return exitErrorf(exitUsage, "invalid value for --workers: %d", c.Workers)
+	}
+	if c.GroupSize < 0 {
+		return exitErrorf(exitUsage, "invalid value for --group-size: %d", c.GroupSize)
+	}
+	if _, err := parseArches(c.Arch); err != nil {
+		return err
+	}
+	files := names(c.Positional.Files)
+	if c.ChangedSince != "" {
+		if c.Watch {
+			return exitErrorf(exitUsage, "cannot use --changed-since with --watch")
+		}
+		var err error
+		if c.changed, err = changedFiles(c.Release, c.ChangedSince); err != nil {
+			return err
+		}
+		if c.base, err = baseSlices(c.Release, c.ChangedSince, c.changed); err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			if files, err = chisel.SliceFiles(c.Release); err != nil {
+				return err
+			}
+		}
+	} else if len(files) == 0 {
+		return exitErrorf(exitUsage, "the required argument `slice definition files` was not provided")
 	}
-	if len(c.Positional.Files) == 0 {
+	if len(files) == 0 {
 		return nil // There is nothing to do.
 	}
+	if err := checkChisel(c.Release); err != nil {
+		return err
+	}
+	err := c.finish(c.run(files))
+	if !c.Watch {
+		return err
+	}
+	if err != nil {
+		log.Print(err)
+	}
+	return c.watch(c.Release, func(changed []string) error {
+		affected := affectedFiles(c.Release, files, changed)
+		if len(affected) == 0 {
+			return nil
+		}
+		return c.finish(c.run(affected))
+	})
+}
+
+// finish shows the results of the tasks of the report, if any, and writes
+// the report, and returns the error of the run.
+func (c *cmdInstall) finish(report *results.Report, err error) error {
+	if report == nil {
+		return err
+	}
+	if c.Report != "" {
+		if werr := writeReport(c.Report, c.ReportFormat, report); werr != nil {
+			return errors.Join(err, fmt.Errorf("cannot write report: %w", werr))
+		}
+	}
+	if len(report.Results) == 0 {
+		return err
+	}
+	t := &table{Header: []string{"SLICES", "ARCH", "STATUS", "DURATION"}}
+	for _, r := range report.Results {
+		status := "passed"
+		switch {
+		case r.Skipped != "":
+			status = "skipped: " + r.Skipped
+		case !r.Passed:
+			status = "failed"
+		}
+		t.Rows = append(t.Rows, []string{r.Name(), r.Arch, status, r.Duration.Round(100 * time.Millisecond).String()})
+	}
+	if perr := printOutput(report, t); perr != nil {
+		return errors.Join(err, perr)
+	}
+	return err
+}
+
+// writeReport writes the report to path in the format, or in the one of the
+// extension of path if empty.
+func writeReport(path, format string, report *results.Report) error {
+	if format == "" {
+		format = "json"
+		if filepath.Ext(path) == ".xml" {
+			format = "junit"
+		}
+	}
+	if format == "json" {
+		return writeJSON(path, report)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := results.WriteJUnit(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-	var slices []*chisel.Slice
-	for _, f := range c.Positional.Files {
+// run installs the slices of the slice definition files and returns the
+// report of the tasks, nil if the slices could not be planned.
+func (c *cmdInstall) run(files []string) (*results.Report, error) {
+	// Interrupting stops the fetching of the indexes as well as the
+	// installation.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var all []*chisel.Slice
+	for _, f := range files {
 		s, err := chisel.ParseSlices(f)
 		if err != nil {
-			return fmt.Errorf("cannot parse slices from file %s: %w", f, err)
+			return nil, exitErrorf(exitFindings, "cannot parse slices from file %s: %w", f, err)
 		}
-		slices = append(slices, s...)
+		all = append(all, s...)
+	}
+
+	if c.changed != nil {
+		all = affectedSlices(c.Release, all, c.base, c.changed)
+		progressf("Affected by the changes: %d slice(s)", len(all))
+	}
+
+	arches, err := parseArches(c.Arch)
+	if err != nil {
+		return nil, err
+	}
+	// Slices to install by arch.
+	archSlices := make(map[string][]*chisel.Slice)
+	for _, arch := range arches {
+		archSlices[arch] = all
 	}
 
 	// "Ensure" and "Ignore" packages before pruning the slices, because once
 	// pruned, some packages may completely be omitted from these checks.
 	if c.Ensure || c.Ignore {
-		pkgInfo, err := c.queryArchive(slices)
+		find, err := c.packageFinder(ctx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if c.Ensure {
-			if err := ensurePackages(slices, pkgInfo); err != nil {
-				return fmt.Errorf("%c Could not ensure packages: %s", cross, err)
+			// Look for the packages on the other arches too, once not
+			// found on those to install.
+			ensureArches := arches
+			for _, arch := range chisel.Arches {
+				if !slices.Contains(ensureArches, arch) {
+					ensureArches = append(ensureArches, arch)
+				}
+			}
+			if err := ensurePackages(all, find, ensureArches); err != nil {
+				// The archives that cannot be reached say nothing
+				// about the packages.
+				var e *exitError
+				if errors.As(err, &e) {
+					return nil, err
+				}
+				return nil, exitErrorf(exitFindings, "%c Could not ensure packages: %s", cross, err)
 			}
 		}
 		if c.Ignore {
-			slices = ignoreMissing(slices, pkgInfo, c.Arch)
+			for _, arch := range arches {
+				found, err := ignoreMissing(all, find, arch)
+				if err != nil {
+					return nil, err
+				}
+				archSlices[arch] = found
+			}
 		}
 	}
 
-	if c.Prune {
-		slices = prune(slices)
+	var tasks []*task
+	for _, arch := range arches {
+		s := archSlices[arch]
+		if c.Prune {
+			progressf("Pruning the list of slices...")
+			s = plan.Prune(s, &plan.PruneOptions{Keep: c.Keep})
+		}
+		g := plan.Group(s, &plan.GroupOptions{
+			Combine:   c.Combine,
+			ByPackage: c.ByPackage,
+			Size:      c.GroupSize,
+		})
+		for _, group := range g {
+			tasks = append(tasks, &task{
+				args:     []string{"cut", "--release", c.Release, "--arch", arch},
+				arch:     arch,
+				slices:   group,
+				showArch: len(arches) > 1,
+			})
+		}
 	}
-
-	g := group(slices, c.Combine)
-	return c.install(g)
+	return c.install(ctx, tasks)
 }
 
-// Group slices for installation. If combine is true, create only one group with
-// all slices in it.
-func group(slices []*chisel.Slice, combine bool) [][]string {
-	var grouped [][]string
-	if combine {
-		var names []string
-		for _, s := range slices {
+// affectedSlices returns the slices of the packages whose slice definition
+// files changed, along with the slices depending on them, in their order.
+// The slices of the base, those of the changed files before the changes,
+// count as changed too, so that the slices depending on the slices that
+// were removed or renamed are affected. All slices are affected if
+// chisel.yaml changed.
+func affectedSlices(release string, all, base []*chisel.Slice, changed []string) []*chisel.Slice {
+	pkgs, whole := changedPackages(release, changed)
+	if whole {
+		return all
+	}
+	g := &graph.Graph{}
+	var names []string
+	for _, s := range base {
+		g.Add(s.Name, s.Essential...)
+		names = append(names, s.Name)
+	}
+	for _, s := range all {
+		g.Add(s.Name, s.Essential...)
+		if pkgs[s.Package] {
 			names = append(names, s.Name)
 		}
-		grouped = append(grouped, names)
-	} else {
-		for _, s := range slices {
-			grouped = append(grouped, []string{s.Name})
+	}
+	affected := make(map[string]bool)
+	for _, name := range append(names, g.RDeps(names...)...) {
+		affected[name] = true
+	}
+	var found []*chisel.Slice
+	for _, s := range all {
+		if affected[s.Name] {
+			found = append(found, s)
 		}
 	}
-	return grouped
+	return found
 }
 
-// Prune the list of slices and return only the top-level slices that no slice
-// depends on. Installing these slices alone should cover all of the slices.
-// It depends on the acyclic dependency policy of chisel slices.
-func prune(slices []*chisel.Slice) []*chisel.Slice {
-	log.Print("Pruning the list of slices...")
-
-	pending := make(map[string]*chisel.Slice)
-	for _, s := range slices {
-		pending[s.Name] = s
+// parseArches returns the architectures of the --arch value.
+func parseArches(value string) ([]string, error) {
+	if value == "all" {
+		return slices.Clone(chisel.Arches), nil
 	}
-	for _, s := range slices {
-		for _, e := range s.Essential {
-			delete(pending, e)
+	var arches []string
+	for _, arch := range strings.Split(value, ",") {
+		arch = strings.TrimSpace(arch)
+		if !slices.Contains(chisel.Arches, arch) {
+			return nil, exitErrorf(exitUsage, "invalid value for --arch: %q, want all or some of %s", arch, strings.Join(chisel.Arches, ", "))
 		}
-	}
-	var todo []*chisel.Slice
-	for _, s := range slices {
-		if _, ok := pending[s.Name]; ok {
-			todo = append(todo, s)
+		if !slices.Contains(arches, arch) {
+			arches = append(arches, arch)
 		}
 	}
-	return todo
+	return arches, nil
 }
 
-// Install the groups of slices, concurrently.
-func (c *cmdInstall) install(slices [][]string) error {
-	if len(slices) == 0 {
+// Install the groups of slices of the tasks, concurrently, and return the
+// report of those that finished. The installation is interrupted once ctx is
+// done.
+func (c *cmdInstall) install(ctx context.Context, all []*task) (report *results.Report, err error) {
+	if len(all) == 0 {
 		log.Printf("%c Nothing to install :)", tick)
 	}
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	bus, closeBus := c.newBus(c.handlers...)
+	defer closeBus()
+	start := time.Now()
+	failed := 0
+	defer func() {
+		bus.Publish(events.RunCompleted, &events.Run{
+			Command:  "install",
+			Tasks:    len(all),
+			Failed:   failed,
+			Duration: time.Since(start),
+		})
+	}()
 
-	tasks := make(chan *task, len(slices)) // Tasks to finish.
-	errs := make(chan error, len(slices))  // Errors from the tasks, if any.
-	for _, s := range slices {
-		tasks <- &task{
-			args:   []string{"cut", "--release", c.Release, "--arch", c.Arch},
-			slices: s,
+	var prov *provenance
+	if c.Provenance != "" {
+		cfg, err := chisel.ParseConfig(filepath.Join(c.Release, "chisel.yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse chisel.yaml: %w", err)
+		}
+		if prov, err = newProvenance(c.Provenance, c.Release, cfg); err != nil {
+			return nil, err
 		}
 	}
+	collector := results.NewCollector(len(all))
+	defer func() {
+		report = collector.Report()
+	}()
+
+	tasks := make(chan *task, len(all)) // Tasks to finish.
+	errs := make(chan error, len(all))  // Errors from the tasks, if any.
+	for _, t := range all {
+		t.provenance = prov
+		tasks <- t
+	}
 	close(tasks)
 
-	done := make(chan bool) // Indicates that the workers are done.
+	done := make(chan struct{}) // Closed once the workers are done.
 	var wg sync.WaitGroup
-	for range min(c.Workers, len(slices)) {
+	var cacheDirs []string
+	defer func() {
+		for _, dir := range cacheDirs {
+			workerCaches.put(dir)
+		}
+	}()
+	// Stop the workers before their caches are put back and the report is
+	// made, and record the tasks they did not start as skipped.
+	defer func() {
+		reason := "not started"
+		if ctx.Err() != nil {
+			reason = skipReason(ctx)
+		}
+		cancel(errors.New("installation stopped"))
+		wg.Wait()
+		for t := range tasks {
+			collector.Add(&results.Result{Slices: t.slices, Arch: t.arch, Skipped: reason})
+		}
+	}()
+	for range min(c.Workers, len(all)) {
+		// We are using an independent cache directory for chisel in each
+		// worker, see [worker].
+		cacheDir, err := workerCaches.get()
+		if err != nil {
+			return nil, fmt.Errorf("cannot create chisel cache directory: %w", err)
+		}
+		cacheDirs = append(cacheDirs, cacheDir)
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			worker(ctx, tasks, errs)
+			worker(ctx, tasks, errs, bus, collector, cacheDir)
 		}()
 	}
 	go func() {
 		wg.Wait()
-		// Wait 1 second before sending the done signal as the workers might
-		// send an error to the "errs" channel at the same time.
-		time.Sleep(1 * time.Second)
-		done <- true
+		close(done)
 	}()
 
 	var allErrs error
@@ -176,31 +426,102 @@ loop:
 	for {
 		select {
 		case <-done:
-			break loop
+			// The workers are done, but the errors they sent last may
+			// not be handled yet.
+			if len(errs) == 0 {
+				break loop
+			}
 		case err := <-errs:
 			if err == nil {
 				continue
 			}
+			failed++
+			if ctx.Err() != nil {
+				// The error is of chisel being killed on interrupt.
+				return nil, fmt.Errorf("%c Installation interrupted: %w", cross, ctx.Err())
+			}
 			if !c.Continue {
-				cancel()
-				return err
+				cancel(errTaskFailed)
+				return nil, err
 			}
 			allErrs = errors.Join(allErrs, err)
 		}
 	}
-	return allErrs
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("%c Installation interrupted: %w", cross, ctx.Err())
+	}
+	if c.Verify != "" {
+		if err := c.verifyArchives(cacheDirs); err != nil {
+			allErrs = errors.Join(allErrs, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("%c Installation interrupted: %w", cross, ctx.Err())
+	}
+	return nil, allErrs
+}
+
+// verifyArchives checks everything chisel fetched into the cache directories
+// against the archive signatures, with the public keys from chisel.yaml, and
+// writes the report.
+func (c *cmdInstall) verifyArchives(cacheDirs []string) error {
+	cfg, err := chisel.ParseConfig(filepath.Join(c.Release, "chisel.yaml"))
+	if err != nil {
+		return fmt.Errorf("cannot parse chisel.yaml: %w", err)
+	}
+	report, err := archive.VerifyCache(cacheDirs, cfg.PublicKeys)
+	if err != nil {
+		return fmt.Errorf("cannot verify archives: %w", err)
+	}
+	if err := writeJSON(c.Verify, report); err != nil {
+		return fmt.Errorf("cannot write verification report: %w", err)
+	}
+	failed := report.Failed()
+	for _, e := range failed {
+		log.Printf("%c Unverified %s %s: %s", cross, e.Kind, e.SHA256, e.Message)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%c %d of %d fetched file(s) could not be verified", cross, len(failed), len(report.Entries))
+	}
+	log.Printf("%c Verified %d fetched file(s)", tick, len(report.Entries))
+	return nil
+}
+
+// errTaskFailed is the cause of the cancellation of the other tasks once one
+// failed, without --continue-on-error.
+var errTaskFailed = errors.New("another task failed")
+
+// skipReason returns why the tasks of the cancelled context are skipped.
+func skipReason(ctx context.Context) string {
+	if cause := context.Cause(ctx); cause != nil && cause != context.Canceled {
+		return cause.Error()
+	}
+	return "interrupted"
 }
 
 type task struct {
 	args   []string // Chisel arguments without positional slice name(s).
+	arch   string   // Package architecture, also part of args.
 	slices []string // Positional argument - slice name(s) to install.
+	// Whether to show the arch along with the slices, when installing for
+	// several.
+	showArch bool
+
+	provenance *provenance // Writes the provenance on success, if not nil.
+	// Root to install into and keep, instead of a temporary one, if not
+	// empty.
+	root string
+	// Checks the root on success, if not nil, with the chisel cache
+	// directory of the worker, the XDG_CACHE_HOME of chisel.
+	check func(root, cacheDir string) error
 }
 
 // worker does the actual installation of a list of slices by executing the
 // chisel cut command in another process.
 // It takes in a context to interrupt when necessary, a stream (channel) of
-// tasks and a channel to send errors to.
-func worker(ctx context.Context, tasks <-chan *task, errs chan<- error) {
+// tasks, a channel to send errors to, a bus to publish task events on, a
+// collector to add the task results to and the cache directory for chisel.
+func worker(ctx context.Context, tasks <-chan *task, errs chan<- error, bus *events.Bus, collector *results.Collector, cacheDir string) {
 	// We are using an independent cache directory for chisel in each worker.
 	// The reason is tricky to detect. When creating files in cache, Chisel
 	// temporary saves a file as "<digest>.tmp" in the cache directory.[^1]
@@ -216,22 +537,56 @@ func worker(ctx context.Context, tasks <-chan *task, errs chan<- error) {
 	//
 	// [^1]: https://github.com/canonical/chisel/blob/main/internal/cache/cache.go#L112
 	// [^2]: https://github.com/canonical/chisel/blob/main/internal/cache/cache.go#L80
-	cacheDir, err := os.MkdirTemp("", "")
-	if err != nil {
-		errs <- fmt.Errorf("cannot create temporary directory: %w", err)
-		return
-	}
 
 	do := func(task *task) {
 		name := strings.Join(task.slices, " ")
-		log.Printf("Installing %s...", name)
+		if task.showArch {
+			name += " on " + task.arch
+		}
+		progressf("Installing %s...", name)
+		bus.Publish(events.TaskStarted, &events.Task{Slices: task.slices, Arch: task.arch})
+
+		start := time.Now()
+		var out []byte
+		var err error
+		skipped := "" // Why chisel was cancelled, if it was.
+		defer func() {
+			e := &events.Task{
+				Slices:   task.slices,
+				Arch:     task.arch,
+				Duration: time.Since(start),
+			}
+			if err != nil {
+				e.Error = err.Error()
+			}
+			bus.Publish(events.TaskFinished, e)
+			r := &results.Result{
+				Slices:   task.slices,
+				Arch:     task.arch,
+				Duration: e.Duration,
+				Passed:   err == nil,
+				Skipped:  skipped,
+				Output:   string(out),
+			}
+			if skipped == "" && err != nil {
+				r.Error = strings.TrimPrefix(e.Error, string(cross)+" ")
+			}
+			collector.Add(r)
+		}()
 
-		dir, err := os.MkdirTemp("", "")
-		if err != nil {
-			errs <- fmt.Errorf("cannot create temporary directory: %w", err)
+		dir := task.root
+		if dir == "" {
+			if dir, err = os.MkdirTemp("", ""); err != nil {
+				err = fmt.Errorf("cannot create temporary directory: %w", err)
+				errs <- err
+				return
+			}
+			defer os.RemoveAll(dir)
+		} else if err = os.MkdirAll(dir, 0755); err != nil {
+			err = fmt.Errorf("cannot create root: %w", err)
+			errs <- err
 			return
 		}
-		defer os.RemoveAll(dir)
 
 		args := append(task.args, "--root", dir)
 		args = append(args, task.slices...)
@@ -240,15 +595,35 @@ func worker(ctx context.Context, tasks <-chan *task, errs chan<- error) {
 		cmd.Env = os.Environ()
 		cmd.Env = append(cmd.Env, "XDG_CACHE_HOME="+cacheDir)
 
-		if out, err := cmd.CombinedOutput(); err != nil {
-			if e, ok := err.(*exec.ExitError); ok && e.ProcessState.ExitCode() != -1 {
-				err = fmt.Errorf("%c Failed to install %s: %w", cross, name, err)
+		if out, err = cmd.CombinedOutput(); err != nil {
+			if ctx.Err() != nil {
+				// Chisel was killed, or not started, as the
+				// installation was stopped.
+				skipped = skipReason(ctx)
+			} else if e, ok := err.(*exec.ExitError); ok && e.ProcessState.ExitCode() != -1 {
+				err = exitErrorf(exitInstall, "%c Failed to install %s: %w", cross, name, err)
 				log.Printf("%s\n%s", err, out)
 			}
 			errs <- err
-		} else {
-			log.Printf("%c Installed %s", tick, name)
+			return
+		}
+		if task.provenance != nil {
+			if err = task.provenance.write(task, dir, start, time.Now()); err != nil {
+				err = exitErrorf(exitInstall, "%c Cannot write provenance of %s: %w", cross, name, err)
+				log.Print(err)
+				errs <- err
+				return
+			}
 		}
+		if task.check != nil {
+			if err = task.check(dir, cacheDir); err != nil {
+				err = fmt.Errorf("%c Cannot check %s: %w", cross, name, err)
+				log.Print(err)
+				errs <- err
+				return
+			}
+		}
+		progressf("%c Installed %s", tick, name)
 	}
 
 loop:
@@ -265,50 +640,77 @@ loop:
 	}
 }
 
-// Query the archives for package existence, using the chisel.yaml
-// configurations.
-func (c *cmdInstall) queryArchive(slices []*chisel.Slice) (map[string]*rmadison.Result, error) {
+// A packageFinder reports whether the package is in the archives for the
+// arch.
+type packageFinder func(arch, pkg string) (bool, error)
+
+// packageFinder returns the finder of the packages in the Packages indexes
+// of the archives of chisel.yaml, fetched until ctx is done. Ubuntu Pro
+// archives are left out, as they need credentials.
+func (c *cmdInstall) packageFinder(ctx context.Context) (packageFinder, error) {
 	p := filepath.Join(c.Release, "chisel.yaml")
 	cfg, err := chisel.ParseConfig(p)
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse chisel.yaml: %w", err)
 	}
-	ubuntu, ok := cfg.Archives["ubuntu"]
-	if !ok {
-		return nil, fmt.Errorf("no 'ubuntu' archive in chisel.yaml")
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-
-	var pkgs []string
-	exists := make(map[string]struct{})
-	for _, s := range slices {
-		if _, ok := exists[s.Package]; !ok {
-			pkgs = append(pkgs, s.Package)
-			exists[s.Package] = struct{}{}
+	var indexes []*archive.Index
+	for _, name := range slices.Sorted(maps.Keys(cfg.Archives)) {
+		a := cfg.Archives[name]
+		if a.Pro != "" {
+			progressf("Leaving out the %s archive %s when finding packages", a.Pro, name)
+			continue
 		}
+		x := &archive.Index{
+			Suites:     a.Suites,
+			Components: a.Components,
+			Client:     &http.Client{Timeout: 2 * time.Minute},
+		}
+		if c.ArchiveURL != "" {
+			x.URL = func(string) string { return c.ArchiveURL }
+		}
+		indexes = append(indexes, x)
 	}
-	res, err := rmadison.QueryWithContext(ctx, &rmadison.QueryOptions{
-		Component: ubuntu.Components,
-		Suite:     ubuntu.Suites,
-		Package:   pkgs,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("cannot query archives: %w", err)
-	}
-	pkgInfo := make(map[string]*rmadison.Result)
-	for _, r := range res {
-		pkgInfo[r.Package] = r
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("no archive to find the packages in")
 	}
-	return pkgInfo, nil
+	return func(arch, pkg string) (bool, error) {
+		for _, x := range indexes {
+			has, err := x.Has(ctx, arch, pkg)
+			if err != nil {
+				if ctx.Err() != nil {
+					return false, withExitCode(exitCancelled, fmt.Errorf("%c Installation interrupted: %w", cross, ctx.Err()))
+				}
+				return false, exitErrorf(exitEnvironment, "cannot find packages: %w", err)
+			}
+			if has {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, nil
 }
 
-// Ensure that the slice packages exist for at least one arch.
-func ensurePackages(slices []*chisel.Slice, pkgInfo map[string]*rmadison.Result) error {
-	log.Println("Ensuring slice packages existence...")
+// Ensure that the slice packages exist for at least one of the arches.
+func ensurePackages(slices []*chisel.Slice, find packageFinder, arches []string) error {
+	progressf("Ensuring slice packages existence...")
+	checked := make(map[string]bool)
 	for _, s := range slices {
-		if _, ok := pkgInfo[s.Package]; !ok {
+		if checked[s.Package] {
+			continue
+		}
+		checked[s.Package] = true
+		found := false
+		for _, arch := range arches {
+			has, err := find(arch, s.Package)
+			if err != nil {
+				return err
+			}
+			if has {
+				found = true
+				break
+			}
+		}
+		if !found {
 			return fmt.Errorf("package %q does not exist", s.Package)
 		}
 	}
@@ -316,27 +718,26 @@ func ensurePackages(slices []*chisel.Slice, pkgInfo map[string]*rmadison.Result)
 }
 
 // Ignore missing slice packages for a particular arch.
-func ignoreMissing(slices []*chisel.Slice, pkgInfo map[string]*rmadison.Result, arch string) []*chisel.Slice {
-	log.Printf("Ignoring missing slice packages on %s...", arch)
+func ignoreMissing(slices []*chisel.Slice, find packageFinder, arch string) ([]*chisel.Slice, error) {
+	progressf("Ignoring missing slice packages on %s...", arch)
 	var found []*chisel.Slice
 	missing := make(map[string]bool)
 	for _, s := range slices {
-		// Use the missing map to avoid costly [strings.Contains] operation
-		// below for all slices of a package.
-		if miss, ok := missing[s.Package]; ok {
-			if !miss {
-				found = append(found, s)
+		miss, ok := missing[s.Package]
+		if !ok {
+			has, err := find(arch, s.Package)
+			if err != nil {
+				return nil, err
+			}
+			miss = !has
+			missing[s.Package] = miss
+			if miss {
+				progressf("... ignored %s for %s", s.Package, arch)
 			}
-			continue
 		}
-		info, ok := pkgInfo[s.Package]
-		if ok && (strings.Contains(info.Arch, arch) || strings.Contains(info.Arch, "all")) {
+		if !miss {
 			found = append(found, s)
-			missing[s.Package] = false
-			continue
 		}
-		log.Printf("... ignored %s for %s", s.Package, arch)
-		missing[s.Package] = true
 	}
-	return found
+	return found, nil
 }
@@ -1,59 +1,30 @@
 package main_test
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
 	"github.com/rebornplusplus/chisel-tools/internal/chisel"
-	"github.com/rebornplusplus/chisel-tools/internal/rmadison"
+	"github.com/rebornplusplus/chisel-tools/internal/fixtures"
+	"github.com/rebornplusplus/chisel-tools/internal/results"
 )
 
-var pruneTests = []struct {
-	slices []*chisel.Slice
-	pruned []string
-}{{
-	slices: []*chisel.Slice{{
-		Name: "pkg1_slice1",
-		Essential: []string{
-			"pkg2_slice1",
-			"pkg1_slice2",
-		},
-	}, {
-		Name: "pkg1_slice2",
-	}, {
-		Name: "pkg2_slice1",
-		Essential: []string{
-			"pkg1_slice2",
-		},
-	}, {
-		Name: "pkg3_slice1",
-	}},
-	pruned: []string{
-		"pkg1_slice1",
-		"pkg3_slice1",
-	},
-}}
-
-func TestPrune(t *testing.T) {
-	for _, tc := range pruneTests {
-		slices := sdf.Prune(tc.slices)
-		var pruned []string
-		for _, s := range slices {
-			pruned = append(pruned, s.Name)
-		}
-		if !reflect.DeepEqual(pruned, tc.pruned) {
-			t.Fatalf("have %v, want %v", pruned, tc.pruned)
-		}
-	}
-}
-
 var ensureIgnoreTests = []struct {
-	slices    []*chisel.Slice             // List of slices to ensure, or ignore missing.
-	pkgs      map[string]*rmadison.Result // Package info from rmadison query.
-	arch      string                      // Package arch to ignore missing for.
-	ensureErr string                      // Expected ensure-existing errors.
-	found     []*chisel.Slice             // Expected slices which are not to be ignored.
+	slices    []*chisel.Slice     // List of slices to ensure, or ignore missing.
+	pkgs      map[string][]string // Arches of the packages in the archive indexes.
+	arches    []string            // Package arches to ensure existence for.
+	arch      string              // Package arch to ignore missing for.
+	ensureErr string              // Expected ensure-existing errors.
+	found     []*chisel.Slice     // Expected slices which are not to be ignored.
 }{{
 	slices: []*chisel.Slice{{
 		Name:    "hello_bins",
@@ -71,17 +42,12 @@ var ensureIgnoreTests = []struct {
 		Name:    "java_extra",
 		Package: "java",
 	}},
-	pkgs: map[string]*rmadison.Result{
-		"hello": {
-			Arch: "amd64, arm64, i386",
-		},
-		"libc6": {
-			Arch: "all",
-		},
-		"java": {
-			Arch: "arm64, i386",
-		},
+	pkgs: map[string][]string{
+		"hello": {"amd64", "arm64", "i386"},
+		"libc6": {"amd64", "arm64", "riscv64"},
+		"java":  {"arm64", "i386"},
 	},
+	arches:    chisel.Arches,
 	arch:      "amd64",
 	ensureErr: `package "python3" does not exist`,
 	found: []*chisel.Slice{{
@@ -94,14 +60,62 @@ var ensureIgnoreTests = []struct {
 		Name:    "libc6_libs",
 		Package: "libc6",
 	}},
+}, {
+	slices: []*chisel.Slice{{
+		Name:    "libc6_libs",
+		Package: "libc6",
+	}, {
+		Name:    "java_extra",
+		Package: "java",
+	}},
+	pkgs: map[string][]string{
+		"libc6": {"amd64", "arm64", "riscv64"},
+		"java":  {"arm64", "i386"},
+	},
+	arches: []string{"riscv64", "arm64"},
+	arch:   "riscv64",
+	found: []*chisel.Slice{{
+		Name:    "libc6_libs",
+		Package: "libc6",
+	}},
+}, {
+	slices: []*chisel.Slice{{
+		Name:    "java_extra",
+		Package: "java",
+	}},
+	pkgs: map[string][]string{
+		"java": {"arm64", "i386"},
+	},
+	arches:    []string{"riscv64", "amd64"},
+	arch:      "arm64",
+	ensureErr: `package "java" does not exist`,
+	found: []*chisel.Slice{{
+		Name:    "java_extra",
+		Package: "java",
+	}},
 }}
 
+// finder returns the package finder of the packages by arch, which records
+// the queries.
+func finder(pkgs map[string][]string, queries *[]string) func(arch, pkg string) (bool, error) {
+	return func(arch, pkg string) (bool, error) {
+		*queries = append(*queries, pkg+"/"+arch)
+		for _, a := range pkgs[pkg] {
+			if a == arch {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
 func TestEnsurePackages(t *testing.T) {
 	for _, tc := range ensureIgnoreTests {
-		err := sdf.EnsurePackages(tc.slices, tc.pkgs)
+		var queries []string
+		err := sdf.EnsurePackages(tc.slices, finder(tc.pkgs, &queries), tc.arches)
 		if tc.ensureErr != "" {
-			if err.Error() != tc.ensureErr {
-				t.Fatalf("have error %q, want %q", err, tc.ensureErr)
+			if err == nil || err.Error() != tc.ensureErr {
+				t.Fatalf("have error %v, want %q", err, tc.ensureErr)
 			}
 		} else {
 			if err != nil {
@@ -109,13 +123,303 @@ func TestEnsurePackages(t *testing.T) {
 			}
 		}
 	}
+
+	// Packages are looked for once, until found.
+	var queries []string
+	slices := []*chisel.Slice{{Name: "java_extra", Package: "java"}, {Name: "java_bins", Package: "java"}}
+	if err := sdf.EnsurePackages(slices, finder(map[string][]string{"java": {"arm64"}}, &queries), chisel.Arches); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"java/amd64", "java/arm64"}; !reflect.DeepEqual(queries, want) {
+		t.Fatalf("have queries %q, want %q", queries, want)
+	}
+	err := sdf.EnsurePackages(slices, func(arch, pkg string) (bool, error) {
+		return false, fmt.Errorf("cannot find packages")
+	}, chisel.Arches)
+	if err == nil || err.Error() != "cannot find packages" {
+		t.Fatalf("have error %v, want the one of the finder", err)
+	}
 }
 
 func TestIgnoreMissing(t *testing.T) {
 	for _, tc := range ensureIgnoreTests {
-		slices := sdf.IgnoreMissing(tc.slices, tc.pkgs, tc.arch)
+		var queries []string
+		slices, err := sdf.IgnoreMissing(tc.slices, finder(tc.pkgs, &queries), tc.arch)
+		if err != nil {
+			t.Fatal(err)
+		}
 		if !reflect.DeepEqual(slices, tc.found) {
 			t.Fatalf("have %v, want %v", slices, tc.found)
 		}
+		// Every package is looked for once.
+		seen := make(map[string]bool)
+		for _, q := range queries {
+			if seen[q] {
+				t.Fatalf("have query %s twice", q)
+			}
+			seen[q] = true
+		}
+	}
+}
+
+var parseArchesTests = []struct {
+	summary string
+	value   string
+	arches  []string
+	err     string
+}{{
+	summary: "Single arch",
+	value:   "amd64",
+	arches:  []string{"amd64"},
+}, {
+	summary: "Several arches, once each",
+	value:   "arm64, riscv64,arm64",
+	arches:  []string{"arm64", "riscv64"},
+}, {
+	summary: "All arches",
+	value:   "all",
+	arches:  chisel.Arches,
+}, {
+	summary: "Unknown arch",
+	value:   "amd64,x86_64",
+	err:     `invalid value for --arch: "x86_64", want all or some of amd64, arm64, armhf, i386, ppc64el, riscv64, s390x`,
+}}
+
+func TestParseArches(t *testing.T) {
+	for _, test := range parseArchesTests {
+		t.Logf("Summary: %s", test.summary)
+		arches, err := sdf.ParseArches(test.value)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("have error %v, want %q", err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(arches, test.arches) {
+			t.Fatalf("have arches %q, want %q", arches, test.arches)
+		}
+	}
+
+	// The arches of "all" are not the global ones.
+	arches, err := sdf.ParseArches("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]string(nil), chisel.Arches...)
+	arches[0] = "x86_64"
+	if !reflect.DeepEqual(chisel.Arches, want) {
+		t.Fatalf("have global arches %q, want %q", chisel.Arches, want)
+	}
+}
+
+var writeReportTests = []struct {
+	summary string
+	file    string
+	format  string
+	prefix  string
+}{{
+	summary: "JSON by default",
+	file:    "report.json",
+	prefix:  "{\n  \"passed\": false,",
+}, {
+	summary: "JUnit for XML files",
+	file:    "report.xml",
+	prefix:  "<?xml",
+}, {
+	summary: "Format given",
+	file:    "report.xml",
+	format:  "json",
+	prefix:  "{",
+}, {
+	summary: "JUnit given",
+	file:    "report",
+	format:  "junit",
+	prefix:  "<?xml",
+}}
+
+func TestWriteReport(t *testing.T) {
+	report := &results.Report{
+		Tasks:  1,
+		Failed: 1,
+		Results: []*results.Result{{
+			Slices: []string{"hello_bins"},
+			Arch:   "amd64",
+			Error:  "exit status 1",
+		}},
+	}
+	for _, test := range writeReportTests {
+		t.Logf("Summary: %s", test.summary)
+		path := filepath.Join(t.TempDir(), test.file)
+		if err := sdf.WriteReport(path, test.format, report); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), test.prefix) {
+			t.Fatalf("have report:\n%s\nwant prefix %q", data, test.prefix)
+		}
+	}
+}
+
+var affectedSlicesTests = []struct {
+	summary  string
+	changed  []string
+	base     []*chisel.Slice
+	affected []string
+}{{
+	summary:  "Slices depending on the changed ones",
+	changed:  []string{"slices/libhello1.yaml"},
+	affected: []string{"hello_bins", "libhello1_copyright", "libhello1_libs"},
+}, {
+	summary:  "Slices depending on no other",
+	changed:  []string{"slices/hello.yaml", "slices/removed.yaml", "tests/hello.yaml"},
+	affected: []string{"hello_bins", "hello_copyright"},
+}, {
+	summary: "Nothing changed",
+	changed: []string{},
+}, {
+	summary:  "Changed chisel.yaml",
+	changed:  []string{"chisel.yaml"},
+	affected: []string{"hello_bins", "hello_copyright", "libhello1_copyright", "libhello1_libs", "hi_bins"},
+}, {
+	summary: "Slices depending on a removed file",
+	changed: []string{"slices/libhi1.yaml"},
+	base: []*chisel.Slice{
+		{Package: "libhi1", Name: "libhi1_libs", Essential: []string{"libhi1_copyright"}},
+		{Package: "libhi1", Name: "libhi1_copyright"},
+	},
+	affected: []string{"hi_bins"},
+}, {
+	summary: "Slices depending on a renamed slice",
+	changed: []string{"slices/hello.yaml"},
+	base: []*chisel.Slice{
+		{Package: "hello", Name: "hello_bins", Essential: []string{"hello_copyright", "libhello1_libs"}},
+		{Package: "hello", Name: "hello_copyright"},
+		{Package: "hello", Name: "hello_libs"},
+	},
+	affected: []string{"hello_bins", "hello_copyright", "hi_bins"},
+}}
+
+func TestAffectedSlices(t *testing.T) {
+	all := []*chisel.Slice{
+		{Package: "hello", Name: "hello_bins", Essential: []string{"hello_copyright", "libhello1_libs"}},
+		{Package: "hello", Name: "hello_copyright"},
+		{Package: "libhello1", Name: "libhello1_copyright"},
+		{Package: "libhello1", Name: "libhello1_libs", Essential: []string{"libhello1_copyright"}},
+		// The slices of libhi1 were removed, and hello_libs renamed to
+		// hello_copyright.
+		{Package: "hi", Name: "hi_bins", Essential: []string{"hello_libs", "libhi1_libs"}},
+	}
+	for _, test := range affectedSlicesTests {
+		t.Logf("Summary: %s", test.summary)
+		var changed []string
+		for _, p := range test.changed {
+			changed = append(changed, filepath.Join("release", p))
+		}
+		var affected []string
+		for _, s := range sdf.AffectedSlices("release", all, test.base, changed) {
+			affected = append(affected, s.Name)
+		}
+		if !reflect.DeepEqual(affected, test.affected) {
+			t.Fatalf("have %v, want %v", affected, test.affected)
+		}
+	}
+}
+
+func TestPackageFinderUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	f, err := fixtures.Write(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	find, err := sdf.PackageFinder(context.Background(), f.Release, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The archives that cannot be reached are not findings of the release.
+	_, err = find("amd64", "hello")
+	if code := sdf.ExitCode(err); code != 5 {
+		t.Fatalf("have exit code %d for error %v, want 5", code, err)
+	}
+}
+
+func TestPackageFinderInterrupted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	f, err := fixtures.Write(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	find, err := sdf.PackageFinder(ctx, f.Release, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The indexes being fetched are not waited for once interrupted.
+	time.AfterFunc(100*time.Millisecond, cancel)
+	_, err = find("amd64", "hello")
+	if code := sdf.ExitCode(err); code != 130 {
+		t.Fatalf("have exit code %d for error %v, want 130", code, err)
+	}
+}
+
+func TestInstallErrors(t *testing.T) {
+	// A chisel that fails to install any slice, at once.
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "chisel"), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	start := time.Now()
+	_, err := sdf.Install(3, true, []string{"a_bins"}, []string{"b_bins"}, []string{"c_bins"})
+	if d := time.Since(start); d >= time.Second {
+		t.Fatalf("have installation of %v, want less than a second", d)
+	}
+	if code := sdf.ExitCode(err); code != 4 {
+		t.Fatalf("have exit code %d for error %v, want 4", code, err)
+	}
+	// The errors of the tasks that finished last are not lost.
+	for _, name := range []string{"a_bins", "b_bins", "c_bins"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("have error %q, want one for %s", err, name)
+		}
+	}
+}
+
+func TestInstallSkipped(t *testing.T) {
+	// A chisel that fails to install the slices of package fail, and takes
+	// long to install the others.
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *fail_*) exit 1;; esac\nexec sleep 10\n"
+	if err := os.WriteFile(filepath.Join(bin, "chisel"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	report, err := sdf.Install(1, false, []string{"fail_bins"}, []string{"a_bins"}, []string{"b_bins"})
+	if code := sdf.ExitCode(err); code != 4 {
+		t.Fatalf("have exit code %d for error %v, want 4", code, err)
+	}
+	if report.Passed || report.Tasks != 3 || report.Failed != 1 || report.Skipped != 2 {
+		t.Fatalf("have passed %v with %d of %d task(s) failed and %d skipped, want false with 1 of 3 and 2", report.Passed, report.Failed, report.Tasks, report.Skipped)
+	}
+	var have []string
+	for _, r := range report.Results {
+		have = append(have, r.Name()+": "+r.Skipped)
+	}
+	want := []string{"a_bins: another task failed", "b_bins: another task failed", "fail_bins: "}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %q, want %q", have, want)
 	}
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/copyright"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+)
+
+type cmdLicenses struct {
+	Root     string `long:"root" description:"Root directory chisel installed slices into" required:"true" path:"yes"`
+	Manifest string `long:"manifest" description:"Chisel manifest path, instead of the one in the root" path:"yes"`
+	Output   string `short:"o" long:"output" description:"Write the inventory as JSON to this file" path:"yes"`
+	Strict   bool   `long:"strict" description:"Fail if any package has unknown or ambiguous licenses"`
+}
+
+func init() {
+	parser.AddCommand(
+		"licenses",
+		"Report the licenses in a root",
+		"The licenses command parses the copyright files in a root and reports the licenses per package, flagging the unknown and ambiguous ones. If the root has a chisel manifest, packages without a copyright file are flagged too",
+		&cmdLicenses{},
+	)
+}
+
+func (c *cmdLicenses) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	var pkgs []string
+	m, err := readManifest(c.Root, c.Manifest)
+	if err == nil {
+		for _, p := range m.Packages {
+			pkgs = append(pkgs, p.Name)
+		}
+	} else if !errors.Is(err, manifest.ErrNotFound) {
+		return err
+	} else {
+		log.Print("No manifest found, reading all copyright files in the root...")
+	}
+
+	inv, err := copyright.ReadInventory(c.Root, pkgs)
+	if err != nil {
+		return fmt.Errorf("cannot read licenses: %w", err)
+	}
+	if err := printInventory(inv); err != nil {
+		return err
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, inv); err != nil {
+			return fmt.Errorf("cannot write inventory: %w", err)
+		}
+	}
+	if flagged := inv.Flagged(); len(flagged) > 0 && c.Strict {
+		return exitErrorf(exitFindings, "%c %d package(s) with unknown or ambiguous licenses", cross, len(flagged))
+	}
+	return nil
+}
+
+func printInventory(inv *copyright.Inventory) error {
+	var ids []string
+	for id := range inv.Licenses {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	licenses := &table{Header: []string{"LICENSE", "PACKAGES"}}
+	for _, id := range ids {
+		licenses.Rows = append(licenses.Rows, []string{id, strings.Join(inv.Licenses[id], ", ")})
+	}
+
+	flagged := inv.Flagged()
+	if len(flagged) == 0 {
+		return printOutput(inv, licenses)
+	}
+	t := &table{Header: []string{"FLAGGED", "STATUS", "DETAILS"}}
+	for _, p := range flagged {
+		details := ""
+		switch p.Status {
+		case copyright.Ambiguous:
+			details = "no SPDX identifier for " + strings.Join(p.Unmapped, ", ")
+		case copyright.Unknown:
+			details = "copyright file is not machine-readable"
+		case copyright.Missing:
+			details = "no copyright file at " + copyright.Path(p.Package)
+		}
+		t.Rows = append(t.Rows, []string{p.Package, string(p.Status), details})
+	}
+	return printOutput(inv, licenses, t)
+}
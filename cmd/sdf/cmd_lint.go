@@ -0,0 +1,155 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/events"
+	"github.com/rebornplusplus/chisel-tools/internal/lint"
+)
+
+type cmdLint struct {
+	Release string   `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Checks  []string `long:"check" description:"Check to run, all of them if none is given (can be repeated)"`
+	NoCache bool     `long:"no-cache" description:"Parse all slice definition files again instead of using the parse cache"`
+
+	watchOptions
+	hookOptions
+
+	bus *events.Bus
+}
+
+func init() {
+	parser.AddCommand(
+		"lint",
+		"Check the slice definitions for mistakes",
+		"The lint command checks the slice definition files of the release for mistakes that chisel only reports when installing the slices, if at all: undefined or cyclic essential slices, files not named after their package and unclean paths. With --watch, it keeps running and reports the issues of the files that change in the release",
+		&cmdLint{},
+	)
+}
+
+func (c *cmdLint) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	checks, err := findChecks(c.Checks)
+	if err != nil {
+		return err
+	}
+	bus, closeBus := c.newBus()
+	defer closeBus()
+	c.bus = bus
+	pc := newParseCache(c.NoCache)
+	err = c.run(pc, checks, nil)
+	if !c.Watch {
+		return err
+	}
+	if err != nil {
+		log.Print(err)
+	}
+	return c.watch(c.Release, func(changed []string) error {
+		return c.run(pc, checks, changed)
+	})
+}
+
+// run reports the issues of the release, only those of the changed files
+// if changed is not nil and chisel.yaml did not change.
+func (c *cmdLint) run(pc *chisel.ParseCache, checks []*lint.Check, changed []string) error {
+	start := time.Now()
+	r, err := readRelease(pc, c.Release)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	issues := lint.Run(r, checks...)
+	if _, all := changedPackages(c.Release, changed); changed != nil && !all {
+		issues = lint.InFiles(issues, changed)
+	}
+	publishFindings(c.bus, "lint", c.Release, issues, start)
+	if err := printIssues(c.Release, issues); err != nil {
+		return err
+	}
+	log.Printf("%c No issues", tick)
+	return nil
+}
+
+func findChecks(names []string) ([]*lint.Check, error) {
+	var checks []*lint.Check
+	for _, name := range names {
+		check := lint.FindCheck(name)
+		if check == nil {
+			var all []string
+			for _, c := range lint.Checks {
+				all = append(all, c.Name)
+			}
+			return nil, exitErrorf(exitUsage, "unknown check %q, want one of %s", name, strings.Join(all, ", "))
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// newParseCache returns the parse cache in its default directory, or nil if
+// disabled or there is no user cache directory.
+func newParseCache(disabled bool) *chisel.ParseCache {
+	if disabled {
+		return nil
+	}
+	dir, err := chisel.DefaultParseCacheDir()
+	if err != nil {
+		return nil
+	}
+	return &chisel.ParseCache{Dir: dir}
+}
+
+// readRelease reads the release with the parse cache, if not nil.
+func readRelease(pc *chisel.ParseCache, dir string) (*chisel.Release, error) {
+	if pc == nil {
+		return chisel.ReadRelease(dir)
+	}
+	return pc.ReadRelease(dir)
+}
+
+// parseSlices parses the slice definition file with the parse cache, if not
+// nil.
+func parseSlices(pc *chisel.ParseCache, path string) ([]*chisel.Slice, error) {
+	if pc == nil {
+		return chisel.ParseSlices(path)
+	}
+	return pc.ParseSlices(path)
+}
+
+// decodeSlices decodes the slices with the parse cache, if not nil.
+func decodeSlices(pc *chisel.ParseCache, data []byte) ([]*chisel.Slice, error) {
+	if pc == nil {
+		return chisel.DecodeSlices(data)
+	}
+	return pc.DecodeSlices(data)
+}
+
+// printIssues prints the issues, with their files relative to the release,
+// and fails if there are any.
+func printIssues(release string, issues []*lint.Issue) error {
+	if len(issues) > 0 || opts.Format != "table" {
+		shown := []*lint.Issue{}
+		t := &table{Header: []string{"FILE", "SLICE", "CHECK", "MESSAGE"}}
+		for _, i := range issues {
+			issue := *i
+			issue.File = relPath(release, i.File)
+			slice := issue.Slice
+			if slice == "" {
+				slice = "-"
+			}
+			t.Rows = append(t.Rows, []string{issue.File, slice, issue.Check, issue.Message})
+			shown = append(shown, &issue)
+		}
+		if err := printOutput(shown, t); err != nil {
+			return err
+		}
+	}
+	if len(issues) > 0 {
+		return exitErrorf(exitFindings, "%c %d issue(s)", cross, len(issues))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type cmdList struct {
+	Release  string   `short:"r" long:"release" description:"Chisel release directory" required:"true" path:"yes"`
+	Packages []string `short:"p" long:"package" description:"List only the slices of this package (can be repeated)"`
+}
+
+func init() {
+	parser.AddCommand(
+		"list",
+		"List the slices of a release",
+		"The list command lists the slices of the release with their package, essential slices and number of paths",
+		&cmdList{},
+	)
+}
+
+// sliceInfo is a slice in the output of the query commands.
+type sliceInfo struct {
+	Name      string   `json:"name"`
+	Package   string   `json:"package"`
+	File      string   `json:"file"`
+	Essential []string `json:"essential"`
+	Contents  []string `json:"contents"`
+	// Only set by the info command.
+	Deps  []string `json:"deps,omitempty"`
+	RDeps []string `json:"rdeps,omitempty"`
+}
+
+func newSliceInfo(r *chisel.Release, s *chisel.Slice) *sliceInfo {
+	file, err := filepath.Rel(r.Path, s.File)
+	if err != nil {
+		file = s.File
+	}
+	return &sliceInfo{
+		Name:      s.Name,
+		Package:   s.Package,
+		File:      file,
+		Essential: append([]string{}, s.Essential...),
+		Contents:  append([]string{}, s.Contents...),
+	}
+}
+
+func (c *cmdList) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	r, err := chisel.ReadRelease(c.Release)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	slices, t := listOutput(r, c.Packages)
+	return printOutput(slices, t)
+}
+
+// listOutput returns the output of the list command, with the slices of
+// the packages only if any is given.
+func listOutput(r *chisel.Release, packages []string) ([]*sliceInfo, *table) {
+	pkgs := make(map[string]bool)
+	for _, p := range packages {
+		pkgs[p] = true
+	}
+	slices := []*sliceInfo{}
+	t := &table{Header: []string{"SLICE", "PACKAGE", "ESSENTIAL", "PATHS"}}
+	for _, s := range r.Slices {
+		if len(pkgs) > 0 && !pkgs[s.Package] {
+			continue
+		}
+		slices = append(slices, newSliceInfo(r, s))
+		essential := strings.Join(s.Essential, ", ")
+		if essential == "" {
+			essential = "-"
+		}
+		t.Rows = append(t.Rows, []string{s.Name, s.Package, essential, strconv.Itoa(len(s.Contents))})
+	}
+	return slices, t
+}
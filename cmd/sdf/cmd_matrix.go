@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/chiselbin"
+	"github.com/rebornplusplus/chisel-tools/internal/debversion"
+)
+
+type cmdMatrix struct {
+	Release  string   `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Arch     string   `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Workers  int      `short:"w" long:"workers" description:"Number of concurrent workers" default:"4"`
+	Versions []string `long:"chisel-version" description:"Chisel version to run, e.g. v1.0.0 (can be repeated)" required:"true"`
+	CacheDir string   `long:"cache-dir" description:"Directory to keep the chisel binaries in (default: sdf/chisel in the user cache directory)" path:"yes"`
+	Output   string   `short:"o" long:"output" description:"Write the results as JSON to this file" path:"yes"`
+
+	Positional struct {
+		Slices []sliceName `positional-arg-name:"slices"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"matrix",
+		"Install slices with several chisel versions",
+		"The matrix command downloads the given chisel versions and installs each slice, by default all slices of the release, alone with each of them. It reports which versions succeed and the minimum version the release requires",
+		&cmdMatrix{},
+	)
+}
+
+type matrixResult struct {
+	Slice   string `json:"slice"`
+	Version string `json:"version"`
+	Passed  bool   `json:"passed"`
+	Error   string `json:"error,omitempty"`
+
+	chisel string
+}
+
+type matrixReport struct {
+	Results []*matrixResult `json:"results"`
+	// Oldest version from which all the newer versions install all the
+	// slices, empty if the newest fails too.
+	Minimum string `json:"minimum"`
+}
+
+func (c *cmdMatrix) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Workers <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --workers: %d", c.Workers)
+	}
+	versions := make([]string, len(c.Versions))
+	for i, v := range c.Versions {
+		versions[i] = "v" + strings.TrimPrefix(v, "v")
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return debversion.Compare(versions[i][1:], versions[j][1:]) < 0
+	})
+
+	d := &chiselbin.Downloader{CacheDir: c.CacheDir}
+	if d.CacheDir == "" {
+		var err error
+		if d.CacheDir, err = chiselbin.DefaultCacheDir(); err != nil {
+			return err
+		}
+	}
+	bins := make(map[string]string)
+	for _, v := range versions {
+		progressf("Getting chisel %s...", v)
+		bin, err := d.Path(v)
+		if err != nil {
+			return err
+		}
+		bins[v] = bin
+	}
+
+	slices := names(c.Positional.Slices)
+	if len(slices) == 0 {
+		r, err := chisel.ReadRelease(c.Release)
+		if err != nil {
+			return withExitCode(exitFindings, err)
+		}
+		for _, s := range r.Slices {
+			slices = append(slices, s.Name)
+		}
+	}
+	var results []*matrixResult
+	for _, s := range slices {
+		for _, v := range versions {
+			results = append(results, &matrixResult{Slice: s, Version: v, chisel: bins[v]})
+		}
+	}
+	forEachCut(c.Workers, results, func(r *matrixResult, cacheDir string, err error) {
+		if err == nil {
+			err = c.cut(r, cacheDir)
+		}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		r.Passed = err == nil
+	})
+
+	report := &matrixReport{Results: results, Minimum: minimumVersion(versions, results)}
+	if err := printMatrix(slices, versions, report); err != nil {
+		return err
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, report); err != nil {
+			return fmt.Errorf("cannot write results: %w", err)
+		}
+	}
+	if report.Minimum == "" {
+		return exitErrorf(exitInstall, "%c No version installs all slices", cross)
+	}
+	log.Printf("%c Minimum chisel version: %s", tick, report.Minimum)
+	return nil
+}
+
+func (c *cmdMatrix) cut(r *matrixResult, cacheDir string) error {
+	root, err := os.MkdirTemp("", "sdf-matrix-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(root)
+	return cut(context.Background(), &cutOptions{
+		Release:  c.Release,
+		Arch:     c.Arch,
+		Root:     root,
+		CacheDir: cacheDir,
+		Slices:   []string{r.Slice},
+		Chisel:   r.chisel,
+	})
+}
+
+// minimumVersion returns the oldest of the sorted versions from which every
+// version passes for every slice.
+func minimumVersion(versions []string, results []*matrixResult) string {
+	failed := make(map[string]bool)
+	for _, r := range results {
+		if !r.Passed {
+			failed[r.Version] = true
+		}
+	}
+	minimum := ""
+	for i := len(versions) - 1; i >= 0 && !failed[versions[i]]; i-- {
+		minimum = versions[i]
+	}
+	return minimum
+}
+
+func printMatrix(slices, versions []string, report *matrixReport) error {
+	passed := make(map[[2]string]bool)
+	for _, r := range report.Results {
+		passed[[2]string{r.Slice, r.Version}] = r.Passed
+	}
+	t := &table{Header: append([]string{"SLICE"}, versions...)}
+	for _, s := range slices {
+		row := []string{s}
+		for _, v := range versions {
+			mark := cross
+			if passed[[2]string{s, v}] {
+				mark = tick
+			}
+			row = append(row, string(mark))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	if err := printOutput(report, t); err != nil {
+		return err
+	}
+	for _, r := range report.Results {
+		if r.Error != "" {
+			log.Printf("%c %s with chisel %s: %s", cross, r.Slice, r.Version, r.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main_test
+
+import (
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+var minimumVersionTests = []struct {
+	summary string
+	failed  []string
+	minimum string
+}{{
+	summary: "All versions pass",
+	minimum: "v0.9.0",
+}, {
+	summary: "Old versions fail",
+	failed:  []string{"v0.9.0"},
+	minimum: "v0.10.0",
+}, {
+	summary: "A version fails in between",
+	failed:  []string{"v0.10.0"},
+	minimum: "v1.0.0",
+}, {
+	summary: "The newest version fails",
+	failed:  []string{"v1.0.0"},
+	minimum: "",
+}}
+
+func TestMinimumVersion(t *testing.T) {
+	versions := []string{"v0.9.0", "v0.10.0", "v1.0.0"}
+	for _, tc := range minimumVersionTests {
+		t.Logf("Summary: %s", tc.summary)
+		failed := make(map[string]bool)
+		for _, v := range tc.failed {
+			failed[v] = true
+		}
+		var results []*sdf.MatrixResult
+		for _, v := range versions {
+			results = append(results,
+				&sdf.MatrixResult{Slice: "a_s", Version: v, Passed: true},
+				&sdf.MatrixResult{Slice: "b_s", Version: v, Passed: !failed[v]})
+		}
+		if minimum := sdf.MinimumVersion(versions, results); minimum != tc.minimum {
+			t.Fatalf("have minimum %q, want %q", minimum, tc.minimum)
+		}
+	}
+}
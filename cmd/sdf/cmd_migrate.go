@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type cmdMigrate struct {
+	Release string `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	To      string `long:"to" description:"Format to migrate to" default:"v3"`
+	DryRun  bool   `long:"dry-run" description:"Only list the files that would change"`
+}
+
+func init() {
+	parser.AddCommand(
+		"migrate",
+		"Migrate a release to a newer format",
+		"The migrate command upgrades chisel.yaml and the slice definition files of a release to a newer chisel format",
+		&cmdMigrate{},
+	)
+}
+
+func (c *cmdMigrate) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	changed, err := chisel.MigrateRelease(c.Release, c.To)
+	if err != nil {
+		return fmt.Errorf("cannot migrate release: %w", err)
+	}
+	if len(changed) == 0 {
+		log.Printf("%c Release is already in format %s", tick, c.To)
+		return nil
+	}
+
+	var paths []string
+	for p := range changed {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if c.DryRun {
+			progressf("Would migrate %s", p)
+			continue
+		}
+		if err := os.WriteFile(p, changed[p], 0644); err != nil {
+			return fmt.Errorf("cannot write %s: %w", p, err)
+		}
+		progressf("Migrated %s", p)
+	}
+	if !c.DryRun {
+		log.Printf("%c Migrated release to format %s", tick, c.To)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/lint"
+	"github.com/rebornplusplus/chisel-tools/internal/precommit"
+)
+
+type cmdPrecommit struct {
+	Release string `short:"r" long:"release" description:"Chisel release path" default:"." path:"yes"`
+	Install bool   `long:"install" description:"Install the command as the pre-commit hook of the repository of the release instead"`
+	Force   bool   `long:"force" description:"Replace a pre-commit hook sdf did not install"`
+	NoCache bool   `long:"no-cache" description:"Parse all slice definition files again instead of using the parse cache"`
+}
+
+func init() {
+	parser.AddCommand(
+		"precommit",
+		"Check the staged slice definitions",
+		"The precommit command checks the slice definition files and chisel.yaml staged in the git repository of the release, removed ones included: it reads the whole release as staged, with the parse cache so that checking takes little time even for large releases, and reports the files that do not parse and the lint issues of the staged files and of the files with slices depending on theirs, or of all files if chisel.yaml is staged. With --install, it installs itself as the pre-commit hook of the repository",
+		&cmdPrecommit{},
+	)
+}
+
+func (c *cmdPrecommit) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	start := time.Now()
+	top, err := precommit.Top(c.Release)
+	if err != nil {
+		return exitErrorf(exitUsage, "release is not in a git repository: %w", err)
+	}
+	// Paths in git are relative to the top of the worktree, which is the
+	// real path of the directory.
+	abs, err := filepath.Abs(c.Release)
+	if err == nil {
+		abs, err = filepath.EvalSymlinks(abs)
+	}
+	if err != nil {
+		return err
+	}
+	prefix, err := filepath.Rel(top, abs)
+	if err != nil {
+		return err
+	}
+	if c.Install {
+		command := "sdf precommit"
+		if prefix != "." {
+			command += " --release " + precommit.Quote(prefix)
+		}
+		path, err := precommit.InstallHook(c.Release, command, c.Force)
+		if err != nil {
+			return fmt.Errorf("cannot install hook: %w", err)
+		}
+		log.Printf("%c Installed %s", tick, path)
+		return nil
+	}
+
+	staged, err := precommit.Staged(c.Release)
+	if err != nil {
+		return err
+	}
+	// releasePath returns the path in the release of the file in the
+	// repository, or false if it is not chisel.yaml or a slice definition
+	// file of the release.
+	releasePath := func(f *precommit.File) (string, bool) {
+		rel, err := filepath.Rel(prefix, filepath.FromSlash(f.Path))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", false
+		}
+		isSlices := strings.HasPrefix(rel, "slices"+string(filepath.Separator)) && strings.HasSuffix(rel, ".yaml")
+		return filepath.Join(c.Release, rel), rel == "chisel.yaml" || isSlices
+	}
+	configPath := filepath.Join(c.Release, "chisel.yaml")
+	var changed []string // Removed files included.
+	var changedSlices []*precommit.File
+	for _, f := range staged {
+		if path, ok := releasePath(f); ok {
+			changed = append(changed, path)
+			if path != configPath {
+				changedSlices = append(changedSlices, f)
+			}
+		}
+	}
+	if len(changed) == 0 {
+		progressf("%c No staged slice definition files", tick)
+		return nil
+	}
+
+	// The whole release is read as staged, which is what is committed,
+	// with the parse cache for the files that did not change.
+	index, err := precommit.Index(c.Release, "chisel.yaml", "slices")
+	if err != nil {
+		return err
+	}
+	pc := newParseCache(c.NoCache)
+	var issues []*lint.Issue
+	var cfg *chisel.Config
+	files := make(map[string][]*chisel.Slice)
+	for _, f := range index {
+		path, ok := releasePath(f)
+		if !ok {
+			continue
+		}
+		if path == configPath {
+			if cfg, err = chisel.DecodeConfig(f.Data); err != nil {
+				issues = append(issues, &lint.Issue{Check: lint.ParseCheck, File: path, Message: err.Error()})
+			}
+			continue
+		}
+		slices, err := decodeSlices(pc, f.Data)
+		if err != nil {
+			issues = append(issues, &lint.Issue{Check: lint.ParseCheck, File: path, Message: err.Error()})
+			continue
+		}
+		for _, s := range slices {
+			s.File = path
+		}
+		files[path] = slices
+	}
+	switch {
+	case cfg != nil:
+		r := chisel.NewRelease(c.Release, cfg, files)
+		issues = append(issues, lint.Run(r)...)
+		if !slices.Contains(changed, configPath) {
+			// Other files may break along with the slices they depend
+			// on, removed ones included.
+			affected, err := dependentFiles(c.Release, r, changedSlices, pc)
+			if err != nil {
+				return err
+			}
+			issues = lint.InFiles(issues, append(affected, changed...))
+		}
+	case len(issues) == 0:
+		return exitErrorf(exitFindings, "cannot read release: chisel.yaml is not staged")
+	}
+	lint.Sort(issues)
+	if err := printIssues(c.Release, issues); err != nil {
+		return err
+	}
+	log.Printf("%c No issues in %d staged file(s), checked in %s", tick, len(changed), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// dependentFiles returns the files of the slices of the release depending
+// on the slices of the changed files, those of the files as staged and as
+// they are in HEAD.
+func dependentFiles(dir string, r *chisel.Release, changed []*precommit.File, pc *chisel.ParseCache) ([]string, error) {
+	names := make(map[string]bool)
+	for _, f := range changed {
+		head, err := precommit.Committed(dir, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range [][]byte{f.Data, head} {
+			if data == nil {
+				continue
+			}
+			// The files that do not parse are reported as such.
+			slices, _ := decodeSlices(pc, data)
+			for _, s := range slices {
+				names[s.Name] = true
+			}
+		}
+	}
+	var files []string
+	for name := range names {
+		for _, dep := range r.RDeps(name) {
+			if s := r.Slice(dep); s != nil {
+				files = append(files, s.File)
+			}
+		}
+	}
+	return files, nil
+}
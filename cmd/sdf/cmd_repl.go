@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/tui"
+)
+
+type cmdREPL struct {
+	Release string `short:"r" long:"release" description:"Chisel release directory" required:"true" path:"yes"`
+	NoCache bool   `long:"no-cache" description:"Parse all slice definition files again instead of using the parse cache"`
+}
+
+func init() {
+	parser.AddCommand(
+		"repl",
+		"Query a release interactively",
+		"The repl command reads the release once and answers the queries typed at its prompt without reading it again: deps, rdeps, info and list, with the output of the commands of the same names, find for the slices holding a path and search for the slices whose names or paths contain a text. Commands, slices, packages and paths are completed with tab, and reload reads the release again after changing it. When the standard input is not a terminal, the queries are read from it, one per line",
+		&cmdREPL{},
+	)
+}
+
+func (c *cmdREPL) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	s, err := newREPL(newParseCache(c.NoCache), c.Release, os.Stdout)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	if !tui.IsTerminal(os.Stdin) {
+		scanner := bufio.NewScanner(os.Stdin)
+		queries, failed := 0, 0
+		for scanner.Scan() {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			queries++
+			quit, err := s.eval(scanner.Text())
+			if err != nil {
+				log.Print(err)
+				failed++
+			}
+			if quit {
+				break
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%c %d of %d queries failed", cross, failed, queries)
+		}
+		return nil
+	}
+
+	term, err := tui.Open(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	progressf("%d slices in %s, type help for the commands", len(s.release.Slices), c.Release)
+	editor := tui.NewLineEditor(s.complete)
+	for {
+		line, err := term.ReadLine("sdf> ", editor)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		quit, err := s.eval(line)
+		if err != nil {
+			log.Print(err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// repl answers the queries about a release read once.
+type repl struct {
+	pc      *chisel.ParseCache
+	dir     string
+	out     io.Writer
+	release *chisel.Release
+}
+
+func newREPL(pc *chisel.ParseCache, dir string, out io.Writer) (*repl, error) {
+	s := &repl{pc: pc, dir: dir, out: out}
+	r, err := readRelease(pc, dir)
+	if err != nil {
+		return nil, err
+	}
+	s.release = r
+	return s, nil
+}
+
+// A replCommand is a query of the repl command.
+type replCommand struct {
+	name string
+	args string
+	help string
+	// Number of arguments, maxArgs is -1 if there is no maximum.
+	minArgs, maxArgs int
+	run              func(s *repl, args []string) error
+	// complete returns the candidates for the last of the arguments, the
+	// one being typed, or nil if there are none.
+	complete func(r *chisel.Release, args []string) []string
+}
+
+var replCommands = []*replCommand{{
+	name:     "deps",
+	args:     "SLICE",
+	help:     "Show the slices installed along with the slice",
+	minArgs:  1,
+	maxArgs:  1,
+	run:      replDeps(false),
+	complete: completeSlices(1),
+}, {
+	name:     "rdeps",
+	args:     "SLICE",
+	help:     "Show the slices installing the slice",
+	minArgs:  1,
+	maxArgs:  1,
+	run:      replDeps(true),
+	complete: completeSlices(1),
+}, {
+	name:     "info",
+	args:     "SLICE...",
+	help:     "Show the details of the slices",
+	minArgs:  1,
+	maxArgs:  -1,
+	complete: completeSlices(0),
+	run: func(s *repl, args []string) error {
+		slices, tables, err := infoOutput(s.release, args)
+		if err != nil {
+			return err
+		}
+		return writeOutput(s.out, opts.Format, slices, tables...)
+	},
+}, {
+	name:     "find",
+	args:     "PATH",
+	help:     "Show the slices whose contents include the path",
+	minArgs:  1,
+	maxArgs:  1,
+	complete: completePaths,
+	run: func(s *repl, args []string) error {
+		if !strings.HasPrefix(args[0], "/") {
+			return fmt.Errorf("invalid path %q, want an absolute path", args[0])
+		}
+		var matches []*pathMatch
+		for _, sl := range s.release.Slices {
+			for _, p := range sl.Contents {
+				if chisel.MatchPath(p, args[0]) {
+					matches = append(matches, &pathMatch{Slice: sl.Name, Path: p})
+				}
+			}
+		}
+		return s.writeMatches(matches)
+	},
+}, {
+	name:    "search",
+	args:    "TEXT",
+	help:    "Show the slices whose names or paths contain the text, ignoring case",
+	minArgs: 1,
+	maxArgs: -1,
+	run: func(s *repl, args []string) error {
+		text := strings.ToLower(strings.Join(args, " "))
+		var matches []*pathMatch
+		for _, sl := range s.release.Slices {
+			if strings.Contains(strings.ToLower(sl.Name), text) {
+				matches = append(matches, &pathMatch{Slice: sl.Name})
+			}
+			for _, p := range sl.Contents {
+				if strings.Contains(strings.ToLower(p), text) {
+					matches = append(matches, &pathMatch{Slice: sl.Name, Path: p})
+				}
+			}
+		}
+		return s.writeMatches(matches)
+	},
+}, {
+	name:    "list",
+	args:    "[PACKAGE...]",
+	help:    "List the slices, of the packages only if any is given",
+	maxArgs: -1,
+	complete: func(r *chisel.Release, args []string) []string {
+		var pkgs []string
+		for _, s := range r.Slices {
+			pkgs = append(pkgs, s.Package)
+		}
+		return pkgs
+	},
+	run: func(s *repl, args []string) error {
+		slices, t := listOutput(s.release, args)
+		return writeOutput(s.out, opts.Format, slices, t)
+	},
+}, {
+	name: "reload",
+	help: "Read the release again",
+	run: func(s *repl, args []string) error {
+		r, err := readRelease(s.pc, s.dir)
+		if err != nil {
+			return fmt.Errorf("cannot read release, keeping the last one: %w", err)
+		}
+		s.release = r
+		log.Printf("%c Release reloaded, %d slices", tick, len(r.Slices))
+		return nil
+	},
+}}
+
+// The commands of the repl itself, which cannot be in replCommands as they
+// refer to it.
+var (
+	replHelp = &replCommand{name: "help", help: "Show the commands"}
+	replQuit = &replCommand{name: "quit", help: "Quit, like exit and ctrl-d"}
+)
+
+func findREPLCommand(name string) *replCommand {
+	for _, cmd := range replCommands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// eval answers the query on the line, and returns whether it asks to quit.
+func (s *repl) eval(line string) (quit bool, err error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	switch fields[0] {
+	case replQuit.name, "exit":
+		return true, nil
+	case replHelp.name:
+		tw := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+		for _, cmd := range append(replCommands, replHelp, replQuit) {
+			fmt.Fprintf(tw, "%s\t%s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.help)
+		}
+		return false, tw.Flush()
+	}
+	cmd := findREPLCommand(fields[0])
+	if cmd == nil {
+		return false, fmt.Errorf("unknown command %q, type help for the commands", fields[0])
+	}
+	if args := len(fields) - 1; args < cmd.minArgs || (cmd.maxArgs >= 0 && args > cmd.maxArgs) {
+		return false, fmt.Errorf("usage: %s", strings.TrimSpace(cmd.name+" "+cmd.args))
+	}
+	return false, cmd.run(s, fields[1:])
+}
+
+// complete returns the candidates for the last of the words of a line,
+// which is being typed.
+func (s *repl) complete(words []string) []string {
+	if len(words) == 1 {
+		names := []string{replHelp.name, replQuit.name, "exit"}
+		for _, cmd := range replCommands {
+			names = append(names, cmd.name)
+		}
+		return names
+	}
+	cmd := findREPLCommand(words[0])
+	if cmd == nil || cmd.complete == nil {
+		return nil
+	}
+	return cmd.complete(s.release, words[1:])
+}
+
+// completeSlices completes the n first arguments with slice names, all of
+// them if n is 0.
+func completeSlices(n int) func(r *chisel.Release, args []string) []string {
+	return func(r *chisel.Release, args []string) []string {
+		if n > 0 && len(args) > n {
+			return nil
+		}
+		var names []string
+		for _, s := range r.Slices {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+}
+
+// completePaths completes the path with the content paths of the slices,
+// up to the end of the next directory, one directory at a time.
+func completePaths(r *chisel.Release, args []string) []string {
+	if len(args) != 1 {
+		return nil
+	}
+	typed := args[0]
+	var paths []string
+	for _, s := range r.Slices {
+		for _, p := range s.Contents {
+			if !strings.HasPrefix(p, typed) {
+				continue
+			}
+			if i := strings.Index(p[len(typed):], "/"); i >= 0 && len(typed)+i < len(p)-1 {
+				p = p[:len(typed)+i+1]
+			}
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+func replDeps(reverse bool) func(s *repl, args []string) error {
+	return func(s *repl, args []string) error {
+		deps, t, err := depsOutput(s.release, args[0], reverse)
+		if err != nil {
+			return err
+		}
+		return writeOutput(s.out, opts.Format, deps, t)
+	}
+}
+
+// pathMatch is a match in the output of the find and search queries.
+type pathMatch struct {
+	Slice string `json:"slice"`
+	// Not set if the name of the slice matched.
+	Path string `json:"path,omitempty"`
+}
+
+func (s *repl) writeMatches(matches []*pathMatch) error {
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Slice < matches[j].Slice })
+	if matches == nil {
+		matches = []*pathMatch{}
+	}
+	t := &table{Header: []string{"SLICE", "PATH"}}
+	for _, m := range matches {
+		path := m.Path
+		if path == "" {
+			path = "-"
+		}
+		t.Rows = append(t.Rows, []string{m.Slice, path})
+	}
+	return writeOutput(s.out, opts.Format, matches, t)
+}
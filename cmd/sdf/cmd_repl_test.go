@@ -0,0 +1,167 @@
+package main_test
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+	"github.com/rebornplusplus/chisel-tools/internal/fixtures"
+)
+
+var replEvalTests = []struct {
+	summary string
+	line    string
+	quit    bool
+	output  string
+	err     string
+}{{
+	summary: "Empty line",
+	line:    "  ",
+}, {
+	summary: "Deps",
+	line:    "deps libhello1_libs",
+	output:  "SLICE                DEFINED\nlibhello1_copyright  ✓\n",
+}, {
+	summary: "Reverse deps",
+	line:    "rdeps hello_copyright",
+	output:  "SLICE       DEFINED\nhello_bins  ✓\n",
+}, {
+	summary: "Find a path",
+	line:    "find /usr/lib/libhello.so.1",
+	output:  "SLICE           PATH\nlibhello1_libs  /usr/lib/libhello.so.1\n",
+}, {
+	summary: "Search names and paths ignoring case",
+	line:    "search Hello_C",
+	output:  "SLICE            PATH\nhello_copyright  -\n",
+}, {
+	summary: "Search paths",
+	line:    "search bin/h",
+	output:  "SLICE       PATH\nhello_bins  /usr/bin/hello\nhello_bins  /usr/bin/hi\n",
+}, {
+	summary: "List a package",
+	line:    "list hello",
+	output: "SLICE            PACKAGE  ESSENTIAL                        PATHS\n" +
+		"hello_bins       hello    hello_copyright, libhello1_libs  2\n" +
+		"hello_copyright  hello    -                                1\n",
+}, {
+	summary: "Quit",
+	line:    "exit",
+	quit:    true,
+}, {
+	summary: "Unknown slice",
+	line:    "info foo_bins",
+	err:     "slice foo_bins not found",
+}, {
+	summary: "Relative path",
+	line:    "find usr/bin/hi",
+	err:     `invalid path "usr/bin/hi", want an absolute path`,
+}, {
+	summary: "Too many arguments",
+	line:    "deps hello_bins hello_copyright",
+	err:     "usage: deps SLICE",
+}, {
+	summary: "Missing arguments",
+	line:    "info",
+	err:     "usage: info SLICE...",
+}, {
+	summary: "Unknown command",
+	line:    "show hello_bins",
+	err:     `unknown command "show", type help for the commands`,
+}}
+
+func TestREPLEval(t *testing.T) {
+	f, err := fixtures.Write(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s, err := sdf.NewREPL(nil, f.Release, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range replEvalTests {
+		t.Logf("Summary: %s", test.summary)
+		out.Reset()
+		quit, err := s.Eval(test.line)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("have error %v, want %q", err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if quit != test.quit {
+			t.Fatalf("have quit %v, want %v", quit, test.quit)
+		}
+		if out.String() != test.output {
+			t.Fatalf("have output:\n%s\nwant:\n%s", out.String(), test.output)
+		}
+	}
+}
+
+var replCompleteTests = []struct {
+	summary    string
+	words      []string
+	candidates []string
+}{{
+	summary:    "Commands",
+	words:      []string{""},
+	candidates: []string{"deps", "exit", "find", "help", "info", "list", "quit", "rdeps", "reload", "search"},
+}, {
+	summary:    "Slices",
+	words:      []string{"deps", "lib"},
+	candidates: []string{"hello_bins", "hello_copyright", "libhello1_copyright", "libhello1_libs"},
+}, {
+	summary: "No second slice for deps",
+	words:   []string{"deps", "hello_bins", ""},
+}, {
+	summary:    "Several slices for info",
+	words:      []string{"info", "hello_bins", ""},
+	candidates: []string{"hello_bins", "hello_copyright", "libhello1_copyright", "libhello1_libs"},
+}, {
+	summary:    "Packages",
+	words:      []string{"list", ""},
+	candidates: []string{"hello", "libhello1"},
+}, {
+	summary:    "Directories of the paths",
+	words:      []string{"find", "/usr/"},
+	candidates: []string{"/usr/bin/", "/usr/lib/", "/usr/share/"},
+}, {
+	summary:    "Paths in a directory",
+	words:      []string{"find", "/usr/lib/"},
+	candidates: []string{"/usr/lib/libhello.so.1", "/usr/lib/libhello.so.1.0"},
+}, {
+	summary: "Unknown command",
+	words:   []string{"show", ""},
+}}
+
+func TestREPLComplete(t *testing.T) {
+	f, err := fixtures.Write(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := sdf.NewREPL(nil, f.Release, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range replCompleteTests {
+		t.Logf("Summary: %s", test.summary)
+		// The editor filters and sorts the candidates.
+		seen := make(map[string]bool)
+		var candidates []string
+		for _, c := range s.Complete(test.words) {
+			if !seen[c] {
+				seen[c] = true
+				candidates = append(candidates, c)
+			}
+		}
+		sort.Strings(candidates)
+		if !reflect.DeepEqual(candidates, test.candidates) {
+			t.Fatalf("have %q, want %q", candidates, test.candidates)
+		}
+	}
+}
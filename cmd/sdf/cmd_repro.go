@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+type cmdRepro struct {
+	Release string `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Arch    string `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Against string `long:"against" description:"Compare a single cut against this listing, e.g. from another host"`
+	Output  string `short:"o" long:"output" description:"Write the listing of the cut root as JSON to this file" path:"yes"`
+
+	Positional struct {
+		Slices []sliceName `positional-arg-name:"slices"`
+	} `positional-args:"yes" required:"true"`
+}
+
+func init() {
+	parser.AddCommand(
+		"repro",
+		"Check that slices install reproducibly",
+		"The repro command cuts the same slices twice, concurrently and with separate caches, and reports the files that differ between the roots. With --against, it cuts once and compares with a listing written by -o on another host",
+		&cmdRepro{},
+	)
+}
+
+// A reproListing is the content of a cut root, to compare across hosts.
+type reproListing struct {
+	Slices  []string        `json:"slices"`
+	Arch    string          `json:"arch"`
+	Digest  string          `json:"digest"`
+	Entries []*rootfs.Entry `json:"entries"`
+}
+
+func (c *cmdRepro) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	var against *reproListing
+	if c.Against != "" {
+		data, err := os.ReadFile(c.Against)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &against); err != nil {
+			return fmt.Errorf("cannot parse listing %s: %w", c.Against, err)
+		}
+		if strings.Join(against.Slices, " ") != strings.Join(names(c.Positional.Slices), " ") || against.Arch != c.Arch {
+			return fmt.Errorf("listing %s is of %s for %s", c.Against, strings.Join(against.Slices, " "), against.Arch)
+		}
+	}
+	if err := checkChisel(c.Release); err != nil {
+		return err
+	}
+
+	runs := 2
+	if against != nil {
+		runs = 1
+	}
+	listings := make([]*reproListing, runs)
+	errs := make([]error, runs)
+	var wg sync.WaitGroup
+	for i := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			listings[i], errs[i] = c.cut()
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, listings[0]); err != nil {
+			return fmt.Errorf("cannot write listing: %w", err)
+		}
+	}
+	if against == nil {
+		against = listings[1]
+	}
+
+	diffs := rootfs.Diff(listings[0].Entries, against.Entries)
+	if len(diffs) == 0 {
+		log.Printf("%c Reproducible: %s", tick, listings[0].Digest)
+		return nil
+	}
+	for _, d := range diffs {
+		log.Printf("%c %s: %s != %s", cross, d.Path, entryString(d.A), entryString(d.B))
+	}
+	return exitErrorf(exitFindings, "%c Not reproducible: %d path(s) differ", cross, len(diffs))
+}
+
+// cut installs the slices into a fresh root, with a fresh cache, and lists
+// the root.
+func (c *cmdRepro) cut() (*reproListing, error) {
+	root, err := os.MkdirTemp("", "sdf-repro-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+	cacheDir, err := os.MkdirTemp("", "sdf-repro-cache-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(cacheDir)
+
+	err = cut(context.Background(), &cutOptions{
+		Release:  c.Release,
+		Arch:     c.Arch,
+		Root:     root,
+		CacheDir: cacheDir,
+		Slices:   names(c.Positional.Slices),
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := rootfs.List(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list root: %w", err)
+	}
+	return &reproListing{
+		Slices:  names(c.Positional.Slices),
+		Arch:    c.Arch,
+		Digest:  rootfs.DigestEntries(entries),
+		Entries: entries,
+	}, nil
+}
+
+func entryString(e *rootfs.Entry) string {
+	if e == nil {
+		return "missing"
+	}
+	return e.String()
+}
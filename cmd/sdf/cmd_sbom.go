@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/copyright"
+	"github.com/rebornplusplus/chisel-tools/internal/sbom"
+)
+
+type cmdSBOM struct {
+	Root     string `long:"root" description:"Root directory chisel installed slices into" required:"true" path:"yes"`
+	Manifest string `long:"manifest" description:"Chisel manifest path, if not in the root" path:"yes"`
+	Name     string `long:"name" description:"Document name (default: name of the root directory)"`
+	Output   string `short:"o" long:"output" description:"Output file (default: stdout)" path:"yes"`
+	Format   string `long:"format" description:"Document format" choice:"spdx" choice:"cyclonedx" default:"spdx"`
+}
+
+func init() {
+	parser.AddCommand(
+		"sbom",
+		"Generate an SBOM for a root",
+		"The sbom command generates an SPDX or CycloneDX document for a root from the chisel manifest in it, with licenses taken from the copyright files of the packages",
+		&cmdSBOM{},
+	)
+}
+
+func (c *cmdSBOM) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	in, err := readSBOMInput(c.Root, c.Manifest, c.Name)
+	if err != nil {
+		return err
+	}
+	switch c.Format {
+	case "cyclonedx":
+		return writeJSON(c.Output, sbom.CycloneDX(in))
+	default:
+		return writeJSON(c.Output, sbom.SPDX(in))
+	}
+}
+
+// readSBOMInput gathers the manifest and copyright files of a root.
+func readSBOMInput(root, manifestPath, name string) (*sbom.Input, error) {
+	m, err := readManifest(root, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		name = filepath.Base(abs)
+	}
+	copyrights := make(map[string]*copyright.Copyright)
+	for _, p := range m.Packages {
+		data, err := os.ReadFile(filepath.Join(root, copyright.Path(p.Name)))
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("No copyright file for %s, license is unknown", p.Name)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read copyright file: %w", err)
+		}
+		copyrights[p.Name] = copyright.Parse(data)
+	}
+	return &sbom.Input{
+		Name:       name,
+		Manifest:   m,
+		Copyrights: copyrights,
+		Created:    time.Now(),
+	}, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+	"github.com/rebornplusplus/chisel-tools/internal/oval"
+)
+
+type cmdScan struct {
+	Root     string `long:"root" description:"Root directory chisel installed slices into" path:"yes"`
+	Manifest string `long:"manifest" description:"Chisel manifest path, instead of the one in the root" path:"yes"`
+	OVAL     string `long:"oval" description:"OVAL data file or URL"`
+	Ubuntu   string `long:"ubuntu" description:"Ubuntu codename to fetch the OVAL data for, e.g. noble"`
+	Output   string `short:"o" long:"output" description:"Write the scan report as JSON to this file" path:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"scan",
+		"Scan a root for open CVEs",
+		"The scan command matches the package versions in a root, or in a chisel manifest, against the Ubuntu OVAL data and reports the open CVEs per slice",
+		&cmdScan{},
+	)
+}
+
+// A scanReport lists the open CVEs of a root per slice.
+type scanReport struct {
+	Findings []*scanFinding `json:"findings"`
+}
+
+type scanFinding struct {
+	*oval.Finding
+	Slices []string `json:"slices"`
+}
+
+func (c *cmdScan) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Root == "" && c.Manifest == "" {
+		return fmt.Errorf("either --root or --manifest must be specified")
+	}
+	location := c.OVAL
+	if location == "" {
+		if c.Ubuntu == "" {
+			return fmt.Errorf("either --oval or --ubuntu must be specified")
+		}
+		location = oval.URL(c.Ubuntu)
+	}
+
+	m, err := readManifest(c.Root, c.Manifest)
+	if err != nil {
+		return err
+	}
+	progressf("Loading OVAL data from %s...", location)
+	defs, err := oval.Load(location)
+	if err != nil {
+		return err
+	}
+
+	report := scan(m, defs)
+	for _, f := range report.Findings {
+		fmt.Printf("%s: %s %s (fixed in %s) %s [%s]\n",
+			strings.Join(f.Slices, ", "), f.Package, f.Version, f.Fixed, f.Title, strings.Join(f.CVEs, " "))
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, report); err != nil {
+			return fmt.Errorf("cannot write scan report: %w", err)
+		}
+	}
+	if n := len(report.Findings); n > 0 {
+		return exitErrorf(exitFindings, "%c Found %d vulnerable package version(s)", cross, n)
+	}
+	log.Printf("%c No open CVEs found", tick)
+	return nil
+}
+
+// scan returns the findings of the OVAL definitions for the packages in the
+// manifest, attributed to the installed slices of each package.
+func scan(m *manifest.Manifest, defs []*oval.Definition) *scanReport {
+	versions := make(map[string]string)
+	for _, p := range m.Packages {
+		versions[p.Name] = p.Version
+	}
+	slices := make(map[string][]string)
+	for _, s := range m.Slices {
+		pkg, _, err := chisel.Parse(s.Name)
+		if err != nil {
+			continue
+		}
+		slices[pkg] = append(slices[pkg], s.Name)
+	}
+	report := &scanReport{Findings: []*scanFinding{}}
+	for _, f := range oval.Scan(defs, versions) {
+		s := slices[f.Package]
+		sort.Strings(s)
+		report.Findings = append(report.Findings, &scanFinding{Finding: f, Slices: s})
+	}
+	return report
+}
@@ -0,0 +1,42 @@
+package main_test
+
+import (
+	"reflect"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+	"github.com/rebornplusplus/chisel-tools/internal/oval"
+)
+
+func TestScan(t *testing.T) {
+	m := &manifest.Manifest{
+		Packages: []*manifest.Package{
+			{Name: "openssl", Version: "3.0.13-0ubuntu3"},
+			{Name: "libc6", Version: "2.39-0ubuntu8"},
+		},
+		Slices: []*manifest.Slice{
+			{Name: "openssl_config"},
+			{Name: "openssl_bins"},
+			{Name: "libc6_libs"},
+		},
+	}
+	defs := []*oval.Definition{{
+		ID:    "oval:1",
+		Title: "USN-1",
+		CVEs:  []string{"CVE-2024-0001"},
+		Fixes: []*oval.Fix{{Package: "openssl", Version: "3.0.13-0ubuntu3.1"}},
+	}, {
+		ID:    "oval:2",
+		Title: "USN-2",
+		Fixes: []*oval.Fix{{Package: "libc6", Version: "2.39-0ubuntu8"}},
+	}}
+	report := sdf.Scan(m, defs)
+	if len(report.Findings) != 1 {
+		t.Fatalf("have %d findings, want 1", len(report.Findings))
+	}
+	f := report.Findings[0]
+	if f.Definition != "oval:1" || !reflect.DeepEqual(f.Slices, []string{"openssl_bins", "openssl_config"}) {
+		t.Fatalf("have finding %+v on %v", f.Finding, f.Slices)
+	}
+}
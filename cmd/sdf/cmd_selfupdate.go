@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/attest"
+	"github.com/rebornplusplus/chisel-tools/internal/debversion"
+	"github.com/rebornplusplus/chisel-tools/internal/selfupdate"
+)
+
+type cmdSelfUpdate struct {
+	Version string `long:"version" description:"Version to install (default: the latest one)"`
+	Check   bool   `long:"check" description:"Only show whether a newer version is available"`
+	Key     string `long:"key" description:"PEM public key the checksums of the release must be signed with (default: the release key built in)" path:"yes"`
+	Force   bool   `long:"force" description:"Install the version even if it is not newer than the running one"`
+	APIURL  string `long:"api-url" description:"GitHub API URL, e.g. of a mirror" default:"https://api.github.com"`
+	Token   string `long:"token" description:"GitHub token, to raise the API rate limit" env:"GITHUB_TOKEN"`
+
+	SkipSignature bool `long:"skip-signature" description:"Do not check the signature of the checksums of the release"`
+}
+
+func init() {
+	parser.AddCommand(
+		"self-update",
+		"Update sdf to its latest release",
+		"The self-update command downloads the latest release of sdf, or the one of --version, for the host and replaces the running binary with it, unless it is not newer. The archive is checked against the SHA-256 checksum published with the release, and the checksums against their signature with the release key built into sdf or the one of --key, unless --skip-signature is given. Development builds are only replaced with --force",
+		&cmdSelfUpdate{},
+	)
+}
+
+// updateCheck is the output of self-update --check.
+type updateCheck struct {
+	Current   string `json:"current"`
+	Latest    string `json:"latest"`
+	Available bool   `json:"available"`
+}
+
+func (c *cmdSelfUpdate) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	u := &selfupdate.Updater{APIURL: c.APIURL, Token: c.Token, SkipSignature: c.SkipSignature}
+	switch {
+	case c.SkipSignature && c.Key != "":
+		return exitErrorf(exitUsage, "cannot use --key with --skip-signature")
+	case c.SkipSignature:
+		log.Printf("Not checking the signature of the release, as --skip-signature was given")
+	case c.Key != "":
+		data, err := os.ReadFile(c.Key)
+		if err != nil {
+			return exitErrorf(exitUsage, "cannot read key: %w", err)
+		}
+		if u.Key, err = attest.ParsePublicKey(data); err != nil {
+			return exitErrorf(exitUsage, "cannot parse key %s: %w", c.Key, err)
+		}
+	default:
+		key, err := selfupdate.BuiltinKey()
+		if err != nil {
+			return err
+		}
+		if key == nil && !c.Check {
+			return exitErrorf(exitUsage, "no release key is built into this sdf, use --key, or --skip-signature to not check the signature")
+		}
+		u.Key = key
+	}
+
+	current := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		current = info.Main.Version
+	}
+	progressf("Finding the release of sdf...")
+	r, err := u.Release(c.Version)
+	if err != nil {
+		return exitErrorf(exitEnvironment, "cannot find release: %w", err)
+	}
+	update, reason := shouldUpdate(current, r.Version)
+	if c.Check {
+		t := &table{
+			Header: []string{"CURRENT", "LATEST", "AVAILABLE"},
+			Rows:   [][]string{{current, r.Version, fmt.Sprint(update)}},
+		}
+		return printOutput(&updateCheck{Current: current, Latest: r.Version, Available: update}, t)
+	}
+	if !update && !c.Force {
+		log.Printf("%c Not updating sdf %s: %s", tick, current, reason)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot find the sdf binary: %w", err)
+	}
+	progressf("Downloading sdf %s...", r.Version)
+	bin, err := u.Download(r)
+	if err != nil {
+		return fmt.Errorf("cannot download sdf %s: %w", r.Version, err)
+	}
+	if err := selfupdate.Replace(exe, bin); err != nil {
+		return exitErrorf(exitEnvironment, "cannot replace %s: %w", exe, err)
+	}
+	log.Printf("%c Updated sdf from %s to %s", tick, current, r.Version)
+	return nil
+}
+
+// Versions of builds from a checkout rather than a release, see
+// https://go.dev/ref/mod#pseudo-versions.
+var develVersion = regexp.MustCompile(`^\(devel\)$|-(0\.)?\d{14}-[0-9a-f]{12}|\+dirty$`)
+
+// shouldUpdate returns whether the running version, current, is to be
+// replaced with the release version, and why not if it is not.
+func shouldUpdate(current, version string) (update bool, reason string) {
+	if develVersion.MatchString(current) {
+		return false, "development build, use --force to replace it"
+	}
+	if cmp := debversion.Compare(debianVersion(current), debianVersion(version)); cmp == 0 {
+		return false, "already up to date"
+	} else if cmp > 0 {
+		return false, fmt.Sprintf("newer than %s, use --force to downgrade", version)
+	}
+	return true, ""
+}
+
+// debianVersion returns the semantic version v as a Debian version sorting
+// the same, with pre-releases before the releases.
+func debianVersion(v string) string {
+	return strings.Replace(strings.TrimPrefix(v, "v"), "-", "~", 1)
+}
@@ -0,0 +1,66 @@
+package main_test
+
+import (
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+var shouldUpdateTests = []struct {
+	summary string
+	current string
+	version string
+	update  bool
+}{{
+	summary: "Newer release",
+	current: "v1.0.0",
+	version: "v1.1.0",
+	update:  true,
+}, {
+	summary: "Newer release with a two digit component",
+	current: "v1.9.0",
+	version: "v1.10.0",
+	update:  true,
+}, {
+	summary: "Same release",
+	current: "v1.1.0",
+	version: "v1.1.0",
+}, {
+	summary: "Older release",
+	current: "v1.1.0",
+	version: "v1.0.3",
+}, {
+	summary: "Release of a pre-release",
+	current: "v1.1.0-rc1",
+	version: "v1.1.0",
+	update:  true,
+}, {
+	summary: "Pre-release of the release",
+	current: "v1.1.0",
+	version: "v1.1.0-rc2",
+}, {
+	summary: "Development build",
+	current: "(devel)",
+	version: "v1.1.0",
+}, {
+	summary: "Pseudo-version",
+	current: "v1.1.1-0.20260101120000-0123456789ab",
+	version: "v1.2.0",
+}, {
+	summary: "Modified checkout",
+	current: "v1.1.0+dirty",
+	version: "v1.2.0",
+}}
+
+func TestShouldUpdate(t *testing.T) {
+	for _, test := range shouldUpdateTests {
+		t.Logf("Summary: %s", test.summary)
+		update, reason := sdf.ShouldUpdate(test.current, test.version)
+		if update != test.update {
+			t.Fatalf("have update %v (%s), want %v", update, reason, test.update)
+		}
+		if !update && reason == "" {
+			t.Fatal("have no reason for not updating")
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/server"
+	"github.com/rebornplusplus/chisel-tools/internal/watch"
+)
+
+type cmdServe struct {
+	Release        string        `short:"r" long:"release" description:"Chisel release directory" required:"true" path:"yes"`
+	Addr           string        `long:"addr" description:"Address to listen on" default:"127.0.0.1:8080"`
+	ReloadInterval time.Duration `long:"reload-interval" description:"How often to check the release for changes" default:"1s"`
+	NoReload       bool          `long:"no-reload" description:"Serve the release as it was when starting"`
+}
+
+func init() {
+	parser.AddCommand(
+		"serve",
+		"Serve a JSON API over a release",
+		"The serve command loads the release and answers queries about it over HTTP until interrupted: GET / for the release, /slices[?package=PKG] and /slices/NAME for the slices, /slices/NAME/deps and /slices/NAME/rdeps for the slices installed along with it and the slices installing it, /find?path=PATH for the slices holding a path and /lint[?check=NAME] for the lint issues. The release is reloaded in the background whenever it changes; if it becomes invalid, the last valid state keeps being served",
+		&cmdServe{},
+	)
+}
+
+func (c *cmdServe) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.ReloadInterval <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --reload-interval: %s", c.ReloadInterval)
+	}
+	store, err := chisel.NewStore(c.Release)
+	if err != nil {
+		return fmt.Errorf("cannot read release: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	l, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler: server.New(store),
+		// Clients slow to send their headers must not hold connections
+		// forever.
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if !c.NoReload {
+		go func() {
+			err := watch.Watch(ctx, c.Release, c.ReloadInterval, func(changed []string) {
+				if err := store.Apply(changed); err != nil {
+					log.Printf("%c Cannot reload release, serving the previous one: %v", cross, err)
+					return
+				}
+				log.Printf("%c Release reloaded, %d slices", tick, len(store.Snapshot().Slices))
+			})
+			if err != nil {
+				log.Printf("%c Cannot watch release, not reloading anymore: %v", cross, err)
+			}
+		}()
+	}
+	log.Printf("Serving %s on http://%s/, interrupt to stop...", c.Release, l.Addr())
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
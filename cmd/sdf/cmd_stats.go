@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type cmdStats struct {
+	Release string `short:"r" long:"release" description:"Chisel release directory" required:"true" path:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"stats",
+		"Show the numbers of a release",
+		"The stats command counts the slice definition files, packages, slices, essential slices and paths of the release, along with the paths of every kind of entry",
+		&cmdStats{},
+	)
+}
+
+// releaseStats is the output of the stats command.
+type releaseStats struct {
+	Files      int `json:"files"`
+	Packages   int `json:"packages"`
+	Slices     int `json:"slices"`
+	Essentials int `json:"essentials"`
+	Paths      int `json:"paths"`
+	// Paths by the kind of their entry.
+	Kinds map[chisel.PathKind]int `json:"kinds"`
+}
+
+func (c *cmdStats) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	r, err := chisel.ReadRelease(c.Release)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	stats, tables := statsOutput(r)
+	return printOutput(stats, tables...)
+}
+
+// statsOutput returns the output of the stats command.
+func statsOutput(r *chisel.Release) (*releaseStats, []*table) {
+	stats := &releaseStats{Kinds: make(map[chisel.PathKind]int)}
+	files := make(map[string]bool)
+	pkgs := make(map[string]bool)
+	for _, s := range r.Slices {
+		files[s.File] = true
+		pkgs[s.Package] = true
+		stats.Slices++
+		stats.Essentials += len(s.Essential)
+		stats.Paths += len(s.Contents)
+		for _, p := range s.Contents {
+			stats.Kinds[s.Paths[p].Kind]++
+		}
+	}
+	stats.Files, stats.Packages = len(files), len(pkgs)
+
+	counts := &table{
+		Header: []string{"FILES", "PACKAGES", "SLICES", "ESSENTIALS", "PATHS"},
+		Rows: [][]string{{
+			strconv.Itoa(stats.Files), strconv.Itoa(stats.Packages), strconv.Itoa(stats.Slices),
+			strconv.Itoa(stats.Essentials), strconv.Itoa(stats.Paths),
+		}},
+	}
+	kinds := &table{Header: []string{"KIND", "PATHS"}}
+	var names []string
+	for k := range stats.Kinds {
+		names = append(names, string(k))
+	}
+	slices.Sort(names)
+	for _, k := range names {
+		kinds.Rows = append(kinds.Rows, []string{k, strconv.Itoa(stats.Kinds[chisel.PathKind(k)])})
+	}
+	return stats, []*table{counts, kinds}
+}
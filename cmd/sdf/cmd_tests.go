@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+type cmdTest struct {
+	Release string `short:"r" long:"release" description:"Chisel release path" required:"true" path:"yes"`
+	Arch    string `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Workers int    `short:"w" long:"workers" description:"Number of concurrent workers" default:"4"`
+	Run     string `long:"run" description:"Run only the tests whose <package>/<test> name matches this regular expression"`
+	Backend string `long:"backend" description:"How to run smoke commands and probes inside the root" choice:"unshare" choice:"chroot" default:"unshare"`
+
+	ServiceBackend string        `long:"service-backend" description:"Container engine to boot roots with for service tests" choice:"podman" choice:"docker" default:"podman"`
+	ServiceTimeout time.Duration `long:"service-timeout" description:"How long to wait for the services of a test to become active" default:"60s"`
+	Output         string        `short:"o" long:"output" description:"Write the results as JSON to this file" path:"yes"`
+
+	watchOptions
+
+	Positional struct {
+		Files []string `positional-arg-name:"test spec files"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"test",
+		"Run slice tests",
+		"The test command cuts the slices of every test in the spec files, by default tests/*.yaml in the release, into a fresh root and checks the expected paths and commands against it. Smoke commands run inside the root, with chroot in a user namespace or plain chroot. Tests may also use built-in probes by name: elf, python-import, java-classpath and ca-certificates. For service tests, the root is booted with systemd in a container. With --watch, it keeps running and runs the tests of the packages that change in the release again",
+		&cmdTest{},
+	)
+}
+
+// A testResult is the outcome of one test of a spec.
+type testResult struct {
+	Package  string               `json:"package"`
+	Test     string               `json:"test"`
+	Slices   []string             `json:"slices"`
+	Passed   bool                 `json:"passed"`
+	Error    string               `json:"error,omitempty"`
+	Failures []*slicetest.Failure `json:"failures,omitempty"`
+
+	test *slicetest.Test
+}
+
+func (r *testResult) name() string {
+	return r.Package + "/" + r.Test
+}
+
+func (c *cmdTest) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Workers <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --workers: %d", c.Workers)
+	}
+	var filter *regexp.Regexp
+	if c.Run != "" {
+		var err error
+		if filter, err = regexp.Compile(c.Run); err != nil {
+			return exitErrorf(exitUsage, "invalid value for --run: %w", err)
+		}
+	}
+	if err := checkChisel(c.Release); err != nil {
+		return err
+	}
+	files := c.Positional.Files
+	if len(files) == 0 {
+		var err error
+		if files, err = slicetest.SpecFiles(c.Release); err != nil {
+			return err
+		}
+	}
+	err := c.runFiles(files, filter, nil)
+	if !c.Watch {
+		return err
+	}
+	if err != nil {
+		log.Print(err)
+	}
+	return c.watch(c.Release, func(changed []string) error {
+		pkgs := testPackages(c.Release, files, changed)
+		if pkgs != nil && len(pkgs) == 0 {
+			return nil
+		}
+		return c.runFiles(files, filter, pkgs)
+	})
+}
+
+// testPackages returns the packages whose tests must run again after the
+// changes to the release, or nil if all of them must. Changed spec files
+// select their package too.
+func testPackages(release string, specs, changed []string) map[string]bool {
+	pkgs, all := changedPackages(release, changed)
+	if all {
+		return nil
+	}
+	for _, f := range affectedFiles(release, specs, changed) {
+		if spec, err := slicetest.ReadFile(f); err == nil {
+			pkgs[spec.Package] = true
+		}
+	}
+	return pkgs
+}
+
+// runFiles runs the tests of the spec files that match the filter. If pkgs
+// is not nil, only the tests of those packages, or installing slices of
+// those packages, are run.
+func (c *cmdTest) runFiles(files []string, filter *regexp.Regexp, pkgs map[string]bool) error {
+	results, err := c.selectTests(files, filter, pkgs)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		log.Printf("%c No tests to run", tick)
+		return nil
+	}
+
+	c.run(results)
+
+	failed := 0
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+	if c.Output != "" {
+		if err := writeJSON(c.Output, results); err != nil {
+			return fmt.Errorf("cannot write results: %w", err)
+		}
+	}
+	if failed > 0 {
+		return exitErrorf(exitInstall, "%c %d of %d test(s) failed", cross, failed, len(results))
+	}
+	log.Printf("%c %d test(s) passed", tick, len(results))
+	return nil
+}
+
+// selectTests returns the tests to run, see [cmdTest.runFiles].
+func (c *cmdTest) selectTests(files []string, filter *regexp.Regexp, pkgs map[string]bool) ([]*testResult, error) {
+	var release *chisel.Release
+	if pkgs != nil {
+		// Without the release, only the slices of the tests are checked
+		// and not their essential ones.
+		release, _ = chisel.ReadRelease(c.Release)
+	}
+	var results []*testResult
+	for _, f := range files {
+		spec, err := slicetest.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range spec.SortedTests() {
+			r := &testResult{Package: spec.Package, Test: t.Name, Slices: t.Slices, test: t}
+			if filter != nil && !filter.MatchString(r.name()) {
+				continue
+			}
+			if pkgs == nil || affectsTest(release, pkgs, r) {
+				results = append(results, r)
+			}
+		}
+	}
+	return results, nil
+}
+
+// affectsTest returns whether changes to the packages affect the test,
+// because it is a test of one of them or because it installs slices of them,
+// essential ones included if the release is not nil.
+func affectsTest(release *chisel.Release, pkgs map[string]bool, r *testResult) bool {
+	if pkgs[r.Package] {
+		return true
+	}
+	seen := make(map[string]bool)
+	todo := append([]string{}, r.Slices...)
+	for len(todo) > 0 {
+		name := todo[0]
+		todo = todo[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if pkg, _, err := chisel.Parse(name); err == nil && pkgs[pkg] {
+			return true
+		}
+		if release != nil {
+			if s := release.Slice(name); s != nil {
+				todo = append(todo, s.Essential...)
+			}
+		}
+	}
+	return false
+}
+
+// run runs the tests concurrently, each in a fresh root.
+func (c *cmdTest) run(results []*testResult) {
+	forEachCut(c.Workers, results, func(r *testResult, cacheDir string, err error) {
+		if err != nil {
+			r.Error = err.Error()
+			return
+		}
+		c.runTest(r, cacheDir)
+	})
+}
+
+func (c *cmdTest) runTest(r *testResult, cacheDir string) {
+	defer func() {
+		if r.Passed {
+			progressf("%c %s", tick, r.name())
+			return
+		}
+		log.Printf("%c %s", cross, r.name())
+		if r.Error != "" {
+			log.Printf("    %s", r.Error)
+		}
+		for _, f := range r.Failures {
+			log.Printf("    %s", f)
+		}
+	}()
+
+	root, err := os.MkdirTemp("", "sdf-test-")
+	if err != nil {
+		r.Error = err.Error()
+		return
+	}
+	defer os.RemoveAll(root)
+
+	ctx := context.Background()
+	err = cut(ctx, &cutOptions{
+		Release:  c.Release,
+		Arch:     c.Arch,
+		Root:     root,
+		CacheDir: cacheDir,
+		Slices:   r.Slices,
+	})
+	if err != nil {
+		r.Error = err.Error()
+		return
+	}
+	r.Failures = slicetest.Check(ctx, root, r.test, slicetest.HostRunner)
+	exec := slicetest.UnshareExecutor
+	if c.Backend == "chroot" {
+		exec = slicetest.ChrootExecutor
+	}
+	if len(r.test.Smoke) > 0 {
+		r.Failures = append(r.Failures, slicetest.Smoke(ctx, root, r.test, exec)...)
+	}
+	if len(r.test.Probes) > 0 {
+		r.Failures = append(r.Failures, slicetest.Probe(ctx, root, r.test, exec)...)
+	}
+	if len(r.test.Services) > 0 {
+		boot := slicetest.PodmanBooter
+		if c.ServiceBackend == "docker" {
+			boot = slicetest.DockerBooter
+		}
+		r.Failures = append(r.Failures, slicetest.Services(ctx, root, r.test, boot, c.ServiceTimeout)...)
+	}
+	r.Passed = len(r.Failures) == 0
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/tui"
+)
+
+type cmdTUI struct {
+	Release string `short:"r" long:"release" description:"Chisel release directory" required:"true" path:"yes"`
+	Arch    string `short:"a" long:"arch" description:"Package architecture" default:"amd64"`
+	Root    string `long:"root" description:"Directory to cut the slices into, a new temporary one per cut if empty" path:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"tui",
+		"Browse a release interactively",
+		"The tui command opens a terminal interface to browse the packages and slices of the release, view the contents and the tree of essential slices of every slice, and cut the selected slice with chisel. Temporary roots are removed when quitting",
+		&cmdTUI{},
+	)
+}
+
+func (c *cmdTUI) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	r, err := chisel.ReadRelease(c.Release)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	if err := checkChisel(c.Release); err != nil {
+		return err
+	}
+	term, err := tui.Open(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if err := term.Start(); err != nil {
+		return err
+	}
+	defer term.Stop()
+
+	var tmpDirs []string
+	defer func() {
+		for _, dir := range tmpDirs {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	// The browser draws plain text, the marks are not colored.
+	b := tui.NewBrowser(r)
+	for {
+		if err := term.Draw(b.Render(term.Size())); err != nil {
+			return err
+		}
+		keys, err := term.ReadKeys()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			switch b.Handle(k) {
+			case tui.Quit:
+				return nil
+			case tui.Reload:
+				r, err := chisel.ReadRelease(c.Release)
+				if err != nil {
+					b.ShowOutput("Cannot read release", err.Error())
+					continue
+				}
+				b.SetRelease(r)
+				b.SetStatus("%c Release reloaded, %d slices", rune(tick), len(r.Slices))
+			case tui.Cut:
+				slice := b.Selected()
+				root := c.Root
+				if root == "" {
+					if root, err = os.MkdirTemp("", "sdf-tui-"); err != nil {
+						return err
+					}
+					tmpDirs = append(tmpDirs, root)
+				}
+				b.SetStatus("Cutting %s...", slice)
+				if err := term.Draw(b.Render(term.Size())); err != nil {
+					return err
+				}
+				err := cut(context.Background(), &cutOptions{
+					Release: c.Release,
+					Arch:    c.Arch,
+					Root:    root,
+					Slices:  []string{slice},
+				})
+				if err != nil {
+					b.ShowOutput(fmt.Sprintf("%c Cannot cut %s", rune(cross), slice), err.Error())
+					continue
+				}
+				b.SetStatus("%c %s installed into %s (%d paths)", rune(tick), slice, root, countPaths(root))
+			}
+		}
+	}
+}
+
+// countPaths returns the number of paths under root, root excluded.
+func countPaths(root string) int {
+	n := 0
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && path != root {
+			n++
+		}
+		return nil
+	})
+	return n
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rebornplusplus/chisel-tools/internal/cache"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+type cmdVerify struct {
+	Root     string `long:"root" description:"Root directory chisel installed slices into" path:"yes"`
+	Manifest string `long:"manifest" description:"Chisel manifest path, instead of the one in the root" path:"yes"`
+	Output   string `short:"o" long:"output" description:"Write the problems as JSON to this file" path:"yes"`
+
+	Release string `short:"r" long:"release" description:"Chisel release path, to install its slices and check them against their contents instead" path:"yes"`
+	Arch    string `short:"a" long:"arch" description:"Package architecture to install the slices for" default:"amd64"`
+	Workers int    `short:"w" long:"workers" description:"Number of concurrent workers" default:"10"`
+	Roots   string `long:"roots" description:"Keep the root of every slice in this directory, which must be empty" path:"yes"`
+
+	Positional struct {
+		Files []sliceFile `positional-arg-name:"slice definition files"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"verify",
+		"Verify a root against its manifest, or slices against their contents",
+		"The verify command checks that every path in the chisel manifest of a root exists with the recorded digest and mode, and that the root has no unlisted paths. With --release instead, it installs every slice of the files, of all slice definition files of the release if none are given, alone into its own root and checks that the root holds what the contents of the slice declare: every path and glob exists with the declared type, mode, symlink target and text, the copies have the type, mode and symlink target of their source in the package, as dpkg-deb reads it, and the paths until mutate were removed. The content of the copies is not checked, and neither is the one of mutable paths if a mutation script ran. The paths chisel takes from another package, preferred by prefer, are only checked to exist",
+		&cmdVerify{},
+	)
+}
+
+func (c *cmdVerify) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	switch {
+	case c.Root != "" && c.Release != "":
+		return exitErrorf(exitUsage, "cannot use --root with --release")
+	case c.Release != "":
+		return c.verifyContents()
+	case c.Root == "":
+		return exitErrorf(exitUsage, "either --root or --release is required")
+	case len(c.Positional.Files) > 0:
+		return exitErrorf(exitUsage, "slice definition files need --release")
+	}
+	m, err := readManifest(c.Root, c.Manifest)
+	if err != nil {
+		return err
+	}
+	progressf("Verifying %s...", c.Root)
+	problems, err := rootfs.Verify(c.Root, m)
+	if err != nil {
+		return fmt.Errorf("cannot verify root: %w", err)
+	}
+	if err := c.writeProblems(problems); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return exitErrorf(exitFindings, "%c Root does not match its manifest: %d problem(s)", cross, len(problems))
+	}
+	log.Printf("%c Root matches its manifest", tick)
+	return nil
+}
+
+// writeProblems prints the problems and writes them to the output, if any.
+func (c *cmdVerify) writeProblems(problems []*rootfs.Problem) error {
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if c.Output != "" {
+		if problems == nil {
+			problems = []*rootfs.Problem{}
+		}
+		if err := writeJSON(c.Output, problems); err != nil {
+			return fmt.Errorf("cannot write problems: %w", err)
+		}
+	}
+	return nil
+}
+
+// verifyContents installs every slice alone and checks its root against
+// its contents.
+func (c *cmdVerify) verifyContents() error {
+	if c.Workers <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --workers: %d", c.Workers)
+	}
+	if !slices.Contains(chisel.Arches, c.Arch) {
+		return exitErrorf(exitUsage, "invalid value for --arch: %q, want one of %s", c.Arch, strings.Join(chisel.Arches, ", "))
+	}
+	if c.Roots != "" {
+		if entries, err := os.ReadDir(c.Roots); err == nil && len(entries) > 0 {
+			return exitErrorf(exitUsage, "cannot keep the roots in %s: directory is not empty", c.Roots)
+		}
+	}
+	r, err := readRelease(newParseCache(false), c.Release)
+	if err != nil {
+		return exitErrorf(exitFindings, "cannot read release: %w", err)
+	}
+	files := make(map[string]bool)
+	for _, f := range names(c.Positional.Files) {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		files[abs] = true
+	}
+	var selected []*chisel.Slice
+	for _, s := range r.Slices {
+		abs, err := filepath.Abs(s.File)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 || files[abs] {
+			selected = append(selected, s)
+		}
+	}
+	if err := checkChisel(c.Release); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("dpkg-deb"); err != nil {
+		return exitErrorf(exitEnvironment, "cannot find dpkg-deb to read the packages, install dpkg: %w", err)
+	}
+
+	var mu sync.Mutex
+	var problems []*rootfs.Problem
+	var tasks []*task
+	debs := &debCache{}
+	for _, s := range selected {
+		var installed []*chisel.Slice
+		pkgs := []string{s.Package}
+		for _, name := range r.Deps(s.Name) {
+			if dep := r.Slice(name); dep != nil {
+				installed = append(installed, dep)
+				pkgs = append(pkgs, dep.Package)
+			}
+		}
+		t := &task{
+			args:   []string{"cut", "--release", c.Release, "--arch", c.Arch},
+			arch:   c.Arch,
+			slices: []string{s.Name},
+			check: func(root, cacheDir string) error {
+				packages, err := debs.packages(filepath.Join(cacheDir, "chisel"), pkgs)
+				if err != nil {
+					return err
+				}
+				found, err := rootfs.CheckContents(root, []*chisel.Slice{s}, &rootfs.ContentsOptions{Arch: c.Arch, Installed: installed, Packages: packages})
+				if err != nil {
+					return withExitCode(exitFindings, err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				problems = append(problems, found...)
+				return nil
+			},
+		}
+		if c.Roots != "" {
+			t.root = filepath.Join(c.Roots, s.Name)
+		}
+		tasks = append(tasks, t)
+	}
+	install := &cmdInstall{Release: c.Release, Arch: c.Arch, Workers: c.Workers, Continue: true}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	_, installErr := install.install(ctx, tasks)
+
+	// The problems of the slices are found in the order they were
+	// installed.
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Slice < problems[j].Slice
+	})
+	if err := c.writeProblems(problems); err != nil {
+		return err
+	}
+	if installErr != nil {
+		return installErr
+	}
+	if len(problems) > 0 {
+		return exitErrorf(exitFindings, "%c Slices do not match their contents: %d problem(s)", cross, len(problems))
+	}
+	log.Printf("%c %d slice(s) match their contents", tick, len(tasks))
+	return nil
+}
+
+// A debCache holds the entries of the packages chisel fetched into the caches
+// of the workers, each deb read once. All workers fetch the same version of
+// a package in a run.
+type debCache struct {
+	mu      sync.Mutex
+	read    map[string]bool                            // Debs read, by path.
+	entries map[string]map[string]*rootfs.PackageEntry // By package.
+}
+
+// packages returns the entries of the packages, of those chisel fetched into
+// the cache directory.
+func (c *debCache) packages(dir string, names []string) (map[string]map[string]*rootfs.PackageEntry, error) {
+	entries, err := cache.Find(&cache.Dirs{Chisel: dir})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the packages chisel fetched: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.read == nil {
+		c.read = make(map[string]bool)
+		c.entries = make(map[string]map[string]*rootfs.PackageEntry)
+	}
+	for _, e := range entries {
+		if e.Kind != cache.Deb || c.read[e.Path] {
+			continue
+		}
+		name, pkg, err := rootfs.ReadDeb(e.Path)
+		if err != nil {
+			return nil, err
+		}
+		c.read[e.Path] = true
+		c.entries[name] = pkg
+	}
+	found := make(map[string]map[string]*rootfs.PackageEntry)
+	for _, name := range names {
+		if pkg, ok := c.entries[name]; ok {
+			found[name] = pkg
+		}
+	}
+	return found, nil
+}
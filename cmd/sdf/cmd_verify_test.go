@@ -0,0 +1,35 @@
+package main_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+	"github.com/rebornplusplus/chisel-tools/internal/fixtures"
+)
+
+func TestVerifyContents(t *testing.T) {
+	if _, err := exec.LookPath("dpkg-deb"); err != nil {
+		t.Skip("dpkg-deb not installed")
+	}
+	f, err := fixtures.Write(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", filepath.Dir(f.Chisel)+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	if err := sdf.VerifyContents(f.Release); err != nil {
+		t.Fatal(err)
+	}
+
+	// The mode of the copies is the one in the package.
+	if err := os.Chmod(filepath.Join(f.Dir, "slices", "hello_bins", "usr", "bin", "hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = sdf.VerifyContents(f.Release)
+	if code := sdf.ExitCode(err); code != 3 {
+		t.Fatalf("have exit code %d for error %v, want 3", code, err)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type cmdVersion struct {
+	Release string `short:"r" long:"release" description:"Also check that chisel supports this release" path:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"version",
+		"Show version information",
+		"The version command shows the version of sdf, the chisel binary on the PATH and the release formats it supports. With --release, it also fails if chisel does not support the format of the release. The commands installing slices check it too before running, and warn or fail as set with --chisel-compat",
+		&cmdVersion{},
+	)
+}
+
+func (c *cmdVersion) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	fmt.Printf("sdf:    %s\n", buildVersion())
+	fmt.Printf("go:     %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	version, err := detectChisel()
+	if err != nil {
+		fmt.Printf("chisel: %v\n", err)
+	} else {
+		fmt.Printf("chisel: %s (formats %s)\n", version, strings.Join(chisel.SupportedFormats(version), ", "))
+	}
+	if c.Release == "" {
+		return nil
+	}
+	cfg, err := chisel.ParseConfig(filepath.Join(c.Release, "chisel.yaml"))
+	if err != nil {
+		return fmt.Errorf("cannot parse chisel.yaml: %w", err)
+	}
+	fmt.Printf("release: %s (format %s)\n", c.Release, cfg.Format)
+	if version == "" {
+		return nil
+	}
+	if err := chisel.CheckFormat(cfg.Format, version); err != nil {
+		return exitErrorf(exitEnvironment, "%c Chisel does not support the release: %w", cross, err)
+	}
+	log.Printf("%c Chisel supports the release", tick)
+	return nil
+}
+
+// buildVersion returns the module version sdf was built from, along with
+// the commit for builds from a checkout.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	settings := make(map[string]string)
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	// Pseudo-versions already hold the commit.
+	if rev := settings["vcs.revision"]; rev != "" && !strings.Contains(version, rev[:min(len(rev), 12)]) {
+		version += " " + rev[:min(len(rev), 12)]
+		if settings["vcs.modified"] == "true" {
+			version += " (modified)"
+		}
+	}
+	return version
+}
+
+// detectChisel returns the version of the chisel binary on the PATH.
+func detectChisel() (string, error) {
+	out, err := chiselVersion()
+	if err != nil {
+		return "", err
+	}
+	return chisel.ParseVersion(out)
+}
+
+// checkChisel checks that the chisel binary on the PATH supports the format
+// of the release, and warns or fails as set with --chisel-compat. Missing
+// binaries are left for chisel cut to report.
+func checkChisel(release string) error {
+	if opts.ChiselCompat == "ignore" {
+		return nil
+	}
+	version, err := detectChisel()
+	if err != nil {
+		return nil
+	}
+	cfg, err := chisel.ParseConfig(filepath.Join(release, "chisel.yaml"))
+	if err != nil {
+		return nil
+	}
+	if err := chisel.CheckFormat(cfg.Format, version); err != nil {
+		if opts.ChiselCompat == "fail" {
+			return exitErrorf(exitEnvironment, "%c Chisel does not support the release: %w", cross, err)
+		}
+		log.Printf("Warning: chisel may not support the release: %v", err)
+	}
+	return nil
+}
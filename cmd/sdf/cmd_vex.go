@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+	"github.com/rebornplusplus/chisel-tools/internal/sbom"
+	"github.com/rebornplusplus/chisel-tools/internal/vex"
+)
+
+type cmdVEX struct {
+	Scan     string `long:"scan" description:"Scan report written by sdf scan -o" required:"true"`
+	Root     string `long:"root" description:"Root directory chisel installed slices into" path:"yes"`
+	Manifest string `long:"manifest" description:"Chisel manifest path, instead of the one in the root" path:"yes"`
+	Files    string `long:"files" description:"YAML file mapping CVEs to patterns of their vulnerable files" path:"yes"`
+	Product  string `long:"product" description:"Product ID the statements are about, e.g. the image reference"`
+	Author   string `long:"author" description:"Author of the document" default:"sdf"`
+	Output   string `short:"o" long:"output" description:"Output file (default: stdout)" path:"yes"`
+}
+
+func init() {
+	parser.AddCommand(
+		"vex",
+		"Generate OpenVEX statements for a scan",
+		"The vex command turns the findings of sdf scan into OpenVEX statements, marking a CVE as not affecting the root when none of its vulnerable files, or only documentation, are installed from the package",
+		&cmdVEX{},
+	)
+}
+
+func (c *cmdVEX) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if c.Root == "" && c.Manifest == "" {
+		return fmt.Errorf("either --root or --manifest must be specified")
+	}
+	data, err := os.ReadFile(c.Scan)
+	if err != nil {
+		return err
+	}
+	var report scanReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return fmt.Errorf("cannot parse scan report: %w", err)
+	}
+	files := make(map[string][]string)
+	if c.Files != "" {
+		data, err := os.ReadFile(c.Files)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(data, &files); err != nil {
+			return fmt.Errorf("cannot parse %s: %w", c.Files, err)
+		}
+	}
+	m, err := readManifest(c.Root, c.Manifest)
+	if err != nil {
+		return err
+	}
+
+	findings, err := vexFindings(&report, m, files)
+	if err != nil {
+		return err
+	}
+	doc := vex.Generate(&vex.Input{
+		Author:   c.Author,
+		Product:  c.Product,
+		Created:  time.Now(),
+		Findings: findings,
+	})
+	notAffected := 0
+	for _, s := range doc.Statements {
+		if s.Status == vex.NotAffected {
+			notAffected++
+		}
+	}
+	log.Printf("%c %d of %d statement(s) are not affected", tick, notAffected, len(doc.Statements))
+	return writeJSON(c.Output, doc)
+}
+
+// vexFindings returns a finding per CVE and package of the report, with the
+// paths the manifest lists for the slices of the package.
+func vexFindings(report *scanReport, m *manifest.Manifest, files map[string][]string) ([]*vex.Finding, error) {
+	pkgs := make(map[string]*manifest.Package)
+	for _, p := range m.Packages {
+		pkgs[p.Name] = p
+	}
+	installed := make(map[string][]string)
+	for _, p := range m.Paths {
+		seen := make(map[string]bool)
+		for _, s := range p.Slices {
+			pkg, _, err := chisel.Parse(s)
+			if err != nil || seen[pkg] {
+				continue
+			}
+			seen[pkg] = true
+			installed[pkg] = append(installed[pkg], p.Path)
+		}
+	}
+	var findings []*vex.Finding
+	for _, f := range report.Findings {
+		pkg, ok := pkgs[f.Package]
+		if !ok {
+			return nil, fmt.Errorf("package %s of the scan report is not in the manifest", f.Package)
+		}
+		for _, cve := range f.CVEs {
+			findings = append(findings, &vex.Finding{
+				CVE:       cve,
+				PURL:      sbom.PURL(pkg),
+				Package:   f.Package,
+				Fixed:     f.Fixed,
+				Installed: installed[f.Package],
+				Files:     files[cve],
+			})
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/tui"
+)
+
+// A mark is the status symbol starting a line of output. It is colored when
+// formatted with %c, if colors are enabled, but not when converted to a
+// string, as in tables.
+type mark rune
+
+const (
+	tick  mark = '\u2713'
+	cross mark = '\u2717'
+	warn  mark = '!'
+	skip  mark = '-'
+)
+
+// ANSI escape sequences.
+const (
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	reset  = "\x1b[0m"
+)
+
+var markColors = map[mark]string{
+	tick:  green,
+	cross: red,
+	warn:  yellow,
+	skip:  yellow,
+}
+
+// useColor is whether the output is colored, see [setColor].
+var useColor bool
+
+// setColor enables colors as set with --color. In auto mode, the output is
+// colored if it goes to a terminal and NO_COLOR is not set, see
+// https://no-color.org.
+func setColor(mode string) {
+	switch mode {
+	case "always":
+		useColor = true
+	case "never":
+		useColor = false
+	default:
+		useColor = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && tui.IsTerminal(os.Stderr)
+	}
+}
+
+func (m mark) Format(f fmt.State, verb rune) {
+	io.WriteString(f, colored(markColors[m], string(m)))
+}
+
+// colored returns the text in the color, if colors are enabled.
+func colored(color, text string) string {
+	if !useColor || color == "" {
+		return text
+	}
+	return color + text + reset
+}
+
+// colorDiff colors the added lines of a diff, starting with "+", in green
+// and the removed ones, starting with "-", in red.
+func colorDiff(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "+"):
+			out[i] = colored(green, l)
+		case strings.HasPrefix(l, "-"):
+			out[i] = colored(red, l)
+		default:
+			out[i] = l
+		}
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package main_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+func TestColor(t *testing.T) {
+	defer sdf.SetColor("never")
+
+	sdf.SetColor("always")
+	if have, want := fmt.Sprintf("%c ok %c", sdf.Tick, sdf.Cross), "\x1b[32m✓\x1b[0m ok \x1b[31m✗\x1b[0m"; have != want {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+	have := sdf.ColorDiff([]string{"-a", "+b", " c"})
+	want := []string{"\x1b[31m-a\x1b[0m", "\x1b[32m+b\x1b[0m", " c"}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+
+	sdf.SetColor("never")
+	if have, want := fmt.Sprintf("%c ok", sdf.Tick), "✓ ok"; have != want {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+
+	// Test output is not a terminal.
+	t.Setenv("NO_COLOR", "")
+	sdf.SetColor("auto")
+	if have, want := fmt.Sprintf("%c", sdf.Cross), "✗"; have != want {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+}
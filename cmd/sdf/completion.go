@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+// releaseArg returns the value of the --release flag in the command line
+// being completed. Option values are not set during completion, so it has to
+// be found by hand.
+func releaseArg() string {
+	return flagArg(os.Args[1:], "r", "release")
+}
+
+// A sliceName is a slice name argument, completed from the slices of the
+// release given with --release.
+type sliceName string
+
+func (*sliceName) Complete(match string) []flags.Completion {
+	dir := releaseArg()
+	if dir == "" {
+		return nil
+	}
+	r, err := chisel.ReadRelease(dir)
+	if err != nil {
+		return nil
+	}
+	var items []flags.Completion
+	for _, s := range r.Slices {
+		if strings.HasPrefix(s.Name, match) {
+			items = append(items, flags.Completion{Item: s.Name})
+		}
+	}
+	return items
+}
+
+// A sliceFile is a slice definition file argument, completed from the
+// directories and YAML files matching, and the slice definition files of the
+// release given with --release.
+type sliceFile string
+
+func (*sliceFile) Complete(match string) []flags.Completion {
+	var items []flags.Completion
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			items = append(items, flags.Completion{Item: p})
+		}
+	}
+	matches, _ := filepath.Glob(match + "*")
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			add(m + "/")
+		} else if strings.HasSuffix(m, ".yaml") {
+			add(m)
+		}
+	}
+	if dir := releaseArg(); dir != "" {
+		files, _ := chisel.SliceFiles(dir)
+		for _, f := range files {
+			if strings.HasPrefix(f, match) {
+				add(f)
+			}
+		}
+	}
+	return items
+}
+
+// names converts the arguments back to strings.
+func names[T ~string](args []T) []string {
+	s := make([]string, len(args))
+	for i, a := range args {
+		s[i] = string(a)
+	}
+	return s
+}
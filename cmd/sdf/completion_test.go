@@ -0,0 +1,81 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+	"github.com/rebornplusplus/chisel-tools/internal/fixtures"
+)
+
+var completeSliceTests = []struct {
+	summary string
+	args    []string
+	match   string
+	items   []string
+}{{
+	summary: "Release flag with a separate value",
+	args:    []string{"golden", "-r", "RELEASE"},
+	match:   "hello_",
+	items:   []string{"hello_bins", "hello_copyright"},
+}, {
+	summary: "Long release flag with an equal sign",
+	args:    []string{"bisect", "--release=RELEASE", "--slice"},
+	match:   "lib",
+	items:   []string{"libhello1_copyright", "libhello1_libs"},
+}, {
+	summary: "Short release flag with the value attached",
+	args:    []string{"matrix", "-rRELEASE"},
+	match:   "",
+	items:   []string{"hello_bins", "hello_copyright", "libhello1_copyright", "libhello1_libs"},
+}, {
+	summary: "No release flag",
+	args:    []string{"golden"},
+	match:   "hello_",
+}}
+
+func TestCompleteSliceName(t *testing.T) {
+	f, err := fixtures.Write(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(args []string) { os.Args = args }(os.Args)
+	for _, test := range completeSliceTests {
+		t.Logf("Summary: %s", test.summary)
+		os.Args = []string{"sdf"}
+		for _, arg := range test.args {
+			os.Args = append(os.Args, strings.ReplaceAll(arg, "RELEASE", f.Release))
+		}
+		var items []string
+		for _, c := range new(sdf.SliceName).Complete(test.match) {
+			items = append(items, c.Item)
+		}
+		if !reflect.DeepEqual(items, test.items) {
+			t.Fatalf("have %q, want %q", items, test.items)
+		}
+	}
+}
+
+func TestCompleteSliceFile(t *testing.T) {
+	f, err := fixtures.Write(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"sdf", "install", "--release", f.Release}
+	var items []string
+	for _, c := range new(sdf.SliceFile).Complete(filepath.Join(f.Release, "s")) {
+		items = append(items, c.Item)
+	}
+	want := []string{
+		filepath.Join(f.Release, "slices") + "/",
+		filepath.Join(f.Release, "slices/hello.yaml"),
+		filepath.Join(f.Release, "slices/libhello1.yaml"),
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("have %q, want %q", items, want)
+	}
+}
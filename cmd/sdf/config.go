@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/rebornplusplus/chisel-tools/internal/sdfconfig"
+)
+
+// flagArg returns the value of the flag in the arguments, before they are
+// parsed, or an empty string if it is not there. The short name may be
+// empty.
+func flagArg(args []string, short, long string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return ""
+		case arg == "--"+long || (short != "" && arg == "-"+short):
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+		case strings.HasPrefix(arg, "--"+long+"="):
+			return strings.TrimPrefix(arg, "--"+long+"=")
+		case short != "" && strings.HasPrefix(arg, "-"+short) && !strings.HasPrefix(arg, "--"):
+			return arg[1+len(short):]
+		}
+	}
+	return ""
+}
+
+// applyConfig sets the defaults of the command flags from the configuration
+// files and the profile, see [sdfconfig]. Values from the environment and
+// the command line still take precedence. The flags tagged path:"yes" are
+// paths relative to the configuration file they are set in.
+func applyConfig(p *flags.Parser, dir, profile string) error {
+	files, err := sdfconfig.Load(dir)
+	if err != nil {
+		return fmt.Errorf("cannot read configuration: %w", err)
+	}
+	if len(files) == 0 && profile == "" {
+		return nil
+	}
+	v, err := sdfconfig.Resolve(files, profile)
+	if err != nil {
+		return err
+	}
+	commands := make(map[string]*flags.Command)
+	for _, cmd := range p.Commands() {
+		commands[cmd.Name] = cmd
+	}
+	var unknown []string
+	for name, values := range v.Commands {
+		cmd, ok := commands[name]
+		if !ok {
+			unknown = append(unknown, fmt.Sprintf("command %q", name))
+			continue
+		}
+		for flag := range values {
+			if cmd.FindOptionByLongName(flag) == nil {
+				unknown = append(unknown, fmt.Sprintf("flag %q of command %s", flag, name))
+			}
+		}
+	}
+	used := make(map[string]bool)
+	for _, cmd := range p.Commands() {
+		for flag, value := range v.For(cmd.Name) {
+			if opt := cmd.FindOptionByLongName(flag); opt != nil {
+				if dir := v.Dir(cmd.Name, flag); dir != "" && isPathOption(opt) {
+					value = resolvePaths(dir, value)
+				}
+				opt.Default = value
+				used[flag] = true
+			}
+		}
+	}
+	for flag := range v.Flags {
+		if !used[flag] {
+			unknown = append(unknown, fmt.Sprintf("flag %q", flag))
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return exitErrorf(exitUsage, "invalid configuration: unknown %s", strings.Join(unknown, ", unknown "))
+	}
+	return nil
+}
+
+// isPathOption returns whether the values of the option are paths, as
+// marked with the path:"yes" tag of its field.
+func isPathOption(opt *flags.Option) bool {
+	return opt.Field().Tag.Get("path") == "yes"
+}
+
+// resolvePaths returns the paths with the relative ones made relative to dir
+// instead of the working directory.
+func resolvePaths(dir string, paths []string) []string {
+	resolved := make([]string, len(paths))
+	for i, p := range paths {
+		if p != "" && !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		resolved[i] = p
+	}
+	return resolved
+}
+
+// configure applies the configuration to the parser for the command line.
+// The profile is looked up by hand, as the flags are not parsed yet.
+func configure(p *flags.Parser, args []string) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	profile := flagArg(args, "", "profile")
+	if profile == "" {
+		profile = os.Getenv(envKey("", "profile"))
+	}
+	return applyConfig(p, dir, profile)
+}
@@ -0,0 +1,141 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+type configCommand struct {
+	Release string   `short:"r" long:"release" required:"true"`
+	Workers int      `long:"workers" default:"4"`
+	Keep    []string `long:"keep"`
+}
+
+func (c *configCommand) Execute(args []string) error {
+	return nil
+}
+
+var applyConfigTests = []struct {
+	summary string
+	config  string
+	profile string
+	args    []string
+	want    configCommand
+	err     string
+}{{
+	summary: "Built-in defaults",
+	args:    []string{"-r", "rel"},
+	want:    configCommand{Release: "rel", Workers: 4},
+}, {
+	summary: "Configured defaults satisfy required flags",
+	config:  "release: rel\ncommands:\n  run:\n    workers: 8\n    keep: [a_b, c_d]\n",
+	want:    configCommand{Release: "rel", Workers: 8, Keep: []string{"a_b", "c_d"}},
+}, {
+	summary: "Command line takes precedence",
+	config:  "release: rel\ncommands:\n  run:\n    workers: 8\n    keep: [a_b]\n",
+	args:    []string{"--workers", "2", "--keep", "e_f"},
+	want:    configCommand{Release: "rel", Workers: 2, Keep: []string{"e_f"}},
+}, {
+	summary: "Profile",
+	config:  "release: rel\nprofiles:\n  quick:\n    workers: 1\n",
+	profile: "quick",
+	want:    configCommand{Release: "rel", Workers: 1},
+}, {
+	summary: "Unknown flags and commands",
+	config:  "relase: rel\ncommands:\n  run:\n    worker: 8\n  walk: {}\n",
+	err:     `invalid configuration: unknown command "walk", unknown flag "relase", unknown flag "worker" of command run`,
+}}
+
+func TestApplyConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, test := range applyConfigTests {
+		t.Logf("Summary: %s", test.summary)
+		dir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if test.config != "" {
+			if err := os.WriteFile(filepath.Join(dir, ".sdf.yaml"), []byte(test.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cmd := &configCommand{}
+		p := flags.NewParser(&struct{}{}, flags.None)
+		if _, err := p.AddCommand("run", "", "", cmd); err != nil {
+			t.Fatal(err)
+		}
+		err := sdf.ApplyConfig(p, dir, test.profile)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("have error %v, want %s", err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.ParseArgs(append([]string{"run"}, test.args...)); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*cmd, test.want) {
+			t.Fatalf("have %+v, want %+v", *cmd, test.want)
+		}
+	}
+}
+
+type configPathsCommand struct {
+	Release string   `long:"release" path:"yes"`
+	Output  string   `long:"output" path:"yes"`
+	Hooks   []string `long:"hook" path:"yes"`
+	Name    string   `long:"name"`
+}
+
+func (c *configPathsCommand) Execute(args []string) error {
+	return nil
+}
+
+func TestApplyConfigPaths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "slices", "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := "release: .\nname: ./name\ncommands:\n  run:\n    output: /tmp/out.json\n    hook: [hooks/a, ../b]\n"
+	if err := os.WriteFile(filepath.Join(dir, ".sdf.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The paths of the configuration are relative to its directory, not to
+	// the subdirectory sdf runs from.
+	t.Chdir(sub)
+	cmd := &configPathsCommand{}
+	p := flags.NewParser(&struct{}{}, flags.None)
+	if _, err := p.AddCommand("run", "", "", cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := sdf.ApplyConfig(p, ".", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ParseArgs([]string{"run"}); err != nil {
+		t.Fatal(err)
+	}
+	want := configPathsCommand{
+		Release: dir,
+		Output:  "/tmp/out.json",
+		Hooks:   []string{filepath.Join(dir, "hooks", "a"), filepath.Join(filepath.Dir(dir), "b")},
+		Name:    "./name",
+	}
+	if !reflect.DeepEqual(*cmd, want) {
+		t.Fatalf("have %+v, want %+v", *cmd, want)
+	}
+}
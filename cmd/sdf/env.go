@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// The prefix of the environment variables setting flags.
+const envPrefix = "SDF_"
+
+// envKey returns the environment variable of the flag of the command, or of
+// the global flag if command is empty, e.g. SDF_INSTALL_WORKERS.
+func envKey(command, flag string) string {
+	key := envPrefix
+	if command != "" {
+		key += command + "_"
+	}
+	return strings.ToUpper(strings.ReplaceAll(key+flag, "-", "_"))
+}
+
+// setEnvKeys makes every flag without an env tag settable from its
+// environment variable, see [envKey]. Repeatable flags take comma-separated
+// values. Like env tags, the variables take precedence over the defaults
+// and the configuration files, but not over the command line.
+func setEnvKeys(p *flags.Parser) {
+	set := func(command string, g *flags.Group) {
+		for _, opt := range g.Options() {
+			if opt.EnvDefaultKey != "" || opt.LongName == "" {
+				continue
+			}
+			opt.EnvDefaultKey = envKey(command, opt.LongName)
+			if opt.Field().Type.Kind() == reflect.Slice {
+				opt.EnvDefaultDelim = ","
+			}
+		}
+	}
+	for _, g := range p.Groups() {
+		set("", g)
+	}
+	for _, cmd := range p.Commands() {
+		set(cmd.Name, cmd.Group)
+		for _, g := range cmd.Groups() {
+			set(cmd.Name, g)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+type envCommand struct {
+	Release  string   `short:"r" long:"release" required:"true"`
+	Workers  int      `long:"workers" default:"4"`
+	Keep     []string `long:"keep"`
+	Combine  bool     `long:"combine"`
+	Password string   `long:"password" env:"OTHER_PASSWORD"`
+}
+
+func (c *envCommand) Execute(args []string) error {
+	return nil
+}
+
+var envTests = []struct {
+	summary string
+	env     map[string]string
+	config  string
+	args    []string
+	want    envCommand
+}{{
+	summary: "Flags from the environment",
+	env: map[string]string{
+		"SDF_RUN_RELEASE":  "rel",
+		"SDF_RUN_WORKERS":  "8",
+		"SDF_RUN_KEEP":     "a_b,c_d",
+		"SDF_RUN_COMBINE":  "true",
+		"SDF_RUN_PASSWORD": "ignored",
+		"OTHER_PASSWORD":   "secret",
+	},
+	want: envCommand{Release: "rel", Workers: 8, Keep: []string{"a_b", "c_d"}, Combine: true, Password: "secret"},
+}, {
+	summary: "Environment takes precedence over the configuration",
+	env:     map[string]string{"SDF_RUN_WORKERS": "8"},
+	config:  "release: rel\nworkers: 2\n",
+	want:    envCommand{Release: "rel", Workers: 8},
+}, {
+	summary: "Command line takes precedence over the environment",
+	env:     map[string]string{"SDF_RUN_RELEASE": "rel", "SDF_RUN_WORKERS": "8"},
+	args:    []string{"--workers", "1"},
+	want:    envCommand{Release: "rel", Workers: 1},
+}}
+
+func TestSetEnvKeys(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, test := range envTests {
+		t.Logf("Summary: %s", test.summary)
+		for k, v := range test.env {
+			t.Setenv(k, v)
+		}
+		dir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".sdf.yaml"), []byte(test.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := &envCommand{}
+		p := flags.NewParser(&struct{}{}, flags.None)
+		if _, err := p.AddCommand("run", "", "", cmd); err != nil {
+			t.Fatal(err)
+		}
+		sdf.SetEnvKeys(p)
+		if err := sdf.ApplyConfig(p, dir, ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.ParseArgs(append([]string{"run"}, test.args...)); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*cmd, test.want) {
+			t.Fatalf("have %+v, want %+v", *cmd, test.want)
+		}
+		for k := range test.env {
+			os.Unsetenv(k)
+		}
+	}
+}
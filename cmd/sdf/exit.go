@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// Exit codes of sdf, by class of failure, so that scripts may act on the
+// cause of a failure without parsing the logs.
+const (
+	exitOK          = 0
+	exitFailure     = 1   // Any failure not in the classes below.
+	exitUsage       = 2   // Invalid flags, arguments or configuration.
+	exitFindings    = 3   // Slice definitions that do not parse or problems found in the release or root.
+	exitInstall     = 4   // Slices that failed to install or did not pass their tests.
+	exitEnvironment = 5   // Missing tools, unreachable archives or a chisel that does not support the release.
+	exitCancelled   = 130 // Interrupted, like a process killed by SIGINT.
+)
+
+// An exitError is an error with the exit code of its class.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns err with the exit code, or nil if err is nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitErrorf is like [fmt.Errorf], but the error has the exit code.
+func exitErrorf(code int, format string, args ...any) error {
+	return withExitCode(code, fmt.Errorf(format, args...))
+}
+
+// exitCode returns the code to exit with after err. Errors without an exit
+// code of their own are classified by what they wrap, if possible.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var flagsErr *flags.Error
+	if errors.As(err, &flagsErr) {
+		if flagsErr.Type == flags.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	var e *exitError
+	switch {
+	case errors.As(err, &e):
+		return e.code
+	case errors.Is(err, ErrExtraArgs):
+		return exitUsage
+	case errors.Is(err, context.Canceled):
+		return exitCancelled
+	case errors.Is(err, exec.ErrNotFound):
+		return exitEnvironment
+	}
+	return exitFailure
+}
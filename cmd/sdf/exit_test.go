@@ -0,0 +1,81 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+var exitCodeTests = []struct {
+	summary string
+	err     error
+	code    int
+}{{
+	summary: "No error",
+	code:    0,
+}, {
+	summary: "Unclassified error",
+	err:     errors.New("boom"),
+	code:    1,
+}, {
+	summary: "Help",
+	err:     &flags.Error{Type: flags.ErrHelp},
+	code:    0,
+}, {
+	summary: "Invalid flag",
+	err:     &flags.Error{Type: flags.ErrUnknownFlag},
+	code:    2,
+}, {
+	summary: "Extra arguments",
+	err:     sdf.ErrExtraArgs,
+	code:    2,
+}, {
+	summary: "Usage error",
+	err:     sdf.ExitErrorf(2, "invalid value for --workers: %d", 0),
+	code:    2,
+}, {
+	summary: "Findings",
+	err:     sdf.WithExitCode(3, errors.New("2 finding(s)")),
+	code:    3,
+}, {
+	summary: "Joined install failures",
+	err:     errors.Join(sdf.ExitErrorf(4, "cannot install a_b"), errors.New("boom")),
+	code:    4,
+}, {
+	summary: "Wrapped exit code",
+	err:     fmt.Errorf("cannot run: %w", sdf.ExitErrorf(5, "chisel does not support the release")),
+	code:    5,
+}, {
+	summary: "Missing tool",
+	err:     fmt.Errorf("chisel cut failed: %w", exec.ErrNotFound),
+	code:    5,
+}, {
+	summary: "Cancelled",
+	err:     fmt.Errorf("installation interrupted: %w", context.Canceled),
+	code:    130,
+}}
+
+func TestExitCode(t *testing.T) {
+	for _, test := range exitCodeTests {
+		t.Logf("Summary: %s", test.summary)
+		if code := sdf.ExitCode(test.err); code != test.code {
+			t.Fatalf("have code %d, want %d", code, test.code)
+		}
+	}
+	if err := sdf.WithExitCode(3, nil); err != nil {
+		t.Fatalf("have %v, want nil", err)
+	}
+	err := sdf.ExitErrorf(4, "cannot install: %w", context.Canceled)
+	if have, want := err.Error(), "cannot install: context canceled"; have != want {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("have %v, want it to wrap %v", err, context.Canceled)
+	}
+}
@@ -1,7 +1,102 @@
 package main
 
+import (
+	"context"
+
+	"github.com/rebornplusplus/chisel-tools/internal/results"
+)
+
 var (
-	Prune          = prune
 	EnsurePackages = ensurePackages
 	IgnoreMissing  = ignoreMissing
+	ParseArches    = parseArches
+)
+
+var FindPlugins = findPlugins
+var GlobalEnv = globalEnv
+
+var Scan = scan
+
+type MatrixResult = matrixResult
+
+var MinimumVersion = minimumVersion
+
+type (
+	SliceName = sliceName
+	SliceFile = sliceFile
+)
+
+var ApplyConfig = applyConfig
+
+var SetEnvKeys = setEnvKeys
+
+var (
+	ChangedPackages = changedPackages
+	AffectedFiles   = affectedFiles
+)
+
+type Table = table
+
+var WriteOutput = writeOutput
+
+var (
+	SetColor  = setColor
+	ColorDiff = colorDiff
+	Tick      = tick
+	Cross     = cross
+)
+
+var (
+	ExitCode     = exitCode
+	ExitErrorf   = exitErrorf
+	WithExitCode = withExitCode
+)
+
+var NewREPL = newREPL
+
+func (s *repl) Eval(line string) (quit bool, err error) { return s.eval(line) }
+
+func (s *repl) Complete(words []string) []string { return s.complete(words) }
+
+type CachePool = cachePool
+
+func (p *cachePool) Keep(dir string) error { return p.keep(dir) }
+
+func (p *cachePool) Get() (string, error) { return p.get() }
+
+func (p *cachePool) Put(dir string) { p.put(dir) }
+
+var ShouldUpdate = shouldUpdate
+
+var (
+	SetLogOutput   = setLogOutput
+	CloseLogOutput = closeLogOutput
+)
+
+var CheckRelease = checkRelease
+var WriteReport = writeReport
+var AffectedSlices = affectedSlices
+
+func PackageFinder(ctx context.Context, release, archiveURL string) (func(arch, pkg string) (bool, error), error) {
+	c := &cmdInstall{Release: release, ArchiveURL: archiveURL}
+	return c.packageFinder(ctx)
+}
+
+func Install(workers int, cont bool, groups ...[]string) (*results.Report, error) {
+	c := &cmdInstall{Workers: workers, Continue: cont}
+	var tasks []*task
+	for _, slices := range groups {
+		tasks = append(tasks, &task{args: []string{"cut", "--arch", "amd64"}, arch: "amd64", slices: slices})
+	}
+	return c.install(context.Background(), tasks)
+}
+
+func VerifyContents(release string) error {
+	c := &cmdVerify{Release: release, Arch: "amd64", Workers: 2}
+	return c.Execute(nil)
+}
+
+var (
+	StatsOutput = statsOutput
+	DiffOutput  = diffOutput
 )
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/events"
+	"github.com/rebornplusplus/chisel-tools/internal/lint"
+)
+
+// hookOptions are the flags of the commands publishing events to hooks.
+type hookOptions struct {
+	Hooks       []string      `long:"hook" description:"Executable to run on every event (can be repeated)" path:"yes"`
+	HookTimeout time.Duration `long:"hook-timeout" description:"How long a hook may take on an event before it is killed" default:"30s"`
+}
+
+// newBus returns a bus delivering the events to the hooks and the handlers,
+// and the function to call once done, which waits for the hooks to get the
+// published events.
+func (o *hookOptions) newBus(handlers ...events.Handler) (*events.Bus, func()) {
+	bus := &events.Bus{}
+	var hooks []*events.Hook
+	for _, path := range o.Hooks {
+		h := events.NewHook(path, o.HookTimeout)
+		bus.Subscribe(h.Handle)
+		hooks = append(hooks, h)
+	}
+	for _, h := range handlers {
+		bus.Subscribe(h)
+	}
+	return bus, func() {
+		for _, h := range hooks {
+			h.Close()
+		}
+	}
+}
+
+// publishFindings publishes the issues as findings, with their files
+// relative to the release, and the completion of the run of the command.
+func publishFindings(bus *events.Bus, command, release string, issues []*lint.Issue, start time.Time) {
+	for _, i := range issues {
+		bus.Publish(events.LintFinding, &events.Finding{
+			Check:   i.Check,
+			File:    relPath(release, i.File),
+			Slice:   i.Slice,
+			Message: i.Message,
+		})
+	}
+	bus.Publish(events.RunCompleted, &events.Run{
+		Command:  command,
+		Findings: len(issues),
+		Duration: time.Since(start),
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/logsink"
+)
+
+// logSink is where the logs go when --log-output is not stderr.
+var logSink logsink.Sink
+
+// setLogOutput sends the logs to the output set with --log-output.
+func setLogOutput(output string) error {
+	var err error
+	switch {
+	case output == "" || output == "stderr":
+		return nil
+	case output == "syslog":
+		logSink, err = logsink.Syslog("sdf")
+	case output == "journald":
+		logSink, err = logsink.Journald("sdf")
+	case strings.HasPrefix(output, "file:") && len(output) > len("file:"):
+		logSink, err = logsink.File(strings.TrimPrefix(output, "file:"))
+	default:
+		return exitErrorf(exitUsage, "invalid value for --log-output: %q, want stderr, syslog, journald or file:PATH", output)
+	}
+	if err != nil {
+		return exitErrorf(exitEnvironment, "cannot open log output: %w", err)
+	}
+	log.SetOutput(sinkWriter{})
+	return nil
+}
+
+// closeLogOutput sends the logs to the standard error again.
+func closeLogOutput() {
+	if logSink == nil {
+		return
+	}
+	log.SetOutput(os.Stderr)
+	logSink.Close()
+	logSink = nil
+}
+
+// logError logs the error a command failed with. The parser prints it to
+// the standard error already.
+func logError(err error) {
+	if logSink != nil && err != nil {
+		logSink.Log(logsink.Error, err.Error())
+	}
+}
+
+var colorCodes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// sinkWriter writes the messages of the log package to logSink, with the
+// priority told by their mark.
+type sinkWriter struct{}
+
+func (sinkWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(colorCodes.ReplaceAllString(string(p), ""), "\n")
+	priority := logsink.Info
+	switch {
+	case strings.HasPrefix(msg, string(cross)):
+		priority = logsink.Error
+	case strings.HasPrefix(msg, string(warn)+" "), strings.HasPrefix(msg, "Warning:"):
+		priority = logsink.Warning
+	}
+	if err := logSink.Log(priority, msg); err != nil {
+		// Do not lose the message, nor stop the command.
+		os.Stderr.Write(p)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,49 @@
+package main_test
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+func TestLogOutput(t *testing.T) {
+	// Like main.
+	defer log.SetFlags(log.Flags())
+	log.SetFlags(0)
+	path := filepath.Join(t.TempDir(), "sdf.log")
+	if err := sdf.SetLogOutput("file:" + path); err != nil {
+		t.Fatal(err)
+	}
+	log.Print("Installing hello_bins...")
+	log.Print("\x1b[31m✗\x1b[0m 1 of 2 task(s) failed")
+	log.Printf("Warning: chisel may not support the release: %s", "format v3")
+	sdf.CloseLogOutput()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		_, line, _ = strings.Cut(line, " ")
+		lines = append(lines, line)
+	}
+	want := []string{
+		"info Installing hello_bins...",
+		"error ✗ 1 of 2 task(s) failed",
+		"warning Warning: chisel may not support the release: format v3",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("have lines %q, want %q", lines, want)
+	}
+
+	for _, output := range []string{"file:", "stdout", "syslog:local0"} {
+		if err := sdf.SetLogOutput(output); err == nil || sdf.ExitCode(err) != 2 {
+			t.Fatalf("have error %v for %q, want a usage error", err, output)
+		}
+	}
+}
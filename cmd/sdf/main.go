@@ -11,21 +11,52 @@ import (
 // ErrExtraArgs is returned  if extra arguments to a command are found
 var ErrExtraArgs = fmt.Errorf("too many arguments for command")
 
-var parser = flags.NewParser(&struct{}{}, flags.Default)
+// globalOptions are the flags of all commands.
+type globalOptions struct {
+	Profile      string `long:"profile" description:"Profile of the configuration files to use"`
+	Quiet        bool   `short:"q" long:"quiet" description:"Print only failures and the final summary, not the progress of every task"`
+	Format       string `long:"format" description:"Output format of the query commands" choice:"table" choice:"json" choice:"yaml" choice:"tsv" default:"table"`
+	OutputFormat string `long:"output-format" description:"Same as --format, for after the commands with a --format of their own" choice:"table" choice:"json" choice:"yaml" choice:"tsv"`
+	Color        string `long:"color" description:"When to color the output, auto if NO_COLOR is not set" choice:"auto" choice:"always" choice:"never" default:"auto"`
+	ChiselCompat string `long:"chisel-compat" description:"What to do when chisel does not support the release format" choice:"warn" choice:"fail" choice:"ignore" default:"warn"`
+	LogOutput    string `long:"log-output" description:"Where to write the logs: stderr, syslog, journald or file:PATH. Errors are printed to stderr too" default:"stderr"`
+}
+
+var opts globalOptions
+
+var parser = flags.NewParser(&opts, flags.Default)
 
 func main() {
 	// We do not care for any date/time prefix on the logs.
 	log.SetFlags(0)
 
-	if _, err := parser.Parse(); err != nil {
-		switch flagsErr := err.(type) {
-		case flags.ErrorType:
-			if flagsErr == flags.ErrHelp {
-				os.Exit(0)
-			}
-			os.Exit(1)
-		default:
-			os.Exit(1)
+	if ok, err := runPlugin(os.Args[1:]); ok {
+		if err != nil {
+			os.Exit(pluginExitCode(err))
 		}
+		os.Exit(0)
+	}
+
+	setEnvKeys(parser)
+	if err := configure(parser, os.Args[1:]); err != nil {
+		log.Print(err)
+		os.Exit(exitUsage)
+	}
+	parser.CommandHandler = func(cmd flags.Commander, args []string) error {
+		if opts.OutputFormat != "" {
+			opts.Format = opts.OutputFormat
+		}
+		setColor(opts.Color)
+		if err := setLogOutput(opts.LogOutput); err != nil {
+			return err
+		}
+		err := cmd.Execute(args)
+		logError(err)
+		return err
+	}
+	_, err := parser.Parse()
+	closeLogOutput()
+	if err != nil {
+		os.Exit(exitCode(err))
 	}
 }
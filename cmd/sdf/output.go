@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A table is a human readable view of the output of a query command.
+type table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// printOutput prints the output of a query command in the format set with
+// --format, see [writeOutput].
+func printOutput(value any, tables ...*table) error {
+	return writeOutput(os.Stdout, opts.Format, value, tables...)
+}
+
+// writeOutput writes the value as JSON or YAML, with the same fields as
+// the JSON reports of the -o flags, or its tables aligned or as
+// tab-separated values.
+func writeOutput(w io.Writer, format string, value any, tables ...*table) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case "yaml":
+		// Go through JSON for the field names and omitempty rules of the
+		// json tags. JSON is YAML, and decoding it into a node keeps the
+		// order of the fields.
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return err
+		}
+		resetStyle(&node)
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "tsv":
+		for i, t := range tables {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			for _, row := range append([][]string{t.Header}, t.Rows...) {
+				fmt.Fprintln(w, joinFields(row))
+			}
+		}
+		return nil
+	case "table", "":
+		var buf bytes.Buffer
+		for i, t := range tables {
+			if i > 0 {
+				fmt.Fprintln(&buf)
+			}
+			tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+			for _, row := range append([][]string{t.Header}, t.Rows...) {
+				fmt.Fprintln(tw, joinFields(row))
+			}
+			tw.Flush()
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// joinFields joins the fields of a row with tabs, replacing the tabs and
+// newlines within them with spaces.
+func joinFields(row []string) string {
+	fields := make([]string, len(row))
+	for i, f := range row {
+		fields[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(f)
+	}
+	return strings.Join(fields, "\t")
+}
+
+// resetStyle drops the flow style and quotes of the nodes decoded from JSON,
+// so that they are written as block YAML. The encoder still quotes the
+// strings that need it.
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
@@ -0,0 +1,97 @@
+package main_test
+
+import (
+	"bytes"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+type outputEntry struct {
+	Name   string   `json:"name"`
+	Value  string   `json:"value,omitempty"`
+	Size   int      `json:"size"`
+	Slices []string `json:"slices"`
+}
+
+var outputValue = []*outputEntry{
+	{Name: "foo", Value: "true", Size: 1, Slices: []string{"foo_bins"}},
+	{Name: "bar baz", Size: 22, Slices: []string{}},
+}
+
+var outputTables = []*sdf.Table{{
+	Header: []string{"NAME", "SIZE"},
+	Rows:   [][]string{{"foo", "1"}, {"bar\tbaz", "22"}},
+}, {
+	Header: []string{"TOTAL"},
+	Rows:   [][]string{{"23"}},
+}}
+
+var writeOutputTests = []struct {
+	format string
+	output string
+}{{
+	format: "table",
+	output: `
+NAME     SIZE
+foo      1
+bar baz  22
+
+TOTAL
+23
+`,
+}, {
+	format: "tsv",
+	output: `
+NAME	SIZE
+foo	1
+bar baz	22
+
+TOTAL
+23
+`,
+}, {
+	format: "json",
+	output: `
+[
+  {
+    "name": "foo",
+    "value": "true",
+    "size": 1,
+    "slices": [
+      "foo_bins"
+    ]
+  },
+  {
+    "name": "bar baz",
+    "size": 22,
+    "slices": []
+  }
+]
+`,
+}, {
+	format: "yaml",
+	output: `
+- name: foo
+  value: "true"
+  size: 1
+  slices:
+    - foo_bins
+- name: bar baz
+  size: 22
+  slices: []
+`,
+}}
+
+func TestWriteOutput(t *testing.T) {
+	for _, test := range writeOutputTests {
+		t.Logf("Format: %s", test.format)
+		var buf bytes.Buffer
+		if err := sdf.WriteOutput(&buf, test.format, outputValue, outputTables...); err != nil {
+			t.Fatal(err)
+		}
+		if have, want := buf.String(), test.output[1:]; have != want {
+			t.Fatalf("have:\n%s\nwant:\n%s", have, want)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/coverage"
+	"github.com/rebornplusplus/chisel-tools/internal/events"
+	"github.com/rebornplusplus/chisel-tools/internal/ghactions"
+	"github.com/rebornplusplus/chisel-tools/internal/lint"
+	"github.com/rebornplusplus/chisel-tools/internal/pipeline"
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+// stageRunner runs the stages of a pipeline on a release, for the action
+// and ci commands.
+type stageRunner struct {
+	release string
+	arch    string
+	workers int
+	changed []string // Changed files, nil if everything changed.
+	// Annotate the lint issues for GitHub Actions.
+	annotate bool
+	// Values of the outputs of the action, set by the stages.
+	outputs map[string]string
+}
+
+// checkStage checks the options of the stage that the pipeline file cannot,
+// so that mistakes are found before running any stage.
+func checkStage(s *pipeline.Stage) error {
+	if _, err := findChecks(s.Checks); err != nil {
+		return err
+	}
+	if s.Run != "" {
+		if _, err := regexp.Compile(s.Run); err != nil {
+			return exitErrorf(exitUsage, "invalid run pattern of stage %s: %w", s.Name, err)
+		}
+	}
+	for _, m := range s.Min {
+		if _, err := coverage.ParseThreshold(m); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+	}
+	return nil
+}
+
+// run runs the stage and returns its result, and an error if it failed.
+func (p *stageRunner) run(s *pipeline.Stage) (*pipeline.Result, error) {
+	progressf("Running stage %s...", s.Name)
+	start := time.Now()
+	changed := p.changed
+	if s.All {
+		changed = nil
+	}
+	var result *pipeline.Result
+	var err error
+	switch s.Name {
+	case "lint":
+		result, err = p.lint(s, changed)
+	case "install":
+		result, err = p.install(s, changed)
+	case "test":
+		result, err = p.test(s, changed)
+	case "coverage":
+		result, err = p.coverage(s, changed)
+	default:
+		err = fmt.Errorf("unknown stage %q", s.Name)
+		result = &pipeline.Result{Status: pipeline.Failed, Summary: err.Error()}
+	}
+	result.Stage = s.Name
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// setChanged restricts the stages to the files changed since the ref.
+func (p *stageRunner) setChanged(ref string) error {
+	changed, err := changedFiles(p.release, ref)
+	if err != nil {
+		return err
+	}
+	p.changed = changed
+	progressf("Changed since %s: %d file(s)", ref, len(changed))
+	return nil
+}
+
+// changedNames returns the changed files relative to the release.
+func (p *stageRunner) changedNames() []string {
+	var names []string
+	for _, f := range p.changed {
+		names = append(names, relPath(p.release, f))
+	}
+	return names
+}
+
+func (p *stageRunner) setOutput(name string, value int) {
+	if p.outputs != nil {
+		p.outputs[name] = strconv.Itoa(value)
+	}
+}
+
+func (p *stageRunner) lint(s *pipeline.Stage, changed []string) (*pipeline.Result, error) {
+	checks, err := findChecks(s.Checks)
+	if err != nil {
+		return &pipeline.Result{Status: pipeline.Failed, Summary: "unknown check", Details: []string{err.Error()}}, err
+	}
+	r, err := readRelease(newParseCache(false), p.release)
+	if err != nil {
+		err = exitErrorf(exitFindings, "cannot read release: %w", err)
+		return &pipeline.Result{Status: pipeline.Failed, Summary: "cannot read release", Details: []string{err.Error()}}, err
+	}
+	issues := lint.Run(r, checks...)
+	if _, all := changedPackages(p.release, changed); changed != nil && !all {
+		issues = lint.InFiles(issues, changed)
+	}
+	p.setOutput("lint-issues", len(issues))
+	result := &pipeline.Result{Status: pipeline.Passed, Summary: fmt.Sprintf("%d issue(s)", len(issues))}
+	for _, i := range issues {
+		if p.annotate {
+			fmt.Println(ghactions.ErrorAnnotation(i.File, i.Check, i.Message))
+		}
+		issue := *i
+		issue.File = relPath(p.release, i.File)
+		result.Details = append(result.Details, issue.String())
+	}
+	if err := printIssues(p.release, issues); err != nil {
+		result.Status = pipeline.Failed
+		return result, err
+	}
+	return result, nil
+}
+
+func (p *stageRunner) install(s *pipeline.Stage, changed []string) (*pipeline.Result, error) {
+	files, err := chisel.SliceFiles(p.release)
+	if err != nil {
+		return &pipeline.Result{Status: pipeline.Failed, Summary: "cannot find slice definition files", Details: []string{err.Error()}}, err
+	}
+	count := len(files)
+	if changed != nil {
+		// The slices of the other files may depend on the changed ones.
+		count = len(affectedFiles(p.release, files, changed))
+	}
+	if count == 0 {
+		return &pipeline.Result{Status: pipeline.Skipped, Summary: "no changed slice definition files"}, nil
+	}
+
+	var mu sync.Mutex
+	var tasks, failed []*events.Task
+	c := &cmdInstall{
+		Release:  p.release,
+		Arch:     p.arch,
+		Workers:  p.workers,
+		Prune:    s.Prune,
+		Combine:  s.Combine,
+		Continue: true,
+		changed:  changed,
+		handlers: []events.Handler{func(e *events.Event) {
+			if e.Type != events.TaskFinished {
+				return
+			}
+			t := e.Data.(*events.Task)
+			mu.Lock()
+			defer mu.Unlock()
+			tasks = append(tasks, t)
+			if t.Error != "" {
+				failed = append(failed, t)
+			}
+		}},
+	}
+	_, err = c.run(files)
+	p.setOutput("install-tasks", len(tasks))
+	p.setOutput("install-failed", len(failed))
+	result := &pipeline.Result{Status: pipeline.Passed, Summary: fmt.Sprintf("%d task(s) from %d file(s)", len(tasks), count)}
+	for _, t := range failed {
+		msg, _, _ := strings.Cut(t.Error, "\n")
+		result.Details = append(result.Details, strings.Join(t.Slices, " ")+": "+msg)
+	}
+	if err != nil {
+		result.Status = pipeline.Failed
+		if len(failed) > 0 {
+			result.Summary = fmt.Sprintf("%d of %d task(s) failed", len(failed), len(tasks))
+		} else {
+			result.Details = append(result.Details, err.Error())
+		}
+		return result, withExitCode(exitInstall, err)
+	}
+	return result, nil
+}
+
+func (p *stageRunner) test(s *pipeline.Stage, changed []string) (*pipeline.Result, error) {
+	specs, err := slicetest.SpecFiles(p.release)
+	if err != nil {
+		return &pipeline.Result{Status: pipeline.Failed, Summary: "cannot find spec files", Details: []string{err.Error()}}, err
+	}
+	var filter *regexp.Regexp
+	if s.Run != "" {
+		if filter, err = regexp.Compile(s.Run); err != nil {
+			return &pipeline.Result{Status: pipeline.Failed, Summary: "invalid run pattern", Details: []string{err.Error()}}, withExitCode(exitUsage, err)
+		}
+	}
+	var pkgs map[string]bool
+	if changed != nil {
+		pkgs = testPackages(p.release, specs, changed)
+	}
+	c := &cmdTest{
+		Release:        p.release,
+		Arch:           p.arch,
+		Workers:        p.workers,
+		Backend:        s.Backend,
+		ServiceBackend: "podman",
+		ServiceTimeout: 60 * time.Second,
+	}
+	results, err := c.selectTests(specs, filter, pkgs)
+	if err != nil {
+		return &pipeline.Result{Status: pipeline.Failed, Summary: "cannot read spec files", Details: []string{err.Error()}}, withExitCode(exitFindings, err)
+	}
+	if len(results) == 0 {
+		return &pipeline.Result{Status: pipeline.Skipped, Summary: "no tests to run"}, nil
+	}
+	c.run(results)
+	result := &pipeline.Result{Status: pipeline.Passed}
+	failed := 0
+	for _, r := range results {
+		if r.Passed {
+			continue
+		}
+		failed++
+		msg := r.Error
+		if msg == "" && len(r.Failures) > 0 {
+			msg = r.Failures[0].String()
+		}
+		first, _, _ := strings.Cut(msg, "\n")
+		result.Details = append(result.Details, r.name()+": "+first)
+	}
+	p.setOutput("tests", len(results))
+	p.setOutput("tests-failed", failed)
+	result.Summary = fmt.Sprintf("%d test(s) passed", len(results))
+	if failed > 0 {
+		result.Status = pipeline.Failed
+		result.Summary = fmt.Sprintf("%d of %d test(s) failed", failed, len(results))
+		return result, exitErrorf(exitInstall, "%c %s", cross, result.Summary)
+	}
+	log.Printf("%c %s", tick, result.Summary)
+	return result, nil
+}
+
+func (p *stageRunner) coverage(s *pipeline.Stage, changed []string) (*pipeline.Result, error) {
+	var thresholds []*coverage.Threshold
+	for _, m := range s.Min {
+		t, err := coverage.ParseThreshold(m)
+		if err != nil {
+			return &pipeline.Result{Status: pipeline.Failed, Summary: "invalid threshold", Details: []string{err.Error()}}, withExitCode(exitUsage, err)
+		}
+		thresholds = append(thresholds, t)
+	}
+	c := &cmdCoverage{
+		Release: p.release,
+		Arch:    p.arch,
+		Workers: p.workers,
+	}
+	if pkgs, all := changedPackages(p.release, changed); changed != nil && !all {
+		r, err := readRelease(newParseCache(false), p.release)
+		if err != nil {
+			err = exitErrorf(exitFindings, "cannot read release: %w", err)
+			return &pipeline.Result{Status: pipeline.Failed, Summary: "cannot read release", Details: []string{err.Error()}}, err
+		}
+		// Packages whose slices were all removed have no coverage.
+		for _, sl := range r.Slices {
+			if pkgs[sl.Package] && !slices.Contains(c.Positional.Packages, sl.Package) {
+				c.Positional.Packages = append(c.Positional.Packages, sl.Package)
+			}
+		}
+		if len(c.Positional.Packages) == 0 {
+			return &pipeline.Result{Status: pipeline.Skipped, Summary: "no changed packages"}, nil
+		}
+		sort.Strings(c.Positional.Packages)
+	}
+	report, err := c.run(thresholds)
+	result := &pipeline.Result{Status: pipeline.Passed}
+	if report != nil {
+		result.Summary = fmt.Sprintf("%.1f%% of the files of %d package(s)", report.Percent(), len(report.Packages))
+		result.Details = report.Check(thresholds)
+	}
+	if err != nil {
+		result.Status = pipeline.Failed
+		if report == nil {
+			result.Summary = "cannot compute the coverage"
+			result.Details = []string{err.Error()}
+		}
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// Plugins are executables named "sdf-<name>" on PATH. When <name> is not a
+// builtin command, "sdf <name> [args...]" runs the plugin with the remaining
+// arguments, much like git does. This lets teams ship their own commands
+// without forking this tool.
+const pluginPrefix = "sdf-"
+
+// runPlugin runs the plugin for the command name of args, if there is one.
+// The global options before the name are passed to the plugin in their
+// environment variables, see [envKey], so that they apply to the sdf it runs
+// too. It returns false if no plugin should handle the arguments.
+func runPlugin(args []string) (bool, error) {
+	env, args, ok := globalEnv(args)
+	if !ok || len(args) == 0 || parser.Find(args[0]) != nil {
+		return false, nil
+	}
+	path, err := exec.LookPath(pluginPrefix + args[0])
+	if err != nil {
+		return false, nil
+	}
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	if self, err := os.Executable(); err == nil {
+		cmd.Env = append(cmd.Env, "SDF_BIN="+self)
+	}
+	return true, cmd.Run()
+}
+
+// globalEnv returns the environment variables of the global options at the
+// start of args, and the arguments after them. It returns false if one of
+// the options is not a global one, or asks for help, leaving the error or
+// the help to the parser.
+func globalEnv(args []string) (env []string, rest []string, ok bool) {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			return nil, nil, false
+		}
+		var opt *flags.Option
+		var value string
+		var hasValue bool
+		if long, ok := strings.CutPrefix(arg, "--"); ok {
+			long, value, hasValue = strings.Cut(long, "=")
+			if opt = parser.FindOptionByLongName(long); opt == nil {
+				return nil, nil, false
+			}
+		} else {
+			short := []rune(arg[1:])
+			if opt = parser.FindOptionByShortName(short[0]); opt == nil {
+				return nil, nil, false
+			}
+			if len(short) > 1 {
+				// The value may follow the option, as in -pprod, but
+				// combined options, as in -qv, are left to the parser.
+				if isBoolOption(opt) {
+					return nil, nil, false
+				}
+				value, hasValue = string(short[1:]), true
+			}
+		}
+		if opt.LongName == "" || opt.LongName == "help" {
+			return nil, nil, false
+		}
+		if isBoolOption(opt) {
+			if !hasValue {
+				value = "true"
+			}
+		} else if !hasValue {
+			if len(args) == 0 {
+				return nil, nil, false
+			}
+			value = args[0]
+			args = args[1:]
+		}
+		env = append(env, envKey("", opt.LongName)+"="+value)
+	}
+	return env, args, true
+}
+
+// isBoolOption reports whether the option takes no value.
+func isBoolOption(opt *flags.Option) bool {
+	return opt.Field().Type.Kind() == reflect.Bool
+}
+
+// findPlugins returns the names of the plugins found in the list of
+// directories, which is formatted like the PATH environment variable. If a
+// plugin is present in multiple directories, the first one wins, as for
+// [exec.LookPath].
+func findPlugins(pathList string) map[string]string {
+	plugins := make(map[string]string)
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), pluginPrefix)
+			if !ok || name == "" || e.IsDir() {
+				continue
+			}
+			if _, ok := plugins[name]; ok {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+			plugins[name] = filepath.Join(dir, e.Name())
+		}
+	}
+	return plugins
+}
+
+type cmdPlugins struct{}
+
+func init() {
+	parser.AddCommand(
+		"plugins",
+		"List plugins",
+		"The plugins command lists the sdf-<name> executables found on PATH, which can be run as sdf <name>",
+		&cmdPlugins{},
+	)
+}
+
+func (c *cmdPlugins) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	plugins := findPlugins(os.Getenv("PATH"))
+	var names []string
+	for name := range plugins {
+		if parser.Find(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s\t%s\n", name, plugins[name])
+	}
+	return nil
+}
+
+// pluginExitCode returns the exit code to use for a failed plugin run.
+func pluginExitCode(err error) int {
+	var e *exec.ExitError
+	if errors.As(err, &e) && e.ExitCode() > 0 {
+		return e.ExitCode()
+	}
+	return 1
+}
@@ -0,0 +1,94 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+func TestFindPlugins(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	files := []struct {
+		path string
+		mode os.FileMode
+	}{
+		{filepath.Join(dir1, "sdf-foo"), 0755},
+		{filepath.Join(dir1, "sdf-noexec"), 0644},
+		{filepath.Join(dir1, "other"), 0755},
+		{filepath.Join(dir2, "sdf-foo"), 0755},
+		{filepath.Join(dir2, "sdf-bar"), 0755},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f.path, []byte("#!/bin/sh\n"), f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir2, "sdf-dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins := sdf.FindPlugins(dir1 + string(filepath.ListSeparator) + dir2)
+	want := map[string]string{
+		"foo": filepath.Join(dir1, "sdf-foo"),
+		"bar": filepath.Join(dir2, "sdf-bar"),
+	}
+	if !reflect.DeepEqual(plugins, want) {
+		t.Fatalf("have %v, want %v", plugins, want)
+	}
+}
+
+var globalEnvTests = []struct {
+	summary string
+	args    []string
+	env     []string
+	rest    []string
+	ok      bool
+}{{
+	summary: "No global options",
+	args:    []string{"foo", "--quiet"},
+	rest:    []string{"foo", "--quiet"},
+	ok:      true,
+}, {
+	summary: "Boolean options",
+	args:    []string{"-q", "--quiet=false", "foo"},
+	env:     []string{"SDF_QUIET=true", "SDF_QUIET=false"},
+	rest:    []string{"foo"},
+	ok:      true,
+}, {
+	summary: "Options with values",
+	args:    []string{"--profile", "prod", "--format=json", "foo", "bar"},
+	env:     []string{"SDF_PROFILE=prod", "SDF_FORMAT=json"},
+	rest:    []string{"foo", "bar"},
+	ok:      true,
+}, {
+	summary: "Missing value",
+	args:    []string{"--profile"},
+}, {
+	summary: "Unknown option",
+	args:    []string{"--workers", "2", "foo"},
+}, {
+	summary: "Help",
+	args:    []string{"-h", "foo"},
+}, {
+	summary: "End of the options",
+	args:    []string{"--", "foo"},
+}}
+
+func TestGlobalEnv(t *testing.T) {
+	for _, test := range globalEnvTests {
+		t.Logf("Summary: %s", test.summary)
+		env, rest, ok := sdf.GlobalEnv(test.args)
+		if ok != test.ok {
+			t.Fatalf("have ok %v, want %v", ok, test.ok)
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(env, test.env) || !reflect.DeepEqual(rest, test.rest) {
+			t.Fatalf("have env %q and args %q, want %q and %q", env, rest, test.env, test.rest)
+		}
+	}
+}
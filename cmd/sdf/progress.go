@@ -0,0 +1,15 @@
+package main
+
+import (
+	"log"
+)
+
+// progressf logs the progress of a command, such as the start or success of
+// one of its tasks, unless --quiet is set. Failures and final summaries are
+// logged with the log package as usual.
+func progressf(format string, args ...any) {
+	if opts.Quiet {
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/attest"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+// provenance writes the SLSA provenance of the install tasks. It holds the
+// inputs common to all the tasks of a run.
+type provenance struct {
+	dir          string
+	release      string
+	invocationID string
+	deps         []*attest.ResourceDescriptor
+	internals    *attest.InstallInternals
+}
+
+func newProvenance(dir, release string, cfg *chisel.Config) (*provenance, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create provenance directory: %w", err)
+	}
+	p := &provenance{
+		dir:          dir,
+		release:      release,
+		invocationID: fmt.Sprintf("%d-%d", time.Now().Unix(), os.Getpid()),
+		internals:    &attest.InstallInternals{},
+	}
+	var err error
+	if p.internals.ChiselVersion, err = chiselVersion(); err != nil {
+		log.Printf("Leaving out the chisel version from provenance: %s", err)
+	}
+
+	if commit, err := releaseCommit(release); err == nil {
+		uri := "git+file://" + release
+		if abs, err := filepath.Abs(release); err == nil {
+			uri = "git+file://" + abs
+		}
+		out, err := exec.Command("git", "-C", release, "config", "--get", "remote.origin.url").Output()
+		if err == nil && len(strings.TrimSpace(string(out))) > 0 {
+			uri = "git+" + strings.TrimSpace(string(out))
+		}
+		p.deps = append(p.deps, &attest.ResourceDescriptor{
+			URI:    uri + "@" + commit,
+			Digest: map[string]string{"gitCommit": commit},
+		})
+	} else {
+		log.Printf("Leaving out the release commit from provenance: %s", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(release, "chisel.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	p.deps = append(p.deps, &attest.ResourceDescriptor{
+		Name:   "chisel.yaml",
+		Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])},
+	})
+
+	// Chisel always fetches the latest indexes of the archives, so the best
+	// description of the archive snapshot used is the time of the run.
+	snapshot := time.Now().UTC().Format(time.RFC3339)
+	for name, a := range cfg.Archives {
+		p.deps = append(p.deps, &attest.ResourceDescriptor{
+			Name: "archive:" + name,
+			Annotations: map[string]any{
+				"suites":     a.Suites,
+				"components": a.Components,
+				"snapshot":   snapshot,
+			},
+		})
+	}
+	return p, nil
+}
+
+// write the provenance of a successful task, given the root it installed.
+func (p *provenance) write(t *task, root string, started, finished time.Time) error {
+	digest, err := rootfs.Digest(root)
+	if err != nil {
+		return fmt.Errorf("cannot compute root digest: %w", err)
+	}
+	name := t.slices[0]
+	if len(t.slices) > 1 {
+		name = fmt.Sprintf("%s+%d", name, len(t.slices)-1)
+	}
+	name += "_" + t.arch
+
+	st := attest.NewStatement(attest.SLSAPredicateType, &attest.Provenance{
+		BuildDefinition: attest.BuildDefinition{
+			BuildType: attest.InstallBuildType,
+			ExternalParameters: &attest.InstallParameters{
+				Slices:  t.slices,
+				Arch:    t.arch,
+				Release: p.release,
+			},
+			InternalParameters:   p.internals,
+			ResolvedDependencies: p.deps,
+		},
+		RunDetails: attest.RunDetails{
+			Builder: attest.Builder{ID: attest.BuilderID},
+			Metadata: &attest.RunMetadata{
+				InvocationID: p.invocationID,
+				StartedOn:    &started,
+				FinishedOn:   &finished,
+			},
+		},
+	}, &attest.Subject{
+		Name:   name,
+		Digest: map[string]string{"sha256": digest},
+	})
+	return writeJSON(filepath.Join(p.dir, name+".intoto.json"), st)
+}
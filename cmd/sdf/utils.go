@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+)
+
+// readManifest reads the chisel manifest at path, or the one in root if path
+// is empty.
+func readManifest(root, path string) (*manifest.Manifest, error) {
+	var m *manifest.Manifest
+	var err error
+	if path != "" {
+		m, err = manifest.ReadFile(path)
+	} else {
+		m, err = manifest.ReadRoot(root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read manifest: %w", err)
+	}
+	return m, nil
+}
+
+// writeJSON writes v as indented JSON to the file at path, or to stdout if
+// path is empty.
+func writeJSON(path string, v any) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// chiselVersion returns the version of the chisel binary on PATH.
+func chiselVersion() (string, error) {
+	out, err := exec.Command("chisel", "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot get chisel version: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// releaseCommit returns the git commit the release directory is at.
+func releaseCommit(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot get release commit: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// relPath returns the path relative to the release, if possible.
+func relPath(release, path string) string {
+	if rel, err := filepath.Rel(release, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// changedFiles returns the files of the release that changed since the
+// merge base of ref and HEAD, joined to the release path. Removed files are
+// included. The list is empty but not nil if nothing changed.
+func changedFiles(release, ref string) ([]string, error) {
+	// Renamed files are listed under both names, as the slices of the old
+	// one are gone.
+	out, err := exec.Command("git", "-C", release, "diff", "--name-only", "--no-renames", "--relative", "-z", ref+"...HEAD").Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("cannot find changes since %s: %s", ref, strings.TrimSpace(string(e.Stderr)))
+		}
+		return nil, fmt.Errorf("cannot find changes since %s: %w", ref, err)
+	}
+	files := []string{}
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			files = append(files, filepath.Join(release, filepath.FromSlash(p)))
+		}
+	}
+	return files, nil
+}
+
+// baseSlices returns the slices the changed slice definition files defined
+// at the merge base of ref and HEAD, those of the files that did not exist
+// or parse there left out.
+func baseSlices(release, ref string, changed []string) ([]*chisel.Slice, error) {
+	out, err := exec.Command("git", "-C", release, "merge-base", ref, "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the merge base of %s: %w", ref, err)
+	}
+	base := strings.TrimSpace(string(out))
+	slicesDir := filepath.Join(release, "slices") + string(filepath.Separator)
+	var all []*chisel.Slice
+	for _, p := range changed {
+		if !strings.HasPrefix(p, slicesDir) || !strings.HasSuffix(p, ".yaml") {
+			continue
+		}
+		rel := filepath.ToSlash(strings.TrimPrefix(p, release+string(filepath.Separator)))
+		data, err := exec.Command("git", "-C", release, "show", base+":./"+rel).Output()
+		if err != nil {
+			continue // Added since.
+		}
+		s, err := chisel.DecodeSlices(data)
+		if err != nil {
+			continue
+		}
+		all = append(all, s...)
+	}
+	return all, nil
+}
+
+// cutOptions holds the arguments of a chisel cut run.
+type cutOptions struct {
+	Release string
+	Arch    string
+	Root    string
+	// XDG_CACHE_HOME for chisel, if not empty.
+	CacheDir string
+	Slices   []string
+	// Chisel binary, "chisel" from the PATH if empty.
+	Chisel string
+	// Output receives the output of chisel as it runs, if not nil.
+	Output io.Writer
+}
+
+// cut installs the slices with chisel cut. The error holds the chisel output
+// on failure.
+func cut(ctx context.Context, opts *cutOptions) error {
+	args := []string{"cut", "--release", opts.Release, "--arch", opts.Arch, "--root", opts.Root}
+	chisel := opts.Chisel
+	if chisel == "" {
+		chisel = "chisel"
+	}
+	cmd := exec.CommandContext(ctx, chisel, append(args, opts.Slices...)...)
+	if opts.CacheDir != "" {
+		cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+opts.CacheDir)
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if opts.Output != nil {
+		cmd.Stdout = io.MultiWriter(&out, opts.Output)
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("chisel cut failed: %w\n%s", err, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
+
+// workerCaches hands out the chisel cache directories of the workers, which
+// concurrent chisel runs must not share, see [worker].
+var workerCaches = &cachePool{}
+
+// A cachePool hands out chisel cache directories. By default, every
+// directory is a new temporary one, removed once returned. Once kept in a
+// directory, see [cachePool.keep], the directories are handed out again
+// after being returned instead, so that later runs find the packages that
+// earlier ones fetched.
+type cachePool struct {
+	mu   sync.Mutex
+	dir  string // Parent of the kept directories, empty if not kept.
+	free []string
+	next int
+}
+
+// keep makes the pool keep its directories in dir, reusing the ones already
+// there.
+func (p *cachePool) keep(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dir = dir
+	for _, e := range entries {
+		if n, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+			p.free = append(p.free, filepath.Join(dir, e.Name()))
+			p.next = max(p.next, n+1)
+		}
+	}
+	return nil
+}
+
+// get returns a directory that no one else uses until it is returned with
+// [cachePool.put].
+func (p *cachePool) get() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.dir == "" {
+		return os.MkdirTemp("", "sdf-cache-")
+	}
+	if n := len(p.free); n > 0 {
+		dir := p.free[n-1]
+		p.free = p.free[:n-1]
+		return dir, nil
+	}
+	dir := filepath.Join(p.dir, strconv.Itoa(p.next))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	p.next++
+	return dir, nil
+}
+
+func (p *cachePool) put(dir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.dir == "" {
+		os.RemoveAll(dir)
+		return
+	}
+	p.free = append(p.free, dir)
+}
+
+// forEachCut calls fn for every item, concurrently on the given number of
+// workers. Every worker has its own chisel cache directory from
+// [workerCaches]. If it cannot be created, fn is called with the error
+// instead.
+func forEachCut[T any](workers int, items []T, fn func(item T, cacheDir string, err error)) {
+	todo := make(chan T, len(items))
+	for _, item := range items {
+		todo <- item
+	}
+	close(todo)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cacheDir, err := workerCaches.get()
+			if err == nil {
+				defer workerCaches.put(cacheDir)
+			}
+			for item := range todo {
+				fn(item, cacheDir, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
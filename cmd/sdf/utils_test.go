@@ -0,0 +1,54 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+func TestCachePool(t *testing.T) {
+	p := &sdf.CachePool{}
+	dir, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(dir)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("temporary directory %s not removed: %v", dir, err)
+	}
+
+	kept := t.TempDir()
+	if err := os.Mkdir(filepath.Join(kept, "3"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p = &sdf.CachePool{}
+	if err := p.Keep(kept); err != nil {
+		t.Fatal(err)
+	}
+	var dirs []string
+	for range 2 {
+		dir, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, dir)
+	}
+	// The directory of an earlier run is reused, and the new one does not
+	// clash with it.
+	if want := []string{filepath.Join(kept, "3"), filepath.Join(kept, "4")}; dirs[0] != want[0] || dirs[1] != want[1] {
+		t.Fatalf("have directories %q, want %q", dirs, want)
+	}
+	p.Put(dirs[1])
+	dir, err = p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != dirs[1] {
+		t.Fatalf("have directory %q, want the returned one %q", dir, dirs[1])
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("kept directory removed: %v", err)
+	}
+}
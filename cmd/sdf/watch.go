@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/watch"
+)
+
+// watchOptions are the flags of the commands that can run again whenever
+// the release changes.
+type watchOptions struct {
+	Watch         bool          `long:"watch" description:"Keep running, and run again for the changed packages whenever the release changes"`
+	WatchInterval time.Duration `long:"watch-interval" description:"How often to check the release for changes" default:"1s"`
+}
+
+// watch calls run with the changed files of the release, until interrupted.
+// Failures of run are logged, as the next change may fix them.
+func (o *watchOptions) watch(release string, run func(changed []string) error) error {
+	if o.WatchInterval <= 0 {
+		return exitErrorf(exitUsage, "invalid value for --watch-interval: %s", o.WatchInterval)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	log.Printf("Watching %s for changes, interrupt to stop...", release)
+	return watch.Watch(ctx, release, o.WatchInterval, func(changed []string) {
+		var rel []string
+		for _, p := range changed {
+			r, err := filepath.Rel(release, p)
+			if err != nil {
+				r = p
+			}
+			rel = append(rel, r)
+		}
+		progressf("Changed: %s", strings.Join(rel, ", "))
+		if err := run(changed); err != nil {
+			log.Print(err)
+		}
+		progressf("Watching for changes...")
+	})
+}
+
+// changedPackages returns the packages whose slice definition files changed,
+// and whether the whole release is affected because chisel.yaml changed.
+func changedPackages(release string, changed []string) (pkgs map[string]bool, all bool) {
+	pkgs = make(map[string]bool)
+	slicesDir := filepath.Join(release, "slices") + string(filepath.Separator)
+	for _, p := range changed {
+		switch {
+		case p == filepath.Join(release, "chisel.yaml"):
+			all = true
+		case strings.HasPrefix(p, slicesDir) && strings.HasSuffix(p, ".yaml"):
+			// Chisel requires slice definition files to be named after
+			// their package.
+			pkgs[strings.TrimSuffix(filepath.Base(p), ".yaml")] = true
+		}
+	}
+	return pkgs, all
+}
+
+// affectedFiles returns the files that still exist among the changed ones,
+// or all the existing files if the whole release is affected. Paths are
+// compared as absolute ones.
+func affectedFiles(release string, files, changed []string) []string {
+	_, all := changedPackages(release, changed)
+	isChanged := make(map[string]bool)
+	for _, p := range changed {
+		if abs, err := filepath.Abs(p); err == nil {
+			isChanged[abs] = true
+		}
+	}
+	var affected []string
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil || !(all || isChanged[abs]) {
+			continue
+		}
+		if _, err := os.Stat(f); err == nil {
+			affected = append(affected, f)
+		}
+	}
+	return affected
+}
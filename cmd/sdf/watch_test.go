@@ -0,0 +1,49 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	sdf "github.com/rebornplusplus/chisel-tools/cmd/sdf"
+)
+
+func TestChangedPackages(t *testing.T) {
+	release := "/release"
+	pkgs, all := sdf.ChangedPackages(release, []string{
+		"/release/slices/hello.yaml",
+		"/release/slices/sub/libc6.yaml",
+		"/release/tests/hello.yaml",
+		"/release/README.md",
+	})
+	if want := map[string]bool{"hello": true, "libc6": true}; !reflect.DeepEqual(pkgs, want) || all {
+		t.Fatalf("have %v, %v, want %v only", pkgs, all, want)
+	}
+	if _, all := sdf.ChangedPackages(release, []string{"/release/chisel.yaml"}); !all {
+		t.Fatal("have chisel.yaml not affecting the whole release")
+	}
+}
+
+func TestAffectedFiles(t *testing.T) {
+	release := t.TempDir()
+	hello := filepath.Join(release, "slices/hello.yaml")
+	libc6 := filepath.Join(release, "slices/libc6.yaml")
+	removed := filepath.Join(release, "slices/removed.yaml")
+	for _, f := range []string{hello, libc6} {
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []string{hello, libc6, removed}
+	if have := sdf.AffectedFiles(release, files, []string{hello, removed}); !reflect.DeepEqual(have, []string{hello}) {
+		t.Fatalf("have %q, want %q", have, hello)
+	}
+	all := []string{filepath.Join(release, "chisel.yaml")}
+	if have := sdf.AffectedFiles(release, files, all); !reflect.DeepEqual(have, []string{hello, libc6}) {
+		t.Fatalf("have %q, want the existing files", have)
+	}
+}
@@ -0,0 +1,86 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Dearmor decodes an ASCII-armored OpenPGP key into its binary form, which
+// is what gpgv takes as a keyring.
+func Dearmor(armor string) ([]byte, error) {
+	var body strings.Builder
+	inBlock, inBody := false, false
+	scanner := bufio.NewScanner(strings.NewReader(armor))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "-----BEGIN "):
+			inBlock = true
+		case !inBlock:
+		case strings.HasPrefix(line, "-----END "):
+			data, err := base64.StdEncoding.DecodeString(body.String())
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode armored key: %w", err)
+			}
+			return data, nil
+		case !inBody && line == "":
+			inBody = true
+		case !inBody && strings.Contains(line, ": "):
+			// Armor header, such as "Comment: ...".
+		case strings.HasPrefix(line, "="):
+			// Checksum, base64 has no "=" at the start of a line.
+		default:
+			inBody = true
+			body.WriteString(line)
+		}
+	}
+	return nil, fmt.Errorf("cannot decode armored key: no complete armor block")
+}
+
+// VerifyClearsigned checks the signature of a clearsigned message, such as an
+// InRelease file, against the binary keys. It returns the signed message and
+// the fingerprint of the primary key that made the signature.
+func VerifyClearsigned(data []byte, keys [][]byte) (msg []byte, fingerprint string, err error) {
+	dir, err := os.MkdirTemp("", "sdf-gpgv-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+	keyring := filepath.Join(dir, "keyring.gpg")
+	if err := os.WriteFile(keyring, bytes.Join(keys, nil), 0600); err != nil {
+		return nil, "", err
+	}
+	output := filepath.Join(dir, "message")
+
+	cmd := exec.Command("gpgv", "--status-fd", "1", "--keyring", keyring, "--output", output, "-")
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	status, err := cmd.Output()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, "", fmt.Errorf("bad signature: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil, "", fmt.Errorf("cannot run gpgv: %w", err)
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 2 && fields[0] == "[GNUPG:]" && fields[1] == "VALIDSIG" {
+			fingerprint = fields[len(fields)-1]
+		}
+	}
+	if fingerprint == "" {
+		return nil, "", fmt.Errorf("bad signature: no valid signature in gpgv status")
+	}
+	msg, err = os.ReadFile(output)
+	if err != nil {
+		return nil, "", err
+	}
+	return msg, fingerprint, nil
+}
@@ -0,0 +1,81 @@
+package archive_test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/archive"
+)
+
+// testKey is a throwaway signing key made with gpg.
+type testKey struct {
+	home        string
+	armor       string
+	fingerprint string
+}
+
+func newTestKey(t *testing.T) *testKey {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	if _, err := exec.LookPath("gpgv"); err != nil {
+		t.Skip("gpgv not installed")
+	}
+	k := &testKey{home: t.TempDir()}
+	k.gpg(t, nil, "--passphrase", "", "--quick-gen-key", "Test <test@example.com>", "ed25519", "sign", "never")
+	k.armor = string(k.gpg(t, nil, "--armor", "--export"))
+	for _, line := range strings.Split(string(k.gpg(t, nil, "--with-colons", "--fingerprint")), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" && k.fingerprint == "" {
+			k.fingerprint = fields[9]
+		}
+	}
+	return k
+}
+
+func (k *testKey) gpg(t *testing.T, stdin []byte, args ...string) []byte {
+	cmd := exec.Command("gpg", append([]string{"--batch", "--quiet"}, args...)...)
+	cmd.Env = append(os.Environ(), "GNUPGHOME="+k.home)
+	if stdin != nil {
+		cmd.Stdin = strings.NewReader(string(stdin))
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("gpg %v: %v", args, err)
+	}
+	return out
+}
+
+func (k *testKey) clearsign(t *testing.T, data []byte) []byte {
+	return k.gpg(t, data, "--clearsign")
+}
+
+func TestVerifyClearsigned(t *testing.T) {
+	key := newTestKey(t)
+	other := newTestKey(t)
+	keyring, err := archive.Dearmor(key.armor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := key.clearsign(t, []byte(sampleRelease))
+	msg, fingerprint, err := archive.VerifyClearsigned(signed, [][]byte{keyring})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != sampleRelease {
+		t.Fatalf("have message %q, want %q", msg, sampleRelease)
+	}
+	if fingerprint != key.fingerprint {
+		t.Fatalf("have fingerprint %s, want %s", fingerprint, key.fingerprint)
+	}
+
+	tampered := []byte(strings.Replace(string(signed), "Suite: noble", "Suite: nobble", 1))
+	if _, _, err := archive.VerifyClearsigned(tampered, [][]byte{keyring}); err == nil {
+		t.Fatal("have no error for a tampered message")
+	}
+	if _, _, err := archive.VerifyClearsigned(other.clearsign(t, []byte(sampleRelease)), [][]byte{keyring}); err == nil {
+		t.Fatal("have no error for a message signed with another key")
+	}
+}
@@ -0,0 +1,124 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// UbuntuURL returns the Ubuntu archive chisel fetches packages of the
+// architecture from.
+func UbuntuURL(arch string) string {
+	if arch == "amd64" || arch == "i386" {
+		return "http://archive.ubuntu.com/ubuntu/"
+	}
+	return "http://ports.ubuntu.com/ubuntu-ports/"
+}
+
+// An Index finds the packages of an archive in its Packages indexes, each
+// fetched once.
+type Index struct {
+	// URL returns the base URL of the archive for the architecture,
+	// [UbuntuURL] if nil.
+	URL        func(arch string) string
+	Suites     []string
+	Components []string
+	Client     *http.Client
+
+	mu    sync.Mutex
+	names map[string]map[string]bool // Package names by architecture.
+}
+
+// Has reports whether the package is in the index of the architecture of
+// any of the suites and components. As in the archives, packages of
+// architecture "all" are in the indexes of every architecture.
+//
+// Indexes the archive does not have are skipped, as long as it has one of
+// them for the architecture.
+//
+// The indexes are not checked against the signature of the archive, which
+// chisel does when fetching the packages: they only tell what to install.
+func (x *Index) Has(ctx context.Context, arch, pkg string) (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	names, ok := x.names[arch]
+	if !ok {
+		var err error
+		if names, err = x.fetch(ctx, arch); err != nil {
+			return false, err
+		}
+		if x.names == nil {
+			x.names = make(map[string]map[string]bool)
+		}
+		x.names[arch] = names
+	}
+	return names[pkg], nil
+}
+
+func (x *Index) fetch(ctx context.Context, arch string) (map[string]bool, error) {
+	base := UbuntuURL(arch)
+	if x.URL != nil {
+		base = x.URL(arch)
+	}
+	names := make(map[string]bool)
+	var missing []string
+	for _, suite := range x.Suites {
+		for _, comp := range x.Components {
+			u := fmt.Sprintf("%s/dists/%s/%s/binary-%s/Packages.gz", strings.TrimSuffix(base, "/"), suite, comp, arch)
+			data, err := x.get(ctx, u)
+			if err != nil {
+				return nil, err
+			}
+			if data == nil {
+				// Not every suite has every component for every
+				// architecture, such as the backports.
+				missing = append(missing, u)
+				continue
+			}
+			gz, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("cannot read %s: %w", u, err)
+			}
+			pkgs, err := ParsePackages(gz)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read %s: %w", u, err)
+			}
+			for _, p := range pkgs {
+				names[p.Name] = true
+			}
+		}
+	}
+	if len(missing) > 0 && len(missing) == len(x.Suites)*len(x.Components) {
+		return nil, fmt.Errorf("cannot download any index for %s: %s not found", arch, strings.Join(missing, ", "))
+	}
+	return names, nil
+}
+
+// get returns the content at the URL, or nil if it is not found.
+func (x *Index) get(ctx context.Context, u string) ([]byte, error) {
+	client := x.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download %s: %s", u, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
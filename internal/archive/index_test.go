@@ -0,0 +1,80 @@
+package archive_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/archive"
+)
+
+func gzipped(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestIndex(t *testing.T) {
+	indexes := map[string][]byte{
+		"/dists/noble/main/binary-amd64/Packages.gz":       gzipped(t, samplePackages),
+		"/dists/noble/universe/binary-amd64/Packages.gz":   gzipped(t, "Package: cowsay\nArchitecture: all\nSHA256: 00\n"),
+		"/dists/noble/main/binary-riscv64/Packages.gz":     gzipped(t, "Package: base-files\nArchitecture: riscv64\nSHA256: 00\n"),
+		"/dists/noble/universe/binary-riscv64/Packages.gz": gzipped(t, ""),
+		"/dists/noble/main/binary-s390x/Packages.gz":       gzipped(t, "Package: hello\nArchitecture: s390x\nSHA256: 00\n"),
+		"/dists/noble/universe/binary-ppc64el/Packages.gz": []byte("not gzipped"),
+	}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		data, ok := indexes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	x := &archive.Index{
+		URL:        func(arch string) string { return srv.URL + "/" },
+		Suites:     []string{"noble"},
+		Components: []string{"main", "universe"},
+	}
+	for _, test := range []struct {
+		arch, pkg string
+		has       bool
+	}{
+		{"amd64", "hello", true},
+		{"amd64", "cowsay", true},
+		{"amd64", "missing", false},
+		{"riscv64", "hello", false},
+		{"riscv64", "base-files", true},
+		// The index of universe is missing.
+		{"s390x", "hello", true},
+	} {
+		has, err := x.Has(context.Background(), test.arch, test.pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if has != test.has {
+			t.Fatalf("have %s on %s %v, want %v", test.pkg, test.arch, has, test.has)
+		}
+	}
+	// Every index is fetched once.
+	if requests != 6 {
+		t.Fatalf("have %d requests, want 6", requests)
+	}
+	if _, err := x.Has(context.Background(), "arm64", "hello"); err == nil {
+		t.Fatal("have no error for missing indexes")
+	}
+	if _, err := x.Has(context.Background(), "ppc64el", "hello"); err == nil {
+		t.Fatal("have no error for a bad index")
+	}
+}
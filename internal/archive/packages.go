@@ -0,0 +1,47 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A Package is an entry of a Packages index.
+type Package struct {
+	Name     string
+	Version  string
+	Arch     string
+	Filename string
+	Size     int64
+	SHA256   string
+}
+
+// ParsePackages parses an uncompressed Packages index.
+func ParsePackages(r io.Reader) ([]*Package, error) {
+	var pkgs []*Package
+	err := paragraphs(r, func(fields map[string]string) error {
+		p := &Package{
+			Name:     fields["Package"],
+			Version:  fields["Version"],
+			Arch:     fields["Architecture"],
+			Filename: fields["Filename"],
+			SHA256:   fields["SHA256"],
+		}
+		if p.Name == "" || p.SHA256 == "" {
+			return fmt.Errorf("package entry without Package or SHA256 field")
+		}
+		if s, ok := fields["Size"]; ok {
+			size, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid size of package %s: %q", p.Name, s)
+			}
+			p.Size = size
+		}
+		pkgs = append(pkgs, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse packages: %w", err)
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,55 @@
+package archive_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/archive"
+)
+
+const samplePackages = `Package: hello
+Architecture: amd64
+Version: 2.10-3build1
+Description: example package based on GNU autoconf
+ The GNU hello program produces a familiar, friendly greeting.
+Filename: pool/main/h/hello/hello_2.10-3build1_amd64.deb
+Size: 27916
+SHA256: 5b1c5bb5fb6d32e0c8e5dbd3e5a3f0af3ed5c6d0c1b2a3f4e5d6c7b8a9f0e1d2
+
+Package: base-files
+Architecture: amd64
+Version: 13ubuntu10
+Filename: pool/main/b/base-files/base-files_13ubuntu10_amd64.deb
+Size: 73552
+SHA256: 0d1c2b3a4f5e6d7c8b9a0f1e2d3c4b5a6f7e8d9c0b1a2f3e4d5c6b7a8f9e0d1c
+`
+
+func TestParsePackages(t *testing.T) {
+	pkgs, err := archive.ParsePackages(strings.NewReader(samplePackages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*archive.Package{{
+		Name:     "hello",
+		Version:  "2.10-3build1",
+		Arch:     "amd64",
+		Filename: "pool/main/h/hello/hello_2.10-3build1_amd64.deb",
+		Size:     27916,
+		SHA256:   "5b1c5bb5fb6d32e0c8e5dbd3e5a3f0af3ed5c6d0c1b2a3f4e5d6c7b8a9f0e1d2",
+	}, {
+		Name:     "base-files",
+		Version:  "13ubuntu10",
+		Arch:     "amd64",
+		Filename: "pool/main/b/base-files/base-files_13ubuntu10_amd64.deb",
+		Size:     73552,
+		SHA256:   "0d1c2b3a4f5e6d7c8b9a0f1e2d3c4b5a6f7e8d9c0b1a2f3e4d5c6b7a8f9e0d1c",
+	}}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Fatalf("have %+v, want %+v", pkgs, want)
+	}
+
+	if _, err := archive.ParsePackages(strings.NewReader("Package: hello\n")); err == nil {
+		t.Fatal("have no error for a package without SHA256")
+	}
+}
@@ -0,0 +1,111 @@
+// Package archive reads the signed indexes of Debian archives and checks the
+// files chisel fetched against them, independently of chisel.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Release is the content of a signed InRelease file.
+type Release struct {
+	Origin   string
+	Suite    string
+	Codename string
+	// Files listed in the SHA256 field, by path relative to the release,
+	// e.g. main/binary-amd64/Packages.gz.
+	Files map[string]*File
+}
+
+type File struct {
+	Path   string
+	Size   int64
+	SHA256 string
+}
+
+// paragraphs calls fn with the fields of each deb822 paragraph in r.
+// Multi-line values keep their continuation lines, without the leading
+// space.
+func paragraphs(r io.Reader, fn func(fields map[string]string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	fields := make(map[string]string)
+	var last string
+	flush := func() error {
+		if len(fields) == 0 {
+			return nil
+		}
+		err := fn(fields)
+		fields = make(map[string]string)
+		last = ""
+		return err
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			if err := flush(); err != nil {
+				return err
+			}
+		case line[0] == ' ' || line[0] == '\t':
+			if last == "" {
+				return fmt.Errorf("continuation line without a field: %q", line)
+			}
+			fields[last] += "\n" + strings.TrimSpace(line)
+		default:
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return fmt.Errorf("invalid line: %q", line)
+			}
+			last = key
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// ParseRelease parses the content of a Release file, or of the message of
+// an InRelease file once its signature is verified.
+func ParseRelease(data []byte) (*Release, error) {
+	var rel *Release
+	err := paragraphs(bytes.NewReader(data), func(fields map[string]string) error {
+		if rel != nil {
+			return nil
+		}
+		rel = &Release{
+			Origin:   fields["Origin"],
+			Suite:    fields["Suite"],
+			Codename: fields["Codename"],
+			Files:    make(map[string]*File),
+		}
+		for _, line := range strings.Split(fields["SHA256"], "\n") {
+			parts := strings.Fields(line)
+			if len(parts) == 0 {
+				continue
+			}
+			if len(parts) != 3 {
+				return fmt.Errorf("invalid SHA256 line: %q", line)
+			}
+			size, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid size in SHA256 line: %q", line)
+			}
+			rel.Files[parts[2]] = &File{Path: parts[2], Size: size, SHA256: parts[0]}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse release: %w", err)
+	}
+	if rel == nil || rel.Suite == "" && rel.Codename == "" {
+		return nil, fmt.Errorf("cannot parse release: no suite or codename")
+	}
+	return rel, nil
+}
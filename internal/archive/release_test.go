@@ -0,0 +1,52 @@
+package archive_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/archive"
+)
+
+const sampleRelease = `Origin: Ubuntu
+Label: Ubuntu
+Suite: noble
+Codename: noble
+Architectures: amd64 arm64
+Components: main universe
+MD5Sum:
+ d41d8cd98f00b204e9800998ecf8427e 0 main/binary-amd64/Packages
+SHA256:
+ 2d3f0a4c3ba1d5bd4c4dfe0e5a8b3c0a0d6bba2a7bd3a8da1a0d0d2b9b3c2c1c 1024 main/binary-amd64/Packages
+ 6a5f3d5bd6c8bd5b0f4b1f52ce4a1df3c1b8b3d36d24f4a5c3b1f2e1d0c9b8a7 321 main/binary-amd64/Packages.gz
+`
+
+func TestParseRelease(t *testing.T) {
+	rel, err := archive.ParseRelease([]byte(sampleRelease))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &archive.Release{
+		Origin:   "Ubuntu",
+		Suite:    "noble",
+		Codename: "noble",
+		Files: map[string]*archive.File{
+			"main/binary-amd64/Packages": {
+				Path:   "main/binary-amd64/Packages",
+				Size:   1024,
+				SHA256: "2d3f0a4c3ba1d5bd4c4dfe0e5a8b3c0a0d6bba2a7bd3a8da1a0d0d2b9b3c2c1c",
+			},
+			"main/binary-amd64/Packages.gz": {
+				Path:   "main/binary-amd64/Packages.gz",
+				Size:   321,
+				SHA256: "6a5f3d5bd6c8bd5b0f4b1f52ce4a1df3c1b8b3d36d24f4a5c3b1f2e1d0c9b8a7",
+			},
+		},
+	}
+	if !reflect.DeepEqual(rel, want) {
+		t.Fatalf("have %+v, want %+v", rel, want)
+	}
+
+	if _, err := archive.ParseRelease([]byte("Origin: Ubuntu\n")); err == nil {
+		t.Fatal("have no error for a release without suite")
+	}
+}
@@ -0,0 +1,260 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type Kind string
+
+const (
+	KindRelease Kind = "release"
+	KindIndex   Kind = "index"
+	KindPackage Kind = "package"
+	KindUnknown Kind = "unknown"
+)
+
+// An Entry is the verification result of one file chisel fetched.
+type Entry struct {
+	SHA256   string `json:"sha256"`
+	Kind     Kind   `json:"kind"`
+	Verified bool   `json:"verified"`
+	Message  string `json:"message,omitempty"`
+	// Suite of the release the file belongs to.
+	Suite string `json:"suite,omitempty"`
+	// Name of the public key in chisel.yaml the release is signed with.
+	Key string `json:"key,omitempty"`
+	// Path of the file in the archive, relative to the release for indexes.
+	Path    string `json:"path,omitempty"`
+	Package string `json:"package,omitempty"`
+	Version string `json:"version,omitempty"`
+	Arch    string `json:"arch,omitempty"`
+}
+
+type Report struct {
+	Entries []*Entry `json:"entries"`
+}
+
+// Failed returns the entries that could not be verified.
+func (r *Report) Failed() []*Entry {
+	var failed []*Entry
+	for _, e := range r.Entries {
+		if !e.Verified {
+			failed = append(failed, e)
+		}
+	}
+	return failed
+}
+
+const clearsignHeader = "-----BEGIN PGP SIGNED MESSAGE-----"
+
+// VerifyCache checks every file in the chisel cache directories, which are
+// content-addressed by their SHA256 digest, against the archive signatures:
+// InRelease files must be signed with one of the public keys, Packages
+// indexes must be listed in a signed InRelease file and debs must be listed
+// in a verified Packages index. Each dir is the XDG_CACHE_HOME chisel ran
+// with.
+func VerifyCache(dirs []string, keys map[string]*chisel.PublicKey) (*Report, error) {
+	var keyring [][]byte
+	for name, key := range keys {
+		data, err := Dearmor(key.Armor)
+		if err != nil {
+			return nil, fmt.Errorf("public key %s: %w", name, err)
+		}
+		keyring = append(keyring, data)
+	}
+
+	blobs := make(map[string]string) // Path by digest.
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "chisel", "sha256", "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if name := filepath.Base(m); !strings.HasSuffix(name, ".tmp") {
+				blobs[name] = m
+			}
+		}
+	}
+	var digests []string
+	for d := range blobs {
+		digests = append(digests, d)
+	}
+	sort.Strings(digests)
+
+	v := &verifier{
+		keyring: keyring,
+		keys:    keys,
+		entries: make(map[string]*Entry),
+		indexes: make(map[string]*indexFile),
+	}
+	// Releases first, then indexes and then the rest, as each step needs
+	// the files verified by the previous one.
+	for _, d := range digests {
+		if err := v.release(d, blobs[d]); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range digests {
+		if err := v.index(d, blobs[d]); err != nil {
+			return nil, err
+		}
+	}
+	report := &Report{Entries: []*Entry{}}
+	for _, d := range digests {
+		e := v.entries[d]
+		if e == nil {
+			e = v.rest(d)
+		}
+		report.Entries = append(report.Entries, e)
+	}
+	return report, nil
+}
+
+type indexFile struct {
+	suite string
+	file  *File
+}
+
+type verifier struct {
+	keyring [][]byte
+	keys    map[string]*chisel.PublicKey
+	entries map[string]*Entry
+	// Files listed in the verified releases, by digest.
+	indexes map[string]*indexFile
+	// Packages listed in the verified indexes, by digest.
+	packages map[string]*Package
+}
+
+func readBlob(digest, p string) ([]byte, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != digest {
+		return nil, nil
+	}
+	return data, nil
+}
+
+func (v *verifier) release(digest, p string) error {
+	data, err := readBlob(digest, p)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		v.entries[digest] = &Entry{
+			SHA256:  digest,
+			Kind:    KindUnknown,
+			Message: "content does not match the digest",
+		}
+		return nil
+	}
+	if !bytes.HasPrefix(data, []byte(clearsignHeader)) {
+		return nil
+	}
+	e := &Entry{SHA256: digest, Kind: KindRelease}
+	v.entries[digest] = e
+	msg, fingerprint, err := VerifyClearsigned(data, v.keyring)
+	if err != nil {
+		e.Message = err.Error()
+		return nil
+	}
+	rel, err := ParseRelease(msg)
+	if err != nil {
+		e.Message = err.Error()
+		return nil
+	}
+	e.Suite = rel.Suite
+	if e.Suite == "" {
+		e.Suite = rel.Codename
+	}
+	for name, key := range v.keys {
+		if key.ID != "" && strings.HasSuffix(strings.ToUpper(fingerprint), strings.ToUpper(key.ID)) {
+			e.Key = name
+		}
+	}
+	e.Verified = true
+	for _, f := range rel.Files {
+		v.indexes[f.SHA256] = &indexFile{suite: e.Suite, file: f}
+	}
+	return nil
+}
+
+func (v *verifier) index(digest, p string) error {
+	idx, ok := v.indexes[digest]
+	if !ok || v.entries[digest] != nil {
+		return nil
+	}
+	e := &Entry{
+		SHA256:   digest,
+		Kind:     KindIndex,
+		Verified: true,
+		Suite:    idx.suite,
+		Path:     idx.file.Path,
+	}
+	v.entries[digest] = e
+	var r io.Reader
+	switch path.Base(idx.file.Path) {
+	case "Packages", "Packages.gz":
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+		if strings.HasSuffix(idx.file.Path, ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				e.Message = fmt.Sprintf("cannot decompress index: %s", err)
+				return nil
+			}
+			r = gz
+		}
+	default:
+		return nil
+	}
+	pkgs, err := ParsePackages(r)
+	if err != nil {
+		e.Message = err.Error()
+		return nil
+	}
+	if v.packages == nil {
+		v.packages = make(map[string]*Package)
+	}
+	for _, pkg := range pkgs {
+		v.packages[pkg.SHA256] = pkg
+	}
+	return nil
+}
+
+func (v *verifier) rest(digest string) *Entry {
+	if pkg, ok := v.packages[digest]; ok {
+		return &Entry{
+			SHA256:   digest,
+			Kind:     KindPackage,
+			Verified: true,
+			Path:     pkg.Filename,
+			Package:  pkg.Name,
+			Version:  pkg.Version,
+			Arch:     pkg.Arch,
+		}
+	}
+	return &Entry{
+		SHA256:  digest,
+		Kind:    KindUnknown,
+		Message: "not listed in any verified release or index",
+	}
+}
@@ -0,0 +1,102 @@
+package archive_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/archive"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+func sha(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeBlob(t *testing.T, dir string, data []byte) string {
+	d := sha(data)
+	p := filepath.Join(dir, "chisel", "sha256", d)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestVerifyCache(t *testing.T) {
+	key := newTestKey(t)
+	dir1, dir2 := t.TempDir(), t.TempDir()
+
+	deb := []byte("!<arch>\nhello deb")
+	packages := fmt.Sprintf("Package: hello\nArchitecture: amd64\nVersion: 1.0\nFilename: pool/h/hello.deb\nSHA256: %s\n", sha(deb))
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(packages))
+	w.Close()
+	release := fmt.Sprintf("Suite: noble\nSHA256:\n %s %d main/binary-amd64/Packages.gz\n", sha(gz.Bytes()), gz.Len())
+
+	relDigest := writeBlob(t, dir1, key.clearsign(t, []byte(release)))
+	idxDigest := writeBlob(t, dir1, gz.Bytes())
+	debDigest := writeBlob(t, dir2, deb)
+	unknownDigest := writeBlob(t, dir2, []byte("unknown"))
+	badDigest := writeBlob(t, dir2, []byte("corrupted"))
+	os.WriteFile(filepath.Join(dir2, "chisel", "sha256", badDigest), []byte("modified"), 0644)
+
+	keys := map[string]*chisel.PublicKey{
+		"test-key": {ID: key.fingerprint[len(key.fingerprint)-16:], Armor: key.armor},
+	}
+	report, err := archive.VerifyCache([]string{dir1, dir2}, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := make(map[string]archive.Entry)
+	for _, e := range report.Entries {
+		have[e.SHA256] = *e
+	}
+	want := map[string]archive.Entry{
+		relDigest: {
+			SHA256: relDigest, Kind: archive.KindRelease, Verified: true,
+			Suite: "noble", Key: "test-key",
+		},
+		idxDigest: {
+			SHA256: idxDigest, Kind: archive.KindIndex, Verified: true,
+			Suite: "noble", Path: "main/binary-amd64/Packages.gz",
+		},
+		debDigest: {
+			SHA256: debDigest, Kind: archive.KindPackage, Verified: true,
+			Path: "pool/h/hello.deb", Package: "hello", Version: "1.0", Arch: "amd64",
+		},
+		unknownDigest: {
+			SHA256: unknownDigest, Kind: archive.KindUnknown,
+			Message: "not listed in any verified release or index",
+		},
+		badDigest: {
+			SHA256: badDigest, Kind: archive.KindUnknown,
+			Message: "content does not match the digest",
+		},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %+v, want %+v", have, want)
+	}
+	if failed := report.Failed(); len(failed) != 2 {
+		t.Fatalf("have %d failed entries, want 2", len(failed))
+	}
+
+	// Without the key, nothing can be verified.
+	report, err = archive.VerifyCache([]string{dir1, dir2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := report.Failed(); len(failed) != 5 {
+		t.Fatalf("have %d failed entries without keys, want 5", len(failed))
+	}
+}
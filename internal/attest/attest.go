@@ -0,0 +1,88 @@
+// Package attest builds in-toto attestations about installed slices and signs
+// them in DSSE envelopes, which cosign and other in-toto tooling can verify.
+// See https://github.com/in-toto/attestation and
+// https://github.com/secure-systems-lab/dsse.
+package attest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PayloadType   = "application/vnd.in-toto+json"
+	// The predicate type of the install attestations of this tool.
+	InstallPredicateType = "https://github.com/rebornplusplus/chisel-tools/attestation/install/v1"
+)
+
+type Statement struct {
+	Type          string     `json:"_type"`
+	Subject       []*Subject `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     any        `json:"predicate"`
+}
+
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// NewStatement returns an in-toto statement about the subjects.
+func NewStatement(predicateType string, predicate any, subjects ...*Subject) *Statement {
+	return &Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: predicateType,
+		Predicate:     predicate,
+	}
+}
+
+// FileSubject returns a subject for the file at path, such as a root tarball.
+func FileSubject(name, path string) (*Subject, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return &Subject{
+		Name:   name,
+		Digest: map[string]string{"sha256": hex.EncodeToString(h.Sum(nil))},
+	}, nil
+}
+
+// DigestSubject returns a subject from a digest formatted as
+// "<algorithm>:<hex>", such as an OCI image digest.
+func DigestSubject(name, digest string) (*Subject, error) {
+	alg, value, ok := strings.Cut(digest, ":")
+	if !ok || alg == "" || value == "" {
+		return nil, fmt.Errorf("invalid digest %q: want <algorithm>:<hex>", digest)
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return nil, fmt.Errorf("invalid digest %q: %w", digest, err)
+	}
+	return &Subject{Name: name, Digest: map[string]string{alg: value}}, nil
+}
+
+// The predicate of the install attestations.
+type Install struct {
+	Slices        []string        `json:"slices"`
+	Arch          string          `json:"arch,omitempty"`
+	ChiselVersion string          `json:"chiselVersion,omitempty"`
+	Release       *Release        `json:"release,omitempty"`
+	Results       json.RawMessage `json:"results,omitempty"`
+}
+
+type Release struct {
+	Path   string `json:"path,omitempty"`
+	Commit string `json:"commit,omitempty"`
+}
@@ -0,0 +1,124 @@
+package attest_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/attest"
+)
+
+func TestSubjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "root.tar")
+	if err := os.WriteFile(path, []byte("tarball"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := attest.FileSubject("root.tar", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "db4b4d0d1cb480bf9aeea253771c00febe627f236765fa37d6a5614f079a3aa0"; s.Digest["sha256"] != want {
+		t.Fatalf("have digest %v, want sha256:%s", s.Digest, want)
+	}
+
+	s, err = attest.DigestSubject("image", "sha256:abcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Digest["sha256"] != "abcd" {
+		t.Fatalf("have digest %v, want sha256:abcd", s.Digest)
+	}
+	if _, err := attest.DigestSubject("image", "abcd"); err == nil {
+		t.Fatal("have no error for a digest without algorithm")
+	}
+}
+
+func pemKey(t *testing.T, key crypto.Signer) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestSignVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := attest.NewStatement(attest.InstallPredicateType, &attest.Install{
+		Slices: []string{"hello_bins"},
+	}, &attest.Subject{Name: "root.tar", Digest: map[string]string{"sha256": "abcd"}})
+
+	for _, key := range []crypto.Signer{edKey, ecKey} {
+		signer, err := attest.ParsePrivateKey(pemKey(t, key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err := attest.Sign(st, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if env.PayloadType != attest.PayloadType || len(env.Signatures) != 1 {
+			t.Fatalf("bad envelope: %+v", env)
+		}
+		got, err := attest.Verify(env, key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Type != attest.StatementType || got.Subject[0].Name != "root.tar" {
+			t.Fatalf("bad statement: %+v", got)
+		}
+
+		// Tampering with the payload must break the signature.
+		env.Payload = env.Payload[:len(env.Payload)-4] + "AAAA"
+		if _, err := attest.Verify(env, key.Public()); err == nil {
+			t.Fatal("have no error for a tampered envelope")
+		}
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ecKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := attest.ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("checksums"))
+	sig, err := ecdsa.SignASN1(rand.Reader, ecKey, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !attest.VerifySignature(pub, []byte("checksums"), sig) {
+		t.Fatal("have an invalid signature, want a valid one")
+	}
+	if attest.VerifySignature(pub, []byte("checksum"), sig) {
+		t.Fatal("have a valid signature of another message")
+	}
+
+	if _, err := attest.ParsePublicKey(pemKey(t, ecKey)); err == nil {
+		t.Fatal("have no error for a private key")
+	}
+	if _, err := attest.ParsePublicKey([]byte("key")); err == nil {
+		t.Fatal("have no error for a key without PEM data")
+	}
+}
@@ -0,0 +1,166 @@
+package attest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+)
+
+// Envelope is a DSSE envelope.
+type Envelope struct {
+	PayloadType string       `json:"payloadType"`
+	Payload     string       `json:"payload"`
+	Signatures  []*Signature `json:"signatures"`
+}
+
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// pae is the DSSE pre-authentication encoding, which is what gets signed.
+func pae(payloadType string, payload []byte) []byte {
+	return fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS#8, PKCS#1 or EC private key.
+// Ed25519, ECDSA and RSA keys are supported.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := key.(crypto.Signer); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// ParsePublicKey parses a PEM encoded PKIX public key. Ed25519, ECDSA and
+// RSA keys are supported.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// KeyID returns the hex SHA-256 digest of the DER encoded public key.
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sign the statement with the key and wrap it in a DSSE envelope.
+func Sign(st *Statement, key crypto.Signer) (*Envelope, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+	msg := pae(PayloadType, payload)
+
+	var sig []byte
+	switch key.(type) {
+	case ed25519.PrivateKey:
+		sig, err = key.Sign(rand.Reader, msg, crypto.Hash(0))
+	case *ecdsa.PrivateKey, *rsa.PrivateKey:
+		sum := sha256.Sum256(msg)
+		sig, err = key.Sign(rand.Reader, sum[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign statement: %w", err)
+	}
+	keyID, err := KeyID(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []*Signature{{
+			KeyID: keyID,
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		}},
+	}, nil
+}
+
+// Verify checks that the envelope has a valid signature from the public key
+// and returns the statement in it.
+func Verify(env *Envelope, pub crypto.PublicKey) (*Statement, error) {
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	msg := pae(env.PayloadType, payload)
+	for _, s := range env.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		if VerifySignature(pub, msg, sig) {
+			st := &Statement{}
+			if err := json.Unmarshal(payload, st); err != nil {
+				return nil, fmt.Errorf("invalid statement: %w", err)
+			}
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("no valid signature")
+}
+
+// VerifySignature reports whether sig is a signature of msg by the public
+// key, as made by Sign: Ed25519 signs the message itself, ECDSA and RSA its
+// SHA-256 digest.
+func VerifySignature(pub crypto.PublicKey, msg, sig []byte) bool {
+	sum := sha256.Sum256(msg)
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, msg, sig)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(pub, sum[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package attest
+
+import (
+	"time"
+)
+
+const (
+	SLSAPredicateType = "https://slsa.dev/provenance/v1"
+	// The build type of the provenance generated for installs, which
+	// defines the meaning of the parameters.
+	InstallBuildType = "https://github.com/rebornplusplus/chisel-tools/buildtypes/install/v1"
+	BuilderID        = "https://github.com/rebornplusplus/chisel-tools/sdf"
+)
+
+// Provenance is the SLSA v1 provenance predicate.
+// See https://slsa.dev/spec/v1.0/provenance.
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string                `json:"buildType"`
+	ExternalParameters   any                   `json:"externalParameters"`
+	InternalParameters   any                   `json:"internalParameters,omitempty"`
+	ResolvedDependencies []*ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+type ResourceDescriptor struct {
+	URI         string            `json:"uri,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Digest      map[string]string `json:"digest,omitempty"`
+	Annotations map[string]any    `json:"annotations,omitempty"`
+}
+
+type RunDetails struct {
+	Builder  Builder      `json:"builder"`
+	Metadata *RunMetadata `json:"metadata,omitempty"`
+}
+
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type RunMetadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// The external parameters of [InstallBuildType].
+type InstallParameters struct {
+	Slices  []string `json:"slices"`
+	Arch    string   `json:"arch"`
+	Release string   `json:"release"`
+}
+
+// The internal parameters of [InstallBuildType].
+type InstallInternals struct {
+	ChiselVersion string `json:"chiselVersion,omitempty"`
+}
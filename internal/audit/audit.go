@@ -0,0 +1,63 @@
+// Package audit checks installed roots for files that should not be shipped
+// in them.
+package audit
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+)
+
+// A Finding is a problem with one path in a root.
+type Finding struct {
+	// Name of the check that found the problem, e.g. "secrets".
+	Check string `json:"check"`
+	// Absolute path in the root, as in the manifest.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	// Slices providing the path, if known.
+	Slices []string `json:"slices,omitempty"`
+}
+
+// Attribute sets the slices that provide the path of each finding, as
+// recorded in the manifest.
+func Attribute(findings []*Finding, m *manifest.Manifest) {
+	slices := make(map[string][]string)
+	for _, p := range m.Paths {
+		slices[p.Path] = p.Slices
+	}
+	for _, f := range findings {
+		f.Slices = slices[f.Path]
+		if f.Slices == nil {
+			// Directories are recorded with a trailing slash.
+			f.Slices = slices[f.Path+"/"]
+		}
+	}
+}
+
+// Sort the findings by path and check.
+func Sort(findings []*Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Check < findings[j].Check
+	})
+}
+
+// walk calls fn for every path in root but root itself, with the path
+// translated into an absolute path in the root.
+func walk(root string, fn func(path, abs string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		return fn(path, "/"+filepath.ToSlash(rel), d)
+	})
+}
@@ -0,0 +1,53 @@
+package audit_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/audit"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+)
+
+type rootEntry struct {
+	path string
+	mode os.FileMode
+	data string
+}
+
+func makeRoot(t *testing.T, entries []rootEntry) string {
+	root := t.TempDir()
+	for _, e := range entries {
+		p := filepath.Join(root, e.path)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(e.data), e.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, e.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestAttribute(t *testing.T) {
+	m := &manifest.Manifest{Paths: []*manifest.Path{
+		{Path: "/etc/", Slices: []string{"base-files_base"}},
+		{Path: "/etc/shadow", Slices: []string{"base-passwd_data"}},
+	}}
+	findings := []*audit.Finding{
+		{Path: "/etc/shadow"},
+		{Path: "/etc"},
+		{Path: "/tmp/unknown"},
+	}
+	audit.Attribute(findings, m)
+	want := [][]string{{"base-passwd_data"}, {"base-files_base"}, nil}
+	for i, f := range findings {
+		if !reflect.DeepEqual(f.Slices, want[i]) {
+			t.Fatalf("%s: have slices %v, want %v", f.Path, f.Slices, want[i])
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// Permissions looks for setuid and setgid files, world-writable files and
+// directories without the sticky bit, and special files such as device
+// nodes, none of which slices are expected to ship.
+func Permissions(root string) ([]*Finding, error) {
+	var findings []*Finding
+	add := func(abs, format string, args ...any) {
+		findings = append(findings, &Finding{
+			Check:   "permissions",
+			Path:    abs,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	err := walk(root, func(p, abs string, d fs.DirEntry) error {
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode()
+		switch {
+		case mode&fs.ModeDevice != 0 && mode&fs.ModeCharDevice != 0:
+			add(abs, "character device")
+		case mode&fs.ModeDevice != 0:
+			add(abs, "block device")
+		case mode&fs.ModeNamedPipe != 0:
+			add(abs, "named pipe")
+		case mode&fs.ModeSocket != 0:
+			add(abs, "socket")
+		}
+		if mode&fs.ModeSetuid != 0 {
+			add(abs, "setuid (mode %s)", octal(mode))
+		}
+		if mode&fs.ModeSetgid != 0 && !mode.IsDir() {
+			add(abs, "setgid (mode %s)", octal(mode))
+		}
+		if mode.Perm()&0002 != 0 && !(mode.IsDir() && mode&fs.ModeSticky != 0) {
+			add(abs, "world-writable (mode %s)", octal(mode))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	Sort(findings)
+	return findings, nil
+}
+
+// octal formats the permissions of mode the way chmod takes them.
+func octal(mode fs.FileMode) string {
+	perm := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&fs.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return fmt.Sprintf("%04o", perm)
+}
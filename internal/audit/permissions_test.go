@@ -0,0 +1,49 @@
+package audit_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/audit"
+)
+
+func TestPermissions(t *testing.T) {
+	root := makeRoot(t, []rootEntry{
+		{"/usr/bin/hello", 0755, ""},
+		{"/usr/bin/su", 0755 | fs.ModeSetuid, ""},
+		{"/usr/bin/wall", 0755 | fs.ModeSetgid, ""},
+		{"/var/log/app.log", 0666, ""},
+		{"/var/tmp/.keep", 0644, ""},
+		{"/var/cache/.keep", 0644, ""},
+	})
+	if err := os.Chmod(filepath.Join(root, "var/tmp"), 0777|fs.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(root, "var/cache"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/dev/null", filepath.Join(root, "var/null")); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := audit.Permissions(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found [][2]string
+	for _, f := range findings {
+		found = append(found, [2]string{f.Path, f.Message})
+	}
+	want := [][2]string{
+		{"/usr/bin/su", "setuid (mode 4755)"},
+		{"/usr/bin/wall", "setgid (mode 2755)"},
+		{"/var/cache", "world-writable (mode 0777)"},
+		{"/var/log/app.log", "world-writable (mode 0666)"},
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Fatalf("have findings %v, want %v", found, want)
+	}
+}
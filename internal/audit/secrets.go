@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Files bigger than this are not searched for secrets.
+const maxSecretsSize = 16 << 20
+
+type secretPattern struct {
+	name string
+	re   *regexp.Regexp
+	// Only search configuration files, as the pattern is too loose for
+	// binaries and other text.
+	config bool
+}
+
+var secretPatterns = []*secretPattern{{
+	name: "private key",
+	re:   regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|ENCRYPTED|PGP) )?PRIVATE KEY( BLOCK)?-----`),
+}, {
+	name: "AWS access key",
+	re:   regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+}, {
+	name: "GitHub token",
+	re:   regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{36,})\b`),
+}, {
+	name: "Slack token",
+	re:   regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+}, {
+	name:   "password",
+	re:     regexp.MustCompile(`(?im)^\s*[a-z0-9_.-]*(password|passwd|secret|api_?key|token)["']?\s*[=:]\s*["']?[^\s"'#]{4,}`),
+	config: true,
+}}
+
+// Paths, as path.Match patterns, that hold credentials when present at all.
+var secretPaths = []struct {
+	pattern string
+	message string
+}{
+	{"/root/.ssh/id_*", "SSH private key"},
+	{"/home/*/.ssh/id_*", "SSH private key"},
+	{"/etc/ssh/ssh_host_*_key", "SSH host key"},
+	{"/root/.netrc", "netrc credentials"},
+	{"/home/*/.netrc", "netrc credentials"},
+	{"/root/.git-credentials", "git credentials"},
+	{"/home/*/.git-credentials", "git credentials"},
+	{"/root/.aws/credentials", "AWS credentials"},
+	{"/home/*/.aws/credentials", "AWS credentials"},
+	{"/root/.docker/config.json", "Docker registry credentials"},
+	{"/home/*/.docker/config.json", "Docker registry credentials"},
+	{"/root/.pgpass", "PostgreSQL password file"},
+	{"/home/*/.pgpass", "PostgreSQL password file"},
+}
+
+// Extensions of configuration files, searched for passwords too.
+var configExts = map[string]bool{
+	".conf": true, ".cfg": true, ".cnf": true, ".ini": true, ".env": true,
+	".json": true, ".yaml": true, ".yml": true, ".toml": true,
+	".properties": true, ".xml": true,
+}
+
+func isConfig(abs string, data []byte) bool {
+	if configExts[path.Ext(abs)] || path.Base(abs) == ".env" {
+		return true
+	}
+	// Text files in /etc are configuration files too.
+	return strings.HasPrefix(abs, "/etc/") && !bytes.Contains(data[:min(len(data), 8000)], []byte{0})
+}
+
+// Secrets looks for private keys, tokens and passwords in the files of the
+// root, and for files known to hold credentials.
+func Secrets(root string) ([]*Finding, error) {
+	var findings []*Finding
+	add := func(abs, format string, args ...any) {
+		findings = append(findings, &Finding{
+			Check:   "secrets",
+			Path:    abs,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	err := walk(root, func(p, abs string, d fs.DirEntry) error {
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		for _, sp := range secretPaths {
+			if ok, _ := path.Match(sp.pattern, abs); ok && !strings.HasSuffix(abs, ".pub") {
+				add(abs, "%s", sp.message)
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxSecretsSize {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if abs == "/etc/shadow" || abs == "/etc/gshadow" {
+			for _, name := range shadowPasswords(data) {
+				add(abs, "password hash for %s", name)
+			}
+			return nil
+		}
+		config := isConfig(abs, data)
+		for _, sp := range secretPatterns {
+			if sp.config && !config {
+				continue
+			}
+			if sp.re.Match(data) {
+				add(abs, "possible %s", sp.name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	Sort(findings)
+	return findings, nil
+}
+
+// shadowPasswords returns the entries of a shadow file that have a password
+// hash, rather than a locked or disabled password.
+func shadowPasswords(data []byte) []string {
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 2 {
+			continue
+		}
+		if strings.HasPrefix(fields[1], "$") {
+			names = append(names, fields[0])
+		}
+	}
+	return names
+}
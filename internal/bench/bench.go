@@ -0,0 +1,205 @@
+// Package bench measures chisel cut runs, breaking their time down by phase
+// with the lines chisel logs as it goes.
+//
+// Chisel logs a line before fetching anything and before extracting every
+// package, but nothing before running the mutation scripts. The time after
+// the last "Extracting" line is thus counted as mutation, the extraction of
+// the last package included.
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Line is a line of chisel output along with when it was written.
+type Line struct {
+	Time time.Time
+	Text string
+}
+
+// A Recorder is an [io.Writer] for the output of chisel that records when
+// every line is written. It is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	buf   []byte
+	lines []Line
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf = append(r.buf, p...)
+	for {
+		i := bytes.IndexByte(r.buf, '\n')
+		if i < 0 {
+			break
+		}
+		r.lines = append(r.lines, Line{Time: now, Text: string(r.buf[:i])})
+		r.buf = r.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the lines written so far, with the text after the last
+// newline as a line of its own.
+func (r *Recorder) Lines() []Line {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := append([]Line(nil), r.lines...)
+	if len(r.buf) > 0 {
+		lines = append(lines, Line{Time: time.Now(), Text: string(r.buf)})
+	}
+	return lines
+}
+
+// Timings is the time a run took, in total and by phase.
+type Timings struct {
+	// Setup is the time before the first fetch: reading the release and
+	// selecting the slices.
+	Setup   time.Duration `json:"setup"`
+	Fetch   time.Duration `json:"fetch"`
+	Extract time.Duration `json:"extract"`
+	Mutate  time.Duration `json:"mutate"`
+	Total   time.Duration `json:"total"`
+}
+
+// Phases returns the names and durations of the phases, in order.
+func (t *Timings) Phases() []Phase {
+	return []Phase{
+		{"setup", t.Setup},
+		{"fetch", t.Fetch},
+		{"extract", t.Extract},
+		{"mutate", t.Mutate},
+		{"total", t.Total},
+	}
+}
+
+// A Phase is the duration of a phase of a run.
+type Phase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Breakdown returns the timings of a run from start to end with the lines
+// chisel logged. Every line starts a phase that lasts until the next line,
+// lines that are not of a known phase continue the current one.
+func Breakdown(lines []Line, start, end time.Time) *Timings {
+	t := &Timings{Total: end.Sub(start)}
+	phase := &t.Setup
+	last := start
+	for _, l := range lines {
+		var next *time.Duration
+		switch {
+		case strings.Contains(l.Text, "Extracting files from package"):
+			next = &t.Extract
+		case strings.Contains(l.Text, "Fetching "):
+			next = &t.Fetch
+		default:
+			continue
+		}
+		*phase += l.Time.Sub(last)
+		phase, last = next, l.Time
+	}
+	if phase == &t.Extract {
+		phase = &t.Mutate
+	}
+	*phase += end.Sub(last)
+	return t
+}
+
+// A Benchmark is the timings of the runs of cutting the slices.
+type Benchmark struct {
+	Slices []string   `json:"slices"`
+	Runs   []*Timings `json:"runs"`
+	Median *Timings   `json:"median"`
+}
+
+// NewBenchmark returns the benchmark of the runs, with their median.
+func NewBenchmark(slices []string, runs []*Timings) *Benchmark {
+	return &Benchmark{Slices: slices, Runs: runs, Median: Median(runs)}
+}
+
+// Median returns the median of every phase of the runs, on its own.
+func Median(runs []*Timings) *Timings {
+	median := func(get func(*Timings) time.Duration) time.Duration {
+		if len(runs) == 0 {
+			return 0
+		}
+		ds := make([]time.Duration, len(runs))
+		for i, r := range runs {
+			ds[i] = get(r)
+		}
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		n := len(ds)
+		if n%2 == 1 {
+			return ds[n/2]
+		}
+		return (ds[n/2-1] + ds[n/2]) / 2
+	}
+	return &Timings{
+		Setup:   median(func(t *Timings) time.Duration { return t.Setup }),
+		Fetch:   median(func(t *Timings) time.Duration { return t.Fetch }),
+		Extract: median(func(t *Timings) time.Duration { return t.Extract }),
+		Mutate:  median(func(t *Timings) time.Duration { return t.Mutate }),
+		Total:   median(func(t *Timings) time.Duration { return t.Total }),
+	}
+}
+
+// A Report is the result of benchmarking with a chisel binary.
+type Report struct {
+	Chisel     string       `json:"chisel"`
+	Arch       string       `json:"arch"`
+	Cached     bool         `json:"cached"`
+	Benchmarks []*Benchmark `json:"benchmarks"`
+}
+
+// A Regression is a phase of a benchmark that is slower than in the base
+// report.
+type Regression struct {
+	Slices  []string
+	Phase   string
+	Base    time.Duration
+	Current time.Duration
+}
+
+// Percent returns how much slower the phase is, in percent.
+func (r *Regression) Percent() float64 {
+	return 100 * float64(r.Current-r.Base) / float64(r.Base)
+}
+
+func (r *Regression) String() string {
+	return fmt.Sprintf("%s: %s is %.1f%% slower, %s against %s", strings.Join(r.Slices, " "), r.Phase, r.Percent(), r.Current.Round(time.Millisecond), r.Base.Round(time.Millisecond))
+}
+
+// Compare returns the phases of the benchmarks in the report whose median is
+// slower than in the base report by more than threshold percent, and by more
+// than minDiff to ignore the noise of short phases. Benchmarks are matched by
+// their slices, those missing from either report are not compared.
+func Compare(base, report *Report, threshold float64, minDiff time.Duration) []*Regression {
+	baseline := make(map[string]*Benchmark)
+	for _, b := range base.Benchmarks {
+		baseline[strings.Join(b.Slices, " ")] = b
+	}
+	var regressions []*Regression
+	for _, b := range report.Benchmarks {
+		old, ok := baseline[strings.Join(b.Slices, " ")]
+		if !ok || old.Median == nil || b.Median == nil {
+			continue
+		}
+		oldPhases := old.Median.Phases()
+		for i, p := range b.Median.Phases() {
+			r := &Regression{Slices: b.Slices, Phase: p.Name, Base: oldPhases[i].Duration, Current: p.Duration}
+			if r.Base <= 0 || r.Current-r.Base <= minDiff || r.Percent() <= threshold {
+				continue
+			}
+			regressions = append(regressions, r)
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,125 @@
+package bench_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/bench"
+)
+
+var start = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(ms int, text string) bench.Line {
+	return bench.Line{Time: start.Add(time.Duration(ms) * time.Millisecond), Text: text}
+}
+
+var breakdownTests = []struct {
+	summary string
+	lines   []bench.Line
+	end     int
+	timings bench.Timings
+}{{
+	summary: "All phases",
+	lines: []bench.Line{
+		at(100, "2026/01/01 00:00:00 Processing 24.04 release..."),
+		at(200, "2026/01/01 00:00:00 Fetching current ubuntu-24.04 noble release..."),
+		at(250, "2026/01/01 00:00:00 Release date: 2024-04-25 15:36:51 +0000 UTC"),
+		at(500, "2026/01/01 00:00:00 Fetching pool/main/h/hello/hello_2.10-3build1_amd64.deb..."),
+		at(1000, `2026/01/01 00:00:01 Extracting files from package "hello"...`),
+		at(1300, `2026/01/01 00:00:01 Extracting files from package "libc6"...`),
+	},
+	end: 2000,
+	timings: bench.Timings{
+		Setup:   200 * time.Millisecond,
+		Fetch:   800 * time.Millisecond,
+		Extract: 300 * time.Millisecond,
+		Mutate:  700 * time.Millisecond,
+		Total:   2000 * time.Millisecond,
+	},
+}, {
+	summary: "Failure while fetching",
+	lines: []bench.Line{
+		at(100, "Fetching current ubuntu-24.04 noble release..."),
+		at(400, "error: cannot fetch from archive"),
+	},
+	end: 500,
+	timings: bench.Timings{
+		Setup: 100 * time.Millisecond,
+		Fetch: 400 * time.Millisecond,
+		Total: 500 * time.Millisecond,
+	},
+}, {
+	summary: "No output",
+	end:     300,
+	timings: bench.Timings{
+		Setup: 300 * time.Millisecond,
+		Total: 300 * time.Millisecond,
+	},
+}}
+
+func TestBreakdown(t *testing.T) {
+	for _, test := range breakdownTests {
+		t.Logf("Summary: %s", test.summary)
+		timings := bench.Breakdown(test.lines, start, start.Add(time.Duration(test.end)*time.Millisecond))
+		if !reflect.DeepEqual(*timings, test.timings) {
+			t.Fatalf("have %+v, want %+v", *timings, test.timings)
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := &bench.Recorder{}
+	for _, s := range []string{"Fetch", "ing a...\nExtracting b...\n", "done"} {
+		fmt.Fprint(r, s)
+	}
+	var texts []string
+	for _, l := range r.Lines() {
+		texts = append(texts, l.Text)
+	}
+	if want := []string{"Fetching a...", "Extracting b...", "done"}; !reflect.DeepEqual(texts, want) {
+		t.Fatalf("have %q, want %q", texts, want)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	runs := []*bench.Timings{
+		{Fetch: 3, Extract: 10, Total: 13},
+		{Fetch: 1, Extract: 30, Total: 31},
+		{Fetch: 2, Extract: 20, Total: 22},
+	}
+	if have, want := *bench.Median(runs), (bench.Timings{Fetch: 2, Extract: 20, Total: 22}); have != want {
+		t.Fatalf("have %+v, want %+v", have, want)
+	}
+	if have, want := *bench.Median(runs[:2]), (bench.Timings{Fetch: 2, Extract: 20, Total: 22}); have != want {
+		t.Fatalf("have %+v, want %+v", have, want)
+	}
+	if have := *bench.Median(nil); have != (bench.Timings{}) {
+		t.Fatalf("have %+v, want zero timings", have)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := &bench.Report{Benchmarks: []*bench.Benchmark{
+		{Slices: []string{"a_bins"}, Median: &bench.Timings{Fetch: time.Second, Extract: time.Second, Mutate: time.Millisecond, Total: 2 * time.Second}},
+		{Slices: []string{"b_bins"}, Median: &bench.Timings{Total: time.Second}},
+	}}
+	report := &bench.Report{Benchmarks: []*bench.Benchmark{
+		// Extract is 50% slower, fetch only 5% and mutate too little to tell.
+		{Slices: []string{"a_bins"}, Median: &bench.Timings{Fetch: 1050 * time.Millisecond, Extract: 1500 * time.Millisecond, Mutate: 3 * time.Millisecond, Total: 2550 * time.Millisecond}},
+		{Slices: []string{"c_bins"}, Median: &bench.Timings{Total: 10 * time.Second}},
+	}}
+	regressions := bench.Compare(base, report, 10, 10*time.Millisecond)
+	var have []string
+	for _, r := range regressions {
+		have = append(have, r.String())
+	}
+	want := []string{
+		"a_bins: extract is 50.0% slower, 1.5s against 1s",
+		"a_bins: total is 27.5% slower, 2.55s against 2s",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+}
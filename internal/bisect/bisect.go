@@ -0,0 +1,119 @@
+// Package bisect drives git-bisect(1) over a repository to find the commit
+// that introduced a failure.
+//
+// The bisection runs in a detached worktree of its own, so that the checkout
+// of the repository is left alone.
+package bisect
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// A Verdict is the outcome of testing one commit.
+type Verdict string
+
+const (
+	Good Verdict = "good"
+	Bad  Verdict = "bad"
+	// Skip commits that cannot be tested.
+	Skip Verdict = "skip"
+)
+
+type Bisection struct {
+	repo string
+	// Dir is the worktree the commits to test are checked out in.
+	Dir string
+}
+
+// git runs the command in dir. Its output is returned on failure too.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(string(out))
+		}
+		return string(out), fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+	}
+	return string(out), nil
+}
+
+// Start a bisection of the repository between the good and bad refs.
+func Start(repo, good, bad string) (*Bisection, error) {
+	for _, ref := range []string{good, bad} {
+		if _, err := git(repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+			return nil, fmt.Errorf("invalid ref %q", ref)
+		}
+	}
+	dir, err := os.MkdirTemp("", "sdf-bisect-")
+	if err != nil {
+		return nil, err
+	}
+	b := &Bisection{repo: repo, Dir: dir}
+	if _, err := git(repo, "worktree", "add", "--detach", dir, bad); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if _, err := git(dir, "bisect", "start", bad, good); err != nil {
+		b.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Commit returns the commit checked out for testing.
+func (b *Bisection) Commit() (string, error) {
+	out, err := git(b.Dir, "rev-parse", "HEAD")
+	return strings.TrimSpace(out), err
+}
+
+// Describe returns the abbreviated hash and the subject of the commit.
+func (b *Bisection) Describe(commit string) (string, error) {
+	out, err := git(b.Dir, "log", "-1", "--format=%h %s", commit)
+	return strings.TrimSpace(out), err
+}
+
+var firstBad = regexp.MustCompile(`(?m)^([0-9a-f]{40}) is the first bad commit`)
+
+// Mark the commit checked out with the verdict. Once the first bad commit is
+// found, it is returned and the bisection is done. If only skipped commits
+// are left, a [SkippedError] is returned.
+func (b *Bisection) Mark(v Verdict) (first string, err error) {
+	out, err := git(b.Dir, "bisect", string(v))
+	if strings.Contains(out, "only 'skip'ped commits left to test") {
+		return "", &SkippedError{Output: strings.TrimSpace(out)}
+	}
+	if err != nil {
+		return "", err
+	}
+	if m := firstBad.FindStringSubmatch(out); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
+
+// SkippedError is returned by [Bisection.Mark] when the first bad commit is
+// among commits that were skipped.
+type SkippedError struct {
+	Output string
+}
+
+func (e *SkippedError) Error() string {
+	return "cannot find the first bad commit among skipped ones:\n" + e.Output
+}
+
+// Close ends the bisection and removes its worktree.
+func (b *Bisection) Close() error {
+	git(b.Dir, "bisect", "reset")
+	_, err := git(b.repo, "worktree", "remove", "--force", b.Dir)
+	os.RemoveAll(b.Dir)
+	return err
+}
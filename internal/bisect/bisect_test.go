@@ -0,0 +1,125 @@
+package bisect_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/bisect"
+)
+
+func run(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// makeRepo returns a repository with a commit per state, and the commits.
+func makeRepo(t *testing.T, states []string) (string, []string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	run(t, repo, "init", "-q")
+	var commits []string
+	for i, state := range states {
+		if err := os.WriteFile(filepath.Join(repo, "state"), []byte(fmt.Sprintf("%d %s", i, state)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run(t, repo, "add", "state")
+		run(t, repo, "commit", "-q", "-m", fmt.Sprintf("commit %d", i))
+		commits = append(commits, run(t, repo, "rev-parse", "HEAD"))
+	}
+	return repo, commits
+}
+
+var bisectTests = []struct {
+	summary string
+	states  []string
+	first   int
+	skipped bool
+}{{
+	summary: "Regression in the middle",
+	states:  []string{"ok", "ok", "ok", "ok", "broken", "broken", "broken", "broken"},
+	first:   4,
+}, {
+	summary: "Regression in the bad commit",
+	states:  []string{"ok", "ok", "ok", "broken"},
+	first:   3,
+}, {
+	summary: "Untestable commits are skipped",
+	states:  []string{"ok", "untestable", "ok", "broken", "broken"},
+	first:   3,
+}, {
+	summary: "First bad commit among skipped ones",
+	states:  []string{"ok", "untestable", "broken"},
+	skipped: true,
+}}
+
+func TestBisect(t *testing.T) {
+	for _, test := range bisectTests {
+		t.Logf("Summary: %s", test.summary)
+		repo, commits := makeRepo(t, test.states)
+		b, err := bisect.Start(repo, commits[0], commits[len(commits)-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var first string
+		for first == "" {
+			data, rerr := os.ReadFile(filepath.Join(b.Dir, "state"))
+			if rerr != nil {
+				t.Fatal(rerr)
+			}
+			v := bisect.Good
+			_, state, _ := strings.Cut(string(data), " ")
+			switch state {
+			case "broken":
+				v = bisect.Bad
+			case "untestable":
+				v = bisect.Skip
+			}
+			first, err = b.Mark(v)
+			if err != nil {
+				break
+			}
+		}
+		var skipped *bisect.SkippedError
+		if test.skipped {
+			if !errors.As(err, &skipped) {
+				t.Fatalf("have error %v, want only skipped commits left", err)
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		} else if first != commits[test.first] {
+			t.Fatalf("have first bad commit %s, want %s", first, commits[test.first])
+		}
+		if err := b.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(b.Dir); !os.IsNotExist(err) {
+			t.Fatalf("worktree %s not removed", b.Dir)
+		}
+		if head := run(t, repo, "rev-parse", "HEAD"); head != commits[len(commits)-1] {
+			t.Fatalf("have repository at %s, want it untouched", head)
+		}
+	}
+}
+
+func TestStartInvalidRef(t *testing.T) {
+	repo, commits := makeRepo(t, []string{"ok"})
+	_, err := bisect.Start(repo, "nope", commits[0])
+	if err == nil || err.Error() != `invalid ref "nope"` {
+		t.Fatalf("have error %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Package buildfile generates the build files that install a set of slices,
+// such as a multi-stage Dockerfile or the parts of a rockcraft.yaml.
+package buildfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Header starts every generated file so that it is not edited by hand.
+const Header = "# Generated by sdf, do not edit. Regenerate with \"sdf generate\"."
+
+type Options struct {
+	// Full names of the slices to install, e.g. libc6_libs.
+	Slices []string
+	// Chisel release to cut the slices from, e.g. ubuntu-24.04.
+	Release string
+	// Release is a directory in the build context rather than a release
+	// name or URL known to chisel.
+	LocalRelease bool
+	// Ubuntu version of the build stage, e.g. 24.04.
+	Ubuntu string
+	// Chisel version to download in the build stage, e.g. v1.1.0.
+	ChiselVersion string
+	// SHA-256 digests of the chisel tarballs of the version, in hex, by the
+	// docker target architecture they are for, e.g. amd64. The build fails
+	// on the other architectures.
+	ChiselSHA256 map[string]string
+	// Base URL of the chisel releases, DefaultChiselURL if empty.
+	ChiselURL string
+	// Name of the rockcraft part.
+	Part string
+}
+
+// The releases of chisel on GitHub.
+const DefaultChiselURL = "https://github.com/canonical/chisel/releases/download"
+
+// ChiselTarballURL returns the URL of the chisel tarball of the version for
+// the docker target architecture, under the base URL of the releases.
+func ChiselTarballURL(base, version, arch string) string {
+	if base == "" {
+		base = DefaultChiselURL
+	}
+	return fmt.Sprintf("%s/%s/chisel_%s_linux_%s.tar.gz", strings.TrimSuffix(base, "/"), version, version, arch)
+}
+
+// ChiselSHA256 downloads the chisel tarball of the version for the docker
+// target architecture and returns its SHA-256 digest, in hex.
+func ChiselSHA256(client *http.Client, base, version, arch string) (string, error) {
+	url := ChiselTarballURL(base, version, arch)
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot download %s: %s", url, resp.Status)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", fmt.Errorf("cannot download %s: %w", url, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var sha256Exp = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// sortedSlices returns the slices sorted and without duplicates, so that the
+// generated files do not change with the order of the slice definitions.
+func sortedSlices(slices []string) []string {
+	seen := make(map[string]bool)
+	var sorted []string
+	for _, s := range slices {
+		if !seen[s] {
+			seen[s] = true
+			sorted = append(sorted, s)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// Dockerfile returns a Dockerfile that cuts the slices in an Ubuntu stage
+// and copies the root into a scratch stage.
+func Dockerfile(opts *Options) ([]byte, error) {
+	if opts.Ubuntu == "" || opts.ChiselVersion == "" || opts.Release == "" {
+		return nil, fmt.Errorf("ubuntu version, chisel version and release are required")
+	}
+	if len(opts.ChiselSHA256) == 0 {
+		return nil, fmt.Errorf("SHA-256 digests of chisel are required")
+	}
+	var arches []string
+	for arch, digest := range opts.ChiselSHA256 {
+		if !sha256Exp.MatchString(digest) {
+			return nil, fmt.Errorf("invalid SHA-256 digest of chisel for %s: %q", arch, digest)
+		}
+		arches = append(arches, arch)
+	}
+	sort.Strings(arches)
+	slices := sortedSlices(opts.Slices)
+	if len(slices) == 0 {
+		return nil, fmt.Errorf("no slices to install")
+	}
+	var b strings.Builder
+	fmt.Fprintln(&b, Header)
+	fmt.Fprintf(&b, "FROM ubuntu:%s AS builder\n", opts.Ubuntu)
+	fmt.Fprintln(&b, "ARG TARGETARCH")
+	fmt.Fprintln(&b, "RUN apt-get update \\")
+	fmt.Fprintln(&b, "    && apt-get install -y --no-install-recommends ca-certificates curl \\")
+	fmt.Fprintln(&b, "    && rm -rf /var/lib/apt/lists/*")
+	// The tarball is checked against the digest of its architecture
+	// before chisel is extracted from it.
+	fmt.Fprintln(&b, "RUN case \"${TARGETARCH}\" in \\")
+	for _, arch := range arches {
+		fmt.Fprintf(&b, "        %s) sha256=%s ;; \\\n", arch, opts.ChiselSHA256[arch])
+	}
+	fmt.Fprintln(&b, "        *) echo \"no SHA-256 digest of chisel for ${TARGETARCH}\" >&2; exit 1 ;; \\")
+	fmt.Fprintln(&b, "    esac \\")
+	fmt.Fprintf(&b, "    && curl -fsSL -o /tmp/chisel.tar.gz %s \\\n", ChiselTarballURL(opts.ChiselURL, opts.ChiselVersion, "${TARGETARCH}"))
+	fmt.Fprintln(&b, "    && echo \"${sha256}  /tmp/chisel.tar.gz\" | sha256sum -c - \\")
+	fmt.Fprintln(&b, "    && tar -xzf /tmp/chisel.tar.gz -C /usr/bin chisel \\")
+	fmt.Fprintln(&b, "    && rm /tmp/chisel.tar.gz")
+	release := opts.Release
+	if opts.LocalRelease {
+		fmt.Fprintf(&b, "COPY %s /release\n", opts.Release)
+		release = "/release"
+	}
+	fmt.Fprintf(&b, "RUN mkdir /rootfs \\\n    && chisel cut --release %s --root /rootfs", release)
+	for _, s := range slices {
+		fmt.Fprintf(&b, " \\\n        %s", s)
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "FROM scratch")
+	fmt.Fprintln(&b, "COPY --from=builder /rootfs /")
+	return []byte(b.String()), nil
+}
+
+type rockcraftPart struct {
+	Plugin        string   `yaml:"plugin"`
+	StagePackages []string `yaml:"stage-packages"`
+}
+
+// Rockcraft returns the parts stanza of a rockcraft.yaml that stages the
+// slices.
+func Rockcraft(opts *Options) ([]byte, error) {
+	slices := sortedSlices(opts.Slices)
+	if len(slices) == 0 {
+		return nil, fmt.Errorf("no slices to install")
+	}
+	part := opts.Part
+	if part == "" {
+		part = "slices"
+	}
+	doc := map[string]map[string]rockcraftPart{
+		"parts": {
+			part: {Plugin: "nil", StagePackages: slices},
+		},
+	}
+	var b bytes.Buffer
+	b.WriteString(Header + "\n")
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
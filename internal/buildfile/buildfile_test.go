@@ -0,0 +1,177 @@
+package buildfile_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/buildfile"
+)
+
+var dockerfileTests = []struct {
+	summary string
+	opts    *buildfile.Options
+	want    string
+	err     string
+}{{
+	summary: "Release known to chisel",
+	opts: &buildfile.Options{
+		Slices:        []string{"pkg2_bins", "pkg1_libs", "pkg2_bins"},
+		Release:       "ubuntu-24.04",
+		Ubuntu:        "24.04",
+		ChiselVersion: "v1.1.0",
+		ChiselSHA256:  map[string]string{"arm64": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", "amd64": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+	},
+	want: buildfile.Header + `
+FROM ubuntu:24.04 AS builder
+ARG TARGETARCH
+RUN apt-get update \
+    && apt-get install -y --no-install-recommends ca-certificates curl \
+    && rm -rf /var/lib/apt/lists/*
+RUN case "${TARGETARCH}" in \
+        amd64) sha256=aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa ;; \
+        arm64) sha256=bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb ;; \
+        *) echo "no SHA-256 digest of chisel for ${TARGETARCH}" >&2; exit 1 ;; \
+    esac \
+    && curl -fsSL -o /tmp/chisel.tar.gz https://github.com/canonical/chisel/releases/download/v1.1.0/chisel_v1.1.0_linux_${TARGETARCH}.tar.gz \
+    && echo "${sha256}  /tmp/chisel.tar.gz" | sha256sum -c - \
+    && tar -xzf /tmp/chisel.tar.gz -C /usr/bin chisel \
+    && rm /tmp/chisel.tar.gz
+RUN mkdir /rootfs \
+    && chisel cut --release ubuntu-24.04 --root /rootfs \
+        pkg1_libs \
+        pkg2_bins
+
+FROM scratch
+COPY --from=builder /rootfs /
+`,
+}, {
+	summary: "Release in the build context",
+	opts: &buildfile.Options{
+		Slices:        []string{"pkg1_libs"},
+		Release:       "chisel-releases",
+		LocalRelease:  true,
+		Ubuntu:        "22.04",
+		ChiselVersion: "v1.0.0",
+		ChiselSHA256:  map[string]string{"amd64": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+	},
+	want: buildfile.Header + `
+FROM ubuntu:22.04 AS builder
+ARG TARGETARCH
+RUN apt-get update \
+    && apt-get install -y --no-install-recommends ca-certificates curl \
+    && rm -rf /var/lib/apt/lists/*
+RUN case "${TARGETARCH}" in \
+        amd64) sha256=aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa ;; \
+        *) echo "no SHA-256 digest of chisel for ${TARGETARCH}" >&2; exit 1 ;; \
+    esac \
+    && curl -fsSL -o /tmp/chisel.tar.gz https://github.com/canonical/chisel/releases/download/v1.0.0/chisel_v1.0.0_linux_${TARGETARCH}.tar.gz \
+    && echo "${sha256}  /tmp/chisel.tar.gz" | sha256sum -c - \
+    && tar -xzf /tmp/chisel.tar.gz -C /usr/bin chisel \
+    && rm /tmp/chisel.tar.gz
+COPY chisel-releases /release
+RUN mkdir /rootfs \
+    && chisel cut --release /release --root /rootfs \
+        pkg1_libs
+
+FROM scratch
+COPY --from=builder /rootfs /
+`,
+}, {
+	summary: "No slices",
+	opts: &buildfile.Options{
+		Release:       "ubuntu-24.04",
+		Ubuntu:        "24.04",
+		ChiselVersion: "v1.1.0",
+		ChiselSHA256:  map[string]string{"amd64": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+	},
+	err: "no slices to install",
+}, {
+	summary: "No digests of chisel",
+	opts: &buildfile.Options{
+		Slices:        []string{"pkg1_libs"},
+		Release:       "ubuntu-24.04",
+		Ubuntu:        "24.04",
+		ChiselVersion: "v1.1.0",
+	},
+	err: "SHA-256 digests of chisel are required",
+}, {
+	summary: "Invalid digest of chisel",
+	opts: &buildfile.Options{
+		Slices:        []string{"pkg1_libs"},
+		Release:       "ubuntu-24.04",
+		Ubuntu:        "24.04",
+		ChiselVersion: "v1.1.0",
+		ChiselSHA256:  map[string]string{"amd64": "abc"},
+	},
+	err: `invalid SHA-256 digest of chisel for amd64: "abc"`,
+}}
+
+func TestDockerfile(t *testing.T) {
+	for _, tc := range dockerfileTests {
+		t.Logf("Summary: %s", tc.summary)
+		data, err := buildfile.Dockerfile(tc.opts)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("have error %v, want %q", err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tc.want {
+			t.Fatalf("have:\n%s\nwant:\n%s", data, tc.want)
+		}
+	}
+}
+
+func TestRockcraft(t *testing.T) {
+	data, err := buildfile.Rockcraft(&buildfile.Options{
+		Slices: []string{"pkg2_bins", "pkg1_libs"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := buildfile.Header + `
+parts:
+  slices:
+    plugin: nil
+    stage-packages:
+      - pkg1_libs
+      - pkg2_bins
+`
+	if string(data) != want {
+		t.Fatalf("have:\n%s\nwant:\n%s", data, want)
+	}
+	if _, err := buildfile.Rockcraft(&buildfile.Options{}); err == nil || !strings.Contains(err.Error(), "no slices") {
+		t.Fatalf("have error %v, want no slices error", err)
+	}
+}
+
+func TestChiselSHA256(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.1.0/chisel_v1.1.0_linux_amd64.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("chisel"))
+	}))
+	defer srv.Close()
+
+	digest, err := buildfile.ChiselSHA256(srv.Client(), srv.URL, "v1.1.0", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("chisel"))
+	if want := hex.EncodeToString(sum[:]); digest != want {
+		t.Fatalf("have %s, want %s", digest, want)
+	}
+	_, err = buildfile.ChiselSHA256(srv.Client(), srv.URL, "v1.1.0", "s390x")
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Fatalf("have error %v, want 404 Not Found", err)
+	}
+}
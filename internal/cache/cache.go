@@ -0,0 +1,290 @@
+// Package cache finds what sdf and the chisel runs it starts leave on disk:
+// the debs and archive indexes chisel downloads, the chisel binaries of
+// other versions, the parsed slice definition files and the temporary
+// directories of interrupted runs.
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	// Debs in the chisel cache.
+	Deb Kind = "deb"
+	// Archive indexes, like InRelease and Packages files, in the chisel
+	// cache.
+	Index Kind = "index"
+	// Chisel binaries kept by version, see chiselbin.
+	Binary Kind = "chisel"
+	// Slices parsed from slice definition files, see chisel.ParseCache.
+	Parse Kind = "parse"
+	// Directories of sdf runs in the temporary directory, like roots and
+	// the chisel caches of the workers, left behind when interrupted.
+	Temp Kind = "temp"
+)
+
+// Kinds are all the kinds of entries.
+var Kinds = []Kind{Deb, Index, Binary, Parse, Temp}
+
+// TempPrefix starts the names of all the temporary directories of sdf.
+const TempPrefix = "sdf-"
+
+// An Entry is a file or directory that can be removed.
+type Entry struct {
+	Kind Kind      `json:"kind"`
+	Path string    `json:"path"`
+	Size int64     `json:"size"`
+	Used time.Time `json:"used"`
+}
+
+// Dirs are the directories to look for entries in.
+type Dirs struct {
+	// Chisel cache, with the blobs under "sha256".
+	Chisel string
+	// Directory of the chisel binaries, by version.
+	Binaries string
+	// Parse cache of the slice definition files.
+	Parse string
+	// Temporary directory.
+	Temp string
+}
+
+// DefaultDirs returns the directories chisel and sdf use by default.
+func DefaultDirs() (*Dirs, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Dirs{
+		Chisel:   filepath.Join(dir, "chisel"),
+		Binaries: filepath.Join(dir, "sdf", "chisel"),
+		Parse:    filepath.Join(dir, "sdf", "parse"),
+		Temp:     os.TempDir(),
+	}, nil
+}
+
+// arMagic starts every deb.
+var arMagic = []byte("!<arch>\n")
+
+// Find returns the entries in the directories, sorted by kind and path.
+// Missing directories have no entries.
+func Find(dirs *Dirs) ([]*Entry, error) {
+	var entries []*Entry
+	blobs, err := readDir(filepath.Join(dirs.Chisel, "sha256"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range blobs {
+		e, err := stat(path)
+		if err != nil {
+			return nil, err
+		}
+		e.Kind = Index
+		if isDeb(path) {
+			e.Kind = Deb
+		}
+		entries = append(entries, e)
+	}
+	versions, err := readDir(dirs.Binaries)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range versions {
+		e, err := stat(path)
+		if err != nil {
+			return nil, err
+		}
+		e.Kind = Binary
+		entries = append(entries, e)
+	}
+	parsed, err := readDir(dirs.Parse)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range parsed {
+		e, err := stat(path)
+		if err != nil {
+			return nil, err
+		}
+		e.Kind = Parse
+		entries = append(entries, e)
+	}
+	temps, err := readDir(dirs.Temp)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range temps {
+		if !strings.HasPrefix(filepath.Base(path), TempPrefix) {
+			continue
+		}
+		if info, err := os.Lstat(path); err != nil || !info.IsDir() {
+			continue
+		}
+		e, err := stat(path)
+		if err != nil {
+			return nil, err
+		}
+		e.Kind = Temp
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return kindIndex(entries[i].Kind) < kindIndex(entries[j].Kind)
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+func kindIndex(k Kind) int {
+	for i, kind := range Kinds {
+		if kind == k {
+			return i
+		}
+	}
+	return len(Kinds)
+}
+
+// readDir returns the paths of the entries in the directory, or none if it
+// does not exist.
+func readDir(dir string) ([]string, error) {
+	des, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, de := range des {
+		paths = append(paths, filepath.Join(dir, de.Name()))
+	}
+	return paths, nil
+}
+
+// stat returns the entry of the file, or of the directory with the total
+// size of its files and the time of its most recently modified one, so that
+// directories still in use do not look old.
+func stat(path string) (*Entry, error) {
+	e := &Entry{Path: path}
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may vanish while an sdf run is cleaning up.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			e.Size += info.Size()
+		}
+		if info.ModTime().After(e.Used) {
+			e.Used = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func isDeb(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, arMagic)
+}
+
+// Filter returns the entries of the kinds, all of them if none are given,
+// last used before the time, if not zero.
+func Filter(entries []*Entry, kinds []Kind, before time.Time) []*Entry {
+	var filtered []*Entry
+	for _, e := range entries {
+		if len(kinds) > 0 && !hasKind(kinds, e.Kind) {
+			continue
+		}
+		if !before.IsZero() && !e.Used.Before(before) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered
+}
+
+func hasKind(kinds []Kind, k Kind) bool {
+	for _, kind := range kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove removes the entries and returns the space freed.
+func Remove(entries []*Entry) (int64, error) {
+	var freed int64
+	for _, e := range entries {
+		if err := os.RemoveAll(e.Path); err != nil {
+			return freed, err
+		}
+		freed += e.Size
+	}
+	return freed, nil
+}
+
+// ParseAge parses a duration like time.ParseDuration, also accepting days
+// and weeks, as in "7d" or "2w".
+func ParseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if v, err := strconv.ParseFloat(n, 64); err == nil && v >= 0 {
+				return time.Duration(v * float64(unit)), nil
+			}
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// FormatSize returns the size in bytes in a human readable form.
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,215 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/cache"
+)
+
+var now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// cacheFiles are the files of the test directories, with their age.
+var cacheFiles = []struct {
+	path string
+	data string
+	age  time.Duration
+}{
+	{"chisel/sha256/aaaa", "!<arch>\ndebian-binary", 48 * time.Hour},
+	{"chisel/sha256/bbbb", "Origin: Ubuntu\n", time.Hour},
+	{"chisel/sha256/cccc", "!<ar", 10 * 24 * time.Hour},
+	{"binaries/v1.0.0/chisel", "binary", 30 * 24 * time.Hour},
+	{"parse/ffff.json", "[]", 2 * time.Hour},
+	{"tmp/sdf-cache-123/chisel/sha256/dddd", "!<arch>\n", 72 * time.Hour},
+	{"tmp/sdf-cache-123/chisel/sha256/eeee", "index", time.Minute},
+	{"tmp/sdf-fuzz-456/usr/bin/hello", "hello", 24 * time.Hour},
+	{"tmp/other/file", "not ours", 24 * time.Hour},
+	{"tmp/sdf-file", "not a directory", 24 * time.Hour},
+}
+
+func writeCache(t *testing.T) (string, *cache.Dirs) {
+	dir := t.TempDir()
+	for _, f := range cacheFiles {
+		path := filepath.Join(dir, f.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-f.age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Directories are older than everything in them.
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			err = os.Chtimes(path, now.AddDate(-1, 0, 0), now.AddDate(-1, 0, 0))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, &cache.Dirs{
+		Chisel:   filepath.Join(dir, "chisel"),
+		Binaries: filepath.Join(dir, "binaries"),
+		Parse:    filepath.Join(dir, "parse"),
+		Temp:     filepath.Join(dir, "tmp"),
+	}
+}
+
+type entry struct {
+	Kind cache.Kind
+	Path string
+	Size int64
+	Age  time.Duration
+}
+
+func entries(dir string, es []*cache.Entry) []entry {
+	var result []entry
+	for _, e := range es {
+		path, _ := filepath.Rel(dir, e.Path)
+		result = append(result, entry{e.Kind, path, e.Size, now.Sub(e.Used)})
+	}
+	return result
+}
+
+func TestFind(t *testing.T) {
+	dir, dirs := writeCache(t)
+	found, err := cache.Find(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []entry{
+		{cache.Deb, "chisel/sha256/aaaa", 21, 48 * time.Hour},
+		{cache.Index, "chisel/sha256/bbbb", 15, time.Hour},
+		{cache.Index, "chisel/sha256/cccc", 4, 10 * 24 * time.Hour},
+		{cache.Binary, "binaries/v1.0.0", 6, 30 * 24 * time.Hour},
+		{cache.Parse, "parse/ffff.json", 2, 2 * time.Hour},
+		{cache.Temp, "tmp/sdf-cache-123", 13, time.Minute},
+		{cache.Temp, "tmp/sdf-fuzz-456", 5, 24 * time.Hour},
+	}
+	if have := entries(dir, found); !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+}
+
+func TestFindMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	found, err := cache.Find(&cache.Dirs{Chisel: dir, Binaries: dir, Temp: dir})
+	if err != nil || len(found) != 0 {
+		t.Fatalf("have %v, %v, want no entries", found, err)
+	}
+}
+
+var filterTests = []struct {
+	summary string
+	kinds   []cache.Kind
+	age     time.Duration
+	paths   []string
+}{{
+	summary: "All",
+	paths:   []string{"chisel/sha256/aaaa", "chisel/sha256/bbbb", "chisel/sha256/cccc", "binaries/v1.0.0", "parse/ffff.json", "tmp/sdf-cache-123", "tmp/sdf-fuzz-456"},
+}, {
+	summary: "Kinds",
+	kinds:   []cache.Kind{cache.Deb, cache.Temp},
+	paths:   []string{"chisel/sha256/aaaa", "tmp/sdf-cache-123", "tmp/sdf-fuzz-456"},
+}, {
+	summary: "Older than",
+	age:     7 * 24 * time.Hour,
+	paths:   []string{"chisel/sha256/cccc", "binaries/v1.0.0"},
+}, {
+	summary: "Temporary directories in use are recent",
+	kinds:   []cache.Kind{cache.Temp},
+	age:     time.Hour,
+	paths:   []string{"tmp/sdf-fuzz-456"},
+}}
+
+func TestFilter(t *testing.T) {
+	dir, dirs := writeCache(t)
+	found, err := cache.Find(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range filterTests {
+		t.Logf("Summary: %s", test.summary)
+		var before time.Time
+		if test.age != 0 {
+			before = now.Add(-test.age)
+		}
+		var paths []string
+		for _, e := range entries(dir, cache.Filter(found, test.kinds, before)) {
+			paths = append(paths, e.Path)
+		}
+		if !reflect.DeepEqual(paths, test.paths) {
+			t.Fatalf("have %v, want %v", paths, test.paths)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	dir, dirs := writeCache(t)
+	found, err := cache.Find(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	freed, err := cache.Remove(cache.Filter(found, []cache.Kind{cache.Temp}, time.Time{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freed != 18 {
+		t.Fatalf("have %d bytes freed, want 18", freed)
+	}
+	for _, path := range []string{"tmp/sdf-cache-123", "tmp/sdf-fuzz-456"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); !os.IsNotExist(err) {
+			t.Fatalf("%s not removed: %v", path, err)
+		}
+	}
+	for _, path := range []string{"tmp/other/file", "tmp/sdf-file", "chisel/sha256/aaaa"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Fatalf("%s removed: %v", path, err)
+		}
+	}
+}
+
+var parseAgeTests = []struct {
+	age string
+	d   time.Duration
+	err string
+}{
+	{age: "90m", d: 90 * time.Minute},
+	{age: "7d", d: 7 * 24 * time.Hour},
+	{age: "1.5d", d: 36 * time.Hour},
+	{age: "2w", d: 14 * 24 * time.Hour},
+	{age: "-1h", err: `invalid age "-1h"`},
+	{age: "xd", err: `invalid age "xd"`},
+	{age: "week", err: `invalid age "week"`},
+}
+
+func TestParseAge(t *testing.T) {
+	for _, test := range parseAgeTests {
+		d, err := cache.ParseAge(test.age)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("%s: have error %v, want %q", test.age, err, test.err)
+			}
+			continue
+		}
+		if err != nil || d != test.d {
+			t.Fatalf("%s: have %v, %v, want %v", test.age, d, err, test.d)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 3 << 30: "3.0 GiB"} {
+		if have := cache.FormatSize(n); have != want {
+			t.Fatalf("%d: have %q, want %q", n, have, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package chisel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/debversion"
+)
+
+// The oldest chisel release supporting each of the [Formats].
+var FormatSince = map[string]string{
+	"v1": "v1.0.0",
+	"v2": "v1.1.0",
+	"v3": "v1.2.0",
+}
+
+// ParseVersion returns the version in the output of "chisel version", with
+// a leading "v".
+func ParseVersion(out string) (string, error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty chisel version")
+	}
+	v := "v" + strings.TrimPrefix(fields[0], "v")
+	if len(v) < 2 || v[1] < '0' || v[1] > '9' {
+		return "", fmt.Errorf("invalid chisel version %q", strings.TrimSpace(out))
+	}
+	return v, nil
+}
+
+// SupportedFormats returns the formats the chisel version supports, oldest
+// first.
+func SupportedFormats(version string) []string {
+	var formats []string
+	for _, f := range Formats {
+		if compareVersions(version, FormatSince[f]) >= 0 {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// CheckFormat returns an error if the chisel version does not support the
+// release format.
+func CheckFormat(format, version string) error {
+	since, ok := FormatSince[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if compareVersions(version, since) < 0 {
+		return fmt.Errorf("format %s requires chisel %s or later, have %s", format, since, version)
+	}
+	return nil
+}
+
+// compareVersions compares chisel versions. Development builds, like
+// v1.1.0-3-gabcdef, count as the release they are based on.
+func compareVersions(a, b string) int {
+	base := func(v string) string {
+		v = strings.TrimPrefix(v, "v")
+		v, _, _ = strings.Cut(v, "-")
+		v, _, _ = strings.Cut(v, "+")
+		return v
+	}
+	return debversion.Compare(base(a), base(b))
+}
@@ -0,0 +1,70 @@
+package chisel_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+var parseVersionTests = []struct {
+	out     string
+	version string
+	err     string
+}{
+	{out: "v1.1.0\n", version: "v1.1.0"},
+	{out: "1.2.0", version: "v1.2.0"},
+	{out: "v1.1.0-3-gabcdef (dirty)", version: "v1.1.0-3-gabcdef"},
+	{out: "", err: "empty chisel version"},
+	{out: "chisel version 1.0", err: `invalid chisel version "chisel version 1.0"`},
+}
+
+func TestParseVersion(t *testing.T) {
+	for _, test := range parseVersionTests {
+		version, err := chisel.ParseVersion(test.out)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("%q: have error %v, want %q", test.out, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != test.version {
+			t.Fatalf("%q: have %q, want %q", test.out, version, test.version)
+		}
+	}
+}
+
+var checkFormatTests = []struct {
+	format  string
+	version string
+	err     string
+}{
+	{format: "v1", version: "v1.0.0"},
+	{format: "v2", version: "v1.0.0", err: "format v2 requires chisel v1.1.0 or later, have v1.0.0"},
+	{format: "v2", version: "v1.1.0"},
+	{format: "v3", version: "v1.1.0-12-gabcdef", err: "format v3 requires chisel v1.2.0 or later, have v1.1.0-12-gabcdef"},
+	{format: "v3", version: "v1.2.0-rc1"},
+	{format: "v3", version: "v1.10.0"},
+	{format: "v9", version: "v1.2.0", err: `unknown format "v9"`},
+}
+
+func TestCheckFormat(t *testing.T) {
+	for _, test := range checkFormatTests {
+		err := chisel.CheckFormat(test.format, test.version)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Fatalf("%s with %s: have error %v, want %q", test.format, test.version, err, test.err)
+		}
+	}
+}
+
+func TestSupportedFormats(t *testing.T) {
+	if have, want := chisel.SupportedFormats("v1.1.5"), []string{"v1", "v2"}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if have := chisel.SupportedFormats("v0.9.0"); have != nil {
+		t.Fatalf("have %v, want none", have)
+	}
+}
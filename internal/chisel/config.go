@@ -1,6 +1,7 @@
 package chisel
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 
@@ -10,31 +11,49 @@ import (
 // chisel.yaml, for a lack of a better name, is the config for Chisel.
 // The "v2-archives" field will be merged into "archives" after parsing.
 type Config struct {
-	Archives   map[string]*Archive `yaml:"archives"`
-	V2Archives map[string]*Archive `yaml:"v2-archives"`
+	Format     string                `yaml:"format"`
+	Archives   map[string]*Archive   `yaml:"archives"`
+	V2Archives map[string]*Archive   `yaml:"v2-archives"`
+	PublicKeys map[string]*PublicKey `yaml:"public-keys"`
 	// TODO add remaining fields when necessary.
 }
 
 type Archive struct {
+	Version    string   `yaml:"version"`
 	Suites     []string `yaml:"suites"`
 	Components []string `yaml:"components"`
+	// Names of the keys in "public-keys" the archive is signed with.
+	PublicKeys []string `yaml:"public-keys"`
+	// Ubuntu Pro archive, like "fips" or "esm-apps", if not empty.
+	Pro string `yaml:"pro"`
 	// TODO add remaining fields when necessary.
 }
 
+type PublicKey struct {
+	ID    string `yaml:"id"`
+	Armor string `yaml:"armor"`
+}
+
 // Parse the chisel.yaml file given it's path.
 func ParseConfig(path string) (*Config, error) {
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	return DecodeConfig(data)
+}
 
+// Decode the content of a chisel.yaml file.
+func DecodeConfig(data []byte) (*Config, error) {
 	cfg := &Config{}
-	d := yaml.NewDecoder(f)
+	d := yaml.NewDecoder(bytes.NewReader(data))
 	if err := d.Decode(cfg); err != nil {
 		return nil, err
 	}
 
+	if cfg.Archives == nil {
+		cfg.Archives = make(map[string]*Archive)
+	}
 	for k, v := range cfg.V2Archives {
 		cfg.Archives[k] = v
 		delete(cfg.V2Archives, k)
@@ -51,6 +70,15 @@ func ParseConfig(path string) (*Config, error) {
 		if len(a.Components) == 0 {
 			return nil, fmt.Errorf("archive %s has no 'components'", name)
 		}
+		for _, key := range a.PublicKeys {
+			if _, ok := cfg.PublicKeys[key]; !ok {
+				return nil, fmt.Errorf("archive %s refers to undefined public key %s", name, key)
+			}
+		}
 	}
 	return cfg, nil
 }
+
+// Arches are the package architectures chisel installs slices for, those of
+// the Ubuntu archives.
+var Arches = []string{"amd64", "arm64", "armhf", "i386", "ppc64el", "riscv64", "s390x"}
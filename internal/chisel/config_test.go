@@ -11,12 +11,20 @@ import (
 const sampleChiselYaml = `
 archives:
   foo:
+    version: 1.0
     suites: [a, b, c]
     components: [p, q, r]
+    public-keys: [foo-key]
 v2-archives:
   bar:
     suites: [x]
     components: [y, z]
+public-keys:
+  foo-key:
+    id: 871920D1991BC93C
+    armor: |
+      -----BEGIN PGP PUBLIC KEY BLOCK-----
+      -----END PGP PUBLIC KEY BLOCK-----
 `
 
 var chiselYamlTests = []struct {
@@ -29,14 +37,22 @@ var chiselYamlTests = []struct {
 	config: &chisel.Config{
 		Archives: map[string]*chisel.Archive{
 			"foo": {
+				Version:    "1.0",
 				Suites:     []string{"a", "b", "c"},
 				Components: []string{"p", "q", "r"},
+				PublicKeys: []string{"foo-key"},
 			},
 			"bar": {
 				Suites:     []string{"x"},
 				Components: []string{"y", "z"},
 			},
 		},
+		PublicKeys: map[string]*chisel.PublicKey{
+			"foo-key": {
+				ID:    "871920D1991BC93C",
+				Armor: "-----BEGIN PGP PUBLIC KEY BLOCK-----\n-----END PGP PUBLIC KEY BLOCK-----\n",
+			},
+		},
 	},
 }}
 
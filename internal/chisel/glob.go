@@ -0,0 +1,40 @@
+package chisel
+
+import "strings"
+
+// MatchPath reports whether the path matches the content path of a slice,
+// which may be a glob: "?" matches any character but "/", "*" any sequence
+// of characters without "/" and "**" any sequence of characters.
+func MatchPath(pattern, path string) bool {
+	for len(pattern) > 0 {
+		switch {
+		case strings.HasPrefix(pattern, "**"):
+			for i := 0; i <= len(path); i++ {
+				if MatchPath(pattern[2:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		case pattern[0] == '*':
+			for i := 0; i <= len(path); i++ {
+				if MatchPath(pattern[1:], path[i:]) {
+					return true
+				}
+				if i < len(path) && path[i] == '/' {
+					break
+				}
+			}
+			return false
+		case len(path) == 0:
+			return false
+		case pattern[0] == '?':
+			if path[0] == '/' {
+				return false
+			}
+		case pattern[0] != path[0]:
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
@@ -0,0 +1,34 @@
+package chisel_test
+
+import (
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+var matchPathTests = []struct {
+	pattern string
+	path    string
+	match   bool
+}{
+	{"/usr/bin/hello", "/usr/bin/hello", true},
+	{"/usr/bin/hello", "/usr/bin/hell", false},
+	{"/usr/bin/hell?", "/usr/bin/hello", true},
+	{"/usr/bin?hello", "/usr/bin/hello", false},
+	{"/usr/lib/*/libc.so.*", "/usr/lib/x86_64-linux-gnu/libc.so.6", true},
+	{"/usr/lib/*/libc.so.*", "/usr/lib/x86_64-linux-gnu/sub/libc.so.6", false},
+	{"/usr/lib/*", "/usr/lib/", true},
+	{"/usr/share/**", "/usr/share/doc/hello/copyright", true},
+	{"/usr/**/copyright", "/usr/share/doc/hello/copyright", true},
+	{"/usr/**/copyright", "/usr/copyright.d", false},
+	{"/etc/**/*.conf", "/etc/a/b/c.conf", true},
+	{"/etc/*.conf", "/etc/a/c.conf", false},
+}
+
+func TestMatchPath(t *testing.T) {
+	for _, test := range matchPathTests {
+		if have := chisel.MatchPath(test.pattern, test.path); have != test.match {
+			t.Errorf("MatchPath(%q, %q): have %v, want %v", test.pattern, test.path, have, test.match)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package chisel
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The chisel.yaml formats known to this tool, oldest first.
+var Formats = []string{"v1", "v2", "v3"}
+
+// A Migration upgrades a release from one format to the next one. The
+// changes are done in place on the YAML nodes so that comments and ordering
+// survive.
+type Migration struct {
+	From    string
+	To      string
+	Summary string
+
+	// The migrations of the documents report whether they changed them.
+	config func(doc *yaml.Node) (bool, error)
+	slices func(doc *yaml.Node) (bool, error)
+}
+
+var migrations = []*Migration{{
+	From:    "v1",
+	To:      "v2",
+	Summary: `merge "v2-archives" into "archives"`,
+	config:  mergeV2Archives,
+}, {
+	From:    "v2",
+	To:      "v3",
+	Summary: `turn the "essential" lists into maps`,
+	slices:  essentialToMap,
+}}
+
+// Migrate returns the migrations needed, in order, to upgrade a release from
+// one format to another.
+func Migrate(from, to string) ([]*Migration, error) {
+	i := slices.Index(Formats, from)
+	if i < 0 {
+		return nil, fmt.Errorf("unknown format %q", from)
+	}
+	j := slices.Index(Formats, to)
+	if j < 0 {
+		return nil, fmt.Errorf("unknown format %q", to)
+	}
+	if i > j {
+		return nil, fmt.Errorf("cannot downgrade format from %s to %s", from, to)
+	}
+	return migrations[i:j], nil
+}
+
+// MigrateConfig applies the migration to a chisel.yaml document, and
+// reports whether it changed anything but the format.
+func (m *Migration) MigrateConfig(doc *yaml.Node) (bool, error) {
+	root, err := documentMap(doc)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	if m.config != nil {
+		if changed, err = m.config(root); err != nil {
+			return false, err
+		}
+	}
+	if format := mapValue(root, "format"); format != nil && format.Kind == yaml.ScalarNode {
+		format.Value = m.To
+	} else {
+		setMapValue(root, "format", &yaml.Node{Kind: yaml.ScalarNode, Value: m.To})
+		changed = true
+	}
+	return changed, nil
+}
+
+// MigrateSlices applies the migration to a slice definition document, and
+// reports whether it changed it.
+func (m *Migration) MigrateSlices(doc *yaml.Node) (bool, error) {
+	root, err := documentMap(doc)
+	if err != nil {
+		return false, err
+	}
+	if m.slices != nil {
+		return m.slices(root)
+	}
+	return false, nil
+}
+
+// MigrateRelease upgrades the release in dir to the given format. It does not
+// write to disk, instead it returns the new content of the changed files.
+func MigrateRelease(dir, to string) (map[string][]byte, error) {
+	cfgPath := filepath.Join(dir, "chisel.yaml")
+	cfg, err := ParseConfig(cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse chisel.yaml: %w", err)
+	}
+	if cfg.Format == "" {
+		return nil, fmt.Errorf("chisel.yaml has no 'format'")
+	}
+	steps, err := Migrate(cfg.Format, to)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string][]byte)
+	if len(steps) == 0 {
+		return changed, nil
+	}
+
+	// migrate applies the steps to the file and keeps its new content if
+	// they changed it. Only the format is to be set in the config if
+	// nothing else changed, which is done on the original text.
+	migrate := func(path string, apply func(*Migration, *yaml.Node) (bool, error), config bool) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		doc := &yaml.Node{}
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return fmt.Errorf("cannot parse %s: %w", path, err)
+		}
+		var format *yaml.Node
+		if config {
+			if root, err := documentMap(doc); err == nil {
+				if f := mapValue(root, "format"); f != nil {
+					orig := *f
+					format = &orig
+				}
+			}
+		}
+		modified := false
+		for _, m := range steps {
+			c, err := apply(m, doc)
+			if err != nil {
+				return fmt.Errorf("cannot migrate %s to %s: %w", path, m.To, err)
+			}
+			modified = modified || c
+		}
+		if config && !modified {
+			if out, ok := replaceScalar(data, format, to); ok {
+				changed[path] = out
+				return nil
+			}
+			modified = true
+		}
+		if !modified {
+			return nil
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(indentOf(data))
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			changed[path] = buf.Bytes()
+		}
+		return nil
+	}
+
+	if err := migrate(cfgPath, (*Migration).MigrateConfig, true); err != nil {
+		return nil, err
+	}
+	files, err := SliceFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := migrate(f, (*Migration).MigrateSlices, false); err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
+
+func mergeV2Archives(root *yaml.Node) (bool, error) {
+	v2 := mapValue(root, "v2-archives")
+	if v2 == nil {
+		return false, nil
+	}
+	if v2.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("'v2-archives' must be a map")
+	}
+	archives := mapValue(root, "archives")
+	if archives == nil {
+		archives = &yaml.Node{Kind: yaml.MappingNode}
+		setMapValue(root, "archives", archives)
+	}
+	if archives.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("'archives' must be a map")
+	}
+	for i := 0; i < len(v2.Content); i += 2 {
+		setMapValue(archives, v2.Content[i].Value, v2.Content[i+1])
+	}
+	deleteMapValue(root, "v2-archives")
+	return true, nil
+}
+
+func essentialToMap(root *yaml.Node) (bool, error) {
+	changed := false
+	convert := func(n *yaml.Node) {
+		e := mapValue(n, "essential")
+		if e == nil || e.Kind != yaml.SequenceNode {
+			return
+		}
+		// The node of the list becomes the one of the map, keeping its
+		// comments and style.
+		var content []*yaml.Node
+		for _, name := range e.Content {
+			key := *name
+			key.Style = 0
+			key.LineComment = ""
+			content = append(content, &key, &yaml.Node{
+				Kind:        yaml.MappingNode,
+				Style:       yaml.FlowStyle,
+				LineComment: name.LineComment,
+			})
+		}
+		e.Kind, e.Tag, e.Content = yaml.MappingNode, "", content
+		changed = true
+	}
+	convert(root)
+	if slices := mapValue(root, "slices"); slices != nil && slices.Kind == yaml.MappingNode {
+		for i := 1; i < len(slices.Content); i += 2 {
+			if s := slices.Content[i]; s.Kind == yaml.MappingNode {
+				convert(s)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// replaceScalar returns the data with the scalar of the node, as parsed from
+// it, replaced with the plain value, if the node can be found in the data.
+func replaceScalar(data []byte, n *yaml.Node, value string) ([]byte, bool) {
+	if n == nil || n.Kind != yaml.ScalarNode || n.Line < 1 || n.Column < 1 {
+		return nil, false
+	}
+	var token string
+	switch n.Style {
+	case 0:
+		token = n.Value
+	case yaml.DoubleQuotedStyle:
+		token = `"` + n.Value + `"`
+	case yaml.SingleQuotedStyle:
+		token = "'" + n.Value + "'"
+	default:
+		return nil, false
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if n.Line > len(lines) {
+		return nil, false
+	}
+	line := lines[n.Line-1]
+	start := n.Column - 1
+	if start+len(token) > len(line) || string(line[start:start+len(token)]) != token {
+		return nil, false
+	}
+	lines[n.Line-1] = slices.Concat(line[:start], []byte(value), line[start+len(token):])
+	return bytes.Join(lines, nil), true
+}
+
+// indentOf returns the indentation of the first indented line of the YAML
+// data, 2 if none is.
+func indentOf(data []byte) int {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " ")
+		if n := len(line) - len(trimmed); n > 0 && len(trimmed) > 0 && trimmed[0] != '#' {
+			return n
+		}
+	}
+	return 2
+}
+
+func documentMap(doc *yaml.Node) (*yaml.Node, error) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("document is not a map")
+	}
+	return doc.Content[0], nil
+}
+
+func mapValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMapValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+func deleteMapValue(m *yaml.Node, key string) {
+	for i := 0; i < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
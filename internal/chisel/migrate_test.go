@@ -0,0 +1,203 @@
+package chisel_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+var migrateTests = []struct {
+	summary string
+	from    string
+	to      string
+	steps   []string
+	err     string
+}{{
+	summary: "All steps",
+	from:    "v1",
+	to:      "v3",
+	steps:   []string{"v2", "v3"},
+}, {
+	summary: "Single step",
+	from:    "v2",
+	to:      "v3",
+	steps:   []string{"v3"},
+}, {
+	summary: "Nothing to do",
+	from:    "v3",
+	to:      "v3",
+}, {
+	summary: "Downgrade",
+	from:    "v3",
+	to:      "v1",
+	err:     "cannot downgrade format from v3 to v1",
+}, {
+	summary: "Unknown format",
+	from:    "v0",
+	to:      "v1",
+	err:     `unknown format "v0"`,
+}}
+
+func TestMigrate(t *testing.T) {
+	for _, tc := range migrateTests {
+		t.Logf("Summary: %s", tc.summary)
+		steps, err := chisel.Migrate(tc.from, tc.to)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("have error %v, want %q", err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var to []string
+		for _, s := range steps {
+			to = append(to, s.To)
+		}
+		if !reflect.DeepEqual(to, tc.steps) {
+			t.Fatalf("have %v, want %v", to, tc.steps)
+		}
+	}
+}
+
+const migrateConfigV1 = `format: v1
+# Comments must be preserved.
+archives:
+  ubuntu:
+    suites: [noble]
+    components: [main]
+v2-archives:
+  pro:
+    suites: [noble]
+    components: [main]
+`
+
+const migrateConfigV3 = `format: v3
+# Comments must be preserved.
+archives:
+  ubuntu:
+    suites: [noble]
+    components: [main]
+  pro:
+    suites: [noble]
+    components: [main]
+`
+
+const migrateSliceV1 = `package: foo
+essential:
+  - foo_copyright
+slices:
+  bins:
+    essential:
+      - libc6_libs # The libraries.
+      - bar_bins
+  copyright: {}
+`
+
+const migrateSliceV3 = `package: foo
+essential:
+  foo_copyright: {}
+slices:
+  bins:
+    essential:
+      libc6_libs: {} # The libraries.
+      bar_bins: {}
+  copyright: {}
+`
+
+func TestMigrateRelease(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "chisel.yaml")
+	slicePath := filepath.Join(dir, "slices", "foo.yaml")
+	if err := os.MkdirAll(filepath.Dir(slicePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfgPath, []byte(migrateConfigV1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(slicePath, []byte(migrateSliceV1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := chisel.MigrateRelease(dir, "v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("have %d changed files, want 2", len(changed))
+	}
+	if have := string(changed[cfgPath]); have != migrateConfigV3 {
+		t.Fatalf("have chisel.yaml:\n%s\nwant:\n%s", have, migrateConfigV3)
+	}
+	if have := string(changed[slicePath]); have != migrateSliceV3 {
+		t.Fatalf("have slice definition:\n%s\nwant:\n%s", have, migrateSliceV3)
+	}
+
+	// The migrated slices must parse to the same result.
+	if err := os.WriteFile(slicePath, changed[slicePath], 0644); err != nil {
+		t.Fatal(err)
+	}
+	slices, err := chisel.ParseSlices(slicePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*chisel.Slice{{
+		Name:      "foo_bins",
+		Package:   "foo",
+		Essential: []string{"libc6_libs", "bar_bins", "foo_copyright"},
+		File:      slicePath,
+	}, {
+		Name:    "foo_copyright",
+		Package: "foo",
+		File:    slicePath,
+	}}
+	if !reflect.DeepEqual(slices, want) {
+		t.Fatalf("have %v, want %v", slices, want)
+	}
+}
+
+func TestMigrateReleaseFormatOnly(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "chisel.yaml")
+	slicePath := filepath.Join(dir, "slices", "foo.yaml")
+	if err := os.MkdirAll(filepath.Dir(slicePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `format: "v1"   # The format.
+
+archives:
+    ubuntu:
+        suites: [noble]
+        components: [main]
+`
+	if err := os.WriteFile(cfgPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(slicePath, []byte(migrateSliceV1+"\n# The end.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing but the format changes from v1 to v2 without "v2-archives",
+	// so the slice definitions are left alone and chisel.yaml keeps its
+	// formatting.
+	changed, err := chisel.MigrateRelease(dir, "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]byte{
+		cfgPath: []byte(`format: v2   # The format.
+
+archives:
+    ubuntu:
+        suites: [noble]
+        components: [main]
+`),
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("have %q, want %q", changed, want)
+	}
+}
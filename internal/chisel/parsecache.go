@@ -0,0 +1,90 @@
+package chisel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// parseCacheVersion is part of the key of every entry of the parse cache, so
+// that changes to [Slice] or to how slices are decoded leave the old entries
+// unused.
+const parseCacheVersion = "3"
+
+// A ParseCache keeps the slices decoded from slice definition files on disk,
+// keyed by the content of the file, so that reading a release again only
+// decodes the files that changed since. The cache is best effort: failures
+// to use it fall back to decoding the file.
+type ParseCache struct {
+	Dir string
+}
+
+// DefaultParseCacheDir returns sdf/parse in the user cache directory.
+func DefaultParseCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sdf", "parse"), nil
+}
+
+// DecodeSlices is like [DecodeSlices], with the result read from the cache
+// if the same content was decoded before. The errors are not cached.
+func (c *ParseCache) DecodeSlices(data []byte) ([]*Slice, error) {
+	h := sha256.New()
+	h.Write([]byte(parseCacheVersion + "\x00"))
+	h.Write(data)
+	path := filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil))+".json")
+
+	var slices []*Slice
+	if cached, err := os.ReadFile(path); err == nil && json.Unmarshal(cached, &slices) == nil {
+		// Mark the entry as used, for cleaning the cache by age.
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return slices, nil
+	}
+	slices, err := DecodeSlices(data)
+	if err != nil {
+		return nil, err
+	}
+	if encoded, err := json.Marshal(slices); err == nil && os.MkdirAll(c.Dir, 0755) == nil {
+		// Write the entry atomically, as other runs may read it.
+		if f, err := os.CreateTemp(c.Dir, ".tmp-"); err == nil {
+			_, err = f.Write(encoded)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err == nil {
+				err = os.Rename(f.Name(), path)
+			}
+			if err != nil {
+				os.Remove(f.Name())
+			}
+		}
+	}
+	return slices, nil
+}
+
+// ParseSlices is like [ParseSlices], using the cache.
+func (c *ParseCache) ParseSlices(path string) ([]*Slice, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	slices, err := c.DecodeSlices(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range slices {
+		s.File = path
+	}
+	return slices, nil
+}
+
+// ReadRelease is like [ReadRelease], using the cache.
+func (c *ParseCache) ReadRelease(dir string) (*Release, error) {
+	return ReadReleaseFunc(dir, c.ParseSlices)
+}
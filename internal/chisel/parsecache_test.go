@@ -0,0 +1,65 @@
+package chisel_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+func TestParseCache(t *testing.T) {
+	dir := writeRelease(t, releaseFiles)
+	c := &chisel.ParseCache{Dir: filepath.Join(t.TempDir(), "parse")}
+	want, err := chisel.ReadRelease(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		r, err := c.ReadRelease(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(r.Slices, want.Slices) {
+			t.Fatalf("have %v, want %v", r.Slices, want.Slices)
+		}
+	}
+	entries, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("have %d cache entries, want 2", len(entries))
+	}
+
+	// Entries are used by content, whatever the file.
+	for _, e := range entries {
+		if err := os.WriteFile(e, []byte(`[{"Name": "cached_bins", "Package": "cached"}]`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	slices, err := c.ParseSlices(filepath.Join(dir, "slices/foo.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slices) != 1 || slices[0].Name != "cached_bins" || slices[0].File != filepath.Join(dir, "slices/foo.yaml") {
+		t.Fatalf("have %v, want the cached slice", slices)
+	}
+
+	// Broken entries are decoded again, and errors are not cached.
+	for _, e := range entries {
+		if err := os.WriteFile(e, []byte("{"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.ParseSlices(filepath.Join(dir, "slices/foo.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.DecodeSlices([]byte("package: foo\n")); err == nil {
+		t.Fatal("have no error, want one")
+	}
+	if entries, _ := filepath.Glob(filepath.Join(c.Dir, "*.json")); len(entries) != 2 {
+		t.Fatalf("have %d cache entries, want 2", len(entries))
+	}
+}
@@ -0,0 +1,235 @@
+package chisel
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rebornplusplus/chisel-tools/internal/graph"
+)
+
+// A release is a chisel.yaml file along with the slice definition files under
+// the "slices" directory.
+//
+// A Release must not be modified once read, so that it can be shared as a
+// snapshot between goroutines. See [Store].
+type Release struct {
+	Path   string
+	Config *Config
+	Slices []*Slice
+
+	files map[string][]*Slice // Slices per slice definition file.
+	names map[string]*Slice
+	graph *graph.Graph
+}
+
+// Read a release from the given directory.
+func ReadRelease(dir string) (*Release, error) {
+	return ReadReleaseFunc(dir, ParseSlices)
+}
+
+// ReadReleaseFunc is like [ReadRelease], parsing the slice definition files
+// with parse. Files without slices are left out.
+func ReadReleaseFunc(dir string, parse func(path string) ([]*Slice, error)) (*Release, error) {
+	cfg, err := ParseConfig(filepath.Join(dir, "chisel.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse chisel.yaml: %w", err)
+	}
+	r := &Release{
+		Path:   dir,
+		Config: cfg,
+		files:  make(map[string][]*Slice),
+	}
+	var file string // The file being parsed.
+	slices := StreamSlicesFunc(dir, func(path string) ([]*Slice, error) {
+		file = path
+		return parse(path)
+	})
+	for s, err := range slices {
+		if err != nil {
+			return nil, err
+		}
+		r.files[file] = append(r.files[file], s)
+	}
+	r.index()
+	return r, nil
+}
+
+// NewRelease returns the release in dir with the config and the slices of
+// the slice definition files, by path. Files without slices are left out.
+func NewRelease(dir string, cfg *Config, files map[string][]*Slice) *Release {
+	r := &Release{
+		Path:   dir,
+		Config: cfg,
+		files:  make(map[string][]*Slice),
+	}
+	for f, slices := range files {
+		if len(slices) > 0 {
+			r.files[f] = slices
+		}
+	}
+	r.index()
+	return r
+}
+
+// Replace returns a copy of the release with the slices of the given slice
+// definition files instead, a nil list of slices removing the file.
+func (r *Release) Replace(files map[string][]*Slice) *Release {
+	n := &Release{
+		Path:   r.Path,
+		Config: r.Config,
+		files:  maps.Clone(r.files),
+	}
+	for f, slices := range files {
+		if slices == nil {
+			delete(n.files, f)
+		} else {
+			n.files[f] = slices
+		}
+	}
+	n.index()
+	return n
+}
+
+// Slice returns the slice with the given name, or nil if there is none.
+func (r *Release) Slice(name string) *Slice {
+	return r.names[name]
+}
+
+// Deps returns the slices chisel installs along with the slice, that is, the
+// closure of its essential slices, sorted by name. Undefined slices are
+// included but not followed.
+func (r *Release) Deps(name string) []string {
+	return r.graph.Deps(name)
+}
+
+// RDeps returns the slices that install the slice along with them, sorted by
+// name.
+func (r *Release) RDeps(name string) []string {
+	return r.graph.RDeps(name)
+}
+
+// index fills the list of slices, ordered by file path and then by name, the
+// lookup table by name and the graph of their essential slices.
+func (r *Release) index() {
+	paths := make([]string, 0, len(r.files))
+	for p := range r.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	r.Slices = nil
+	r.names = make(map[string]*Slice)
+	r.graph = &graph.Graph{}
+	for _, p := range paths {
+		for _, s := range r.files[p] {
+			r.Slices = append(r.Slices, s)
+			r.names[s.Name] = s
+			r.graph.Add(s.Name, s.Essential...)
+		}
+	}
+}
+
+// Find all slice definition files of a release, sorted by path.
+func SliceFiles(dir string) ([]string, error) {
+	var files []string
+	root := filepath.Join(dir, "slices")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".yaml") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find slice definition files: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// A Store holds the current snapshot of a release with copy-on-write
+// semantics. Readers get an immutable [Release] from [Store.Snapshot] and may
+// use it for as long as they want without locking, while updates build a new
+// release on the side and swap it in atomically once complete.
+type Store struct {
+	dir string
+	mu  sync.Mutex // Serializes the updates.
+	cur atomic.Pointer[Release]
+}
+
+// NewStore reads the release in dir and returns a store holding it.
+func NewStore(dir string) (*Store, error) {
+	r, err := ReadRelease(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir}
+	s.cur.Store(r)
+	return s, nil
+}
+
+// Snapshot returns the current release.
+func (s *Store) Snapshot() *Release {
+	return s.cur.Load()
+}
+
+// Reload reads the whole release again. On failure, the current snapshot is
+// kept.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := ReadRelease(s.dir)
+	if err != nil {
+		return err
+	}
+	s.cur.Store(r)
+	return nil
+}
+
+// Apply re-reads only the given files, which may be chisel.yaml or slice
+// definition files that were added, changed or removed. On failure, the
+// current snapshot is kept.
+func (s *Store) Apply(paths []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.cur.Load()
+	r := &Release{
+		Path:   old.Path,
+		Config: old.Config,
+		files:  maps.Clone(old.files),
+	}
+	slicesDir := filepath.Join(s.dir, "slices") + string(filepath.Separator)
+	for _, p := range paths {
+		switch {
+		case p == filepath.Join(s.dir, "chisel.yaml"):
+			cfg, err := ParseConfig(p)
+			if err != nil {
+				return fmt.Errorf("cannot parse chisel.yaml: %w", err)
+			}
+			r.Config = cfg
+		case strings.HasPrefix(p, slicesDir) && strings.HasSuffix(p, ".yaml"):
+			slices, err := ParseSlices(p)
+			if errors.Is(err, os.ErrNotExist) {
+				delete(r.files, p)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("cannot parse slices from file %s: %w", p, err)
+			}
+			r.files[p] = slices
+		}
+	}
+	r.index()
+	s.cur.Store(r)
+	return nil
+}
@@ -0,0 +1,223 @@
+package chisel_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+var releaseFiles = map[string]string{
+	"chisel.yaml": `
+format: v1
+archives:
+  ubuntu:
+    suites: [noble]
+    components: [main]
+`,
+	"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    essential:
+      - bar_libs
+`,
+	"slices/sub/bar.yaml": `
+package: bar
+slices:
+  libs: {}
+`,
+	"slices/README.md": "Not a slice definition file.",
+}
+
+func writeRelease(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func sliceNames(r *chisel.Release) []string {
+	var names []string
+	for _, s := range r.Slices {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestReadRelease(t *testing.T) {
+	dir := writeRelease(t, releaseFiles)
+	r, err := chisel.ReadRelease(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Config.Format != "v1" {
+		t.Fatalf("have format %q, want %q", r.Config.Format, "v1")
+	}
+	want := []*chisel.Slice{{
+		Name:      "foo_bins",
+		Package:   "foo",
+		Essential: []string{"bar_libs"},
+		File:      filepath.Join(dir, "slices/foo.yaml"),
+	}, {
+		Name:    "bar_libs",
+		Package: "bar",
+		File:    filepath.Join(dir, "slices/sub/bar.yaml"),
+	}}
+	if !reflect.DeepEqual(r.Slices, want) {
+		t.Fatalf("have %v, want %v", r.Slices, want)
+	}
+	if s := r.Slice("bar_libs"); s != r.Slices[1] {
+		t.Fatalf("have slice %v, want %v", s, r.Slices[1])
+	}
+	if s := r.Slice("bar_bins"); s != nil {
+		t.Fatalf("have slice %v, want nil", s)
+	}
+}
+
+func TestStoreApply(t *testing.T) {
+	dir := writeRelease(t, releaseFiles)
+	store, err := chisel.NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := store.Snapshot()
+
+	foo := filepath.Join(dir, "slices/foo.yaml")
+	bar := filepath.Join(dir, "slices/sub/bar.yaml")
+	baz := filepath.Join(dir, "slices/baz.yaml")
+	if err := os.Remove(bar); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(baz, []byte("package: baz\nslices:\n  bins: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Apply([]string{bar, baz}); err != nil {
+		t.Fatal(err)
+	}
+
+	after := store.Snapshot()
+	if have, want := sliceNames(after), []string{"baz_bins", "foo_bins"}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	// The old snapshot must not change.
+	if have, want := sliceNames(before), []string{"foo_bins", "bar_libs"}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("old snapshot changed: have %v, want %v", have, want)
+	}
+
+	// A failed update keeps the current snapshot.
+	if err := os.WriteFile(foo, []byte("package: foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Apply([]string{foo}); err == nil {
+		t.Fatal("have no error, want one")
+	}
+	if store.Snapshot() != after {
+		t.Fatal("snapshot changed after a failed update")
+	}
+}
+
+func TestStoreConcurrency(t *testing.T) {
+	dir := writeRelease(t, releaseFiles)
+	store, err := chisel.NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				r := store.Snapshot()
+				if len(r.Slices) != 2 || r.Slice("foo_bins") == nil {
+					t.Errorf("inconsistent snapshot: %v", sliceNames(r))
+					return
+				}
+			}
+		}()
+	}
+	for range 10 {
+		if err := store.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
+
+var depsFiles = map[string]string{
+	"chisel.yaml": releaseFiles["chisel.yaml"],
+	"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    essential: [foo_libs, bar_libs]
+  libs:
+    essential: [bar_libs, baz_libs]
+`,
+	"slices/bar.yaml": `
+package: bar
+slices:
+  libs:
+    essential: [bar_config]
+  config: {}
+`,
+}
+
+var depsTests = []struct {
+	slice string
+	deps  []string
+	rdeps []string
+}{
+	{slice: "foo_bins", deps: []string{"bar_config", "bar_libs", "baz_libs", "foo_libs"}},
+	{slice: "foo_libs", deps: []string{"bar_config", "bar_libs", "baz_libs"}, rdeps: []string{"foo_bins"}},
+	{slice: "bar_config", rdeps: []string{"bar_libs", "foo_bins", "foo_libs"}},
+	{slice: "baz_libs", rdeps: []string{"foo_bins", "foo_libs"}},
+}
+
+func TestDeps(t *testing.T) {
+	r, err := chisel.ReadRelease(writeRelease(t, depsFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range depsTests {
+		if deps := r.Deps(test.slice); !reflect.DeepEqual(deps, test.deps) {
+			t.Fatalf("%s: have deps %v, want %v", test.slice, deps, test.deps)
+		}
+		if rdeps := r.RDeps(test.slice); !reflect.DeepEqual(rdeps, test.rdeps) {
+			t.Fatalf("%s: have rdeps %v, want %v", test.slice, rdeps, test.rdeps)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	dir := writeRelease(t, releaseFiles)
+	r, err := chisel.ReadRelease(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baz := &chisel.Slice{Name: "baz_bins", Package: "baz", File: filepath.Join(dir, "slices/baz.yaml")}
+	n := r.Replace(map[string][]*chisel.Slice{
+		filepath.Join(dir, "slices/sub/bar.yaml"): nil,
+		filepath.Join(dir, "slices/baz.yaml"):     {baz},
+	})
+	if have, want := sliceNames(n), []string{"baz_bins", "foo_bins"}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if n.Slice("baz_bins") != baz || n.Slice("bar_libs") != nil {
+		t.Fatalf("have %v, want baz_bins only", sliceNames(n))
+	}
+	if have, want := sliceNames(r), []string{"foo_bins", "bar_libs"}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("release changed: have %v, want %v", have, want)
+	}
+}
@@ -1,8 +1,10 @@
 package chisel
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 
@@ -11,28 +13,190 @@ import (
 
 // The interesting bits about a chisel slice.
 type Slice struct {
-	Name      string   `yaml:"-"`
-	Package   string   `yaml:"-"`
-	Essential []string `yaml:"essential,omitempty"`
+	Name      string
+	Package   string
+	Essential []string
+	// Paths in the contents of the slice, sorted. They may be globs, see
+	// [MatchPath].
+	Contents []string
+	// Entries of the paths in the contents, by path.
+	Paths map[string]*PathInfo
+	// Starlark script chisel runs once the slices are installed, editing
+	// the mutable paths.
+	Mutate string
+	// Slice definition file the slice was parsed from, if any.
+	File string
 	// TODO add remaining fields when necessary.
 }
 
 type sliceDef struct {
-	Package   string           `yaml:"package"`
-	Essential []string         `yaml:"essential,omitempty"`
-	Slices    map[string]Slice `yaml:"slices"`
+	Package   string               `yaml:"package"`
+	Essential essentialList        `yaml:"essential,omitempty"`
+	Slices    map[string]sliceYAML `yaml:"slices"`
+}
+
+type sliceYAML struct {
+	Essential essentialList        `yaml:"essential,omitempty"`
+	Contents  map[string]*pathYAML `yaml:"contents,omitempty"`
+	Mutate    string               `yaml:"mutate,omitempty"`
+}
+
+// PathKind is the kind of the entry of a path in the contents of a slice.
+type PathKind string
+
+const (
+	CopyPath     PathKind = "copy"
+	GlobPath     PathKind = "glob"
+	DirPath      PathKind = "dir"
+	TextPath     PathKind = "text"
+	SymlinkPath  PathKind = "symlink"
+	GeneratePath PathKind = "generate"
+)
+
+// A PathInfo is the entry of a path in the contents of a slice.
+type PathInfo struct {
+	Kind PathKind
+	// Source path of copies, if not the path itself, content of texts,
+	// target of symlinks and what generates the path.
+	Info    string
+	Mode    uint
+	Mutable bool
+	// Until is "mutate" for paths removed once the mutation scripts ran.
+	Until string
+	// Architectures the path is installed on, all of them if empty.
+	Arch []string
+	// Package to take the path from, among those of the slices installing
+	// it, if not empty.
+	Prefer string
+}
+
+// OnArch reports whether the path is installed on the arch.
+func (p *PathInfo) OnArch(arch string) bool {
+	return len(p.Arch) == 0 || slices.Contains(p.Arch, arch)
+}
+
+// SameContent reports whether both entries install the same content, which
+// chisel requires of the entries of a path in several slices.
+func (p *PathInfo) SameContent(o *PathInfo) bool {
+	return p.Kind == o.Kind && p.Info == o.Info && p.Mode == o.Mode && p.Mutable == o.Mutable
+}
+
+type pathYAML struct {
+	Copy     string   `yaml:"copy"`
+	Make     bool     `yaml:"make"`
+	Text     *string  `yaml:"text"`
+	Symlink  string   `yaml:"symlink"`
+	Generate string   `yaml:"generate"`
+	Mode     uint     `yaml:"mode"`
+	Mutable  bool     `yaml:"mutable"`
+	Until    string   `yaml:"until"`
+	Arch     archList `yaml:"arch"`
+	Prefer   string   `yaml:"prefer"`
+}
+
+// The "arch" of a path is a name or a list of names.
+type archList []string
+
+func (l *archList) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*l = []string{n.Value}
+		return nil
+	}
+	var names []string
+	if err := n.Decode(&names); err != nil {
+		return fmt.Errorf("line %d: 'arch' must be a name or a list", n.Line)
+	}
+	*l = names
+	return nil
+}
+
+// pathInfo returns the entry of the path as chisel reads it.
+func (y *pathYAML) pathInfo(path string) (*PathInfo, error) {
+	info := &PathInfo{Kind: CopyPath}
+	if y == nil {
+		y = &pathYAML{}
+	}
+	kinds := 0
+	if y.Copy != "" {
+		kinds++
+		info.Info = y.Copy
+	}
+	if y.Make {
+		kinds++
+		info.Kind = DirPath
+		if !strings.HasSuffix(path, "/") {
+			return nil, fmt.Errorf("path %s must end in / for 'make' to be valid", path)
+		}
+	}
+	if y.Text != nil {
+		kinds++
+		info.Kind, info.Info = TextPath, *y.Text
+	}
+	if y.Symlink != "" {
+		kinds++
+		info.Kind, info.Info = SymlinkPath, y.Symlink
+	}
+	if y.Generate != "" {
+		kinds++
+		info.Kind, info.Info = GeneratePath, y.Generate
+	}
+	if kinds > 1 {
+		return nil, fmt.Errorf("path %s has more than one of copy, make, text, symlink and generate", path)
+	}
+	if kinds == 0 && strings.ContainsAny(path, "*?") {
+		info.Kind = GlobPath
+	}
+	if y.Until != "" && y.Until != "mutate" {
+		return nil, fmt.Errorf("path %s has invalid 'until' value: %q", path, y.Until)
+	}
+	info.Mode, info.Mutable, info.Until, info.Arch, info.Prefer = y.Mode, y.Mutable, y.Until, y.Arch, y.Prefer
+	return info, nil
+}
+
+// Format v3 turned the "essential" lists into maps keyed by slice name. Both
+// are accepted here so that releases of any format can be parsed.
+type essentialList []string
+
+func (l *essentialList) UnmarshalYAML(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		var names []string
+		if err := n.Decode(&names); err != nil {
+			return err
+		}
+		*l = names
+	case yaml.MappingNode:
+		var names []string
+		for i := 0; i < len(n.Content); i += 2 {
+			names = append(names, n.Content[i].Value)
+		}
+		*l = names
+	default:
+		return fmt.Errorf("line %d: 'essential' must be a list or a map", n.Line)
+	}
+	return nil
 }
 
 // Parse all slices from a slice definition file.
 func ParseSlices(path string) ([]*Slice, error) {
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	slices, err := DecodeSlices(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range slices {
+		s.File = path
+	}
+	return slices, nil
+}
 
+// Decode all slices from the content of a slice definition file.
+func DecodeSlices(data []byte) ([]*Slice, error) {
 	def := &sliceDef{}
-	d := yaml.NewDecoder(f)
+	d := yaml.NewDecoder(bytes.NewReader(data))
 	if err := d.Decode(def); err != nil {
 		return nil, err
 	}
@@ -50,16 +214,43 @@ func ParseSlices(path string) ([]*Slice, error) {
 	}
 
 	var slices []*Slice
-	for name, slice := range def.Slices {
-		for _, e := range slice.Essential {
+	for name, s := range def.Slices {
+		for _, e := range s.Essential {
 			if _, _, err := Parse(e); err != nil {
 				return nil, fmt.Errorf("slice %s 'essential': %w", name, err)
 			}
 		}
-		slice.Essential = append(slice.Essential, def.Essential...)
-		slice.Name = Name(def.Package, name)
-		slice.Package = def.Package
-		slices = append(slices, &slice)
+		var contents []string
+		var paths map[string]*PathInfo
+		for p, y := range s.Contents {
+			if paths == nil {
+				paths = make(map[string]*PathInfo)
+			}
+			info, err := y.pathInfo(p)
+			if err != nil {
+				return nil, fmt.Errorf("slice %s: %w", name, err)
+			}
+			contents = append(contents, p)
+			paths[p] = info
+		}
+		sort.Strings(contents)
+		sliceName := Name(def.Package, name)
+		essential := append([]string(nil), s.Essential...)
+		for _, e := range def.Essential {
+			// As in chisel, the essential slices of the package are
+			// not essential to themselves.
+			if e != sliceName {
+				essential = append(essential, e)
+			}
+		}
+		slices = append(slices, &Slice{
+			Name:      sliceName,
+			Package:   def.Package,
+			Essential: essential,
+			Contents:  contents,
+			Paths:     paths,
+			Mutate:    s.Mutate,
+		})
 	}
 	sort.Slice(slices, func(i, j int) bool {
 		return slices[i].Name < slices[j].Name
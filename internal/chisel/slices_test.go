@@ -20,6 +20,10 @@ slices:
     essential:
       - foo_foo
       - buz_foo
+    contents:
+      /usr/bin/foo:
+      /usr/lib/*/libfoo.so.*: {}
+      /etc/foo.conf: {text: "FOO"}
     extra: # extra field, must be ignored
       - foo
 extra: # extra field, must be ignored.
@@ -37,11 +41,67 @@ var sliceData = []struct {
 		Name:      "foo_bar",
 		Package:   "foo",
 		Essential: []string{"foo_foo", "buz_foo", "bar_foo"},
+		Contents:  []string{"/etc/foo.conf", "/usr/bin/foo", "/usr/lib/*/libfoo.so.*"},
+		Paths: map[string]*chisel.PathInfo{
+			"/etc/foo.conf":          {Kind: chisel.TextPath, Info: "FOO"},
+			"/usr/bin/foo":           {Kind: chisel.CopyPath},
+			"/usr/lib/*/libfoo.so.*": {Kind: chisel.GlobPath},
+		},
 	}, {
 		Name:      "foo_foo",
 		Package:   "foo",
 		Essential: []string{"bar_bar", "bar_foo"},
 	}},
+}, {
+	summary: "Package essentials are not essential to themselves",
+	data: `
+package: foo
+essential:
+  - foo_copyright
+slices:
+  bins: {}
+  copyright: {}
+`,
+	slices: []*chisel.Slice{{
+		Name:      "foo_bins",
+		Package:   "foo",
+		Essential: []string{"foo_copyright"},
+	}, {
+		Name:    "foo_copyright",
+		Package: "foo",
+	}},
+}, {
+	summary: "Path entries",
+	data: `
+package: foo
+slices:
+  bins:
+    contents:
+      /usr/bin/foo: {copy: /usr/bin/foo.real, mode: 0755}
+      /usr/bin/bar: {symlink: foo}
+      /var/lib/foo/: {make: true, mode: 01777}
+      /etc/foo.conf: {text: "", mutable: true}
+      /tmp/foo: {until: mutate, arch: amd64}
+      /usr/lib/foo.so: {arch: [amd64, arm64], prefer: bar}
+      /var/lib/chisel/**: {generate: manifest}
+    mutate: |
+      content.write("/etc/foo.conf", "foo")
+`,
+	slices: []*chisel.Slice{{
+		Name:     "foo_bins",
+		Package:  "foo",
+		Contents: []string{"/etc/foo.conf", "/tmp/foo", "/usr/bin/bar", "/usr/bin/foo", "/usr/lib/foo.so", "/var/lib/chisel/**", "/var/lib/foo/"},
+		Paths: map[string]*chisel.PathInfo{
+			"/usr/bin/foo":       {Kind: chisel.CopyPath, Info: "/usr/bin/foo.real", Mode: 0755},
+			"/usr/bin/bar":       {Kind: chisel.SymlinkPath, Info: "foo"},
+			"/var/lib/foo/":      {Kind: chisel.DirPath, Mode: 01777},
+			"/etc/foo.conf":      {Kind: chisel.TextPath, Mutable: true},
+			"/tmp/foo":           {Kind: chisel.CopyPath, Until: "mutate", Arch: []string{"amd64"}},
+			"/usr/lib/foo.so":    {Kind: chisel.CopyPath, Arch: []string{"amd64", "arm64"}, Prefer: "bar"},
+			"/var/lib/chisel/**": {Kind: chisel.GeneratePath, Info: "manifest"},
+		},
+		Mutate: "content.write(\"/etc/foo.conf\", \"foo\")\n",
+	}},
 }}
 
 func TestParseSliceDef(t *testing.T) {
@@ -61,8 +121,57 @@ func TestParseSliceDef(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+		for _, s := range tc.slices {
+			s.File = f.Name()
+		}
 		if !reflect.DeepEqual(slices, tc.slices) {
 			t.Fatalf("have %v, want %v", slices, tc.slices)
 		}
 	}
 }
+
+var invalidSliceData = []struct {
+	summary string
+	data    string
+	err     string
+}{{
+	summary: "Path of two kinds",
+	data: `
+package: foo
+slices:
+  bins:
+    contents:
+      /usr/bin/foo: {copy: /usr/bin/bar, symlink: bar}
+`,
+	err: "slice bins: path /usr/bin/foo has more than one of copy, make, text, symlink and generate",
+}, {
+	summary: "Directory without trailing slash",
+	data: `
+package: foo
+slices:
+  bins:
+    contents:
+      /var/lib/foo: {make: true}
+`,
+	err: "slice bins: path /var/lib/foo must end in / for 'make' to be valid",
+}, {
+	summary: "Invalid until",
+	data: `
+package: foo
+slices:
+  bins:
+    contents:
+      /tmp/foo: {until: install}
+`,
+	err: `slice bins: path /tmp/foo has invalid 'until' value: "install"`,
+}}
+
+func TestParseInvalidSliceDef(t *testing.T) {
+	for _, tc := range invalidSliceData {
+		t.Logf("Summary: %s", tc.summary)
+		_, err := chisel.DecodeSlices([]byte(tc.data))
+		if err == nil || err.Error() != tc.err {
+			t.Fatalf("have error %v, want %q", err, tc.err)
+		}
+	}
+}
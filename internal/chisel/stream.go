@@ -0,0 +1,50 @@
+package chisel
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path/filepath"
+	"strings"
+)
+
+// StreamSlices yields the slices of the release in dir while walking the
+// "slices" directory, parsing one slice definition file at a time. Only the
+// slices of the current file are held in memory, which keeps huge releases
+// manageable. Files are visited in lexical order.
+//
+// On failure, the error is yielded with a nil slice and the iteration stops.
+func StreamSlices(dir string) iter.Seq2[*Slice, error] {
+	return StreamSlicesFunc(dir, ParseSlices)
+}
+
+// StreamSlicesFunc is like [StreamSlices], parsing the slice definition files
+// with parse.
+func StreamSlicesFunc(dir string, parse func(path string) ([]*Slice, error)) iter.Seq2[*Slice, error] {
+	return func(yield func(*Slice, error) bool) {
+		stop := errors.New("stop")
+		root := filepath.Join(dir, "slices")
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return fmt.Errorf("cannot find slice definition files: %w", err)
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".yaml") {
+				return nil
+			}
+			slices, err := parse(path)
+			if err != nil {
+				return fmt.Errorf("cannot parse slices from file %s: %w", path, err)
+			}
+			for _, s := range slices {
+				if !yield(s, nil) {
+					return stop
+				}
+			}
+			return nil
+		})
+		if err != nil && err != stop {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package chisel_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+func TestStreamSlices(t *testing.T) {
+	dir := writeRelease(t, releaseFiles)
+	var names []string
+	for s, err := range chisel.StreamSlices(dir) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, s.Name)
+	}
+	want := []string{"foo_bins", "bar_libs"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("have %v, want %v", names, want)
+	}
+
+	// Breaking out of the loop must stop the walk.
+	names = nil
+	for s, err := range chisel.StreamSlices(dir) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, s.Name)
+		break
+	}
+	if !reflect.DeepEqual(names, want[:1]) {
+		t.Fatalf("have %v, want %v", names, want[:1])
+	}
+}
+
+func TestStreamSlicesError(t *testing.T) {
+	dir := writeRelease(t, map[string]string{
+		"slices/a.yaml": "package: a\nslices:\n  bins: {}\n",
+		"slices/b.yaml": "package: b\n",
+	})
+	var names []string
+	var errs []error
+	for s, err := range chisel.StreamSlices(dir) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		names = append(names, s.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"a_bins"}) {
+		t.Fatalf("have %v, want [a_bins]", names)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("have %d errors, want 1", len(errs))
+	}
+	want := "cannot parse slices from file " + dir + "/slices/b.yaml: missing 'slices' field"
+	if errs[0].Error() != want {
+		t.Fatalf("have error %q, want %q", errs[0], want)
+	}
+}
@@ -0,0 +1,148 @@
+// Package chiselbin downloads released chisel binaries, to run slices with
+// chisel versions other than the one installed.
+package chiselbin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const DefaultBaseURL = "https://github.com/canonical/chisel/releases/download"
+
+type Downloader struct {
+	// Base URL of the releases, DefaultBaseURL if empty.
+	BaseURL string
+	// Directory to keep the binaries in, by version.
+	CacheDir string
+	Client   *http.Client
+}
+
+// DefaultCacheDir returns the directory binaries are kept in by default.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sdf", "chisel"), nil
+}
+
+// Asset returns the name of the release asset of the version for the host.
+func Asset(version string) string {
+	return fmt.Sprintf("chisel_%s_linux_%s.tar.gz", version, runtime.GOARCH)
+}
+
+// Path returns the path of the chisel binary of the version, downloading it
+// first if it is not in the cache. The download is checked against the
+// SHA384 checksum published with the release.
+func (d *Downloader) Path(version string) (string, error) {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	bin := filepath.Join(d.CacheDir, version, "chisel")
+	if _, err := os.Stat(bin); err == nil {
+		return bin, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	base := d.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	u := strings.TrimSuffix(base, "/") + "/" + version + "/" + Asset(version)
+	archive, err := d.get(u)
+	if err != nil {
+		return "", err
+	}
+	sum, err := d.get(u + ".sha384")
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(sum))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum at %s", u+".sha384")
+	}
+	h := sha512.Sum384(archive)
+	if hex.EncodeToString(h[:]) != fields[0] {
+		return "", fmt.Errorf("checksum mismatch for %s", u)
+	}
+
+	data, err := extract(archive)
+	if err != nil {
+		return "", fmt.Errorf("cannot extract %s: %w", u, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(bin), 0755); err != nil {
+		return "", err
+	}
+	// Write next to the binary and rename, so that concurrent downloads
+	// never see a partial binary.
+	tmp, err := os.CreateTemp(filepath.Dir(bin), "chisel-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Chmod(0755); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), bin); err != nil {
+		return "", err
+	}
+	return bin, nil
+}
+
+func (d *Downloader) get(u string) ([]byte, error) {
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download %s: %s", u, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extract returns the chisel binary from the release archive.
+func extract(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no chisel binary in archive")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == "chisel" {
+			return io.ReadAll(tr)
+		}
+	}
+}
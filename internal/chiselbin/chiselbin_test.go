@@ -0,0 +1,80 @@
+package chiselbin_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chiselbin"
+)
+
+func releaseArchive(t *testing.T, bin string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range map[string]string{"LICENSE": "GPL", "chisel": bin} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(data))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestPath(t *testing.T) {
+	archive := releaseArchive(t, "#!/bin/sh\necho v1.0.0\n")
+	sum := sha512.Sum384(archive)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/v1.0.0/" + chiselbin.Asset("v1.0.0"):
+			w.Write(archive)
+		case "/v1.0.0/" + chiselbin.Asset("v1.0.0") + ".sha384":
+			w.Write([]byte(hex.EncodeToString(sum[:]) + "  " + chiselbin.Asset("v1.0.0") + "\n"))
+		case "/v0.9.0/" + chiselbin.Asset("v0.9.0"):
+			w.Write(archive)
+		case "/v0.9.0/" + chiselbin.Asset("v0.9.0") + ".sha384":
+			w.Write([]byte("0000"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	d := &chiselbin.Downloader{BaseURL: srv.URL, CacheDir: t.TempDir()}
+	bin, err := d.Path("1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "#!/bin/sh\necho v1.0.0\n" {
+		t.Fatalf("have binary %q", data)
+	}
+	if info, err := os.Stat(bin); err != nil || info.Mode().Perm() != 0755 {
+		t.Fatalf("have binary mode %v (%v), want 0755", info.Mode(), err)
+	}
+
+	// Cached binaries are not downloaded again.
+	if again, err := d.Path("v1.0.0"); err != nil || again != bin || requests != 2 {
+		t.Fatalf("have %s (%v) after %d requests, want %s after 2", again, err, requests, bin)
+	}
+
+	if _, err := d.Path("v0.9.0"); err == nil {
+		t.Fatal("have no error for a checksum mismatch")
+	}
+	if _, err := d.Path("v0.1.0"); err == nil {
+		t.Fatal("have no error for a missing release")
+	}
+}
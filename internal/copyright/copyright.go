@@ -0,0 +1,161 @@
+// Package copyright parses the Debian copyright files shipped in
+// /usr/share/doc/<package>/copyright, usually included in the "copyright"
+// slice of each package.
+package copyright
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strings"
+)
+
+// Location of the copyright file of a package, relative to the root.
+func Path(pkg string) string {
+	return path.Join("/usr/share/doc", pkg, "copyright")
+}
+
+// The header field present in machine-readable (DEP-5) copyright files.
+const formatField = "format"
+
+type Copyright struct {
+	// Whether the file follows the machine-readable format (DEP-5). The
+	// licenses of other files can only be guessed, and are thus left empty.
+	Machine bool
+	// Short names of the licenses, in the order they are first mentioned.
+	Licenses []string
+}
+
+// Parse the content of a copyright file.
+func Parse(data []byte) *Copyright {
+	c := &Copyright{}
+	seen := make(map[string]bool)
+	for i, p := range paragraphs(data) {
+		if i == 0 {
+			if _, ok := p[formatField]; ok {
+				c.Machine = true
+			}
+		}
+		if !c.Machine {
+			break
+		}
+		l, ok := p["license"]
+		if !ok {
+			continue
+		}
+		// The first line is the license short name, possibly an
+		// expression like "GPL-2+ or Artistic". The rest is its text.
+		name, _, _ := strings.Cut(l, "\n")
+		for _, n := range splitExpression(name) {
+			if !seen[n] {
+				seen[n] = true
+				c.Licenses = append(c.Licenses, n)
+			}
+		}
+	}
+	return c
+}
+
+// splitExpression returns the license names in a DEP-5 license expression.
+func splitExpression(expr string) []string {
+	var names []string
+	for _, f := range strings.Fields(expr) {
+		switch strings.ToLower(f) {
+		case "or", "and":
+			continue
+		}
+		f = strings.Trim(f, ",()")
+		if f != "" {
+			names = append(names, f)
+		}
+	}
+	return names
+}
+
+// paragraphs splits a deb822 document into paragraphs of fields. Field names
+// are lowercased, continuation lines are joined with a newline.
+func paragraphs(data []byte) []map[string]string {
+	var paras []map[string]string
+	cur := make(map[string]string)
+	key := ""
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			if len(cur) > 0 {
+				paras = append(paras, cur)
+				cur = make(map[string]string)
+			}
+			key = ""
+		case strings.HasPrefix(line, "#"):
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			if key != "" {
+				cur[key] += "\n" + strings.TrimSpace(line)
+			}
+		default:
+			k, v, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			key = strings.ToLower(strings.TrimSpace(k))
+			cur[key] = strings.TrimSpace(v)
+		}
+	}
+	if len(cur) > 0 {
+		paras = append(paras, cur)
+	}
+	return paras
+}
+
+// Well-known DEP-5 short names and their SPDX identifiers.
+var spdxIDs = map[string]string{
+	"apache-2.0":    "Apache-2.0",
+	"artistic":      "Artistic-1.0-Perl",
+	"artistic-2.0":  "Artistic-2.0",
+	"bsd-2-clause":  "BSD-2-Clause",
+	"bsd-3-clause":  "BSD-3-Clause",
+	"bsd-4-clause":  "BSD-4-Clause",
+	"cc0-1.0":       "CC0-1.0",
+	"expat":         "MIT",
+	"mit":           "MIT",
+	"gfdl-1.2+":     "GFDL-1.2-or-later",
+	"gfdl-1.3+":     "GFDL-1.3-or-later",
+	"gpl-1+":        "GPL-1.0-or-later",
+	"gpl-2":         "GPL-2.0-only",
+	"gpl-2+":        "GPL-2.0-or-later",
+	"gpl-3":         "GPL-3.0-only",
+	"gpl-3+":        "GPL-3.0-or-later",
+	"isc":           "ISC",
+	"lgpl-2":        "LGPL-2.0-only",
+	"lgpl-2+":       "LGPL-2.0-or-later",
+	"lgpl-2.1":      "LGPL-2.1-only",
+	"lgpl-2.1+":     "LGPL-2.1-or-later",
+	"lgpl-3":        "LGPL-3.0-only",
+	"lgpl-3+":       "LGPL-3.0-or-later",
+	"mpl-1.1":       "MPL-1.1",
+	"mpl-2.0":       "MPL-2.0",
+	"openssl":       "OpenSSL",
+	"psf-2":         "PSF-2.0",
+	"public-domain": "LicenseRef-public-domain",
+	"zlib":          "Zlib",
+}
+
+// SPDX returns the SPDX identifier for a DEP-5 license short name. Unknown
+// names are turned into a "LicenseRef-" identifier and ok is false.
+func SPDX(name string) (id string, ok bool) {
+	if id, ok := spdxIDs[strings.ToLower(name)]; ok {
+		return id, true
+	}
+	var b strings.Builder
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return "LicenseRef-" + b.String(), false
+}
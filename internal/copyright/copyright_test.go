@@ -0,0 +1,82 @@
+package copyright_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/copyright"
+)
+
+const machineCopyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: hello
+Source: https://ftp.gnu.org/gnu/hello/
+
+Files: *
+Copyright: 1992-2022 Free Software Foundation, Inc.
+License: GPL-3+
+
+Files: debian/*
+Copyright: 1994-2022 Santiago Vila
+License: GPL-3+ or Artistic
+ Some text which is not a license name.
+
+# Comments are ignored.
+License: GPL-3+
+ This program is free software.
+ .
+ On Debian systems, ...
+`
+
+const legacyCopyright = `This is the Debian package of hello.
+
+It is licensed under the GPL.
+`
+
+var parseTests = []struct {
+	summary   string
+	data      string
+	copyright *copyright.Copyright
+}{{
+	summary: "Machine-readable",
+	data:    machineCopyright,
+	copyright: &copyright.Copyright{
+		Machine:  true,
+		Licenses: []string{"GPL-3+", "Artistic"},
+	},
+}, {
+	summary:   "Free form",
+	data:      legacyCopyright,
+	copyright: &copyright.Copyright{},
+}, {
+	summary:   "Empty",
+	copyright: &copyright.Copyright{},
+}}
+
+func TestParse(t *testing.T) {
+	for _, tc := range parseTests {
+		t.Logf("Summary: %s", tc.summary)
+		c := copyright.Parse([]byte(tc.data))
+		if !reflect.DeepEqual(c, tc.copyright) {
+			t.Fatalf("have %+v, want %+v", c, tc.copyright)
+		}
+	}
+}
+
+var spdxTests = []struct {
+	name string
+	id   string
+	ok   bool
+}{
+	{"GPL-2+", "GPL-2.0-or-later", true},
+	{"expat", "MIT", true},
+	{"Custom License", "LicenseRef-Custom-License", false},
+}
+
+func TestSPDX(t *testing.T) {
+	for _, tc := range spdxTests {
+		id, ok := copyright.SPDX(tc.name)
+		if id != tc.id || ok != tc.ok {
+			t.Fatalf("%s: have (%q, %v), want (%q, %v)", tc.name, id, ok, tc.id, tc.ok)
+		}
+	}
+}
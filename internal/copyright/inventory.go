@@ -0,0 +1,94 @@
+package copyright
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type Status string
+
+const (
+	// All licenses of the package are known SPDX licenses.
+	Known Status = "known"
+	// The copyright file is not machine-readable or has no license.
+	Unknown Status = "unknown"
+	// Some licenses have no SPDX identifier and need a human to look at them.
+	Ambiguous Status = "ambiguous"
+	// There is no copyright file for the package in the root.
+	Missing Status = "missing"
+)
+
+type PackageLicenses struct {
+	Package  string   `json:"package"`
+	Status   Status   `json:"status"`
+	Licenses []string `json:"licenses,omitempty"`
+	// Licenses without an SPDX identifier, as written in the file.
+	Unmapped []string `json:"unmapped,omitempty"`
+}
+
+// An Inventory aggregates the licenses of the packages in a root.
+type Inventory struct {
+	Packages []*PackageLicenses `json:"packages"`
+	// Packages per SPDX license identifier.
+	Licenses map[string][]string `json:"licenses"`
+}
+
+// Flagged returns the packages whose status is not [Known].
+func (inv *Inventory) Flagged() []*PackageLicenses {
+	var flagged []*PackageLicenses
+	for _, p := range inv.Packages {
+		if p.Status != Known {
+			flagged = append(flagged, p)
+		}
+	}
+	return flagged
+}
+
+// ReadInventory reads the copyright files of the packages in a root. If pkgs
+// is empty, all the copyright files present in the root are read.
+func ReadInventory(root string, pkgs []string) (*Inventory, error) {
+	if len(pkgs) == 0 {
+		matches, err := filepath.Glob(filepath.Join(root, "usr/share/doc/*/copyright"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			pkgs = append(pkgs, filepath.Base(filepath.Dir(m)))
+		}
+	}
+	pkgs = append([]string(nil), pkgs...)
+	sort.Strings(pkgs)
+
+	inv := &Inventory{Licenses: make(map[string][]string)}
+	for _, pkg := range pkgs {
+		pl := &PackageLicenses{Package: pkg}
+		inv.Packages = append(inv.Packages, pl)
+
+		data, err := os.ReadFile(filepath.Join(root, Path(pkg)))
+		if errors.Is(err, os.ErrNotExist) {
+			pl.Status = Missing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c := Parse(data)
+		if !c.Machine || len(c.Licenses) == 0 {
+			pl.Status = Unknown
+			continue
+		}
+		pl.Status = Known
+		for _, l := range c.Licenses {
+			id, ok := SPDX(l)
+			if !ok {
+				pl.Status = Ambiguous
+				pl.Unmapped = append(pl.Unmapped, l)
+			}
+			pl.Licenses = append(pl.Licenses, id)
+			inv.Licenses[id] = append(inv.Licenses[id], pkg)
+		}
+	}
+	return inv, nil
+}
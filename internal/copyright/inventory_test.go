@@ -0,0 +1,68 @@
+package copyright_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/copyright"
+)
+
+func TestReadInventory(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"hello":  machineCopyright,
+		"legacy": legacyCopyright,
+		"custom": "Format: x\n\nFiles: *\nLicense: Expat and Foo-Custom\n",
+	}
+	for pkg, data := range files {
+		p := filepath.Join(root, copyright.Path(pkg))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inv, err := copyright.ReadInventory(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &copyright.Inventory{
+		Packages: []*copyright.PackageLicenses{{
+			Package:  "custom",
+			Status:   copyright.Ambiguous,
+			Licenses: []string{"MIT", "LicenseRef-Foo-Custom"},
+			Unmapped: []string{"Foo-Custom"},
+		}, {
+			Package:  "hello",
+			Status:   copyright.Known,
+			Licenses: []string{"GPL-3.0-or-later", "Artistic-1.0-Perl"},
+		}, {
+			Package: "legacy",
+			Status:  copyright.Unknown,
+		}},
+		Licenses: map[string][]string{
+			"MIT":                   {"custom"},
+			"LicenseRef-Foo-Custom": {"custom"},
+			"GPL-3.0-or-later":      {"hello"},
+			"Artistic-1.0-Perl":     {"hello"},
+		},
+	}
+	if !reflect.DeepEqual(inv, want) {
+		t.Fatalf("have %+v, want %+v", inv, want)
+	}
+	if flagged := inv.Flagged(); len(flagged) != 2 {
+		t.Fatalf("have %d flagged packages, want 2", len(flagged))
+	}
+
+	inv, err = copyright.ReadInventory(root, []string{"hello", "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv.Packages) != 2 || inv.Packages[1].Status != copyright.Missing {
+		t.Fatalf("have %+v, want hello and a missing none", inv.Packages)
+	}
+}
@@ -0,0 +1,207 @@
+// Package coverage computes how much of the content of Debian packages their
+// slices install.
+//
+// Only regular files and symlinks count: directories are implied by the
+// paths under them and chisel creates parents as needed.
+package coverage
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DebFiles returns the paths of the regular files and symlinks in the deb,
+// sorted. It reads the data archive with dpkg-deb(1).
+func DebFiles(deb string) ([]string, error) {
+	cmd := exec.Command("dpkg-deb", "--fsys-tarfile", deb)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w: %s", deb, err, strings.TrimSpace(stderr.String()))
+	}
+	var files []string
+	tr := tar.NewReader(bytes.NewReader(out))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", deb, err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeSymlink, tar.TypeLink:
+			files = append(files, path.Clean("/"+hdr.Name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// DebPackage returns the name of the package in the deb, or an empty string
+// if the file is not a deb.
+func DebPackage(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	magic := make([]byte, 8)
+	_, err = io.ReadFull(f, magic)
+	f.Close()
+	if err != nil || string(magic) != "!<arch>\n" {
+		return "", nil
+	}
+	out, err := exec.Command("dpkg-deb", "--field", p, "Package").Output()
+	if err != nil {
+		return "", nil
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// FindDebs returns the debs chisel fetched in its cache, by package name.
+// Each dir is the XDG_CACHE_HOME chisel ran with.
+func FindDebs(dirs ...string) (map[string]string, error) {
+	debs := make(map[string]string)
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "chisel", "sha256", "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if strings.HasSuffix(m, ".tmp") {
+				continue
+			}
+			pkg, err := DebPackage(m)
+			if err != nil {
+				return nil, err
+			}
+			if pkg != "" {
+				debs[pkg] = m
+			}
+		}
+	}
+	return debs, nil
+}
+
+// Package is the coverage of one package.
+type Package struct {
+	Name    string   `json:"name"`
+	Files   int      `json:"files"`
+	Covered int      `json:"covered"`
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Percent returns the percentage of the files of the package that is
+// covered. Packages without files are fully covered.
+func (p *Package) Percent() float64 {
+	return percent(p.Covered, p.Files)
+}
+
+func percent(covered, files int) float64 {
+	if files == 0 {
+		return 100
+	}
+	return 100 * float64(covered) / float64(files)
+}
+
+// Compute returns the coverage of the package with the given files in a root
+// its slices were installed into.
+func Compute(name string, files []string, root string) (*Package, error) {
+	p := &Package{Name: name, Files: len(files)}
+	for _, f := range files {
+		_, err := os.Lstat(filepath.Join(root, f))
+		if err == nil {
+			p.Covered++
+		} else if errors.Is(err, os.ErrNotExist) {
+			p.Missing = append(p.Missing, f)
+		} else {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Report is the coverage of a release.
+type Report struct {
+	Packages []*Package `json:"packages"`
+	Files    int        `json:"files"`
+	Covered  int        `json:"covered"`
+}
+
+// NewReport returns the report of the packages, sorted by name.
+func NewReport(pkgs []*Package) *Report {
+	r := &Report{Packages: append([]*Package{}, pkgs...)}
+	sort.Slice(r.Packages, func(i, j int) bool {
+		return r.Packages[i].Name < r.Packages[j].Name
+	})
+	for _, p := range r.Packages {
+		r.Files += p.Files
+		r.Covered += p.Covered
+	}
+	return r
+}
+
+// Percent returns the percentage of all package files that is covered.
+func (r *Report) Percent() float64 {
+	return percent(r.Covered, r.Files)
+}
+
+// A Threshold is the minimum coverage of a package, or of the whole release
+// if Package is empty.
+type Threshold struct {
+	Package string
+	Min     float64
+}
+
+// ParseThreshold parses "N%" or "N" for a release threshold and "pkg=N%"
+// for a package one.
+func ParseThreshold(s string) (*Threshold, error) {
+	t := &Threshold{}
+	value := s
+	if pkg, v, ok := strings.Cut(s, "="); ok {
+		if pkg == "" {
+			return nil, fmt.Errorf("invalid threshold %q: empty package name", s)
+		}
+		t.Package, value = pkg, v
+	}
+	min, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || min < 0 || min > 100 {
+		return nil, fmt.Errorf("invalid threshold %q: want a percentage between 0 and 100", s)
+	}
+	t.Min = min
+	return t, nil
+}
+
+// Check returns a message for every threshold the report falls below.
+func (r *Report) Check(thresholds []*Threshold) []string {
+	pkgs := make(map[string]*Package)
+	for _, p := range r.Packages {
+		pkgs[p.Name] = p
+	}
+	var failed []string
+	for _, t := range thresholds {
+		if t.Package == "" {
+			if have := r.Percent(); have < t.Min {
+				failed = append(failed, fmt.Sprintf("release coverage %.1f%% is below %g%%", have, t.Min))
+			}
+			continue
+		}
+		p, ok := pkgs[t.Package]
+		if !ok {
+			failed = append(failed, fmt.Sprintf("package %s has no coverage", t.Package))
+		} else if have := p.Percent(); have < t.Min {
+			failed = append(failed, fmt.Sprintf("package %s coverage %.1f%% is below %g%%", t.Package, have, t.Min))
+		}
+	}
+	return failed
+}
@@ -0,0 +1,180 @@
+package coverage_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/coverage"
+)
+
+// buildDeb builds a deb of the package with the given files and symlinks in
+// the cache dir layout chisel uses.
+func buildDeb(t *testing.T, cacheDir, pkg string, files []string, links map[string]string) {
+	if _, err := exec.LookPath("dpkg-deb"); err != nil {
+		t.Skip("dpkg-deb not installed")
+	}
+	tree := t.TempDir()
+	control := "Package: " + pkg + "\nVersion: 1.0\nArchitecture: all\nMaintainer: Test <test@example.com>\nDescription: test\n"
+	if err := os.MkdirAll(filepath.Join(tree, "DEBIAN"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tree, "DEBIAN/control"), []byte(control), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tree, f)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tree, f), []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for l, target := range links {
+		if err := os.Symlink(target, filepath.Join(tree, l)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := filepath.Join(cacheDir, "chisel", "sha256")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("dpkg-deb", "--root-owner-group", "--build", tree, filepath.Join(dir, pkg+"-digest")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("cannot build deb: %v: %s", err, out)
+	}
+}
+
+func TestDebFiles(t *testing.T) {
+	cacheDir := t.TempDir()
+	buildDeb(t, cacheDir, "hello", []string{"usr/bin/hello", "usr/share/doc/hello/copyright"}, map[string]string{
+		"usr/bin/hi": "hello",
+	})
+	// Other files chisel caches are not debs.
+	if err := os.WriteFile(filepath.Join(cacheDir, "chisel/sha256/index"), []byte("Package: hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	debs, err := coverage.FindDebs(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(debs) != 1 || debs["hello"] == "" {
+		t.Fatalf("have debs %v, want hello only", debs)
+	}
+	files, err := coverage.DebFiles(debs["hello"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/usr/bin/hello", "/usr/bin/hi", "/usr/share/doc/hello/copyright"}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("have files %q, want %q", files, want)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "usr/bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "usr/bin/hello"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("missing", filepath.Join(root, "usr/bin/hi")); err != nil {
+		t.Fatal(err)
+	}
+	p, err := coverage.Compute("hello", []string{"/usr/bin/hello", "/usr/bin/hi", "/usr/share/doc/hello/copyright", "/usr/share/man/man1/hello.1.gz"}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &coverage.Package{
+		Name:    "hello",
+		Files:   4,
+		Covered: 2,
+		Missing: []string{"/usr/share/doc/hello/copyright", "/usr/share/man/man1/hello.1.gz"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("have %+v, want %+v", p, want)
+	}
+	if p.Percent() != 50 {
+		t.Fatalf("have %v%%, want 50%%", p.Percent())
+	}
+}
+
+var thresholdTests = []struct {
+	summary string
+	input   string
+	want    *coverage.Threshold
+	err     string
+}{{
+	summary: "Release threshold",
+	input:   "80%",
+	want:    &coverage.Threshold{Min: 80},
+}, {
+	summary: "Release threshold without percent sign",
+	input:   "12.5",
+	want:    &coverage.Threshold{Min: 12.5},
+}, {
+	summary: "Package threshold",
+	input:   "hello=95%",
+	want:    &coverage.Threshold{Package: "hello", Min: 95},
+}, {
+	summary: "Out of range",
+	input:   "120%",
+	err:     `invalid threshold "120%": want a percentage between 0 and 100`,
+}, {
+	summary: "Empty package name",
+	input:   "=50%",
+	err:     `invalid threshold "=50%": empty package name`,
+}, {
+	summary: "Not a number",
+	input:   "hello=most",
+	err:     `invalid threshold "hello=most": want a percentage between 0 and 100`,
+}}
+
+func TestParseThreshold(t *testing.T) {
+	for _, test := range thresholdTests {
+		t.Logf("Summary: %s", test.summary)
+		have, err := coverage.ParseThreshold(test.input)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("have error %v, want %s", err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(have, test.want) {
+			t.Fatalf("have %+v, want %+v", have, test.want)
+		}
+	}
+}
+
+func TestReportCheck(t *testing.T) {
+	r := coverage.NewReport([]*coverage.Package{
+		{Name: "libc6", Files: 10, Covered: 9},
+		{Name: "hello", Files: 4, Covered: 1},
+		{Name: "base-files", Files: 0},
+	})
+	if r.Packages[0].Name != "base-files" || r.Files != 14 || r.Covered != 10 {
+		t.Fatalf("have report %+v", r)
+	}
+	failed := r.Check([]*coverage.Threshold{
+		{Min: 70},
+		{Min: 75},
+		{Package: "libc6", Min: 90},
+		{Package: "hello", Min: 50},
+		{Package: "base-files", Min: 100},
+		{Package: "openssl", Min: 10},
+	})
+	want := []string{
+		"release coverage 71.4% is below 75%",
+		"package hello coverage 25.0% is below 50%",
+		"package openssl has no coverage",
+	}
+	if !reflect.DeepEqual(failed, want) {
+		t.Fatalf("have %q, want %q", failed, want)
+	}
+}
@@ -0,0 +1,116 @@
+// Package debversion compares Debian package versions the way dpkg does.
+// See deb-version(7).
+package debversion
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Version struct {
+	Epoch    int
+	Upstream string
+	Revision string
+}
+
+// Parse a version string of the form [epoch:]upstream[-revision]. Invalid
+// epochs are treated as part of the upstream version, like dpkg leniently
+// does for comparisons.
+func Parse(s string) Version {
+	var v Version
+	s = strings.TrimSpace(s)
+	if e, rest, ok := strings.Cut(s, ":"); ok {
+		if n, err := strconv.Atoi(e); err == nil {
+			v.Epoch = n
+			s = rest
+		}
+	}
+	if i := strings.LastIndex(s, "-"); i >= 0 {
+		v.Upstream, v.Revision = s[:i], s[i+1:]
+	} else {
+		v.Upstream = s
+	}
+	return v
+}
+
+// Compare returns -1, 0 or 1 if version a is respectively lower than, equal
+// to or greater than version b.
+func Compare(a, b string) int {
+	va, vb := Parse(a), Parse(b)
+	if va.Epoch != vb.Epoch {
+		if va.Epoch < vb.Epoch {
+			return -1
+		}
+		return 1
+	}
+	if c := compareString(va.Upstream, vb.Upstream); c != 0 {
+		return c
+	}
+	return compareString(va.Revision, vb.Revision)
+}
+
+// order returns the weight of a character in the non-digit parts. Letters
+// sort before non-letters and "~" sorts before everything, even the end.
+func order(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return 0
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func compareString(a, b string) int {
+	for a != "" || b != "" {
+		// Compare the non-digit prefixes.
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			var ca, cb int
+			if a != "" {
+				ca = order(a[0])
+			}
+			if b != "" {
+				cb = order(b[0])
+			}
+			if ca != cb {
+				if ca < cb {
+					return -1
+				}
+				return 1
+			}
+			a, b = a[1:], b[1:]
+		}
+		// Compare the numeric prefixes.
+		for a != "" && a[0] == '0' {
+			a = a[1:]
+		}
+		for b != "" && b[0] == '0' {
+			b = b[1:]
+		}
+		i, j := 0, 0
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		if i != j {
+			if i < j {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(a[:i], b[:j]); c != 0 {
+			return c
+		}
+		a, b = a[i:], b[j:]
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package debversion_test
+
+import (
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/debversion"
+)
+
+var compareTests = []struct {
+	a, b string
+	res  int
+}{
+	{"1.0", "1.0", 0},
+	{"1.0", "1.1", -1},
+	{"1.10", "1.9", 1},
+	{"1.0-1", "1.0-2", -1},
+	{"1:1.0", "2.0", 1},
+	{"0:1.0", "1.0", 0},
+	{"1.0~rc1", "1.0", -1},
+	{"1.0~rc1", "1.0~rc2", -1},
+	{"1.0~~", "1.0~", -1},
+	{"1.0a", "1.0", 1},
+	{"1.0a", "1.0+", -1},
+	{"1.0.1", "1.0a", 1},
+	{"2.39-0ubuntu8", "2.39-0ubuntu8.3", -1},
+	{"2.39-0ubuntu8.3", "2.39-0ubuntu8.10", -1},
+	{"1.001", "1.1", 0},
+	{"3.0.13-0ubuntu3.1", "3.0.13-0ubuntu3", 1},
+	{"1.2.3-1-2", "1.2.3-1-10", -1},
+}
+
+func TestCompare(t *testing.T) {
+	for _, tc := range compareTests {
+		if res := debversion.Compare(tc.a, tc.b); res != tc.res {
+			t.Fatalf("Compare(%q, %q): have %d, want %d", tc.a, tc.b, res, tc.res)
+		}
+		if res := debversion.Compare(tc.b, tc.a); res != -tc.res {
+			t.Fatalf("Compare(%q, %q): have %d, want %d", tc.b, tc.a, res, -tc.res)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package doctor checks the environment sdf runs in for the programs and
+// resources the commands need, and suggests fixes for what is missing.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/cache"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type Status int
+
+const (
+	OK Status = iota
+	// Warning is for what only some commands need.
+	Warning
+	Failure
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Warning:
+		return "warning"
+	}
+	return "failure"
+}
+
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// A Result is the outcome of a check, with a suggested fix unless it is OK.
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// Tool checks that the program is on the PATH. The status is the one to
+// report if it is not, along with the fix.
+func Tool(name, purpose string, status Status, fix string) *Result {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return &Result{
+			Name:    name,
+			Status:  status,
+			Message: fmt.Sprintf("not found, needed %s", purpose),
+			Fix:     fix,
+		}
+	}
+	return &Result{Name: name, Status: OK, Message: path}
+}
+
+// Chisel checks that chisel is on the PATH and, if format is not empty, that
+// it supports the release format.
+func Chisel(ctx context.Context, format string) *Result {
+	r := &Result{Name: "chisel"}
+	path, err := exec.LookPath("chisel")
+	if err != nil {
+		r.Status = Failure
+		r.Message = "not found, needed to install slices"
+		r.Fix = "install chisel with \"snap install chisel\" or \"go install github.com/canonical/chisel/cmd/chisel@latest\", and add it to the PATH"
+		return r
+	}
+	out, err := exec.CommandContext(ctx, path, "version").Output()
+	if err != nil {
+		r.Status = Failure
+		r.Message = fmt.Sprintf("%s version failed: %v", path, err)
+		r.Fix = "reinstall chisel"
+		return r
+	}
+	version, err := chisel.ParseVersion(string(out))
+	if err != nil {
+		r.Status = Failure
+		r.Message = fmt.Sprintf("%s: %v", path, err)
+		r.Fix = "reinstall chisel"
+		return r
+	}
+	r.Message = fmt.Sprintf("%s %s", path, version)
+	if format == "" {
+		return r
+	}
+	if err := chisel.CheckFormat(format, version); err != nil {
+		r.Status = Failure
+		r.Message += ": " + err.Error()
+		if since, ok := chisel.FormatSince[format]; ok {
+			r.Fix = fmt.Sprintf("upgrade chisel to %s or later", since)
+		}
+		return r
+	}
+	r.Message += fmt.Sprintf(", supports format %s", format)
+	return r
+}
+
+// ContainerEngine checks that podman or docker can run containers, which
+// service tests need.
+func ContainerEngine(ctx context.Context) *Result {
+	r := &Result{Name: "container engine"}
+	var problems []string
+	for _, engine := range []string{"podman", "docker"} {
+		path, err := exec.LookPath(engine)
+		if err != nil {
+			continue
+		}
+		out, err := exec.CommandContext(ctx, path, "info").CombinedOutput()
+		if err == nil {
+			r.Message = path
+			return r
+		}
+		msg := strings.TrimSpace(string(out))
+		if i := strings.IndexByte(msg, '\n'); i >= 0 {
+			msg = msg[:i]
+		}
+		problems = append(problems, fmt.Sprintf("%s info failed: %s", engine, msg))
+	}
+	r.Status = Warning
+	if len(problems) == 0 {
+		r.Message = "neither podman nor docker found, needed for service tests"
+		r.Fix = "install podman with \"apt install podman\""
+	} else {
+		r.Message = strings.Join(problems, "; ")
+		r.Fix = "check that the engine runs for the current user, e.g. that the docker daemon is started and the user is in the docker group"
+	}
+	return r
+}
+
+// FreeSpace checks that the directory has at least min bytes available.
+func FreeSpace(dir string, min uint64) *Result {
+	r := &Result{Name: "free space"}
+	free, err := freeSpace(dir)
+	if err != nil {
+		r.Status = Warning
+		r.Message = fmt.Sprintf("cannot get free space of %s: %v", dir, err)
+		return r
+	}
+	r.Message = fmt.Sprintf("%s available in %s", cache.FormatSize(int64(free)), dir)
+	if free < min {
+		r.Status = Failure
+		r.Message += fmt.Sprintf(", want at least %s", cache.FormatSize(int64(min)))
+		r.Fix = "free some space, e.g. with \"sdf cache clean\", or point TMPDIR to a larger file system"
+	}
+	return r
+}
+
+// Archive checks that the InRelease file of the suite can be fetched from
+// the archive at the base URL.
+func Archive(ctx context.Context, client *http.Client, name, baseURL, suite string) *Result {
+	r := &Result{Name: fmt.Sprintf("archive %s (%s)", name, suite)}
+	u := strings.TrimSuffix(baseURL, "/") + "/dists/" + suite + "/InRelease"
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		r.Status = Failure
+		r.Message = err.Error()
+		return r
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		r.Status = Failure
+		r.Message = fmt.Sprintf("cannot reach %s: %v", u, err)
+		r.Fix = "check the network connection, and set HTTP_PROXY and HTTPS_PROXY if a proxy is needed"
+		return r
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		r.Status = Failure
+		r.Message = fmt.Sprintf("cannot fetch %s: %s", u, resp.Status)
+		r.Fix = fmt.Sprintf("check that suite %s exists in the archive", suite)
+		return r
+	}
+	r.Message = u
+	return r
+}
@@ -0,0 +1,170 @@
+package doctor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/doctor"
+)
+
+// fakeTools puts scripts with the given bodies first in the PATH, and
+// nothing else.
+func fakeTools(t *testing.T, tools map[string]string) string {
+	dir := t.TempDir()
+	for name, body := range tools {
+		script := "#!/bin/sh\n" + body + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+var chiselTests = []struct {
+	summary string
+	script  string
+	format  string
+	status  doctor.Status
+	message string
+	fix     string
+}{{
+	summary: "Not found",
+	status:  doctor.Failure,
+	message: "not found, needed to install slices",
+	fix:     "install chisel",
+}, {
+	summary: "Version only",
+	script:  "echo v1.1.0",
+	status:  doctor.OK,
+	message: "$DIR/chisel v1.1.0",
+}, {
+	summary: "Supported format",
+	script:  "echo v1.2.0",
+	format:  "v3",
+	status:  doctor.OK,
+	message: "$DIR/chisel v1.2.0, supports format v3",
+}, {
+	summary: "Unsupported format",
+	script:  "echo v1.1.0",
+	format:  "v3",
+	status:  doctor.Failure,
+	message: "$DIR/chisel v1.1.0: format v3 requires chisel v1.2.0 or later, have v1.1.0",
+	fix:     "upgrade chisel to v1.2.0 or later",
+}, {
+	summary: "Broken binary",
+	script:  "exit 1",
+	status:  doctor.Failure,
+	message: "$DIR/chisel version failed: exit status 1",
+	fix:     "reinstall chisel",
+}}
+
+func TestChisel(t *testing.T) {
+	for _, test := range chiselTests {
+		t.Logf("Summary: %s", test.summary)
+		tools := map[string]string{}
+		if test.script != "" {
+			tools["chisel"] = test.script
+		}
+		dir := fakeTools(t, tools)
+		r := doctor.Chisel(context.Background(), test.format)
+		message := strings.ReplaceAll(test.message, "$DIR", dir)
+		if r.Status != test.status || r.Message != message || !strings.HasPrefix(r.Fix, test.fix) {
+			t.Fatalf("have %+v, want status %v, message %q and fix %q", r, test.status, message, test.fix)
+		}
+		if test.status != doctor.OK && r.Fix == "" {
+			t.Fatalf("no fix for %+v", r)
+		}
+	}
+}
+
+func TestTool(t *testing.T) {
+	dir := fakeTools(t, map[string]string{"git": "true"})
+	r := doctor.Tool("git", "to bisect", doctor.Failure, "install git")
+	if r.Status != doctor.OK || r.Message != filepath.Join(dir, "git") {
+		t.Fatalf("have %+v, want git found", r)
+	}
+	r = doctor.Tool("zstd", "to read manifests", doctor.Warning, "install zstd")
+	if r.Status != doctor.Warning || r.Message != "not found, needed to read manifests" || r.Fix != "install zstd" {
+		t.Fatalf("have %+v, want zstd missing", r)
+	}
+}
+
+var containerEngineTests = []struct {
+	summary string
+	tools   map[string]string
+	status  doctor.Status
+	message string
+}{{
+	summary: "None",
+	status:  doctor.Warning,
+	message: "neither podman nor docker found, needed for service tests",
+}, {
+	summary: "Podman",
+	tools:   map[string]string{"podman": "true", "docker": "exit 1"},
+	status:  doctor.OK,
+	message: "$DIR/podman",
+}, {
+	summary: "Docker when podman fails",
+	tools:   map[string]string{"podman": "echo no runtime; exit 1", "docker": "true"},
+	status:  doctor.OK,
+	message: "$DIR/docker",
+}, {
+	summary: "Docker daemon not running",
+	tools:   map[string]string{"docker": "echo Cannot connect to the Docker daemon; echo more; exit 1"},
+	status:  doctor.Warning,
+	message: "docker info failed: Cannot connect to the Docker daemon",
+}}
+
+func TestContainerEngine(t *testing.T) {
+	for _, test := range containerEngineTests {
+		t.Logf("Summary: %s", test.summary)
+		dir := fakeTools(t, test.tools)
+		r := doctor.ContainerEngine(context.Background())
+		message := strings.ReplaceAll(test.message, "$DIR", dir)
+		if r.Status != test.status || r.Message != message {
+			t.Fatalf("have %+v, want status %v and message %q", r, test.status, message)
+		}
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	dir := t.TempDir()
+	if r := doctor.FreeSpace(dir, 1); r.Status != doctor.OK {
+		t.Fatalf("have %+v, want some free space", r)
+	}
+	r := doctor.FreeSpace(dir, 1<<62)
+	if r.Status != doctor.Failure || !strings.HasSuffix(r.Message, "want at least 4.0 EiB") || r.Fix == "" {
+		t.Fatalf("have %+v, want not enough space", r)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ubuntu/dists/noble/InRelease" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	r := doctor.Archive(ctx, srv.Client(), "ubuntu", srv.URL+"/ubuntu/", "noble")
+	if r.Status != doctor.OK || r.Name != "archive ubuntu (noble)" {
+		t.Fatalf("have %+v, want the archive reachable", r)
+	}
+	r = doctor.Archive(ctx, srv.Client(), "ubuntu", srv.URL+"/ubuntu", "nope")
+	if want := "cannot fetch " + srv.URL + "/ubuntu/dists/nope/InRelease: 404 Not Found"; r.Status != doctor.Failure || r.Message != want {
+		t.Fatalf("have %+v, want message %q", r, want)
+	}
+	url := srv.URL
+	srv.Close()
+	r = doctor.Archive(ctx, http.DefaultClient, "ubuntu", url, "noble")
+	if r.Status != doctor.Failure || !strings.HasPrefix(r.Message, "cannot reach ") || r.Fix == "" {
+		t.Fatalf("have %+v, want the archive unreachable", r)
+	}
+}
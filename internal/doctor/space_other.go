@@ -0,0 +1,9 @@
+//go:build !unix
+
+package doctor
+
+import "errors"
+
+func freeSpace(dir string) (uint64, error) {
+	return 0, errors.New("not supported on this platform")
+}
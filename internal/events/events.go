@@ -0,0 +1,206 @@
+// Package events lets tool integrations follow what a command is doing
+// without parsing its logs.
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+type Type string
+
+const (
+	TaskStarted  Type = "task-started"
+	TaskFinished Type = "task-finished"
+	LintFinding  Type = "lint-finding"
+	RunCompleted Type = "run-completed"
+)
+
+type Event struct {
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+// Data of the [TaskStarted] and [TaskFinished] events.
+type Task struct {
+	Slices []string `json:"slices"`
+	Arch   string   `json:"arch,omitempty"`
+	// Duration is written in JSON as duration_seconds, once finished.
+	Duration time.Duration `json:"-"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func (t *Task) MarshalJSON() ([]byte, error) {
+	type task Task
+	return json.Marshal(&struct {
+		*task
+		Duration float64 `json:"duration_seconds,omitempty"`
+	}{(*task)(t), t.Duration.Seconds()})
+}
+
+// Data of the [LintFinding] event.
+type Finding struct {
+	Check   string `json:"check"`
+	File    string `json:"file,omitempty"`
+	Slice   string `json:"slice,omitempty"`
+	Message string `json:"message"`
+}
+
+// Data of the [RunCompleted] event.
+type Run struct {
+	Command  string `json:"command"`
+	Tasks    int    `json:"tasks"`
+	Failed   int    `json:"failed"`
+	Findings int    `json:"findings,omitempty"`
+	// Duration is written in JSON as duration_seconds.
+	Duration time.Duration `json:"-"`
+}
+
+func (r *Run) MarshalJSON() ([]byte, error) {
+	type run Run
+	return json.Marshal(&struct {
+		*run
+		Duration float64 `json:"duration_seconds"`
+	}{(*run)(r), r.Duration.Seconds()})
+}
+
+type Handler func(e *Event)
+
+// A Bus delivers the published events to all of its subscribers. Handlers
+// are called synchronously, in the order they subscribed, and may be called
+// from multiple goroutines.
+//
+// A nil *Bus is valid and discards all events.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+func (b *Bus) Publish(t Type, data any) {
+	if b == nil {
+		return
+	}
+	e := &Event{Type: t, Time: time.Now(), Data: data}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		h(e)
+	}
+}
+
+// A Hook runs an executable for every event. The event is written as JSON
+// to the standard input of the process and its type is set in the SDF_EVENT
+// environment variable. Failures are logged but otherwise ignored, as hooks
+// must not break the command they observe.
+//
+// The events are delivered one at a time, in the order they were handled, by
+// a goroutine of the hook, so that slow hooks do not hold up the publisher.
+type Hook struct {
+	path    string
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending []*Event
+	wake    chan struct{}
+	closed  bool
+	done    chan struct{}
+}
+
+// NewHook returns a hook running the executable at path, killed if it takes
+// longer than the timeout, if not zero.
+func NewHook(path string, timeout time.Duration) *Hook {
+	h := &Hook{
+		path:    path,
+		timeout: timeout,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go h.loop()
+	return h
+}
+
+// Handle queues the event for the hook. Events handled once the hook is
+// closed are dropped.
+func (h *Hook) Handle(e *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.pending = append(h.pending, e)
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close waits for the queued events to be delivered and stops the hook.
+func (h *Hook) Close() {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.wake)
+	}
+	h.mu.Unlock()
+	<-h.done
+}
+
+func (h *Hook) loop() {
+	defer close(h.done)
+	for range h.wake {
+		for {
+			h.mu.Lock()
+			if len(h.pending) == 0 {
+				h.mu.Unlock()
+				break
+			}
+			e := h.pending[0]
+			h.pending = h.pending[1:]
+			h.mu.Unlock()
+			h.run(e)
+		}
+	}
+	// The events queued along with the close.
+	for _, e := range h.pending {
+		h.run(e)
+	}
+}
+
+func (h *Hook) run(e *Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("cannot encode %s event: %s", e.Type, err)
+		return
+	}
+	ctx := context.Background()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, h.path)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(), "SDF_EVENT="+string(e.Type))
+	// The output of processes the hook started must not keep it waiting.
+	cmd.WaitDelay = time.Second
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("timed out after %s", h.timeout)
+		}
+		log.Printf("hook %s failed on %s event: %s\n%s", h.path, e.Type, err, out)
+	}
+}
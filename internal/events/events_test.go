@@ -0,0 +1,147 @@
+package events_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/events"
+)
+
+func TestBus(t *testing.T) {
+	var got []events.Type
+	bus := &events.Bus{}
+	bus.Subscribe(func(e *events.Event) {
+		got = append(got, e.Type)
+	})
+	bus.Subscribe(func(e *events.Event) {
+		got = append(got, e.Type+"-again")
+	})
+	bus.Publish(events.TaskStarted, nil)
+	bus.Publish(events.RunCompleted, &events.Run{})
+	want := []events.Type{"task-started", "task-started-again", "run-completed", "run-completed-again"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("have %v, want %v", got, want)
+	}
+
+	// A nil bus discards events.
+	var nilBus *events.Bus
+	nilBus.Publish(events.TaskStarted, nil)
+}
+
+var marshalTests = []struct {
+	summary string
+	data    any
+	json    string
+}{{
+	summary: "Task started",
+	data:    &events.Task{Slices: []string{"hello_bins"}, Arch: "amd64"},
+	json:    `{"slices":["hello_bins"],"arch":"amd64"}`,
+}, {
+	summary: "Task finished",
+	data:    &events.Task{Slices: []string{"hello_bins"}, Duration: 1500 * time.Millisecond, Error: "failed"},
+	json:    `{"slices":["hello_bins"],"error":"failed","duration_seconds":1.5}`,
+}, {
+	summary: "Run completed",
+	data:    &events.Run{Command: "install", Tasks: 2, Failed: 1, Duration: 90 * time.Second},
+	json:    `{"command":"install","tasks":2,"failed":1,"duration_seconds":90}`,
+}}
+
+func TestMarshalJSON(t *testing.T) {
+	for _, test := range marshalTests {
+		t.Logf("Summary: %s", test.summary)
+		data, err := json.Marshal(test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.json {
+			t.Fatalf("have %s, want %s", data, test.json)
+		}
+	}
+}
+
+func TestHook(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	hook := filepath.Join(dir, "hook")
+	script := "#!/bin/sh\necho \"$SDF_EVENT\" > " + out + ".type\ncat > " + out + "\n"
+	if err := os.WriteFile(hook, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	h := events.NewHook(hook, time.Minute)
+	bus := &events.Bus{}
+	bus.Subscribe(h.Handle)
+	bus.Publish(events.TaskFinished, &events.Task{
+		Slices:   []string{"hello_bins"},
+		Arch:     "amd64",
+		Duration: 2 * time.Second,
+		Error:    "failed",
+	})
+	h.Close()
+
+	typ, err := os.ReadFile(out + ".type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(typ) != "task-finished\n" {
+		t.Fatalf("have SDF_EVENT %q, want %q", typ, "task-finished\n")
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e struct {
+		Type string
+		Data struct {
+			events.Task
+			DurationSeconds float64 `json:"duration_seconds"`
+		}
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	want := events.Task{Slices: []string{"hello_bins"}, Arch: "amd64", Error: "failed"}
+	if e.Type != "task-finished" || !reflect.DeepEqual(e.Data.Task, want) || e.Data.DurationSeconds != 2 {
+		t.Fatalf("have event %s, want task-finished with %+v", data, want)
+	}
+}
+
+func TestHookOrderAndTimeout(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	hook := filepath.Join(dir, "hook")
+	script := "#!/bin/sh\necho \"$SDF_EVENT\" >> " + out + "\n[ \"$SDF_EVENT\" != task-started ] || exec sleep 60\n"
+	if err := os.WriteFile(hook, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The hook is killed on the first event, which must not hold up the
+	// publisher nor delay the next events past the timeout.
+	h := events.NewHook(hook, 100*time.Millisecond)
+	start := time.Now()
+	h.Handle(&events.Event{Type: events.TaskStarted})
+	h.Handle(&events.Event{Type: events.TaskFinished})
+	h.Handle(&events.Event{Type: events.RunCompleted})
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Fatalf("handling the events took %s", d)
+	}
+	h.Close()
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("the hook was not killed: closing took %s", d)
+	}
+	// Events handled once closed are dropped.
+	h.Handle(&events.Event{Type: events.LintFinding})
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "task-started\ntask-finished\nrun-completed\n"
+	if string(data) != want {
+		t.Fatalf("have events %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,102 @@
+package fixtures
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Deb returns the content of a deb holding the files and symlinks of the
+// package. Parent directories are added as needed, and all entries are owned
+// by root with a zero timestamp so that the same package gives the same
+// deb.
+func Deb(p *Package) ([]byte, error) {
+	control := fmt.Sprintf("Package: %s\nVersion: %s\nArchitecture: %s\nMaintainer: sdf fixtures <sdf@example.com>\nDescription: %s fixture package\n",
+		p.Name, p.Version, p.Arch, p.Name)
+	controlTar, err := tarGz(map[string]string{"control": control}, nil)
+	if err != nil {
+		return nil, err
+	}
+	dataTar, err := tarGz(p.Files, p.Links)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("!<arch>\n")
+	for _, m := range []struct {
+		name string
+		data []byte
+	}{
+		{"debian-binary", []byte("2.0\n")},
+		{"control.tar.gz", controlTar},
+		{"data.tar.gz", dataTar},
+	} {
+		fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8o%-10d`\n", m.name, 0, 0, 0, 0644, len(m.data))
+		buf.Write(m.data)
+		if len(m.data)%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// tarGz returns a gzipped tarball of the files and symlinks, with paths
+// relative to "./" as dpkg-deb writes them.
+func tarGz(files, links map[string]string) ([]byte, error) {
+	dirs := make(map[string]bool)
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	for p := range links {
+		paths = append(paths, p)
+	}
+	for _, p := range paths {
+		for d := path.Dir(strings.TrimPrefix(p, "/")); d != "."; d = path.Dir(d) {
+			dirs[d] = true
+		}
+	}
+	for d := range dirs {
+		paths = append(paths, d+"/")
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		name := "./" + strings.TrimPrefix(p, "/")
+		var err error
+		if content, ok := files[p]; ok {
+			mode := int64(0644)
+			if dir := path.Base(path.Dir(p)); dir == "bin" || dir == "sbin" {
+				mode = 0755
+			}
+			err = tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: mode, Size: int64(len(content))})
+			if err == nil {
+				_, err = tw.Write([]byte(content))
+			}
+		} else if target, ok := links[p]; ok {
+			err = tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeSymlink, Mode: 0777, Linkname: target})
+		} else {
+			err = tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
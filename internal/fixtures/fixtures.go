@@ -0,0 +1,384 @@
+// Package fixtures generates hermetic test environments for chisel
+// releases: tiny debs, an archive holding them, a release slicing them and a
+// stub chisel that installs the slices without any network access.
+//
+// The layout of a fixture directory is:
+//
+//	archive/   dists/<suite>/Release, the Packages index and pool/ with the debs
+//	cache/     the archive files by SHA256 digest, as chisel caches them
+//	release/   chisel.yaml and slices/<package>.yaml
+//	slices/    the files of each slice, along with its essential slices
+//	bin/chisel the stub chisel
+//
+// The stub supports "chisel version" and "chisel cut". A cut copies the
+// files of the slices into the root and the archive files into the chisel
+// cache in $XDG_CACHE_HOME, so that the commands reading either work on
+// fixtures as they do on real installs.
+package fixtures
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Package struct {
+	Name    string
+	Version string
+	// Architecture, the one of the fixture if empty.
+	Arch string
+	// File contents by absolute path.
+	Files map[string]string
+	// Symlink targets by absolute path.
+	Links  map[string]string
+	Slices map[string]*Slice
+}
+
+type Slice struct {
+	// Full names of the slices this one needs, e.g. "libc6_libs".
+	Essential []string
+	// Paths of the package the slice installs.
+	Contents []string
+}
+
+type Options struct {
+	// Defaults to "noble".
+	Suite string
+	// Defaults to "amd64".
+	Arch string
+	// Version "chisel version" reports, defaults to "v1.1.0".
+	ChiselVersion string
+	// Defaults to [DefaultPackages].
+	Packages []*Package
+	// Slices the stub fails to install.
+	Fail []string
+}
+
+// DefaultPackages returns a program package depending on a library package,
+// both with copyright files and content that no slice installs.
+func DefaultPackages() []*Package {
+	return []*Package{{
+		Name:    "hello",
+		Version: "2.10-3",
+		Files: map[string]string{
+			"/usr/bin/hello":                 "#!/bin/sh\necho 'Hello, world!'\n",
+			"/usr/share/doc/hello/copyright": "Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n\nFiles: *\nLicense: GPL-3+\n",
+			"/usr/share/man/man1/hello.1.gz": "hello(1)\n",
+		},
+		Links: map[string]string{
+			"/usr/bin/hi": "hello",
+		},
+		Slices: map[string]*Slice{
+			"bins": {
+				Essential: []string{"hello_copyright", "libhello1_libs"},
+				Contents:  []string{"/usr/bin/hello", "/usr/bin/hi"},
+			},
+			"copyright": {
+				Contents: []string{"/usr/share/doc/hello/copyright"},
+			},
+		},
+	}, {
+		Name:    "libhello1",
+		Version: "1.0-1",
+		Files: map[string]string{
+			"/usr/lib/libhello.so.1.0":           "libhello\n",
+			"/usr/share/doc/libhello1/copyright": "Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n\nFiles: *\nLicense: MIT\n",
+		},
+		Links: map[string]string{
+			"/usr/lib/libhello.so.1": "libhello.so.1.0",
+		},
+		Slices: map[string]*Slice{
+			"libs": {
+				Essential: []string{"libhello1_copyright"},
+				Contents:  []string{"/usr/lib/libhello.so.1", "/usr/lib/libhello.so.1.0"},
+			},
+			"copyright": {
+				Contents: []string{"/usr/share/doc/libhello1/copyright"},
+			},
+		},
+	}}
+}
+
+// A Fixture is a generated test environment, see the package documentation.
+type Fixture struct {
+	Dir     string
+	Archive string
+	Cache   string
+	Release string
+	// Path of the stub chisel.
+	Chisel string
+}
+
+// Write generates a fixture in dir.
+func Write(dir string, opts *Options) (*Fixture, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Suite == "" {
+		o.Suite = "noble"
+	}
+	if o.Arch == "" {
+		o.Arch = "amd64"
+	}
+	if o.ChiselVersion == "" {
+		o.ChiselVersion = "v1.1.0"
+	}
+	if o.Packages == nil {
+		o.Packages = DefaultPackages()
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fixture{
+		Dir:     dir,
+		Archive: filepath.Join(dir, "archive"),
+		Cache:   filepath.Join(dir, "cache"),
+		Release: filepath.Join(dir, "release"),
+		Chisel:  filepath.Join(dir, "bin", "chisel"),
+	}
+	if err := f.writeArchive(&o); err != nil {
+		return nil, fmt.Errorf("cannot write archive: %w", err)
+	}
+	if err := f.writeRelease(&o); err != nil {
+		return nil, fmt.Errorf("cannot write release: %w", err)
+	}
+	if err := f.writeSlices(&o); err != nil {
+		return nil, fmt.Errorf("cannot write slices: %w", err)
+	}
+	if err := writeFile(f.Chisel, []byte(stubScript(f.Dir, &o)), 0755); err != nil {
+		return nil, fmt.Errorf("cannot write stub chisel: %w", err)
+	}
+	return f, nil
+}
+
+func writeFile(p string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, perm)
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// put writes the archive file and its copy in the cache.
+func (f *Fixture) put(rel string, data []byte) error {
+	if err := writeFile(filepath.Join(f.Archive, rel), data, 0644); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(f.Cache, digest(data)), data, 0644)
+}
+
+func (f *Fixture) writeArchive(o *Options) error {
+	var index bytes.Buffer
+	for _, p := range o.Packages {
+		arch := p.Arch
+		if arch == "" {
+			arch = o.Arch
+		}
+		pkg := *p
+		pkg.Arch = arch
+		deb, err := Deb(&pkg)
+		if err != nil {
+			return err
+		}
+		filename := path.Join("pool/main", p.Name[:1], p.Name, fmt.Sprintf("%s_%s_%s.deb", p.Name, p.Version, arch))
+		if err := f.put(filename, deb); err != nil {
+			return err
+		}
+		fmt.Fprintf(&index, "Package: %s\nVersion: %s\nArchitecture: %s\nFilename: %s\nSize: %d\nSHA256: %s\n\n",
+			p.Name, p.Version, arch, filename, len(deb), digest(deb))
+	}
+
+	indexPath := fmt.Sprintf("main/binary-%s/Packages", o.Arch)
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(index.Bytes())
+	if err := w.Close(); err != nil {
+		return err
+	}
+	indexes := map[string][]byte{
+		indexPath:         index.Bytes(),
+		indexPath + ".gz": gz.Bytes(),
+	}
+	var release bytes.Buffer
+	fmt.Fprintf(&release, "Origin: sdf\nLabel: sdf\nSuite: %s\nCodename: %s\nArchitectures: %s\nComponents: main\nSHA256:\n", o.Suite, o.Suite, o.Arch)
+	for _, p := range []string{indexPath, indexPath + ".gz"} {
+		fmt.Fprintf(&release, " %s %d %s\n", digest(indexes[p]), len(indexes[p]), p)
+		if err := f.put(path.Join("dists", o.Suite, p), indexes[p]); err != nil {
+			return err
+		}
+	}
+	return f.put(path.Join("dists", o.Suite, "Release"), release.Bytes())
+}
+
+type sliceDef struct {
+	Package   string                   `yaml:"package"`
+	Essential []string                 `yaml:"essential,omitempty"`
+	Slices    map[string]sliceContents `yaml:"slices"`
+}
+
+type sliceContents struct {
+	Essential []string            `yaml:"essential,omitempty"`
+	Contents  map[string]struct{} `yaml:"contents"`
+}
+
+func (f *Fixture) writeRelease(o *Options) error {
+	config := fmt.Sprintf("format: v1\narchives:\n  ubuntu:\n    version: \"24.04\"\n    suites: [%s]\n    components: [main]\n", o.Suite)
+	if err := writeFile(filepath.Join(f.Release, "chisel.yaml"), []byte(config), 0644); err != nil {
+		return err
+	}
+	for _, p := range o.Packages {
+		def := sliceDef{Package: p.Name, Slices: make(map[string]sliceContents)}
+		for name, s := range p.Slices {
+			c := sliceContents{Essential: s.Essential, Contents: make(map[string]struct{})}
+			for _, path := range s.Contents {
+				c.Contents[path] = struct{}{}
+			}
+			def.Slices[name] = c
+		}
+		data, err := yaml.Marshal(&def)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(f.Release, "slices", p.Name+".yaml"), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSlices writes the files every slice installs, essential slices
+// included, where the stub copies them from.
+func (f *Fixture) writeSlices(o *Options) error {
+	pkgs := make(map[string]*Package)
+	for _, p := range o.Packages {
+		pkgs[p.Name] = p
+	}
+	for _, p := range o.Packages {
+		for name := range p.Slices {
+			full := p.Name + "_" + name
+			dir := filepath.Join(f.Dir, "slices", full)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			seen := make(map[string]bool)
+			todo := []string{full}
+			for len(todo) > 0 {
+				cur := todo[0]
+				todo = todo[1:]
+				if seen[cur] {
+					continue
+				}
+				seen[cur] = true
+				pkgName, sliceName, _ := strings.Cut(cur, "_")
+				pkg := pkgs[pkgName]
+				if pkg == nil || pkg.Slices[sliceName] == nil {
+					return fmt.Errorf("slice %s needs undefined slice %s", full, cur)
+				}
+				s := pkg.Slices[sliceName]
+				todo = append(todo, s.Essential...)
+				for _, p := range s.Contents {
+					var err error
+					if target, ok := pkg.Links[p]; ok {
+						err = os.MkdirAll(filepath.Dir(filepath.Join(dir, p)), 0755)
+						if err == nil {
+							os.Remove(filepath.Join(dir, p))
+							err = os.Symlink(target, filepath.Join(dir, p))
+						}
+					} else if content, ok := pkg.Files[p]; ok {
+						perm := os.FileMode(0644)
+						if d := path.Base(path.Dir(p)); d == "bin" || d == "sbin" {
+							perm = 0755
+						}
+						err = writeFile(filepath.Join(dir, p), []byte(content), perm)
+					} else {
+						err = fmt.Errorf("slice %s has path %s not in package %s", cur, p, pkgName)
+					}
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stubScript returns the stub chisel for the fixture in dir.
+func stubScript(dir string, o *Options) string {
+	fail := append([]string{}, o.Fail...)
+	sort.Strings(fail)
+	return fmt.Sprintf(`#!/bin/sh
+# Stub chisel generated by sdf fixtures. It installs the pre-extracted files
+# of the slices of the fixture in %[1]s.
+set -e
+fixture=%[3]s
+fail=%[4]s
+case "$1" in
+version)
+	echo %[2]s
+	exit 0
+	;;
+cut)
+	shift
+	;;
+*)
+	echo "error: stub chisel does not support command $1" >&2
+	exit 2
+	;;
+esac
+root=
+slices=
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--root) root=$2; shift 2 ;;
+	--release|--arch) shift 2 ;;
+	--*) shift ;;
+	*) slices="$slices $1"; shift ;;
+	esac
+done
+if [ -z "$root" ]; then
+	echo "error: the required flag --root was not specified" >&2
+	exit 1
+fi
+if [ -n "$SDF_STUB_LOG" ]; then
+	echo "cut$slices" >>"$SDF_STUB_LOG"
+fi
+for s in $slices; do
+	case " $fail " in
+	*" $s "*)
+		echo "error: cannot install slice $s" >&2
+		exit 1
+		;;
+	esac
+	if [ ! -d "$fixture/slices/$s" ]; then
+		echo "error: slice \"$s\" not found" >&2
+		exit 1
+	fi
+done
+cache="${XDG_CACHE_HOME:-$HOME/.cache}/chisel/sha256"
+mkdir -p "$root" "$cache"
+cp "$fixture"/cache/* "$cache"/
+for s in $slices; do
+	cp -R "$fixture/slices/$s/." "$root/"
+done
+`, dir, o.ChiselVersion, shellQuote(dir), shellQuote(strings.Join(fail, " ")))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,152 @@
+package fixtures_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/archive"
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/coverage"
+	"github.com/rebornplusplus/chisel-tools/internal/fixtures"
+)
+
+func TestWrite(t *testing.T) {
+	f, err := fixtures.Write(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := chisel.ReadRelease(f.Release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range r.Slices {
+		names = append(names, s.Name)
+	}
+	want := []string{"hello_bins", "hello_copyright", "libhello1_copyright", "libhello1_libs"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("have slices %q, want %q", names, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(f.Archive, "dists/noble/Release"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := archive.ParseRelease(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := rel.Files["main/binary-amd64/Packages"]
+	if rel.Suite != "noble" || index == nil {
+		t.Fatalf("have release %+v", rel)
+	}
+	// The index is cached by digest, like chisel does.
+	idx, err := os.Open(filepath.Join(f.Cache, index.SHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	pkgs, err := archive.ParsePackages(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 2 || pkgs[0].Name != "hello" || pkgs[0].Filename != "pool/main/h/hello/hello_2.10-3_amd64.deb" {
+		t.Fatalf("have packages %+v", pkgs)
+	}
+	deb, err := os.ReadFile(filepath.Join(f.Archive, pkgs[0].Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(deb)) != pkgs[0].Size || !bytes.HasPrefix(deb, []byte("!<arch>\ndebian-binary   ")) {
+		t.Fatalf("have invalid deb of %d bytes", len(deb))
+	}
+
+	if _, err := exec.LookPath("dpkg-deb"); err != nil {
+		t.Skip("dpkg-deb not installed")
+	}
+	files, err := coverage.DebFiles(filepath.Join(f.Archive, pkgs[0].Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"/usr/bin/hello", "/usr/bin/hi", "/usr/share/doc/hello/copyright", "/usr/share/man/man1/hello.1.gz"}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("have deb files %q, want %q", files, want)
+	}
+}
+
+func TestDebReproducible(t *testing.T) {
+	p := fixtures.DefaultPackages()[0]
+	p.Arch = "amd64"
+	a, err := fixtures.Deb(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fixtures.Deb(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Fatal("debs of the same package differ")
+	}
+}
+
+func stubCut(t *testing.T, f *fixtures.Fixture, root, cacheDir string, slices ...string) (string, error) {
+	args := append([]string{"cut", "--release", f.Release, "--arch", "amd64", "--root", root}, slices...)
+	cmd := exec.Command(f.Chisel, args...)
+	cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+cacheDir, "SDF_STUB_LOG="+filepath.Join(cacheDir, "log"))
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+func TestStubChisel(t *testing.T) {
+	f, err := fixtures.Write(filepath.Join(t.TempDir(), "it's here"), &fixtures.Options{
+		ChiselVersion: "v1.2.0",
+		Fail:          []string{"hello_copyright"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(f.Chisel, "version").Output()
+	if err != nil || string(out) != "v1.2.0\n" {
+		t.Fatalf("have version %q, %v", out, err)
+	}
+
+	root := filepath.Join(t.TempDir(), "root")
+	cacheDir := t.TempDir()
+	if out, err := stubCut(t, f, root, cacheDir, "hello_bins"); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	for _, p := range []string{"usr/bin/hello", "usr/share/doc/hello/copyright", "usr/lib/libhello.so.1.0", "usr/share/doc/libhello1/copyright"} {
+		if _, err := os.Stat(filepath.Join(root, p)); err != nil {
+			t.Fatalf("stub did not install %s: %v", p, err)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(root, "usr/lib/libhello.so.1")); err != nil || target != "libhello.so.1.0" {
+		t.Fatalf("have link %q, %v", target, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "usr/share/man/man1/hello.1.gz")); !os.IsNotExist(err) {
+		t.Fatal("stub installed a path no slice has")
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "chisel/sha256")); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := stubCut(t, f, t.TempDir(), cacheDir, "hello_copyright"); err == nil || out != "error: cannot install slice hello_copyright" {
+		t.Fatalf("have %q, %v, want failure", out, err)
+	}
+	if out, err := stubCut(t, f, t.TempDir(), cacheDir, "hello_nope"); err == nil || out != `error: slice "hello_nope" not found` {
+		t.Fatalf("have %q, %v, want failure", out, err)
+	}
+	log, err := os.ReadFile(filepath.Join(cacheDir, "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(log) != "cut hello_bins\ncut hello_copyright\ncut hello_nope\n" {
+		t.Fatalf("have log %q", log)
+	}
+}
@@ -0,0 +1,112 @@
+// Package ghactions lets a command run as a GitHub Actions step: the inputs
+// of the action are read from the environment, and the outputs, the step
+// summary and the annotations are written where the runner picks them up.
+//
+// See https://docs.github.com/en/actions/reference/workflow-commands-for-github-actions.
+package ghactions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Input returns the value of the input of the action, or def if it is not
+// set or empty. The runner sets the inputs as INPUT_<NAME> variables, with
+// the name upper-cased and its spaces replaced by underscores.
+func Input(name, def string) string {
+	key := "INPUT_" + strings.ToUpper(strings.ReplaceAll(name, " ", "_"))
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+// SetOutputs appends the outputs of the step to the $GITHUB_OUTPUT file, in
+// order of name. It does nothing when not running in GitHub Actions.
+func SetOutputs(outputs map[string]string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		value := outputs[name]
+		if !strings.ContainsAny(value, "\r\n") {
+			fmt.Fprintf(&b, "%s=%s\n", name, value)
+			continue
+		}
+		delim, err := delimiter()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", name, delim, value, delim)
+	}
+	return appendFile(path, b.String())
+}
+
+// delimiter returns a random delimiter for a multi-line output value, so
+// that the value cannot end it early.
+func delimiter() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "ghadelimiter_" + hex.EncodeToString(buf), nil
+}
+
+// AppendSummary appends the markdown to the $GITHUB_STEP_SUMMARY file. It
+// does nothing when not running in GitHub Actions.
+func AppendSummary(markdown string) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+	return appendFile(path, markdown)
+}
+
+func appendFile(path, data string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ErrorAnnotation returns the workflow command that annotates the file, if
+// not empty, with the error message. It must be printed on a line of its own
+// to the standard output.
+func ErrorAnnotation(file, title, message string) string {
+	var props []string
+	if file != "" {
+		props = append(props, "file="+escapeProperty(file))
+	}
+	if title != "" {
+		props = append(props, "title="+escapeProperty(title))
+	}
+	cmd := "::error"
+	if len(props) > 0 {
+		cmd += " " + strings.Join(props, ",")
+	}
+	return cmd + "::" + escapeData(message)
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
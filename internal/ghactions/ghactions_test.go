@@ -0,0 +1,84 @@
+package ghactions_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/ghactions"
+)
+
+func TestInput(t *testing.T) {
+	t.Setenv("INPUT_RELEASE", " path/to/release ")
+	t.Setenv("INPUT_CHANGED-SINCE", "origin/main")
+	t.Setenv("INPUT_MY_INPUT", "spaces")
+	t.Setenv("INPUT_ARCH", "")
+	for _, test := range []struct{ name, def, value string }{
+		{"release", ".", "path/to/release"},
+		{"changed-since", "", "origin/main"},
+		{"my input", "", "spaces"},
+		{"arch", "amd64", "amd64"},
+		{"missing", "default", "default"},
+	} {
+		if value := ghactions.Input(test.name, test.def); value != test.value {
+			t.Fatalf("%s: have %q, want %q", test.name, value, test.value)
+		}
+	}
+}
+
+func TestSetOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", "")
+	if err := ghactions.SetOutputs(map[string]string{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_OUTPUT", path)
+	if err := os.WriteFile(path, []byte("before=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := ghactions.SetOutputs(map[string]string{
+		"result":  "success",
+		"details": "line 1\nline 2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := regexp.MustCompile(`^before=1\ndetails<<(ghadelimiter_[0-9a-f]{32})\nline 1\nline 2\n(ghadelimiter_[0-9a-f]{32})\nresult=success\n$`)
+	m := want.FindStringSubmatch(string(data))
+	if m == nil || m[1] != m[2] {
+		t.Fatalf("have %q, want it to match %s", data, want)
+	}
+}
+
+func TestAppendSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary")
+	t.Setenv("GITHUB_STEP_SUMMARY", path)
+	for _, s := range []string{"## a\n", "## b\n"} {
+		if err := ghactions.AppendSummary(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := string(data), "## a\n## b\n"; have != want {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+}
+
+func TestErrorAnnotation(t *testing.T) {
+	have := ghactions.ErrorAnnotation("slices/a,b:c.yaml", "lint", "100% wrong\nreally")
+	want := "::error file=slices/a%2Cb%3Ac.yaml,title=lint::100%25 wrong%0Areally"
+	if have != want {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+	if have, want := ghactions.ErrorAnnotation("", "", "boom"), "::error::boom"; have != want {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,73 @@
+// Package graph holds the dependencies between slices, the edges from each
+// slice to its essential slices, and answers which slices a slice installs
+// along with it, and which install it.
+package graph
+
+import (
+	"sort"
+)
+
+// A Graph of the dependencies of nodes, with the reverse edges. The zero
+// value is an empty graph.
+type Graph struct {
+	nodes map[string]bool
+	deps  map[string][]string
+	rdeps map[string][]string
+}
+
+// Add adds the node along with its dependencies, which need not be added
+// themselves.
+func (g *Graph) Add(node string, deps ...string) {
+	if g.nodes == nil {
+		g.nodes = make(map[string]bool)
+		g.deps = make(map[string][]string)
+		g.rdeps = make(map[string][]string)
+	}
+	g.nodes[node] = true
+	for _, d := range deps {
+		g.deps[node] = append(g.deps[node], d)
+		g.rdeps[d] = append(g.rdeps[d], node)
+	}
+}
+
+// Has returns whether the node was added, rather than only depended on.
+func (g *Graph) Has(node string) bool {
+	return g.nodes[node]
+}
+
+// Deps returns the transitive dependencies of the nodes, sorted, without
+// the nodes themselves. Dependencies that were not added are included but
+// have none of their own.
+func (g *Graph) Deps(nodes ...string) []string {
+	return walk(g.deps, nodes)
+}
+
+// RDeps returns the nodes depending on the nodes, transitively, sorted,
+// without the nodes themselves.
+func (g *Graph) RDeps(nodes ...string) []string {
+	return walk(g.rdeps, nodes)
+}
+
+// walk returns the nodes reachable from the start ones along the edges,
+// breadth first.
+func walk(edges map[string][]string, start []string) []string {
+	seen := make(map[string]bool)
+	for _, n := range start {
+		seen[n] = true
+	}
+	queue := append([]string(nil), start...)
+	var found []string
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, n := range edges[cur] {
+			if !seen[n] {
+				seen[n] = true
+				found = append(found, n)
+				queue = append(queue, n)
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
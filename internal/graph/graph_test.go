@@ -0,0 +1,78 @@
+package graph_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/graph"
+)
+
+// Slices along with their essential slices.
+var testSlices = map[string][]string{
+	"a_bins":   {"a_libs", "b_libs"},
+	"a_libs":   {"c_libs"},
+	"b_libs":   {"c_libs", "x_libs"},
+	"c_libs":   nil,
+	"d_bins":   {"a_bins"},
+	"e_cycle1": {"e_cycle2"},
+	"e_cycle2": {"e_cycle1"},
+}
+
+var graphTests = []struct {
+	summary string
+	nodes   []string
+	deps    []string
+	rdeps   []string
+}{{
+	summary: "Dependencies and reverse ones",
+	nodes:   []string{"a_libs"},
+	deps:    []string{"c_libs"},
+	rdeps:   []string{"a_bins", "d_bins"},
+}, {
+	summary: "Nodes which were not added are not followed",
+	nodes:   []string{"b_libs"},
+	deps:    []string{"c_libs", "x_libs"},
+	rdeps:   []string{"a_bins", "d_bins"},
+}, {
+	summary: "Reverse dependencies of a node only depended on",
+	nodes:   []string{"x_libs"},
+	rdeps:   []string{"a_bins", "b_libs", "d_bins"},
+}, {
+	summary: "Several nodes",
+	nodes:   []string{"c_libs", "a_bins"},
+	deps:    []string{"a_libs", "b_libs", "x_libs"},
+	rdeps:   []string{"a_libs", "b_libs", "d_bins"},
+}, {
+	summary: "Cycles",
+	nodes:   []string{"e_cycle1"},
+	deps:    []string{"e_cycle2"},
+	rdeps:   []string{"e_cycle2"},
+}, {
+	summary: "Unknown node",
+	nodes:   []string{"y_bins"},
+}}
+
+func TestGraph(t *testing.T) {
+	g := &graph.Graph{}
+	for name, deps := range testSlices {
+		g.Add(name, deps...)
+	}
+	for _, test := range graphTests {
+		t.Logf("Summary: %s", test.summary)
+		if deps := g.Deps(test.nodes...); !reflect.DeepEqual(deps, test.deps) {
+			t.Fatalf("have deps %v, want %v", deps, test.deps)
+		}
+		if rdeps := g.RDeps(test.nodes...); !reflect.DeepEqual(rdeps, test.rdeps) {
+			t.Fatalf("have rdeps %v, want %v", rdeps, test.rdeps)
+		}
+	}
+	if !g.Has("c_libs") || g.Has("x_libs") {
+		t.Fatalf("have c_libs %v and x_libs %v, want true and false", g.Has("c_libs"), g.Has("x_libs"))
+	}
+
+	// The zero value is an empty graph.
+	var empty graph.Graph
+	if deps := empty.Deps("a_bins"); deps != nil {
+		t.Fatalf("have deps %v in an empty graph", deps)
+	}
+}
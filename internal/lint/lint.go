@@ -0,0 +1,329 @@
+// Package lint checks chisel releases for mistakes that chisel itself only
+// reports when installing the slices, if at all.
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+// An Issue is a problem found in a release.
+type Issue struct {
+	Check   string `json:"check"`
+	File    string `json:"file,omitempty"`
+	Slice   string `json:"slice,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i *Issue) String() string {
+	subject := i.File
+	if i.Slice != "" {
+		subject += ": " + i.Slice
+	}
+	return fmt.Sprintf("%s: %s (%s)", subject, i.Message, i.Check)
+}
+
+// A Check finds the issues of one kind in a release.
+type Check struct {
+	Name    string
+	Summary string
+	Run     func(r *chisel.Release) []*Issue
+}
+
+// Checks are the available checks, sorted by name.
+var Checks = []*Check{{
+	Name:    "duplicate-slice",
+	Summary: "slices must be defined once",
+	Run:     duplicateSlices,
+}, {
+	Name:    "essential-cycle",
+	Summary: "slices must not depend on themselves through their essential slices",
+	Run:     essentialCycles,
+}, {
+	Name:    "file-name",
+	Summary: "slice definition files must be named after their package",
+	Run:     fileNames,
+}, {
+	Name:    "path",
+	Summary: "content paths must be absolute and clean",
+	Run:     paths,
+}, {
+	Name:    "path-conflict",
+	Summary: "slices must agree on the entries of the paths they share, and only those of a package may share copied paths",
+	Run:     pathConflicts,
+}, {
+	Name:    "undefined-essential",
+	Summary: "essential slices and their packages must be defined in the release",
+	Run:     undefinedEssentials,
+}}
+
+// ParseCheck is the check of the issues of slice definition files that
+// cannot be parsed, so that no other check could run on them.
+const ParseCheck = "parse"
+
+// FindCheck returns the check with the name, or nil if there is none.
+func FindCheck(name string) *Check {
+	for _, c := range Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Run runs the checks, all of them if none are given, and returns the issues
+// sorted by file, slice and check.
+func Run(r *chisel.Release, checks ...*Check) []*Issue {
+	if len(checks) == 0 {
+		checks = Checks
+	}
+	var issues []*Issue
+	for _, c := range checks {
+		for _, i := range c.Run(r) {
+			i.Check = c.Name
+			issues = append(issues, i)
+		}
+	}
+	Sort(issues)
+	return issues
+}
+
+// InFiles returns the issues of the given files only, in the same order.
+func InFiles(issues []*Issue, files []string) []*Issue {
+	keep := make(map[string]bool)
+	for _, f := range files {
+		keep[filepath.Clean(f)] = true
+	}
+	var in []*Issue
+	for _, i := range issues {
+		if keep[filepath.Clean(i.File)] {
+			in = append(in, i)
+		}
+	}
+	return in
+}
+
+// Sort sorts the issues by file, slice and check.
+func Sort(issues []*Issue) {
+	sort.SliceStable(issues, func(a, b int) bool {
+		x, y := issues[a], issues[b]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Slice != y.Slice {
+			return x.Slice < y.Slice
+		}
+		return x.Check < y.Check
+	})
+}
+
+func fileNames(r *chisel.Release) []*Issue {
+	var issues []*Issue
+	seen := make(map[string]string) // File by package.
+	for _, s := range r.Slices {
+		if prev, ok := seen[s.Package]; ok {
+			if prev != s.File {
+				issues = append(issues, &Issue{File: s.File, Message: fmt.Sprintf("package %s is also defined in %s", s.Package, relPath(r, prev))})
+				seen[s.Package] = s.File
+			}
+			continue
+		}
+		seen[s.Package] = s.File
+		if base := filepath.Base(s.File); s.File != "" && base != s.Package+".yaml" {
+			issues = append(issues, &Issue{File: s.File, Message: fmt.Sprintf("file of package %s is named %s", s.Package, base)})
+		}
+	}
+	return issues
+}
+
+func undefinedEssentials(r *chisel.Release) []*Issue {
+	var issues []*Issue
+	pkgs := make(map[string]bool)
+	for _, s := range r.Slices {
+		pkgs[s.Package] = true
+	}
+	for _, s := range r.Slices {
+		for _, e := range s.Essential {
+			if e == s.Name {
+				issues = append(issues, &Issue{File: s.File, Slice: s.Name, Message: "slice is essential to itself"})
+			} else if r.Slice(e) == nil {
+				msg := fmt.Sprintf("essential slice %s is not defined", e)
+				if pkg, _, err := chisel.Parse(e); err == nil && !pkgs[pkg] {
+					msg = fmt.Sprintf("essential slice %s is of undefined package %s", e, pkg)
+				}
+				issues = append(issues, &Issue{File: s.File, Slice: s.Name, Message: msg})
+			}
+		}
+	}
+	return issues
+}
+
+func duplicateSlices(r *chisel.Release) []*Issue {
+	var issues []*Issue
+	seen := make(map[string]*chisel.Slice)
+	for _, s := range r.Slices {
+		if prev, ok := seen[s.Name]; ok {
+			issues = append(issues, &Issue{File: s.File, Slice: s.Name, Message: fmt.Sprintf("slice is also defined in %s", relPath(r, prev.File))})
+			continue
+		}
+		seen[s.Name] = s
+	}
+	return issues
+}
+
+func essentialCycles(r *chisel.Release) []*Issue {
+	var issues []*Issue
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+	reported := make(map[string]bool)
+	var visit func(s *chisel.Slice)
+	visit = func(s *chisel.Slice) {
+		state[s.Name] = visiting
+		stack = append(stack, s.Name)
+		for _, name := range s.Essential {
+			e := r.Slice(name)
+			if e == nil || e == s {
+				continue
+			}
+			switch state[name] {
+			case visiting:
+				i := len(stack) - 1
+				for stack[i] != name {
+					i--
+				}
+				cycle := append(append([]string{}, stack[i:]...), name)
+				// Report every cycle once, from its smallest slice.
+				key := canonicalCycle(cycle[:len(cycle)-1])
+				if !reported[key] {
+					reported[key] = true
+					first := r.Slice(cycle[0])
+					issues = append(issues, &Issue{File: first.File, Slice: first.Name, Message: "essential cycle " + strings.Join(cycle, " -> ")})
+				}
+			case 0:
+				visit(e)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[s.Name] = done
+	}
+	for _, s := range r.Slices {
+		if state[s.Name] == 0 {
+			visit(s)
+		}
+	}
+	return issues
+}
+
+// canonicalCycle returns the cycle rotated to start with its smallest name.
+func canonicalCycle(cycle []string) string {
+	min := 0
+	for i, name := range cycle {
+		if name < cycle[min] {
+			min = i
+		}
+	}
+	return strings.Join(append(append([]string{}, cycle[min:]...), cycle[:min]...), " ")
+}
+
+func paths(r *chisel.Release) []*Issue {
+	var issues []*Issue
+	for _, s := range r.Slices {
+		for _, p := range s.Contents {
+			switch {
+			case !strings.HasPrefix(p, "/"):
+				issues = append(issues, &Issue{File: s.File, Slice: s.Name, Message: fmt.Sprintf("path %s is not absolute", p)})
+			case cleanPath(p) != p:
+				issues = append(issues, &Issue{File: s.File, Slice: s.Name, Message: fmt.Sprintf("path %s is not clean, want %s", p, cleanPath(p))})
+			}
+		}
+	}
+	return issues
+}
+
+// pathInfo returns the entry of the path in the contents of the slice.
+func pathInfo(s *chisel.Slice, path string) *chisel.PathInfo {
+	if info, ok := s.Paths[path]; ok && info != nil {
+		return info
+	}
+	if strings.ContainsAny(path, "*?") {
+		return &chisel.PathInfo{Kind: chisel.GlobPath}
+	}
+	return &chisel.PathInfo{Kind: chisel.CopyPath}
+}
+
+// pathConflicts reports the paths chisel refuses to install from several
+// slices: those whose entries differ, and the copied ones when the slices
+// are of different packages, as their content may differ. The entries of
+// different packages do not conflict if one of them prefers either package,
+// as chisel then takes the path from the preferred one. Each conflict is
+// reported on the slice that comes last.
+func pathConflicts(r *chisel.Release) []*Issue {
+	var issues []*Issue
+	// First slice of every package by path, in order.
+	first := make(map[string][]*chisel.Slice)
+	for _, s := range r.Slices {
+		for _, p := range s.Contents {
+			own := false // Whether the package of the slice has the path.
+			for _, prev := range first[p] {
+				own = own || prev.Package == s.Package
+				old, cur := pathInfo(prev, p), pathInfo(s, p)
+				copied := cur.Kind == chisel.CopyPath || cur.Kind == chisel.GlobPath
+				preferred := func(pkg string) bool { return pkg == s.Package || pkg == prev.Package }
+				switch {
+				case s.Package != prev.Package && (preferred(old.Prefer) || preferred(cur.Prefer)):
+				case !cur.SameContent(old):
+					issues = append(issues, &Issue{File: s.File, Slice: s.Name, Message: fmt.Sprintf("path %s has a different entry in slice %s", p, prev.Name)})
+				case copied && s.Package != prev.Package:
+					issues = append(issues, &Issue{File: s.File, Slice: s.Name, Message: fmt.Sprintf("path %s is also copied from package %s by slice %s", p, prev.Package, prev.Name)})
+				}
+			}
+			if !own {
+				first[p] = append(first[p], s)
+			}
+		}
+	}
+	// Globs conflict with the paths of other packages they match.
+	for _, s := range r.Slices {
+		for _, g := range s.Contents {
+			if pathInfo(s, g).Kind != chisel.GlobPath {
+				continue
+			}
+			for _, o := range r.Slices {
+				if o.Package == s.Package {
+					continue
+				}
+				for _, p := range o.Contents {
+					if pathInfo(o, p).Kind != chisel.GlobPath && chisel.MatchPath(g, p) {
+						issues = append(issues, &Issue{File: s.File, Slice: s.Name, Message: fmt.Sprintf("glob %s matches path %s of slice %s of package %s", g, p, o.Name, o.Package)})
+					}
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// relPath returns the path relative to the release directory, if possible.
+func relPath(r *chisel.Release, path string) string {
+	if rel, err := filepath.Rel(r.Path, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// cleanPath cleans the path, keeping the trailing slash of directories.
+func cleanPath(p string) string {
+	clean := filepath.Clean(p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+	return clean
+}
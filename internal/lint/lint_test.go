@@ -0,0 +1,351 @@
+package lint_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/lint"
+)
+
+const chiselYAML = `
+format: v1
+archives:
+  ubuntu:
+    suites: [noble]
+    components: [main]
+`
+
+var lintTests = []struct {
+	summary string
+	files   map[string]string
+	checks  []string
+	issues  []lint.Issue
+}{{
+	summary: "Clean release",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    essential: [bar_libs]
+    contents:
+      /usr/bin/foo:
+      /usr/share/foo/**:
+`,
+		"slices/bar.yaml": `
+package: bar
+slices:
+  libs: {}
+`,
+	},
+}, {
+	summary: "Undefined and self essentials",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    essential: [foo_bins, bar_libs]
+`,
+	},
+	issues: []lint.Issue{{
+		Check:   "undefined-essential",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "slice is essential to itself",
+	}, {
+		Check:   "undefined-essential",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "essential slice bar_libs is of undefined package bar",
+	}},
+}, {
+	summary: "Package essentials are not essential to themselves",
+	files: map[string]string{
+		"slices/hello.yaml": `
+package: hello
+essential:
+  - hello_copyright
+slices:
+  bins:
+    contents:
+      /usr/bin/hello:
+  copyright:
+    contents:
+      /usr/share/doc/hello/copyright:
+`,
+	},
+}, {
+	summary: "Undefined essential of a defined package",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    essential: [foo_libs]
+  config: {}
+`,
+	},
+	issues: []lint.Issue{{
+		Check:   "undefined-essential",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "essential slice foo_libs is not defined",
+	}},
+}, {
+	summary: "Duplicate slices",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  bins: {}
+`,
+		"slices/sub/foo.yaml": `
+package: foo
+slices:
+  bins: {}
+  libs: {}
+`,
+	},
+	issues: []lint.Issue{{
+		Check:   "file-name",
+		File:    "slices/sub/foo.yaml",
+		Message: "package foo is also defined in slices/foo.yaml",
+	}, {
+		Check:   "duplicate-slice",
+		File:    "slices/sub/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "slice is also defined in slices/foo.yaml",
+	}},
+}, {
+	summary: "Path conflicts",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    contents:
+      /usr/bin/foo:
+      /etc/foo.conf: {text: "a"}
+      /var/lib/foo/: {make: true}
+      /usr/lib/*.so:
+  config:
+    contents:
+      /usr/bin/foo:
+      /etc/foo.conf: {text: "b", mutable: true}
+      /var/lib/foo/: {make: true, until: mutate}
+`,
+		"slices/bar.yaml": `
+package: bar
+slices:
+  libs:
+    contents:
+      /usr/bin/foo:
+      /usr/lib/libbar.so:
+      /var/lib/foo/: {make: true}
+`,
+	},
+	issues: []lint.Issue{{
+		Check:   "path-conflict",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "path /usr/bin/foo is also copied from package bar by slice bar_libs",
+	}, {
+		Check:   "path-conflict",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "glob /usr/lib/*.so matches path /usr/lib/libbar.so of slice bar_libs of package bar",
+	}, {
+		Check:   "path-conflict",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_config",
+		Message: "path /etc/foo.conf has a different entry in slice foo_bins",
+	}, {
+		Check:   "path-conflict",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_config",
+		Message: "path /usr/bin/foo is also copied from package bar by slice bar_libs",
+	}},
+}, {
+	summary: "Preferred packages resolve path conflicts",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    contents:
+      /usr/bin/foo: {prefer: foo}
+      /usr/bin/bar:
+      /usr/bin/baz: {prefer: qux}
+      /etc/foo.conf: {text: "a", prefer: foo}
+  config:
+    contents:
+      /etc/foo.conf: {text: "b", prefer: foo}
+`,
+		"slices/bar.yaml": `
+package: bar
+slices:
+  bins:
+    contents:
+      /usr/bin/foo:
+      /usr/bin/bar: {prefer: foo}
+      /usr/bin/baz:
+      /etc/foo.conf: {text: "c"}
+`,
+	},
+	issues: []lint.Issue{{
+		Check:   "path-conflict",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "path /usr/bin/baz is also copied from package bar by slice bar_bins",
+	}, {
+		Check:   "path-conflict",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_config",
+		Message: "path /etc/foo.conf has a different entry in slice foo_bins",
+	}},
+}, {
+	summary: "Essential cycle is reported once",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  a:
+    essential: [foo_b]
+  b:
+    essential: [foo_c]
+  c:
+    essential: [foo_a]
+`,
+	},
+	issues: []lint.Issue{{
+		Check:   "essential-cycle",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_a",
+		Message: "essential cycle foo_a -> foo_b -> foo_c -> foo_a",
+	}},
+}, {
+	summary: "File names",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: bar
+slices:
+  libs: {}
+`,
+		"slices/sub/bar.yaml": `
+package: bar
+slices:
+  bins: {}
+`,
+	},
+	issues: []lint.Issue{{
+		Check:   "file-name",
+		File:    "slices/foo.yaml",
+		Message: "file of package bar is named foo.yaml",
+	}, {
+		Check:   "file-name",
+		File:    "slices/sub/bar.yaml",
+		Message: "package bar is also defined in slices/foo.yaml",
+	}},
+}, {
+	summary: "Paths",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    contents:
+      usr/bin/foo:
+      /usr/lib/../bin/bar:
+      /usr/share/foo/:
+`,
+	},
+	issues: []lint.Issue{{
+		Check:   "path",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "path /usr/lib/../bin/bar is not clean, want /usr/bin/bar",
+	}, {
+		Check:   "path",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "path usr/bin/foo is not absolute",
+	}},
+}, {
+	summary: "Selected checks only",
+	files: map[string]string{
+		"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    essential: [bar_libs]
+    contents:
+      usr/bin/foo:
+`,
+	},
+	checks: []string{"path"},
+	issues: []lint.Issue{{
+		Check:   "path",
+		File:    "slices/foo.yaml",
+		Slice:   "foo_bins",
+		Message: "path usr/bin/foo is not absolute",
+	}},
+}}
+
+func TestRun(t *testing.T) {
+	for _, test := range lintTests {
+		t.Logf("Summary: %s", test.summary)
+		dir := t.TempDir()
+		files := map[string]string{"chisel.yaml": chiselYAML}
+		for name, data := range test.files {
+			files[name] = data
+		}
+		for name, data := range files {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r, err := chisel.ReadRelease(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var checks []*lint.Check
+		for _, name := range test.checks {
+			c := lint.FindCheck(name)
+			if c == nil {
+				t.Fatalf("unknown check %q", name)
+			}
+			checks = append(checks, c)
+		}
+		var issues []lint.Issue
+		for _, i := range lint.Run(r, checks...) {
+			i.File, _ = filepath.Rel(dir, i.File)
+			issues = append(issues, *i)
+		}
+		if !reflect.DeepEqual(issues, test.issues) {
+			t.Fatalf("have %+v, want %+v", issues, test.issues)
+		}
+	}
+}
+
+func TestInFiles(t *testing.T) {
+	issues := []*lint.Issue{
+		{Check: "path", File: "release/slices/a.yaml", Message: "a"},
+		{Check: "path", File: "release/slices/b.yaml", Message: "b"},
+		{Check: "file-name", File: "release/slices/a.yaml", Message: "c"},
+	}
+	in := lint.InFiles(issues, []string{"release/slices/../slices/a.yaml", "release/slices/c.yaml"})
+	if want := []*lint.Issue{issues[0], issues[2]}; !reflect.DeepEqual(in, want) {
+		t.Fatalf("have %v, want %v", in, want)
+	}
+	if in := lint.InFiles(issues, nil); in != nil {
+		t.Fatalf("have %v, want no issues", in)
+	}
+}
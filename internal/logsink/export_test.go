@@ -0,0 +1,11 @@
+package logsink
+
+// SetSockets sets the sockets of the system loggers until restore is
+// called.
+func SetSockets(syslog, journal string) (restore func()) {
+	oldSyslog, oldJournal := syslogSocket, journalSocket
+	syslogSocket, journalSocket = syslog, journal
+	return func() {
+		syslogSocket, journalSocket = oldSyslog, oldJournal
+	}
+}
@@ -0,0 +1,93 @@
+// Package logsink writes logs to the system logs, syslog or the systemd
+// journal, or to a file, for commands running unattended on servers. The
+// system logs are not supported on Windows and Plan 9.
+package logsink
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Priority int
+
+const (
+	Info Priority = iota
+	Warning
+	Error
+)
+
+// A Sink receives the log messages, one at a time.
+type Sink interface {
+	Log(p Priority, msg string) error
+	Close() error
+}
+
+// Sockets of the system loggers, the default ones of the platform if empty.
+var (
+	syslogSocket  = ""
+	journalSocket = "/run/systemd/journal/socket"
+)
+
+// File returns a sink appending to the file at path, with the time and the
+// priority of every message. The file is opened again when it is moved or
+// removed, so that it can be rotated by logrotate without copytruncate.
+func File(path string) (Sink, error) {
+	s := &fileSink{path: path}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	s.f = f
+	return nil
+}
+
+// reopen opens the file again if it is not at its path anymore.
+func (s *fileSink) reopen() error {
+	info, err := os.Stat(s.path)
+	if err == nil {
+		if open, err := s.f.Stat(); err == nil && os.SameFile(info, open) {
+			return nil
+		}
+	}
+	s.f.Close()
+	return s.open()
+}
+
+var fileLevels = map[Priority]string{
+	Info:    "info",
+	Warning: "warning",
+	Error:   "error",
+}
+
+func (s *fileSink) Log(p Priority, msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reopen(); err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%s %s %s\n", time.Now().Format(time.RFC3339), fileLevels[p], strings.TrimSuffix(msg, "\n"))
+	_, err := s.f.WriteString(line)
+	return err
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
@@ -0,0 +1,77 @@
+package logsink_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/logsink"
+)
+
+// logAll logs a message of every priority, the error one on two lines.
+func logAll(t *testing.T, s logsink.Sink) {
+	for _, m := range []struct {
+		p   logsink.Priority
+		msg string
+	}{
+		{logsink.Info, "Installing hello_bins..."},
+		{logsink.Warning, "Warning: chisel may not support the release"},
+		{logsink.Error, "cannot install hello_bins:\nno such package"},
+	} {
+		if err := s.Log(m.p, m.msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sdf.log")
+	s, err := logsink.File(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	logAll(t, s)
+
+	// After a rotation, the messages go to a new file.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Log(logsink.Info, "Installed hello_bins\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, want := range map[string][]string{
+		path + ".1": {
+			"info Installing hello_bins...",
+			"warning Warning: chisel may not support the release",
+			"error cannot install hello_bins:",
+			"no such package",
+		},
+		path: {"info Installed hello_bins"},
+	} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("have %s lines %q, want %q", filepath.Base(file), lines, want)
+		}
+		for i, line := range lines {
+			// The first lines of the messages start with the time.
+			if !strings.HasPrefix(line, "no such") {
+				stamp, rest, _ := strings.Cut(line, " ")
+				if _, err := time.Parse(time.RFC3339, stamp); err != nil {
+					t.Fatalf("have line %q without the time: %v", line, err)
+				}
+				line = rest
+			}
+			if line != want[i] {
+				t.Fatalf("have %s line %q, want %q", filepath.Base(file), line, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+//go:build !windows && !plan9
+
+package logsink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+)
+
+// Syslog returns a sink logging to the local syslog daemon, with the tag.
+func Syslog(tag string) (Sink, error) {
+	var w *syslog.Writer
+	var err error
+	if syslogSocket == "" {
+		w, err = syslog.New(syslog.LOG_INFO|syslog.LOG_USER, tag)
+	} else {
+		w, err = syslog.Dial("unixgram", syslogSocket, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to syslog: %w", err)
+	}
+	return &syslogSink{w}, nil
+}
+
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+func (s *syslogSink) Log(p Priority, msg string) error {
+	switch p {
+	case Error:
+		return s.w.Err(msg)
+	case Warning:
+		return s.w.Warning(msg)
+	default:
+		return s.w.Info(msg)
+	}
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
+
+// Journald returns a sink logging to the systemd journal with its native
+// protocol, with the tag as SYSLOG_IDENTIFIER, see
+// https://systemd.io/JOURNAL_NATIVE_PROTOCOL.
+func Journald(tag string) (Sink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the journal: %w", err)
+	}
+	return &journalSink{conn: conn, tag: tag}, nil
+}
+
+type journalSink struct {
+	conn *net.UnixConn
+	tag  string
+}
+
+// Syslog priorities of the journal entries.
+var journalPriorities = map[Priority]string{
+	Info:    "6",
+	Warning: "4",
+	Error:   "3",
+}
+
+func (s *journalSink) Log(p Priority, msg string) error {
+	var buf bytes.Buffer
+	field := func(name, value string) {
+		if !strings.Contains(value, "\n") {
+			fmt.Fprintf(&buf, "%s=%s\n", name, value)
+			return
+		}
+		// Values with newlines are given with their length instead.
+		buf.WriteString(name + "\n")
+		binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value + "\n")
+	}
+	field("MESSAGE", msg)
+	field("PRIORITY", journalPriorities[p])
+	field("SYSLOG_IDENTIFIER", s.tag)
+	field("SYSLOG_PID", fmt.Sprint(os.Getpid()))
+	_, err := s.conn.Write(buf.Bytes())
+	return err
+}
+
+func (s *journalSink) Close() error {
+	return s.conn.Close()
+}
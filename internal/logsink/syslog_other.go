@@ -0,0 +1,18 @@
+//go:build windows || plan9
+
+package logsink
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errUnsupported = errors.New("not supported on this platform")
+
+func Syslog(tag string) (Sink, error) {
+	return nil, fmt.Errorf("cannot connect to syslog: %w", errUnsupported)
+}
+
+func Journald(tag string) (Sink, error) {
+	return nil, fmt.Errorf("cannot connect to the journal: %w", errUnsupported)
+}
@@ -0,0 +1,111 @@
+//go:build !windows && !plan9
+
+package logsink_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/logsink"
+)
+
+// listen returns a socket at path, receiving the datagrams of a logger.
+func listen(t *testing.T, path string) *net.UnixConn {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func receive(t *testing.T, conn *net.UnixConn) []byte {
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[:n]
+}
+
+func TestSyslog(t *testing.T) {
+	dir := t.TempDir()
+	conn := listen(t, filepath.Join(dir, "log"))
+	defer logsink.SetSockets(filepath.Join(dir, "log"), "")()
+
+	s, err := logsink.Syslog("sdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	logAll(t, s)
+	// LOG_USER with the info, warning and error severities.
+	for _, want := range []string{
+		fmt.Sprintf(`^<14>.* sdf\[%d\]: Installing hello_bins...\n$`, os.Getpid()),
+		`^<12>.* sdf\[\d+\]: Warning: chisel may not support the release\n$`,
+		`^<11>.* sdf\[\d+\]: cannot install hello_bins:\nno such package\n$`,
+	} {
+		if msg := receive(t, conn); !regexp.MustCompile(want).Match(msg) {
+			t.Fatalf("have message %q, want it to match %q", msg, want)
+		}
+	}
+}
+
+// journalFields parses the datagram of the native journal protocol.
+func journalFields(t *testing.T, data []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		line, rest, _ := bytes.Cut(data, []byte("\n"))
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			data = rest
+			continue
+		}
+		if len(rest) < 8 {
+			t.Fatalf("invalid field %q", data)
+		}
+		n := binary.LittleEndian.Uint64(rest)
+		fields[string(line)] = string(rest[8 : 8+n])
+		data = rest[8+n+1:]
+	}
+	return fields
+}
+
+func TestJournald(t *testing.T) {
+	dir := t.TempDir()
+	conn := listen(t, filepath.Join(dir, "socket"))
+	defer logsink.SetSockets("", filepath.Join(dir, "socket"))()
+
+	s, err := logsink.Journald("sdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	logAll(t, s)
+	for _, want := range []struct{ priority, message string }{
+		{"6", "Installing hello_bins..."},
+		{"4", "Warning: chisel may not support the release"},
+		{"3", "cannot install hello_bins:\nno such package"},
+	} {
+		fields := journalFields(t, receive(t, conn))
+		if fields["PRIORITY"] != want.priority || fields["MESSAGE"] != want.message || fields["SYSLOG_IDENTIFIER"] != "sdf" || fields["SYSLOG_PID"] != fmt.Sprint(os.Getpid()) {
+			t.Fatalf("have fields %q, want message %q with priority %s", fields, want.message, want.priority)
+		}
+	}
+
+	if _, err := logsink.Journald("sdf"); err != nil {
+		t.Fatal(err)
+	}
+	defer logsink.SetSockets("", filepath.Join(dir, "missing"))()
+	if _, err := logsink.Journald("sdf"); err == nil {
+		t.Fatal("have no error without a journal")
+	}
+}
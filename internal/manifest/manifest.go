@@ -0,0 +1,195 @@
+// Package manifest reads the manifest that chisel writes into a root when
+// one of the installed slices has "generate: manifest" paths.
+//
+// The manifest is a zstd-compressed jsonwall database: a header line followed
+// by one JSON object per line, sorted by kind. See
+// https://github.com/canonical/chisel/blob/main/public/manifest/manifest.go.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The file name chisel uses for the manifest.
+const FileName = "manifest.wall"
+
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Digest  string `json:"sha256"`
+	Arch    string `json:"arch"`
+}
+
+type Slice struct {
+	Name string `json:"name"`
+}
+
+type Path struct {
+	Path        string   `json:"path"`
+	Mode        string   `json:"mode"`
+	Slices      []string `json:"slices"`
+	SHA256      string   `json:"sha256,omitempty"`
+	FinalSHA256 string   `json:"final_sha256,omitempty"`
+	Size        uint64   `json:"size,omitempty"`
+	Link        string   `json:"link,omitempty"`
+	Inode       uint64   `json:"inode,omitempty"`
+}
+
+// Digest returns the expected digest of the file at the path, taking
+// mutations into account. It is empty for directories and symlinks.
+func (p *Path) Digest() string {
+	if p.FinalSHA256 != "" {
+		return p.FinalSHA256
+	}
+	return p.SHA256
+}
+
+type Content struct {
+	Slice string `json:"slice"`
+	Path  string `json:"path"`
+}
+
+type Manifest struct {
+	Packages []*Package
+	Slices   []*Slice
+	Paths    []*Path
+	Contents []*Content
+}
+
+type header struct {
+	Version string `json:"jsonwall"`
+	Schema  string `json:"schema"`
+	Count   int    `json:"count"`
+}
+
+type entry struct {
+	Kind string `json:"kind"`
+}
+
+// Read an uncompressed manifest.
+func Read(r io.Reader) (*Manifest, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<24)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty manifest")
+	}
+	var h header
+	if err := json.Unmarshal(sc.Bytes(), &h); err != nil || h.Version == "" {
+		return nil, fmt.Errorf("invalid manifest header: %s", sc.Text())
+	}
+	if h.Schema != "1.0" {
+		return nil, fmt.Errorf("unsupported manifest schema %q", h.Schema)
+	}
+
+	m := &Manifest{}
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("invalid manifest entry: %s", line)
+		}
+		var err error
+		switch e.Kind {
+		case "package":
+			p := &Package{}
+			err = json.Unmarshal(line, p)
+			m.Packages = append(m.Packages, p)
+		case "slice":
+			s := &Slice{}
+			err = json.Unmarshal(line, s)
+			m.Slices = append(m.Slices, s)
+		case "path":
+			p := &Path{}
+			err = json.Unmarshal(line, p)
+			m.Paths = append(m.Paths, p)
+		case "content":
+			c := &Content{}
+			err = json.Unmarshal(line, c)
+			m.Contents = append(m.Contents, c)
+		default:
+			// Ignore unknown kinds, newer chisel versions may add some.
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest entry: %s", line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// zstdMagic starts every zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// ReadFile reads the manifest at path. Compressed manifests are decompressed
+// with the zstd(1) tool.
+func ReadFile(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, zstdMagic) {
+		cmd := exec.Command("zstd", "-dc")
+		cmd.Stdin = bytes.NewReader(data)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if data, err = cmd.Output(); err != nil {
+			return nil, fmt.Errorf("cannot decompress manifest: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	return Read(bytes.NewReader(data))
+}
+
+// Find the manifest files in a root. Chisel writes one copy of the manifest
+// per "generate: manifest" directory.
+func Find(root string) ([]string, error) {
+	var found []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && d.Name() == FileName {
+			found = append(found, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(found)
+	return found, nil
+}
+
+// ErrNotFound is returned by [ReadRoot] if no installed slice generates a
+// manifest.
+var ErrNotFound = errors.New("no manifest found")
+
+// ReadRoot reads the manifest in a root, or fails if there is none.
+func ReadRoot(root string) (*Manifest, error) {
+	found, err := Find(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find manifest: %w", err)
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNotFound, root)
+	}
+	return ReadFile(found[0])
+}
@@ -0,0 +1,120 @@
+package manifest_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+)
+
+const sampleManifest = `{"jsonwall":"1.0","schema":"1.0","count":9}
+{"kind":"content","slice":"hello_bins","path":"/usr/bin/hello"}
+{"kind":"content","slice":"hello_bins","path":"/var/lib/chisel/manifest.wall"}
+{"kind":"package","name":"hello","version":"2.10-3","sha256":"abcd","arch":"amd64"}
+{"kind":"path","path":"/usr/bin/hello","mode":"0755","slices":["hello_bins"],"sha256":"1234","size":26856}
+{"kind":"path","path":"/usr/bin/hi","mode":"0777","slices":["hello_bins"],"link":"hello"}
+{"kind":"path","path":"/var/lib/chisel/manifest.wall","mode":"0644","slices":["hello_bins"]}
+{"kind":"slice","name":"hello_bins"}
+{"kind":"unknown","foo":"bar"}
+`
+
+var readTests = []struct {
+	summary  string
+	data     string
+	manifest *manifest.Manifest
+	err      string
+}{{
+	summary: "Sample manifest",
+	data:    sampleManifest,
+	manifest: &manifest.Manifest{
+		Packages: []*manifest.Package{{
+			Name:    "hello",
+			Version: "2.10-3",
+			Digest:  "abcd",
+			Arch:    "amd64",
+		}},
+		Slices: []*manifest.Slice{{Name: "hello_bins"}},
+		Paths: []*manifest.Path{{
+			Path:   "/usr/bin/hello",
+			Mode:   "0755",
+			Slices: []string{"hello_bins"},
+			SHA256: "1234",
+			Size:   26856,
+		}, {
+			Path:   "/usr/bin/hi",
+			Mode:   "0777",
+			Slices: []string{"hello_bins"},
+			Link:   "hello",
+		}, {
+			Path:   "/var/lib/chisel/manifest.wall",
+			Mode:   "0644",
+			Slices: []string{"hello_bins"},
+		}},
+		Contents: []*manifest.Content{{
+			Slice: "hello_bins",
+			Path:  "/usr/bin/hello",
+		}, {
+			Slice: "hello_bins",
+			Path:  "/var/lib/chisel/manifest.wall",
+		}},
+	},
+}, {
+	summary: "Empty",
+	data:    "",
+	err:     "empty manifest",
+}, {
+	summary: "Bad header",
+	data:    "foo\n",
+	err:     "invalid manifest header: foo",
+}, {
+	summary: "Unsupported schema",
+	data:    `{"jsonwall":"1.0","schema":"2.0","count":1}`,
+	err:     `unsupported manifest schema "2.0"`,
+}, {
+	summary: "Bad entry",
+	data:    "{\"jsonwall\":\"1.0\",\"schema\":\"1.0\",\"count\":1}\n{\"kind\":\"path\",\"size\":\"x\"}\n",
+	err:     `invalid manifest entry: {"kind":"path","size":"x"}`,
+}}
+
+func TestRead(t *testing.T) {
+	for _, tc := range readTests {
+		t.Logf("Summary: %s", tc.summary)
+		m, err := manifest.Read(strings.NewReader(tc.data))
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("have error %v, want %q", err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, tc.manifest) {
+			t.Fatalf("have %+v, want %+v", m, tc.manifest)
+		}
+	}
+}
+
+func TestReadRoot(t *testing.T) {
+	root := t.TempDir()
+	if _, err := manifest.ReadRoot(root); err == nil {
+		t.Fatal("have no error for a root without manifest")
+	}
+	dir := filepath.Join(root, "var/lib/chisel")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifest.FileName), []byte(sampleManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := manifest.ReadRoot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Paths) != 3 {
+		t.Fatalf("have %d paths, want 3", len(m.Paths))
+	}
+}
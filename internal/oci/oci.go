@@ -0,0 +1,278 @@
+// Package oci turns chisel roots into single-layer OCI images, written as an
+// OCI image layout or pushed to a registry. See
+// https://github.com/opencontainers/image-spec.
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+const (
+	MediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeConfig   = "application/vnd.oci.image.config.v1+json"
+	MediaTypeLayer    = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+	// The annotation holding the tag of an image in an image layout.
+	AnnotationRefName = "org.opencontainers.image.ref.name"
+)
+
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *Platform         `json:"platform,omitempty"`
+}
+
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+type Manifest struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	MediaType     string        `json:"mediaType"`
+	Config        Descriptor    `json:"config"`
+	Layers        []*Descriptor `json:"layers"`
+}
+
+type Index struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	MediaType     string        `json:"mediaType"`
+	Manifests     []*Descriptor `json:"manifests"`
+}
+
+type ImageConfig struct {
+	Created      string          `json:"created,omitempty"`
+	Architecture string          `json:"architecture"`
+	Variant      string          `json:"variant,omitempty"`
+	OS           string          `json:"os"`
+	Config       ContainerConfig `json:"config"`
+	RootFS       RootFS          `json:"rootfs"`
+}
+
+type ContainerConfig struct {
+	User       string            `json:"User,omitempty"`
+	Env        []string          `json:"Env,omitempty"`
+	Entrypoint []string          `json:"Entrypoint,omitempty"`
+	Cmd        []string          `json:"Cmd,omitempty"`
+	WorkingDir string            `json:"WorkingDir,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty"`
+}
+
+type RootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+type Options struct {
+	// Debian architecture of the root, e.g. amd64 or armhf.
+	Arch       string
+	User       string
+	Env        []string
+	Entrypoint []string
+	Cmd        []string
+	WorkingDir string
+	Labels     map[string]string
+	// Creation time of the image, also used for all the files of the layer.
+	// The zero value means the Unix epoch, for reproducible images.
+	Created time.Time
+}
+
+// An Image is a scratch-based image with the root as its only layer.
+type Image struct {
+	Manifest *Descriptor
+	manifest []byte
+	blobs    map[string][]byte // Layer and config by digest.
+}
+
+// Debian to OCI architectures.
+var platforms = map[string]Platform{
+	"amd64":   {Architecture: "amd64", OS: "linux"},
+	"arm64":   {Architecture: "arm64", OS: "linux", Variant: "v8"},
+	"armhf":   {Architecture: "arm", OS: "linux", Variant: "v7"},
+	"i386":    {Architecture: "386", OS: "linux"},
+	"ppc64el": {Architecture: "ppc64le", OS: "linux"},
+	"riscv64": {Architecture: "riscv64", OS: "linux"},
+	"s390x":   {Architecture: "s390x", OS: "linux"},
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Build the image of a root.
+func Build(root string, opts *Options) (*Image, error) {
+	platform, ok := platforms[opts.Arch]
+	if !ok {
+		return nil, fmt.Errorf("unsupported architecture %q", opts.Arch)
+	}
+	created := opts.Created
+	if created.IsZero() {
+		created = time.Unix(0, 0)
+	}
+
+	var layerTar bytes.Buffer
+	if err := rootfs.WriteTar(&layerTar, root, &rootfs.TarOptions{ModTime: created}); err != nil {
+		return nil, fmt.Errorf("cannot archive root: %w", err)
+	}
+	var layer bytes.Buffer
+	gz := gzip.NewWriter(&layer)
+	if _, err := gz.Write(layerTar.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	config, err := json.Marshal(&ImageConfig{
+		Created:      created.UTC().Format(time.RFC3339),
+		Architecture: platform.Architecture,
+		Variant:      platform.Variant,
+		OS:           platform.OS,
+		Config: ContainerConfig{
+			User:       opts.User,
+			Env:        opts.Env,
+			Entrypoint: opts.Entrypoint,
+			Cmd:        opts.Cmd,
+			WorkingDir: opts.WorkingDir,
+			Labels:     opts.Labels,
+		},
+		RootFS: RootFS{
+			Type:    "layers",
+			DiffIDs: []string{digest(layerTar.Bytes())},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := json.Marshal(&Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeManifest,
+		Config: Descriptor{
+			MediaType: MediaTypeConfig,
+			Digest:    digest(config),
+			Size:      int64(len(config)),
+		},
+		Layers: []*Descriptor{{
+			MediaType: MediaTypeLayer,
+			Digest:    digest(layer.Bytes()),
+			Size:      int64(layer.Len()),
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	img := &Image{
+		Manifest: &Descriptor{
+			MediaType: MediaTypeManifest,
+			Digest:    digest(manifest),
+			Size:      int64(len(manifest)),
+			Platform:  &platform,
+		},
+		manifest: manifest,
+		blobs: map[string][]byte{
+			digest(layer.Bytes()): layer.Bytes(),
+			digest(config):        config,
+		},
+	}
+	return img, nil
+}
+
+// layoutFiles returns the files of the image layout, by path.
+func (img *Image) layoutFiles(tag string) (map[string][]byte, error) {
+	desc := *img.Manifest
+	if tag != "" {
+		desc.Annotations = map[string]string{AnnotationRefName: tag}
+	}
+	index, err := json.Marshal(&Index{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeIndex,
+		Manifests:     []*Descriptor{&desc},
+	})
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		"oci-layout": []byte(`{"imageLayoutVersion":"1.0.0"}`),
+		"index.json": index,
+	}
+	files["blobs/sha256/"+img.Manifest.Digest[len("sha256:"):]] = img.manifest
+	for d, b := range img.blobs {
+		files["blobs/sha256/"+d[len("sha256:"):]] = b
+	}
+	return files, nil
+}
+
+// WriteLayout writes the image as an OCI image layout in dir.
+func (img *Image) WriteLayout(dir, tag string) error {
+	files, err := img.layoutFiles(tag)
+	if err != nil {
+		return err
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteArchive writes the image as a tar archive of an OCI image layout, as
+// accepted by "podman load" or "skopeo copy oci-archive:...".
+func (img *Image) WriteArchive(w io.Writer, tag string) error {
+	files, err := img.layoutFiles(tag)
+	if err != nil {
+		return err
+	}
+	names := []string{"oci-layout", "index.json", "blobs/", "blobs/sha256/"}
+	for name := range files {
+		if name != "oci-layout" && name != "index.json" {
+			names = append(names, name)
+		}
+	}
+	// Keep the blobs sorted for reproducible archives.
+	blobs := names[4:]
+	sort.Strings(blobs)
+
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0644, ModTime: time.Unix(0, 0), Format: tar.FormatPAX}
+		data, ok := files[name]
+		if ok {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(data))
+		} else {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
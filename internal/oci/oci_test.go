@@ -0,0 +1,112 @@
+package oci_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/oci"
+)
+
+func makeRoot(t *testing.T) string {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "usr/bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "usr/bin/hello"), []byte("hello"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func blobPath(dir, digest string) string {
+	return filepath.Join(dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
+
+func TestWriteLayout(t *testing.T) {
+	img, err := oci.Build(makeRoot(t), &oci.Options{
+		Arch:       "arm64",
+		User:       "584792",
+		Entrypoint: []string{"/usr/bin/hello"},
+		Labels:     map[string]string{"org.example": "yes"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := img.WriteLayout(dir, "v1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var index oci.Index
+	readJSON(t, filepath.Join(dir, "index.json"), &index)
+	if len(index.Manifests) != 1 || index.Manifests[0].Annotations[oci.AnnotationRefName] != "v1" {
+		t.Fatalf("bad index: %+v", index)
+	}
+	if p := index.Manifests[0].Platform; p.Architecture != "arm64" || p.Variant != "v8" {
+		t.Fatalf("bad platform: %+v", p)
+	}
+
+	var manifest oci.Manifest
+	readJSON(t, blobPath(dir, index.Manifests[0].Digest), &manifest)
+	if len(manifest.Layers) != 1 || manifest.Layers[0].MediaType != oci.MediaTypeLayer {
+		t.Fatalf("bad manifest: %+v", manifest)
+	}
+	if _, err := os.Stat(blobPath(dir, manifest.Layers[0].Digest)); err != nil {
+		t.Fatal(err)
+	}
+
+	var config oci.ImageConfig
+	readJSON(t, blobPath(dir, manifest.Config.Digest), &config)
+	want := oci.ContainerConfig{
+		User:       "584792",
+		Entrypoint: []string{"/usr/bin/hello"},
+		Labels:     map[string]string{"org.example": "yes"},
+	}
+	if !reflect.DeepEqual(config.Config, want) || config.Created != "1970-01-01T00:00:00Z" {
+		t.Fatalf("bad config: %+v", config)
+	}
+}
+
+func TestBuildReproducible(t *testing.T) {
+	opts := &oci.Options{Arch: "amd64"}
+	img1, err := oci.Build(makeRoot(t), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img2, err := oci.Build(makeRoot(t), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img1.Manifest.Digest != img2.Manifest.Digest {
+		t.Fatalf("images of identical roots differ: %s != %s", img1.Manifest.Digest, img2.Manifest.Digest)
+	}
+	var buf1, buf2 bytes.Buffer
+	if err := img1.WriteArchive(&buf1, "latest"); err != nil {
+		t.Fatal(err)
+	}
+	if err := img2.WriteArchive(&buf2, "latest"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+		t.Fatal("archives of identical images differ")
+	}
+
+	if _, err := oci.Build(makeRoot(t), &oci.Options{Arch: "mips"}); err == nil {
+		t.Fatal("have no error for an unsupported architecture")
+	}
+}
@@ -0,0 +1,245 @@
+package oci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// A Reference names an image in a registry.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+func (r *Reference) String() string {
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// ParseReference parses "[registry/]repository[:tag]". Without registry,
+// Docker Hub is assumed, and without tag, "latest".
+func ParseReference(s string) (*Reference, error) {
+	ref := &Reference{Registry: "registry-1.docker.io", Tag: "latest"}
+	name := s
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		name, ref.Tag = s[:i], s[i+1:]
+	}
+	first, rest, ok := strings.Cut(name, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry = first
+		name = rest
+	} else if !ok {
+		name = "library/" + name
+	}
+	if name == "" || ref.Tag == "" {
+		return nil, fmt.Errorf("invalid image reference %q", s)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+type PushOptions struct {
+	Username string
+	Password string
+	// Use plain HTTP, for local registries.
+	Insecure bool
+	Client   *http.Client
+}
+
+type pusher struct {
+	ref   *Reference
+	opts  *PushOptions
+	base  string
+	token string
+}
+
+// Push the image to a registry with the OCI distribution API.
+func (img *Image) Push(ref *Reference, opts *PushOptions) error {
+	if opts == nil {
+		opts = &PushOptions{}
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	scheme := "https"
+	if opts.Insecure {
+		scheme = "http"
+	}
+	p := &pusher{
+		ref:  ref,
+		opts: opts,
+		base: fmt.Sprintf("%s://%s/v2/%s", scheme, ref.Registry, ref.Repository),
+	}
+	for d, blob := range img.blobs {
+		if err := p.pushBlob(d, blob); err != nil {
+			return fmt.Errorf("cannot push blob %s: %w", d, err)
+		}
+	}
+	resp, err := p.do(http.MethodPut, p.base+"/manifests/"+ref.Tag, MediaTypeManifest, img.manifest)
+	if err != nil {
+		return fmt.Errorf("cannot push manifest: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot push manifest: %s", resp.Status)
+	}
+	return nil
+}
+
+func (p *pusher) pushBlob(digest string, blob []byte) error {
+	resp, err := p.do(http.MethodHead, p.base+"/blobs/"+digest, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil // Already there.
+	}
+
+	resp, err = p.do(http.MethodPost, p.base+"/blobs/uploads/", "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("cannot start upload: %s", resp.Status)
+	}
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location: %w", err)
+	}
+	q := loc.Query()
+	q.Set("digest", digest)
+	loc.RawQuery = q.Encode()
+
+	resp, err = p.do(http.MethodPut, loc.String(), "application/octet-stream", blob)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("cannot upload: %s", resp.Status)
+	}
+	return nil
+}
+
+// do sends a request, authenticating and retrying once if the registry asks
+// for it.
+func (p *pusher) do(method, u, contentType string, body []byte) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequest(method, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		switch {
+		case p.token != "":
+			req.Header.Set("Authorization", "Bearer "+p.token)
+		case p.opts.Username != "":
+			req.SetBasicAuth(p.opts.Username, p.opts.Password)
+		}
+		return p.opts.Client.Do(req)
+	}
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, fmt.Errorf("unauthorized: check the registry credentials")
+	}
+	if err := p.authenticate(challenge); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// authenticate gets a bearer token following a WWW-Authenticate challenge.
+// See https://distribution.github.io/distribution/spec/auth/token/.
+func (p *pusher) authenticate(challenge string) error {
+	params := parseChallenge(challenge[len("bearer "):])
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid authentication challenge %q", challenge)
+	}
+	q := realm.Query()
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + p.ref.Repository + ":pull,push"
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if p.opts.Username != "" {
+		req.SetBasicAuth(p.opts.Username, p.opts.Password)
+	}
+	resp, err := p.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot get registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot get registry token: %s", resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return fmt.Errorf("invalid registry token response: %w", err)
+	}
+	p.token = token.Token
+	if p.token == "" {
+		p.token = token.AccessToken
+	}
+	if p.token == "" {
+		return fmt.Errorf("registry returned an empty token")
+	}
+	return nil
+}
+
+// parseChallenge parses the comma separated key="value" pairs of a
+// WWW-Authenticate header.
+func parseChallenge(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+		params[key] = value
+		s = strings.TrimLeft(rest, ", ")
+	}
+	return params
+}
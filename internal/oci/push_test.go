@@ -0,0 +1,116 @@
+package oci_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/oci"
+)
+
+var referenceTests = []struct {
+	ref  string
+	want *oci.Reference
+}{
+	{"hello", &oci.Reference{"registry-1.docker.io", "library/hello", "latest"}},
+	{"org/hello:1.0", &oci.Reference{"registry-1.docker.io", "org/hello", "1.0"}},
+	{"ghcr.io/org/hello:edge", &oci.Reference{"ghcr.io", "org/hello", "edge"}},
+	{"localhost:5000/hello", &oci.Reference{"localhost:5000", "hello", "latest"}},
+}
+
+func TestParseReference(t *testing.T) {
+	for _, tc := range referenceTests {
+		ref, err := oci.ParseReference(tc.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ref, tc.want) {
+			t.Fatalf("%s: have %+v, want %+v", tc.ref, ref, tc.want)
+		}
+	}
+	if _, err := oci.ParseReference("hello:"); err == nil {
+		t.Fatal("have no error for an empty tag")
+	}
+}
+
+// fakeRegistry implements just enough of the distribution API, behind a
+// bearer token authentication.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string]bool
+	manifests map[string]string
+	realm     string
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.URL.Path == "/token" {
+		if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, `{"token":"secret"}`)
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer secret" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.realm+`",service="fake"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/v2/org/hello"
+	path := strings.TrimPrefix(req.URL.Path, prefix)
+	switch {
+	case req.Method == http.MethodHead && strings.HasPrefix(path, "/blobs/"):
+		if !r.blobs[strings.TrimPrefix(path, "/blobs/")] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case req.Method == http.MethodPost && path == "/blobs/uploads/":
+		w.Header().Set("Location", prefix+"/blobs/uploads/123?state=x")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPut && path == "/blobs/uploads/123":
+		r.blobs[req.URL.Query().Get("digest")] = true
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodPut && strings.HasPrefix(path, "/manifests/"):
+		data, _ := io.ReadAll(req.Body)
+		r.manifests[strings.TrimPrefix(path, "/manifests/")] = string(data)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestPush(t *testing.T) {
+	reg := &fakeRegistry{blobs: make(map[string]bool), manifests: make(map[string]string)}
+	srv := httptest.NewServer(reg)
+	defer srv.Close()
+	reg.realm = srv.URL + "/token"
+
+	img, err := oci.Build(makeRoot(t), &oci.Options{Arch: "amd64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := oci.ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/org/hello:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = img.Push(ref, &oci.PushOptions{Username: "user", Password: "pass", Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.blobs) != 2 {
+		t.Fatalf("have %d blobs pushed, want 2", len(reg.blobs))
+	}
+	if !strings.Contains(reg.manifests["v1"], oci.MediaTypeLayer) {
+		t.Fatalf("bad manifest pushed: %q", reg.manifests["v1"])
+	}
+
+	err = img.Push(ref, &oci.PushOptions{Username: "user", Password: "wrong", Insecure: true})
+	if err == nil || !strings.Contains(err.Error(), "cannot get registry token") {
+		t.Fatalf("have error %v, want a token error", err)
+	}
+}
@@ -0,0 +1,246 @@
+// Package oval reads the OVAL data published by Ubuntu Security to find the
+// vulnerable package versions. See https://ubuntu.com/security/oval.
+//
+// Only the dpkginfo tests are considered, which covers both the USN and the
+// CVE OVAL feeds.
+package oval
+
+import (
+	"compress/bzip2"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/debversion"
+)
+
+// URL of the USN OVAL feed of an Ubuntu release, by codename.
+func URL(codename string) string {
+	return fmt.Sprintf("https://security-metadata.canonical.com/oval/com.ubuntu.%s.usn.oval.xml.bz2", codename)
+}
+
+type Definition struct {
+	ID    string
+	Title string
+	CVEs  []string
+	Fixes []*Fix
+}
+
+// A Fix tells that the versions of a package lower than Version are
+// vulnerable.
+type Fix struct {
+	Package string
+	Version string
+}
+
+type ovalDoc struct {
+	Definitions []*ovalDefinition `xml:"definitions>definition"`
+	Tests       ovalItems         `xml:"tests"`
+	Objects     ovalItems         `xml:"objects"`
+	States      ovalItems         `xml:"states"`
+	Variables   ovalItems         `xml:"variables"`
+}
+
+type ovalDefinition struct {
+	ID         string          `xml:"id,attr"`
+	Title      string          `xml:"metadata>title"`
+	References []ovalReference `xml:"metadata>reference"`
+	AdvCVEs    []string        `xml:"metadata>advisory>cve"`
+	Criteria   ovalCriteria    `xml:"criteria"`
+}
+
+type ovalReference struct {
+	Source string `xml:"source,attr"`
+	ID     string `xml:"ref_id,attr"`
+}
+
+type ovalCriteria struct {
+	Criterions []ovalRef       `xml:"criterion"`
+	Criteria   []*ovalCriteria `xml:"criteria"`
+}
+
+type ovalRef struct {
+	Test   string `xml:"test_ref,attr"`
+	Object string `xml:"object_ref,attr"`
+	State  string `xml:"state_ref,attr"`
+	Var    string `xml:"var_ref,attr"`
+	Op     string `xml:"operation,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// The tests, objects, states and variables are all kept as generic items
+// as their elements depend on the platform.
+type ovalItems struct {
+	Items []*ovalItem `xml:",any"`
+}
+
+type ovalItem struct {
+	XMLName xml.Name
+	ID      string   `xml:"id,attr"`
+	Object  ovalRef  `xml:"object"`
+	State   ovalRef  `xml:"state"`
+	Name    ovalRef  `xml:"name"`
+	EVR     ovalRef  `xml:"evr"`
+	Values  []string `xml:"value"`
+}
+
+func index(items ovalItems) map[string]*ovalItem {
+	m := make(map[string]*ovalItem)
+	for _, i := range items.Items {
+		m[i.ID] = i
+	}
+	return m
+}
+
+// Parse an OVAL document.
+func Parse(r io.Reader) ([]*Definition, error) {
+	var doc ovalDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("cannot parse OVAL data: %w", err)
+	}
+	tests := index(doc.Tests)
+	objects := index(doc.Objects)
+	states := index(doc.States)
+	variables := index(doc.Variables)
+
+	// fixes resolves a dpkginfo test into the fixed version of each package.
+	fixes := func(testID string) []*Fix {
+		test, ok := tests[testID]
+		if !ok || test.XMLName.Local != "dpkginfo_test" {
+			return nil
+		}
+		obj, ok := objects[test.Object.Object]
+		if !ok {
+			return nil
+		}
+		state, ok := states[test.State.State]
+		if !ok || state.EVR.Op != "less than" {
+			return nil
+		}
+		var names []string
+		if obj.Name.Var != "" {
+			if v, ok := variables[obj.Name.Var]; ok {
+				names = v.Values
+			}
+		} else if n := strings.TrimSpace(obj.Name.Value); n != "" {
+			names = []string{n}
+		}
+		var res []*Fix
+		for _, n := range names {
+			res = append(res, &Fix{
+				Package: strings.TrimSpace(n),
+				Version: strings.TrimSpace(state.EVR.Value),
+			})
+		}
+		return res
+	}
+
+	var defs []*Definition
+	for _, d := range doc.Definitions {
+		def := &Definition{ID: d.ID, Title: strings.TrimSpace(d.Title)}
+		seen := make(map[string]bool)
+		addCVE := func(cve string) {
+			cve = strings.TrimSpace(cve)
+			if strings.HasPrefix(cve, "CVE-") && !seen[cve] {
+				seen[cve] = true
+				def.CVEs = append(def.CVEs, cve)
+			}
+		}
+		for _, r := range d.References {
+			if r.Source == "CVE" {
+				addCVE(r.ID)
+			}
+		}
+		for _, c := range d.AdvCVEs {
+			addCVE(c)
+		}
+		var walk func(c *ovalCriteria)
+		walk = func(c *ovalCriteria) {
+			for _, cr := range c.Criterions {
+				def.Fixes = append(def.Fixes, fixes(cr.Test)...)
+			}
+			for _, sub := range c.Criteria {
+				walk(sub)
+			}
+		}
+		walk(&d.Criteria)
+		if len(def.Fixes) > 0 {
+			defs = append(defs, def)
+		}
+	}
+	return defs, nil
+}
+
+// Load the OVAL data from a file path or an HTTP(S) URL. Data ending in
+// ".bz2" is decompressed.
+func Load(location string) ([]*Definition, error) {
+	var r io.Reader
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		resp, err := http.Get(location)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch OVAL data: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("cannot fetch OVAL data: %s: %s", location, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	if strings.HasSuffix(location, ".bz2") {
+		r = bzip2.NewReader(r)
+	}
+	return Parse(r)
+}
+
+type Finding struct {
+	Package    string   `json:"package"`
+	Version    string   `json:"version"`
+	Fixed      string   `json:"fixed"`
+	Definition string   `json:"definition"`
+	Title      string   `json:"title"`
+	CVEs       []string `json:"cves"`
+}
+
+// Scan returns the definitions affecting the given package versions, sorted
+// by package and definition.
+func Scan(defs []*Definition, versions map[string]string) []*Finding {
+	var findings []*Finding
+	for _, d := range defs {
+		seen := make(map[string]bool)
+		for _, f := range d.Fixes {
+			v, ok := versions[f.Package]
+			if !ok || seen[f.Package] {
+				continue
+			}
+			if debversion.Compare(v, f.Version) < 0 {
+				seen[f.Package] = true
+				findings = append(findings, &Finding{
+					Package:    f.Package,
+					Version:    v,
+					Fixed:      f.Version,
+					Definition: d.ID,
+					Title:      d.Title,
+					CVEs:       d.CVEs,
+				})
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Package != findings[j].Package {
+			return findings[i].Package < findings[j].Package
+		}
+		return findings[i].Definition < findings[j].Definition
+	})
+	return findings
+}
@@ -0,0 +1,135 @@
+package oval_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/oval"
+)
+
+const sampleOVAL = `<?xml version="1.0" ?>
+<oval_definitions
+    xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5"
+    xmlns:ind-def="http://oval.mitre.org/XMLSchema/oval-definitions-5#independent"
+    xmlns:linux-def="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <definitions>
+    <definition class="patch" id="oval:com.ubuntu.noble:def:67891000000" version="1">
+      <metadata>
+        <title>USN-6789-1 -- OpenSSL vulnerabilities</title>
+        <reference source="USN" ref_id="USN-6789-1" ref_url="https://ubuntu.com/security/notices/USN-6789-1"/>
+        <reference source="CVE" ref_id="CVE-2024-0001" ref_url="https://ubuntu.com/security/CVE-2024-0001"/>
+        <advisory>
+          <cve href="https://ubuntu.com/security/CVE-2024-0001">CVE-2024-0001</cve>
+          <cve href="https://ubuntu.com/security/CVE-2024-0002">CVE-2024-0002</cve>
+        </advisory>
+      </metadata>
+      <criteria operator="AND">
+        <extend_definition definition_ref="oval:com.ubuntu.noble:def:100"/>
+        <criteria operator="OR">
+          <criterion test_ref="oval:com.ubuntu.noble:tst:67891000000" comment="binaries"/>
+        </criteria>
+      </criteria>
+    </definition>
+    <definition class="patch" id="oval:com.ubuntu.noble:def:11110000000" version="1">
+      <metadata>
+        <title>USN-1111-1 -- hello vulnerability</title>
+        <reference source="CVE" ref_id="CVE-2023-1111"/>
+      </metadata>
+      <criteria>
+        <criterion test_ref="oval:com.ubuntu.noble:tst:11110000000" comment="hello"/>
+      </criteria>
+    </definition>
+    <definition class="inventory" id="oval:com.ubuntu.noble:def:100" version="1">
+      <metadata><title>Check that Ubuntu 24.04 LTS is installed.</title></metadata>
+      <criteria>
+        <criterion test_ref="oval:com.ubuntu.noble:tst:100" comment="release"/>
+      </criteria>
+    </definition>
+  </definitions>
+  <tests>
+    <ind-def:textfilecontent54_test id="oval:com.ubuntu.noble:tst:100" check="at least one">
+      <ind-def:object object_ref="oval:com.ubuntu.noble:obj:100"/>
+    </ind-def:textfilecontent54_test>
+    <linux-def:dpkginfo_test id="oval:com.ubuntu.noble:tst:67891000000" check="at least one">
+      <linux-def:object object_ref="oval:com.ubuntu.noble:obj:67891000000"/>
+      <linux-def:state state_ref="oval:com.ubuntu.noble:ste:67891000000"/>
+    </linux-def:dpkginfo_test>
+    <linux-def:dpkginfo_test id="oval:com.ubuntu.noble:tst:11110000000" check="at least one">
+      <linux-def:object object_ref="oval:com.ubuntu.noble:obj:11110000000"/>
+      <linux-def:state state_ref="oval:com.ubuntu.noble:ste:11110000000"/>
+    </linux-def:dpkginfo_test>
+  </tests>
+  <objects>
+    <linux-def:dpkginfo_object id="oval:com.ubuntu.noble:obj:67891000000" version="1">
+      <linux-def:name var_ref="oval:com.ubuntu.noble:var:67891000000" var_check="at least one"/>
+    </linux-def:dpkginfo_object>
+    <linux-def:dpkginfo_object id="oval:com.ubuntu.noble:obj:11110000000" version="1">
+      <linux-def:name>hello</linux-def:name>
+    </linux-def:dpkginfo_object>
+  </objects>
+  <states>
+    <linux-def:dpkginfo_state id="oval:com.ubuntu.noble:ste:67891000000" version="1">
+      <linux-def:evr datatype="debian_evr_string" operation="less than">0:3.0.13-0ubuntu3.1</linux-def:evr>
+    </linux-def:dpkginfo_state>
+    <linux-def:dpkginfo_state id="oval:com.ubuntu.noble:ste:11110000000" version="1">
+      <linux-def:evr datatype="debian_evr_string" operation="less than">2.10-3ubuntu1</linux-def:evr>
+    </linux-def:dpkginfo_state>
+  </states>
+  <variables>
+    <constant_variable id="oval:com.ubuntu.noble:var:67891000000" version="1" datatype="string">
+      <value>libssl3t64</value>
+      <value>openssl</value>
+    </constant_variable>
+  </variables>
+</oval_definitions>
+`
+
+func TestParse(t *testing.T) {
+	defs, err := oval.Parse(strings.NewReader(sampleOVAL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*oval.Definition{{
+		ID:    "oval:com.ubuntu.noble:def:67891000000",
+		Title: "USN-6789-1 -- OpenSSL vulnerabilities",
+		CVEs:  []string{"CVE-2024-0001", "CVE-2024-0002"},
+		Fixes: []*oval.Fix{
+			{Package: "libssl3t64", Version: "0:3.0.13-0ubuntu3.1"},
+			{Package: "openssl", Version: "0:3.0.13-0ubuntu3.1"},
+		},
+	}, {
+		ID:    "oval:com.ubuntu.noble:def:11110000000",
+		Title: "USN-1111-1 -- hello vulnerability",
+		CVEs:  []string{"CVE-2023-1111"},
+		Fixes: []*oval.Fix{
+			{Package: "hello", Version: "2.10-3ubuntu1"},
+		},
+	}}
+	if !reflect.DeepEqual(defs, want) {
+		t.Fatalf("have %+v, want %+v", defs, want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	defs, err := oval.Parse(strings.NewReader(sampleOVAL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := oval.Scan(defs, map[string]string{
+		"libssl3t64": "3.0.13-0ubuntu3",
+		"hello":      "2.10-3ubuntu1",
+		"libc6":      "2.39-0ubuntu8",
+	})
+	want := []*oval.Finding{{
+		Package:    "libssl3t64",
+		Version:    "3.0.13-0ubuntu3",
+		Fixed:      "0:3.0.13-0ubuntu3.1",
+		Definition: "oval:com.ubuntu.noble:def:67891000000",
+		Title:      "USN-6789-1 -- OpenSSL vulnerabilities",
+		CVEs:       []string{"CVE-2024-0001", "CVE-2024-0002"},
+	}}
+	if !reflect.DeepEqual(findings, want) {
+		t.Fatalf("have %+v, want %+v", findings, want)
+	}
+}
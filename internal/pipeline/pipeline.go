@@ -0,0 +1,183 @@
+// Package pipeline reads the pipeline files of sdf ci, which declare the
+// stages to run on a release, and reports the results of the stages.
+//
+// For example:
+//
+//	release: .
+//	arch: amd64
+//	# Only check what changed since the ref, everything if not set.
+//	changed-since: origin/main
+//	# Chisel cache shared by the stages and kept between runs.
+//	cache-dir: .cache/sdf
+//	stages:
+//	  - lint
+//	  - name: install
+//	    prune: true
+//	  - name: test
+//	    run: ^openssl/
+//	  - name: coverage
+//	    all: true
+//	    min: [80%]
+//	reports:
+//	  markdown: report.md
+//	  json: report.json
+//
+// Relative paths are relative to the directory of the pipeline file.
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Release string `yaml:"release"`
+	Arch    string `yaml:"arch"`
+	Workers int    `yaml:"workers"`
+	// Ref to find the changed files since, with git. All files changed if
+	// empty.
+	ChangedSince string `yaml:"changed-since"`
+	// Directory of the chisel caches of the stages, a temporary one if
+	// empty.
+	CacheDir string `yaml:"cache-dir"`
+	// Whether to skip the stages after the first one failing.
+	FailFast bool     `yaml:"fail-fast"`
+	Stages   []*Stage `yaml:"stages"`
+	Reports  Reports  `yaml:"reports"`
+}
+
+// Reports are the files to write the report of the run to, if not empty.
+type Reports struct {
+	Markdown string `yaml:"markdown"`
+	JSON     string `yaml:"json"`
+}
+
+// Stages by name, with the options each of them accepts.
+var stageOptions = map[string][]string{
+	"lint":     {"checks"},
+	"install":  {"all", "prune", "combine"},
+	"test":     {"all", "run", "backend"},
+	"coverage": {"all", "min"},
+}
+
+// StageNames returns the names of the stages, sorted.
+func StageNames() []string {
+	var names []string
+	for name := range stageOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// A Stage is a stage of the pipeline. It is given by its name only when it
+// has no options.
+type Stage struct {
+	Name string `yaml:"name"`
+	// Lint checks to run, all of them if empty.
+	Checks []string `yaml:"checks"`
+	// Whether to install, test or measure the coverage of all the slices,
+	// not only those affected by the changes.
+	All bool `yaml:"all"`
+	// Install options, see the flags of the same names.
+	Prune   bool `yaml:"prune"`
+	Combine bool `yaml:"combine"`
+	// Test options, see the flags of the same names.
+	Run     string `yaml:"run"`
+	Backend string `yaml:"backend"`
+	// Coverage thresholds, see the flag of the same name.
+	Min []string `yaml:"min"`
+}
+
+func (s *Stage) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		s.Name = n.Value
+		if _, ok := stageOptions[s.Name]; !ok {
+			return fmt.Errorf("line %d: unknown stage %q, want one of %s", n.Line, s.Name, strings.Join(StageNames(), ", "))
+		}
+		return nil
+	}
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: stage must be a name or a map", n.Line)
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == "name" {
+			s.Name = n.Content[i+1].Value
+		}
+	}
+	options, ok := stageOptions[s.Name]
+	if !ok {
+		return fmt.Errorf("line %d: unknown stage %q, want one of %s", n.Line, s.Name, strings.Join(StageNames(), ", "))
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		key := n.Content[i]
+		if key.Value != "name" && !slices.Contains(options, key.Value) {
+			return fmt.Errorf("line %d: unknown option %q of stage %s", key.Line, key.Value, s.Name)
+		}
+	}
+	type plain Stage
+	return n.Decode((*plain)(s))
+}
+
+// Parse parses the pipeline file, setting the defaults of the values not
+// given.
+func Parse(data []byte) (*Config, error) {
+	c := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return nil, err
+	}
+	if c.Release == "" {
+		c.Release = "."
+	}
+	if c.Arch == "" {
+		c.Arch = "amd64"
+	}
+	if c.Workers == 0 {
+		c.Workers = 4
+	}
+	if c.Workers < 0 {
+		return nil, fmt.Errorf("invalid workers: %d", c.Workers)
+	}
+	if len(c.Stages) == 0 {
+		return nil, fmt.Errorf("no stages")
+	}
+	for _, s := range c.Stages {
+		switch s.Backend {
+		case "":
+			s.Backend = "unshare"
+		case "unshare", "chroot":
+		default:
+			return nil, fmt.Errorf("invalid backend of stage %s: %q, want unshare or chroot", s.Name, s.Backend)
+		}
+	}
+	return c, nil
+}
+
+// ReadFile reads the pipeline file at path, joining the relative paths in it
+// to the directory of the file.
+func ReadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for _, p := range []*string{&c.Release, &c.CacheDir, &c.Reports.Markdown, &c.Reports.JSON} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return c, nil
+}
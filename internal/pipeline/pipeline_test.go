@@ -0,0 +1,141 @@
+package pipeline_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/pipeline"
+)
+
+var parseTests = []struct {
+	summary string
+	input   string
+	config  *pipeline.Config
+	err     string
+}{{
+	summary: "Defaults",
+	input:   "stages: [lint]",
+	config: &pipeline.Config{
+		Release: ".",
+		Arch:    "amd64",
+		Workers: 4,
+		Stages:  []*pipeline.Stage{{Name: "lint", Backend: "unshare"}},
+	},
+}, {
+	summary: "All values",
+	input: `
+release: releases/ubuntu-24.04
+arch: arm64
+workers: 8
+changed-since: origin/main
+cache-dir: /var/cache/sdf
+fail-fast: true
+stages:
+  - name: lint
+    checks: [path]
+  - name: install
+    prune: true
+    combine: true
+  - name: test
+    run: ^hello/
+    backend: chroot
+  - name: coverage
+    all: true
+    min: [80%, hello=90%]
+reports:
+  markdown: report.md
+  json: report.json
+`,
+	config: &pipeline.Config{
+		Release:      "releases/ubuntu-24.04",
+		Arch:         "arm64",
+		Workers:      8,
+		ChangedSince: "origin/main",
+		CacheDir:     "/var/cache/sdf",
+		FailFast:     true,
+		Stages: []*pipeline.Stage{
+			{Name: "lint", Checks: []string{"path"}, Backend: "unshare"},
+			{Name: "install", Prune: true, Combine: true, Backend: "unshare"},
+			{Name: "test", Run: "^hello/", Backend: "chroot"},
+			{Name: "coverage", All: true, Min: []string{"80%", "hello=90%"}, Backend: "unshare"},
+		},
+		Reports: pipeline.Reports{Markdown: "report.md", JSON: "report.json"},
+	},
+}, {
+	summary: "Unknown field",
+	input:   "stages: [lint]\nstage: test\n",
+	err:     "yaml: unmarshal errors:\n  line 2: field stage not found in type pipeline.Config",
+}, {
+	summary: "Unknown stage",
+	input:   "stages: [lint, build]",
+	err:     `line 1: unknown stage "build", want one of coverage, install, lint, test`,
+}, {
+	summary: "Unknown stage in a map",
+	input:   "stages:\n  - name: build\n",
+	err:     `line 2: unknown stage "build", want one of coverage, install, lint, test`,
+}, {
+	summary: "Option of another stage",
+	input:   "stages:\n  - name: lint\n    prune: true\n",
+	err:     `line 3: unknown option "prune" of stage lint`,
+}, {
+	summary: "Invalid backend",
+	input:   "stages:\n  - name: test\n    backend: podman\n",
+	err:     `invalid backend of stage test: "podman", want unshare or chroot`,
+}, {
+	summary: "No stages",
+	input:   "release: .",
+	err:     "no stages",
+}, {
+	summary: "Negative workers",
+	input:   "workers: -1\nstages: [lint]",
+	err:     "invalid workers: -1",
+}}
+
+func TestParse(t *testing.T) {
+	for _, test := range parseTests {
+		t.Logf("Summary: %s", test.summary)
+		c, err := pipeline.Parse([]byte(test.input))
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("have error %v, want %q", err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c, test.config) {
+			t.Fatalf("have %+v, want %+v", c, test.config)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ci", "pipeline.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := "release: ..\ncache-dir: /cache\nstages: [lint]\nreports:\n  json: out/report.json\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := pipeline.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Release != dir {
+		t.Fatalf("have release %q, want %q", c.Release, dir)
+	}
+	if c.CacheDir != "/cache" {
+		t.Fatalf("have cache directory %q, want %q", c.CacheDir, "/cache")
+	}
+	if want := filepath.Join(dir, "ci", "out", "report.json"); c.Reports.JSON != want {
+		t.Fatalf("have JSON report %q, want %q", c.Reports.JSON, want)
+	}
+	if c.Reports.Markdown != "" {
+		t.Fatalf("have markdown report %q, want none", c.Reports.Markdown)
+	}
+}
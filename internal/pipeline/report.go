@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Status string
+
+const (
+	Passed  Status = "passed"
+	Failed  Status = "failed"
+	Skipped Status = "skipped"
+)
+
+// A Result is the outcome of a stage of a run.
+type Result struct {
+	Stage    string        `json:"stage"`
+	Status   Status        `json:"status"`
+	Summary  string        `json:"summary"`
+	Duration time.Duration `json:"duration"`
+	// Details are listed for the stages that failed.
+	Details []string `json:"details,omitempty"`
+}
+
+// A Report is the outcome of a run of the pipeline.
+type Report struct {
+	Release string `json:"release"`
+	// Files changed since the ref of the pipeline, relative to the release.
+	// Not set if everything was checked.
+	Changed  []string      `json:"changed,omitempty"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration"`
+	Results  []*Result     `json:"results"`
+}
+
+var statusIcons = map[Status]string{
+	Passed:  "✅",
+	Failed:  "❌",
+	Skipped: "⏭️",
+}
+
+// Markdown returns the markdown report of the results: a table of the
+// status of the stages, followed by the details of the failed ones.
+func Markdown(title string, results []*Result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", title)
+	b.WriteString("| Stage | Status | Summary | Duration |\n|---|---|---|---|\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "| %s | %s %s | %s | %s |\n", escapeCell(r.Stage), statusIcons[r.Status], r.Status, escapeCell(r.Summary), r.Duration.Round(100*time.Millisecond))
+	}
+	for _, r := range results {
+		if r.Status != Failed || len(r.Details) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details><summary>%s</summary>\n\n", r.Stage)
+		for _, d := range r.Details {
+			fmt.Fprintf(&b, "- `%s`\n", strings.ReplaceAll(strings.ReplaceAll(d, "`", "'"), "\n", " "))
+		}
+		b.WriteString("\n</details>\n")
+	}
+	return b.String()
+}
+
+func escapeCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
@@ -0,0 +1,41 @@
+package pipeline_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/pipeline"
+)
+
+func TestMarkdown(t *testing.T) {
+	have := pipeline.Markdown("sdf", []*pipeline.Result{{
+		Stage:    "lint",
+		Status:   pipeline.Failed,
+		Summary:  "2 issue(s)",
+		Duration: 120 * time.Millisecond,
+		Details:  []string{"slices/a.yaml: a_bins: path `a` is not absolute", "b | c"},
+	}, {
+		Stage:    "install",
+		Status:   pipeline.Passed,
+		Summary:  "3 slice(s) | 1 file(s)",
+		Duration: 83*time.Second + 449*time.Millisecond,
+		Details:  []string{"not shown"},
+	}, {
+		Stage:   "test",
+		Status:  pipeline.Skipped,
+		Summary: "no tests",
+	}})
+	want := "## sdf\n\n" +
+		"| Stage | Status | Summary | Duration |\n" +
+		"|---|---|---|---|\n" +
+		"| lint | ❌ failed | 2 issue(s) | 100ms |\n" +
+		"| install | ✅ passed | 3 slice(s) \\| 1 file(s) | 1m23.4s |\n" +
+		"| test | ⏭️ skipped | no tests | 0s |\n" +
+		"\n<details><summary>lint</summary>\n\n" +
+		"- `slices/a.yaml: a_bins: path 'a' is not absolute`\n" +
+		"- `b | c`\n" +
+		"\n</details>\n"
+	if have != want {
+		t.Fatalf("have:\n%s\nwant:\n%s", have, want)
+	}
+}
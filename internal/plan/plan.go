@@ -0,0 +1,101 @@
+// Package plan decides which slices to install and how to group them into
+// chisel invocations.
+package plan
+
+import (
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type PruneOptions struct {
+	// Slices to keep even if other slices depend on them.
+	Keep []string
+}
+
+// Prune the list of slices and return only the top-level slices that no slice
+// depends on. Installing these slices alone should cover all of the slices.
+// It depends on the acyclic dependency policy of chisel slices.
+func Prune(slices []*chisel.Slice, opts *PruneOptions) []*chisel.Slice {
+	if opts == nil {
+		opts = &PruneOptions{}
+	}
+	pending := make(map[string]*chisel.Slice)
+	for _, s := range slices {
+		pending[s.Name] = s
+	}
+	for _, s := range slices {
+		for _, e := range s.Essential {
+			delete(pending, e)
+		}
+	}
+	keep := make(map[string]bool)
+	for _, name := range opts.Keep {
+		keep[name] = true
+	}
+	var todo []*chisel.Slice
+	for _, s := range slices {
+		if _, ok := pending[s.Name]; ok || keep[s.Name] {
+			todo = append(todo, s)
+		}
+	}
+	return todo
+}
+
+type GroupOptions struct {
+	// Create only one group with all slices in it.
+	Combine bool
+	// Create one group per package.
+	ByPackage bool
+	// Split the groups so that none has more than Size slices. Zero means
+	// no limit.
+	Size int
+}
+
+// Group slices for installation. The order of the slices is kept, and the
+// groups are ordered by their first slice.
+func Group(slices []*chisel.Slice, opts *GroupOptions) [][]string {
+	if opts == nil {
+		opts = &GroupOptions{}
+	}
+	var grouped [][]string
+	switch {
+	case opts.Combine:
+		var names []string
+		for _, s := range slices {
+			names = append(names, s.Name)
+		}
+		if len(names) > 0 {
+			grouped = append(grouped, names)
+		}
+	case opts.ByPackage:
+		index := make(map[string]int)
+		for _, s := range slices {
+			i, ok := index[s.Package]
+			if !ok {
+				i = len(grouped)
+				index[s.Package] = i
+				grouped = append(grouped, nil)
+			}
+			grouped[i] = append(grouped[i], s.Name)
+		}
+	default:
+		for _, s := range slices {
+			grouped = append(grouped, []string{s.Name})
+		}
+	}
+	if opts.Size > 0 {
+		grouped = split(grouped, opts.Size)
+	}
+	return grouped
+}
+
+func split(groups [][]string, size int) [][]string {
+	var split [][]string
+	for _, g := range groups {
+		for len(g) > size {
+			split = append(split, g[:size:size])
+			g = g[size:]
+		}
+		split = append(split, g)
+	}
+	return split
+}
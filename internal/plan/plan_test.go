@@ -0,0 +1,134 @@
+package plan_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/plan"
+)
+
+var sampleSlices = []*chisel.Slice{{
+	Name:    "pkg1_slice1",
+	Package: "pkg1",
+	Essential: []string{
+		"pkg2_slice1",
+		"pkg1_slice2",
+	},
+}, {
+	Name:    "pkg1_slice2",
+	Package: "pkg1",
+}, {
+	Name:    "pkg2_slice1",
+	Package: "pkg2",
+	Essential: []string{
+		"pkg1_slice2",
+	},
+}, {
+	Name:    "pkg3_slice1",
+	Package: "pkg3",
+}}
+
+var pruneTests = []struct {
+	summary string
+	slices  []*chisel.Slice
+	opts    *plan.PruneOptions
+	pruned  []string
+}{{
+	summary: "Top level slices only",
+	slices:  sampleSlices,
+	pruned: []string{
+		"pkg1_slice1",
+		"pkg3_slice1",
+	},
+}, {
+	summary: "Keep slices",
+	slices:  sampleSlices,
+	opts: &plan.PruneOptions{
+		Keep: []string{"pkg1_slice2", "pkg9_unknown"},
+	},
+	pruned: []string{
+		"pkg1_slice1",
+		"pkg1_slice2",
+		"pkg3_slice1",
+	},
+}, {
+	summary: "No slices",
+}}
+
+func TestPrune(t *testing.T) {
+	for _, tc := range pruneTests {
+		t.Logf("Summary: %s", tc.summary)
+		slices := plan.Prune(tc.slices, tc.opts)
+		var pruned []string
+		for _, s := range slices {
+			pruned = append(pruned, s.Name)
+		}
+		if !reflect.DeepEqual(pruned, tc.pruned) {
+			t.Fatalf("have %v, want %v", pruned, tc.pruned)
+		}
+	}
+}
+
+var groupTests = []struct {
+	summary string
+	slices  []*chisel.Slice
+	opts    *plan.GroupOptions
+	groups  [][]string
+}{{
+	summary: "One slice per group",
+	slices:  sampleSlices,
+	groups: [][]string{
+		{"pkg1_slice1"},
+		{"pkg1_slice2"},
+		{"pkg2_slice1"},
+		{"pkg3_slice1"},
+	},
+}, {
+	summary: "Combine",
+	slices:  sampleSlices,
+	opts:    &plan.GroupOptions{Combine: true},
+	groups: [][]string{
+		{"pkg1_slice1", "pkg1_slice2", "pkg2_slice1", "pkg3_slice1"},
+	},
+}, {
+	summary: "Combine with group size",
+	slices:  sampleSlices,
+	opts:    &plan.GroupOptions{Combine: true, Size: 3},
+	groups: [][]string{
+		{"pkg1_slice1", "pkg1_slice2", "pkg2_slice1"},
+		{"pkg3_slice1"},
+	},
+}, {
+	summary: "Group by package",
+	slices:  sampleSlices,
+	opts:    &plan.GroupOptions{ByPackage: true},
+	groups: [][]string{
+		{"pkg1_slice1", "pkg1_slice2"},
+		{"pkg2_slice1"},
+		{"pkg3_slice1"},
+	},
+}, {
+	summary: "Group by package with group size",
+	slices:  sampleSlices,
+	opts:    &plan.GroupOptions{ByPackage: true, Size: 1},
+	groups: [][]string{
+		{"pkg1_slice1"},
+		{"pkg1_slice2"},
+		{"pkg2_slice1"},
+		{"pkg3_slice1"},
+	},
+}, {
+	summary: "No slices",
+	opts:    &plan.GroupOptions{Combine: true},
+}}
+
+func TestGroup(t *testing.T) {
+	for _, tc := range groupTests {
+		t.Logf("Summary: %s", tc.summary)
+		groups := plan.Group(tc.slices, tc.opts)
+		if !reflect.DeepEqual(groups, tc.groups) {
+			t.Fatalf("have %v, want %v", groups, tc.groups)
+		}
+	}
+}
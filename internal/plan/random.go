@@ -0,0 +1,57 @@
+package plan
+
+import (
+	"math/rand/v2"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+type RandomOptions struct {
+	// Seed of the generator. The same seed, options and slices give the
+	// same groups.
+	Seed uint64
+	// Number of groups.
+	Count int
+	// Minimum and maximum number of slices per group, both included. They
+	// are capped to the number of slices.
+	Min, Max int
+}
+
+// Random returns random groups of distinct slices, in random order, to find
+// the conflicts and ordering bugs that only appear when specific slices are
+// installed together.
+func Random(slices []*chisel.Slice, opts *RandomOptions) [][]string {
+	if len(slices) == 0 {
+		return nil
+	}
+	lo := max(1, min(opts.Min, len(slices)))
+	hi := max(lo, min(opts.Max, len(slices)))
+	r := rand.New(rand.NewPCG(opts.Seed, 0))
+	groups := make([][]string, 0, opts.Count)
+	for range opts.Count {
+		size := lo + r.IntN(hi-lo+1)
+		group := make([]string, 0, size)
+		for _, i := range r.Perm(len(slices))[:size] {
+			group = append(group, slices[i].Name)
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// Shrink returns a minimal subset of the failing group that still fails, by
+// removing one slice at a time for as long as fails is true. The order of
+// the slices is kept.
+func Shrink(group []string, fails func([]string) bool) []string {
+	for i := 0; i < len(group) && len(group) > 1; {
+		smaller := make([]string, 0, len(group)-1)
+		smaller = append(smaller, group[:i]...)
+		smaller = append(smaller, group[i+1:]...)
+		if fails(smaller) {
+			group = smaller
+		} else {
+			i++
+		}
+	}
+	return group
+}
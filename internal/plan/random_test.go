@@ -0,0 +1,86 @@
+package plan_test
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/plan"
+)
+
+func TestRandom(t *testing.T) {
+	opts := &plan.RandomOptions{Seed: 42, Count: 20, Min: 2, Max: 3}
+	groups := plan.Random(sampleSlices, opts)
+	if len(groups) != 20 {
+		t.Fatalf("have %d groups, want 20", len(groups))
+	}
+	for _, g := range groups {
+		if len(g) < 2 || len(g) > 3 {
+			t.Fatalf("have group %v, want 2 to 3 slices", g)
+		}
+		sorted := slices.Clone(g)
+		slices.Sort(sorted)
+		if len(slices.Compact(sorted)) != len(g) {
+			t.Fatalf("have group %v with duplicates", g)
+		}
+	}
+	if again := plan.Random(sampleSlices, opts); !reflect.DeepEqual(again, groups) {
+		t.Fatalf("have %v, want %v with the same seed", again, groups)
+	}
+	opts.Seed = 43
+	if other := plan.Random(sampleSlices, opts); reflect.DeepEqual(other, groups) {
+		t.Fatal("have the same groups with another seed")
+	}
+}
+
+func TestRandomSizes(t *testing.T) {
+	groups := plan.Random(sampleSlices, &plan.RandomOptions{Count: 5, Max: 10})
+	for _, g := range groups {
+		if len(g) < 1 || len(g) > len(sampleSlices) {
+			t.Fatalf("have group %v, want 1 to %d slices", g, len(sampleSlices))
+		}
+	}
+	if groups := plan.Random(nil, &plan.RandomOptions{Count: 5}); groups != nil {
+		t.Fatalf("have %v, want no groups", groups)
+	}
+}
+
+var shrinkTests = []struct {
+	summary string
+	group   []string
+	// The group fails if it holds all of these.
+	culprits []string
+	shrunk   []string
+}{{
+	summary:  "Conflicting pair",
+	group:    []string{"a_x", "b_x", "c_x", "d_x", "e_x"},
+	culprits: []string{"b_x", "d_x"},
+	shrunk:   []string{"b_x", "d_x"},
+}, {
+	summary:  "Single culprit",
+	group:    []string{"a_x", "b_x", "c_x"},
+	culprits: []string{"c_x"},
+	shrunk:   []string{"c_x"},
+}, {
+	summary:  "Nothing to remove",
+	group:    []string{"a_x", "b_x"},
+	culprits: []string{"a_x", "b_x"},
+	shrunk:   []string{"a_x", "b_x"},
+}}
+
+func TestShrink(t *testing.T) {
+	for _, tc := range shrinkTests {
+		t.Logf("Summary: %s", tc.summary)
+		shrunk := plan.Shrink(tc.group, func(g []string) bool {
+			for _, c := range tc.culprits {
+				if !slices.Contains(g, c) {
+					return false
+				}
+			}
+			return true
+		})
+		if !reflect.DeepEqual(shrunk, tc.shrunk) {
+			t.Fatalf("have %v, want %v", shrunk, tc.shrunk)
+		}
+	}
+}
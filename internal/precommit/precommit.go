@@ -0,0 +1,193 @@
+// Package precommit reads what is staged in a git repository, for checking
+// the slice definition files about to be committed, and installs the
+// pre-commit hook that does so.
+package precommit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A File is a file staged in the repository.
+type File struct {
+	// Path relative to the top of the repository.
+	Path string
+	// Data is the staged content, nil if the file is staged for removal.
+	Data []byte
+}
+
+// git runs the command in dir. Its output is returned on failure too.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Top returns the top directory of the worktree dir is in.
+func Top(dir string) (string, error) {
+	out, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Staged returns the files that differ between the index and HEAD in the
+// repository of dir, sorted by path. Renames are a removal and an addition.
+func Staged(dir string) ([]*File, error) {
+	out, err := git(dir, "diff", "--cached", "--name-status", "--no-renames", "-z")
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(fields) == 1 && fields[0] == "" {
+		return nil, nil
+	}
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("cannot parse staged files: %q", out)
+	}
+	var files []*File
+	for i := 0; i < len(fields); i += 2 {
+		status, path := fields[i], fields[i+1]
+		f := &File{Path: path}
+		if status != "D" {
+			if f.Data, err = git(dir, "show", ":"+path); err != nil {
+				return nil, err
+			}
+			if f.Data == nil {
+				f.Data = []byte{}
+			}
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Index returns the files in the index of the repository of dir under the
+// paths, which are relative to dir, as they are staged, sorted by path.
+func Index(dir string, paths ...string) ([]*File, error) {
+	out, err := git(dir, append([]string{"ls-files", "-z", "--stage", "--full-name", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	var batch bytes.Buffer
+	for _, entry := range strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00") {
+		if entry == "" {
+			continue
+		}
+		// <mode> <object> <stage>\t<path>
+		info, path, ok := strings.Cut(entry, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("cannot parse index entry: %q", entry)
+		}
+		if fields[0] == "160000" || fields[2] != "0" {
+			continue // Submodules have no content, conflicts no staged one.
+		}
+		files = append(files, &File{Path: path})
+		batch.WriteString(fields[1] + "\n")
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	// The objects are read at once, as releases have many files.
+	cmd := exec.Command("git", "-C", dir, "cat-file", "--batch")
+	cmd.Stdin = &batch
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	for _, f := range files {
+		header, rest, ok := bytes.Cut(data, []byte("\n"))
+		fields := strings.Fields(string(header))
+		var size int
+		if ok && len(fields) == 3 {
+			size, err = strconv.Atoi(fields[2])
+		}
+		if !ok || len(fields) != 3 || err != nil || size+1 > len(rest) {
+			return nil, fmt.Errorf("cannot read %s from the index: %q", f.Path, header)
+		}
+		f.Data = rest[:size:size]
+		data = rest[size+1:]
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// Committed returns the content of the file in HEAD in the repository of
+// dir, nil if it is not there. The path is relative to the top of the
+// repository.
+func Committed(dir, path string) ([]byte, error) {
+	if _, err := git(dir, "cat-file", "-e", "HEAD:"+path); err != nil {
+		// Neither the file nor HEAD may exist.
+		return nil, nil
+	}
+	data, err := git(dir, "show", "HEAD:"+path)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
+
+// hookMarker is in every hook written by [InstallHook], so that they can be
+// replaced.
+const hookMarker = "# Installed by sdf precommit --install."
+
+// HookScript returns the pre-commit hook running the command. The command
+// runs from the top of the worktree, as git runs hooks.
+func HookScript(command string) string {
+	return "#!/bin/sh\n" + hookMarker + "\nexec " + command + "\n"
+}
+
+// InstallHook writes the pre-commit hook running the command into the
+// repository of dir and returns its path. Hooks that sdf did not write are
+// only replaced if force is set.
+func InstallHook(dir, command string, force bool) (string, error) {
+	out, err := git(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	hooks := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(hooks) {
+		hooks = filepath.Join(dir, hooks)
+	}
+	path := filepath.Join(hooks, "pre-commit")
+	old, err := os.ReadFile(path)
+	if err == nil && !force && !bytes.Contains(old, []byte(hookMarker)) {
+		return "", fmt.Errorf("hook %s exists, replace it with --force", path)
+	} else if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err := os.MkdirAll(hooks, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(HookScript(command)), 0755); err != nil {
+		return "", err
+	}
+	// WriteFile leaves the mode of existing files alone.
+	return path, os.Chmod(path, 0755)
+}
+
+// Quote quotes s for the shell, if needed.
+func Quote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./=:") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,170 @@
+package precommit_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/precommit"
+)
+
+func run(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+}
+
+func write(t *testing.T, path, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func makeRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	run(t, repo, "init", "-q")
+	write(t, filepath.Join(repo, "release/slices/a.yaml"), "a")
+	write(t, filepath.Join(repo, "release/slices/b.yaml"), "b")
+	run(t, repo, "add", ".")
+	run(t, repo, "commit", "-q", "-m", "initial")
+	return repo
+}
+
+func TestStaged(t *testing.T) {
+	repo := makeRepo(t)
+	files, err := precommit.Staged(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("have %v, want no staged files", files)
+	}
+
+	write(t, filepath.Join(repo, "release/slices/a.yaml"), "staged a")
+	write(t, filepath.Join(repo, "release/slices/c.yaml"), "")
+	run(t, repo, "add", ".")
+	run(t, repo, "rm", "-q", "release/slices/b.yaml")
+	// Only the staged content counts.
+	write(t, filepath.Join(repo, "release/slices/a.yaml"), "unstaged a")
+
+	files, err = precommit.Staged(filepath.Join(repo, "release"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*precommit.File{
+		{Path: "release/slices/a.yaml", Data: []byte("staged a")},
+		{Path: "release/slices/b.yaml"},
+		{Path: "release/slices/c.yaml", Data: []byte{}},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("have %+v, want %+v", files, want)
+	}
+	top, err := precommit.Top(filepath.Join(repo, "release"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if real, _ := filepath.EvalSymlinks(repo); top != real {
+		t.Fatalf("have top %s, want %s", top, real)
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	repo := makeRepo(t)
+	path, err := precommit.InstallHook(filepath.Join(repo, "release"), "sdf precommit -r release", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(repo, ".git/hooks/pre-commit"); path != want {
+		t.Fatalf("have path %s, want %s", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := string(data), precommit.HookScript("sdf precommit -r release"); have != want {
+		t.Fatalf("have %q, want %q", have, want)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0755 {
+		t.Fatalf("have %v, %v, want an executable hook", info, err)
+	}
+
+	// Hooks of sdf are replaced, others only if forced.
+	if _, err := precommit.InstallHook(repo, "sdf precommit", false); err != nil {
+		t.Fatal(err)
+	}
+	write(t, path, "#!/bin/sh\nmake check\n")
+	if _, err := precommit.InstallHook(repo, "sdf precommit", false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("have %v, want an error about --force", err)
+	}
+	if _, err := precommit.InstallHook(repo, "sdf precommit", true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var quoteTests = []struct {
+	s, quoted string
+}{
+	{"release", "release"},
+	{"path/to/release-1.0", "path/to/release-1.0"},
+	{"my release", "'my release'"},
+	{"it's", `'it'\''s'`},
+	{"", "''"},
+}
+
+func TestQuote(t *testing.T) {
+	for _, test := range quoteTests {
+		if have := precommit.Quote(test.s); have != test.quoted {
+			t.Fatalf("have %s, want %s", have, test.quoted)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	repo := makeRepo(t)
+	write(t, filepath.Join(repo, "release/slices/a.yaml"), "staged a")
+	write(t, filepath.Join(repo, "release/slices/sub/c.yaml"), "")
+	write(t, filepath.Join(repo, "other/d.yaml"), "d")
+	run(t, repo, "add", ".")
+	run(t, repo, "rm", "-q", "release/slices/b.yaml")
+	// Only the staged content counts, and untracked files are not in the
+	// index.
+	write(t, filepath.Join(repo, "release/slices/a.yaml"), "unstaged a")
+	write(t, filepath.Join(repo, "release/slices/e.yaml"), "e")
+
+	files, err := precommit.Index(filepath.Join(repo, "release"), "chisel.yaml", "slices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*precommit.File{
+		{Path: "release/slices/a.yaml", Data: []byte("staged a")},
+		{Path: "release/slices/sub/c.yaml", Data: []byte{}},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("have %+v, want %+v", files, want)
+	}
+
+	data, err := precommit.Committed(repo, "release/slices/b.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "b" {
+		t.Fatalf("have %q in HEAD, want %q", data, "b")
+	}
+	if data, err := precommit.Committed(repo, "release/slices/sub/c.yaml"); err != nil || data != nil {
+		t.Fatalf("have %q, %v in HEAD, want nothing", data, err)
+	}
+}
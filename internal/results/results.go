@@ -0,0 +1,205 @@
+// Package results collects the outcome of the tasks of an install and
+// writes them as a report, in JSON or in the JUnit XML format CI systems
+// show the test results of.
+package results
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Result is the outcome of the installation of a group of slices.
+type Result struct {
+	Slices []string `json:"slices"`
+	Arch   string   `json:"arch"`
+	// Duration is written in JSON as duration_seconds.
+	Duration time.Duration `json:"-"`
+	Passed   bool          `json:"passed"`
+	Error    string        `json:"error,omitempty"`
+	// Why the installation was not run, or cancelled, if it was.
+	Skipped string `json:"skipped,omitempty"`
+	// Combined output of chisel.
+	Output string `json:"output,omitempty"`
+}
+
+func (r *Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(&struct {
+		*result
+		Duration float64 `json:"duration_seconds"`
+	}{(*result)(r), r.Duration.Seconds()})
+}
+
+// Name returns the name of the result, its slices.
+func (r *Result) Name() string {
+	return strings.Join(r.Slices, " ")
+}
+
+// A Report of the results of the tasks. It passed if every task passed,
+// none failed or was skipped.
+type Report struct {
+	Passed  bool `json:"passed"`
+	Tasks   int  `json:"tasks"`
+	Failed  int  `json:"failed"`
+	Skipped int  `json:"skipped"`
+	// Duration is written in JSON as duration_seconds.
+	Duration time.Duration `json:"-"`
+	Results  []*Result     `json:"results"`
+}
+
+func (r *Report) MarshalJSON() ([]byte, error) {
+	type report Report
+	return json.Marshal(&struct {
+		*report
+		Duration float64 `json:"duration_seconds"`
+	}{(*report)(r), r.Duration.Seconds()})
+}
+
+// A Collector gathers the results of tasks running concurrently.
+type Collector struct {
+	mu      sync.Mutex
+	start   time.Time
+	tasks   int
+	results []*Result
+}
+
+// NewCollector returns a collector of the results of the number of tasks.
+func NewCollector(tasks int) *Collector {
+	return &Collector{start: time.Now(), tasks: tasks}
+}
+
+func (c *Collector) Add(r *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, r)
+}
+
+// Report returns the report of the results collected so far, sorted by
+// arch and slices.
+func (c *Collector) Report() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := &Report{
+		Tasks:    c.tasks,
+		Duration: time.Since(c.start),
+		Results:  append([]*Result(nil), c.results...),
+	}
+	sort.SliceStable(r.Results, func(i, j int) bool {
+		a, b := r.Results[i], r.Results[j]
+		if a.Arch != b.Arch {
+			return a.Arch < b.Arch
+		}
+		return a.Name() < b.Name()
+	})
+	for _, res := range c.results {
+		switch {
+		case res.Skipped != "":
+			r.Skipped++
+		case !res.Passed:
+			r.Failed++
+		}
+	}
+	r.Passed = r.Failed == 0 && r.Skipped == 0
+	return r
+}
+
+type junitSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Name     string        `xml:"name,attr"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Skipped  int           `xml:"skipped,attr"`
+	Time     string        `xml:"time,attr"`
+	Suites   []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Cases    []*junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit writes the report in the JUnit XML format, with a test suite
+// for every arch and a test case for every task. The output of chisel is
+// the text of the failures, or the standard output of the cases that passed.
+// The tasks that were skipped have the reason as the message.
+func WriteJUnit(w io.Writer, r *Report) error {
+	doc := &junitSuites{
+		Name:     "install",
+		Tests:    r.Tasks,
+		Failures: r.Failed,
+		Skipped:  r.Skipped,
+		Time:     seconds(r.Duration),
+	}
+	suites := make(map[string]*junitSuite)
+	durations := make(map[string]time.Duration)
+	for _, res := range r.Results {
+		s, ok := suites[res.Arch]
+		if !ok {
+			s = &junitSuite{Name: "install on " + res.Arch}
+			suites[res.Arch] = s
+			doc.Suites = append(doc.Suites, s)
+		}
+		c := &junitCase{
+			Name:      res.Name(),
+			ClassName: res.Arch,
+			Time:      seconds(res.Duration),
+		}
+		switch {
+		case res.Skipped != "":
+			c.Skipped = &junitSkipped{Message: res.Skipped}
+			c.SystemOut = res.Output
+			s.Skipped++
+		case res.Passed:
+			c.SystemOut = res.Output
+		default:
+			c.Failure = &junitFailure{Message: res.Error, Text: res.Output}
+			s.Failures++
+		}
+		s.Tests++
+		s.Cases = append(s.Cases, c)
+		durations[res.Arch] += res.Duration
+		s.Time = seconds(durations[res.Arch])
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
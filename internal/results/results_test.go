@@ -0,0 +1,124 @@
+package results_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/results"
+)
+
+func TestCollector(t *testing.T) {
+	c := results.NewCollector(5)
+	c.Add(&results.Result{Slices: []string{"b_bins"}, Arch: "amd64", Error: "failed"})
+	c.Add(&results.Result{Slices: []string{"a_bins"}, Arch: "arm64", Passed: true})
+	c.Add(&results.Result{Slices: []string{"a_bins"}, Arch: "amd64", Passed: true})
+	r := c.Report()
+	// The tasks are those to run, not those that finished.
+	if r.Passed || r.Tasks != 5 || r.Failed != 1 || r.Skipped != 0 {
+		t.Fatalf("have passed %v with %d of %d task(s) failed and %d skipped, want false with 1 of 5 and 0", r.Passed, r.Failed, r.Tasks, r.Skipped)
+	}
+	var names []string
+	for _, res := range r.Results {
+		names = append(names, res.Name()+" on "+res.Arch)
+	}
+	if want := []string{"a_bins on amd64", "b_bins on amd64", "a_bins on arm64"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("have %v, want %v", names, want)
+	}
+
+	// The results added later are not in the report.
+	c.Add(&results.Result{Slices: []string{"c_bins"}, Arch: "amd64", Passed: true})
+	if len(r.Results) != 3 {
+		t.Fatalf("have %d result(s), want 3", len(r.Results))
+	}
+	if r := results.NewCollector(0).Report(); !r.Passed {
+		t.Fatalf("empty report did not pass")
+	}
+
+	// Skipped tasks are not failures, but the report does not pass.
+	c = results.NewCollector(1)
+	c.Add(&results.Result{Slices: []string{"a_bins"}, Arch: "amd64", Skipped: "interrupted"})
+	if r := c.Report(); r.Passed || r.Failed != 0 || r.Skipped != 1 {
+		t.Fatalf("have passed %v with %d failed and %d skipped, want false with 0 and 1", r.Passed, r.Failed, r.Skipped)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	r := &results.Report{
+		Tasks:    1,
+		Skipped:  1,
+		Duration: 1500 * time.Millisecond,
+		Results: []*results.Result{{
+			Slices:   []string{"a_bins"},
+			Arch:     "amd64",
+			Duration: 250 * time.Millisecond,
+			Skipped:  "another task failed",
+		}},
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"passed":false,"tasks":1,"failed":0,"skipped":1,"results":[{"slices":["a_bins"],"arch":"amd64","passed":false,"skipped":"another task failed","duration_seconds":0.25}],"duration_seconds":1.5}`
+	if have := string(data); have != want {
+		t.Fatalf("have %s, want %s", have, want)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	r := &results.Report{
+		Tasks:    4,
+		Failed:   1,
+		Skipped:  1,
+		Duration: 3 * time.Second,
+		Results: []*results.Result{{
+			Slices:   []string{"a_bins", "a_libs"},
+			Arch:     "amd64",
+			Duration: 1500 * time.Millisecond,
+			Passed:   true,
+			Output:   "fetched a",
+		}, {
+			Slices:   []string{"b_bins"},
+			Arch:     "arm64",
+			Duration: time.Second,
+			Error:    "exit status 1",
+			Output:   "error: <b> & c",
+		}, {
+			Slices:   []string{"b_bins"},
+			Arch:     "amd64",
+			Duration: 250 * time.Millisecond,
+			Passed:   true,
+		}, {
+			Slices:  []string{"c_bins"},
+			Arch:    "arm64",
+			Skipped: "interrupted",
+		}},
+	}
+	var buf bytes.Buffer
+	if err := results.WriteJUnit(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="install" tests="4" failures="1" skipped="1" time="3.000">
+  <testsuite name="install on amd64" tests="2" failures="0" skipped="0" time="1.750">
+    <testcase name="a_bins a_libs" classname="amd64" time="1.500">
+      <system-out>fetched a</system-out>
+    </testcase>
+    <testcase name="b_bins" classname="amd64" time="0.250"></testcase>
+  </testsuite>
+  <testsuite name="install on arm64" tests="2" failures="1" skipped="1" time="1.000">
+    <testcase name="b_bins" classname="arm64" time="1.000">
+      <failure message="exit status 1">error: &lt;b&gt; &amp; c</failure>
+    </testcase>
+    <testcase name="c_bins" classname="arm64" time="0.000">
+      <skipped message="interrupted"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if have := buf.String(); have != want {
+		t.Fatalf("have:\n%s\nwant:\n%s", have, want)
+	}
+}
@@ -1,6 +0,0 @@
-package rmadison
-
-var (
-	CmdArgs      = cmdArgs
-	FormatOutput = formatOutput
-)
@@ -0,0 +1,304 @@
+package rootfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+const (
+	BadText   ProblemKind = "text"
+	Unremoved ProblemKind = "unremoved"
+)
+
+type ContentsOptions struct {
+	// Architecture the slices were installed for. The paths of all
+	// architectures are checked if empty.
+	Arch string
+	// Other slices installed into the root, such as the essential ones.
+	// Their contents are not checked, but they keep the paths they share
+	// with the checked slices from being removed until mutate, their
+	// mutation scripts may write the mutable paths and their packages may
+	// be preferred for the paths they share.
+	Installed []*chisel.Slice
+	// Entries of the data of the packages, by package name, see
+	// [ReadDeb]. The copies from the packages here are checked against
+	// their source.
+	Packages map[string]map[string]*PackageEntry
+}
+
+// CheckContents checks that the root chisel installed the slices into holds
+// what their contents declare: every path and glob exists, with the
+// declared type, mode, symlink target and text, the copies have the type,
+// mode and symlink target of their source in the package, if given, and the
+// paths until mutate were removed.
+//
+// The content of mutable paths is not checked if any of the slices has a
+// mutation script, which may have written them, and neither is the content
+// copied from the packages. The paths taken from another package, which
+// chisel prefers, are only checked to exist.
+func CheckContents(root string, slices []*chisel.Slice, opts *ContentsOptions) ([]*Problem, error) {
+	if opts == nil {
+		opts = &ContentsOptions{}
+	}
+	onArch := func(info *chisel.PathInfo) bool {
+		return opts.Arch == "" || info.OnArch(opts.Arch)
+	}
+	var problems []*Problem
+	report := func(slice, p string, kind ProblemKind, format string, args ...any) {
+		problems = append(problems, &Problem{Slice: slice, Path: p, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Chisel removes the paths until mutate only if every slice installing
+	// them says so, and takes the paths several packages install from the
+	// one they prefer.
+	kept := make(map[string]bool)
+	var keptGlobs []string
+	owners := make(map[string]map[string]string) // Preferred package by package, by path.
+	mutated := false
+	for _, s := range append(slices[:len(slices):len(slices)], opts.Installed...) {
+		if s.Mutate != "" {
+			mutated = true
+		}
+		for p, info := range s.Paths {
+			if !onArch(info) {
+				continue
+			}
+			if info.Until == "" {
+				kept[p] = true
+				if info.Kind == chisel.GlobPath || info.Kind == chisel.GeneratePath {
+					keptGlobs = append(keptGlobs, p)
+				}
+			}
+			if owners[p] == nil {
+				owners[p] = make(map[string]string)
+			}
+			if prefer, ok := owners[p][s.Package]; !ok || prefer == "" {
+				owners[p][s.Package] = info.Prefer
+			}
+		}
+	}
+	isKept := func(have string) bool {
+		if kept[have] {
+			return true
+		}
+		for _, g := range keptGlobs {
+			if chisel.MatchPath(g, have) {
+				return true
+			}
+		}
+		return false
+	}
+	var paths []string // All paths of the root, once listed.
+	listed := func() ([]string, error) {
+		if paths == nil {
+			var err error
+			paths, err = listPaths(root)
+			return paths, err
+		}
+		return paths, nil
+	}
+
+	for _, s := range slices {
+		for _, p := range s.Contents {
+			info := s.Paths[p]
+			if !onArch(info) {
+				continue
+			}
+			fpath := filepath.Join(root, p)
+			isDir := strings.HasSuffix(p, "/")
+
+			if info.Until == "mutate" && !kept[p] {
+				if info.Kind == chisel.GlobPath {
+					all, err := listed()
+					if err != nil {
+						return nil, err
+					}
+					for _, have := range all {
+						// Directories are only removed once empty.
+						if !strings.HasSuffix(have, "/") && chisel.MatchPath(p, have) && !isKept(have) {
+							report(s.Name, have, Unremoved, "path matches %s, which is until mutate, but was not removed", p)
+						}
+					}
+					continue
+				}
+				fi, err := os.Lstat(fpath)
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				if fi.IsDir() {
+					if entries, err := os.ReadDir(fpath); err != nil {
+						return nil, err
+					} else if len(entries) > 0 {
+						continue // Chisel leaves the directories of the kept paths.
+					}
+				}
+				report(s.Name, p, Unremoved, "path is until mutate but was not removed")
+				continue
+			}
+
+			if info.Kind == chisel.GlobPath || info.Kind == chisel.GeneratePath {
+				all, err := listed()
+				if err != nil {
+					return nil, err
+				}
+				found := false
+				for _, have := range all {
+					if chisel.MatchPath(p, have) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					report(s.Name, p, Missing, "no path matches")
+				}
+				continue
+			}
+
+			fi, err := os.Lstat(fpath)
+			if errors.Is(err, os.ErrNotExist) {
+				report(s.Name, p, Missing, "path does not exist")
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if from := preferred(owners[p], s.Package); from != s.Package {
+				// The entry of the slice is not the one installed.
+				continue
+			}
+			switch {
+			case info.Kind == chisel.SymlinkPath:
+				if fi.Mode()&fs.ModeSymlink == 0 {
+					report(s.Name, p, BadType, "want symlink, have %s", typeName(fi.Mode()))
+					continue
+				}
+				target, err := os.Readlink(fpath)
+				if err != nil {
+					return nil, err
+				}
+				if target != info.Info {
+					report(s.Name, p, BadLink, "want target %q, have %q", info.Info, target)
+				}
+				continue // The mode of symlinks is meaningless.
+			case isDir:
+				if !fi.IsDir() {
+					report(s.Name, p, BadType, "want directory, have %s", typeName(fi.Mode()))
+					continue
+				}
+			case info.Kind == chisel.TextPath:
+				if !fi.Mode().IsRegular() {
+					report(s.Name, p, BadType, "want file, have %s", typeName(fi.Mode()))
+					continue
+				}
+				if info.Mutable && mutated {
+					break // The mutation scripts may have written it.
+				}
+				data, err := os.ReadFile(fpath)
+				if err != nil {
+					return nil, err
+				}
+				if string(data) != info.Info {
+					report(s.Name, p, BadText, "want %q, have %q", info.Info, data)
+				}
+			case fi.IsDir():
+				// Copies of directories are declared with a trailing
+				// slash.
+				report(s.Name, p, BadType, "want no directory, have %s", typeName(fi.Mode()))
+				continue
+			}
+
+			mode := uint32(info.Mode)
+			if entries, ok := opts.Packages[s.Package]; ok && info.Kind == chisel.CopyPath {
+				src := p
+				if info.Info != "" {
+					src = info.Info
+					if isDir && !strings.HasSuffix(src, "/") {
+						src += "/"
+					}
+				}
+				e := entries[src]
+				switch {
+				case e == nil:
+					report(s.Name, p, Missing, "source %s is not in package %s", src, s.Package)
+					continue
+				case typeName(fi.Mode()) != typeName(e.Mode):
+					report(s.Name, p, BadType, "want %s as %s in package %s, have %s", typeName(e.Mode), src, s.Package, typeName(fi.Mode()))
+					continue
+				case e.Mode&fs.ModeSymlink != 0:
+					target, err := os.Readlink(fpath)
+					if err != nil {
+						return nil, err
+					}
+					if target != e.Link {
+						report(s.Name, p, BadLink, "want target %q as %s in package %s, have %q", e.Link, src, s.Package, target)
+					}
+					continue
+				}
+				if mode == 0 {
+					mode = unixPerm(e.Mode)
+				}
+			}
+			if mode != 0 {
+				if have := unixPerm(fi.Mode()); have != mode {
+					report(s.Name, p, BadMode, "want %04o, have %04o", mode, have)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Slice != problems[j].Slice {
+			return problems[i].Slice < problems[j].Slice
+		}
+		return problems[i].Path < problems[j].Path
+	})
+	return problems, nil
+}
+
+// preferred returns the package chisel takes a path from, given the package
+// each package installing it prefers, if any: the one the others lead to. It
+// is pkg if none is preferred.
+func preferred(prefers map[string]string, pkg string) string {
+	seen := make(map[string]bool)
+	for !seen[pkg] {
+		seen[pkg] = true
+		next := prefers[pkg]
+		if _, installs := prefers[next]; next == "" || !installs {
+			break
+		}
+		pkg = next
+	}
+	return pkg
+}
+
+// listPaths returns the paths of the root as absolute ones, with a trailing
+// slash for directories.
+func listPaths(root string) ([]string, error) {
+	paths := []string{}
+	err := filepath.WalkDir(root, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, fpath)
+		if err != nil || rel == "." {
+			return err
+		}
+		p := "/" + filepath.ToSlash(rel)
+		if d.IsDir() {
+			p += "/"
+		}
+		paths = append(paths, p)
+		return nil
+	})
+	return paths, err
+}
@@ -0,0 +1,171 @@
+package rootfs_test
+
+import (
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+func TestCheckContents(t *testing.T) {
+	root := makeRoot(t, []rootEntry{
+		{path: "usr/bin/hello", mode: 0755, data: "hello"},
+		{path: "usr/bin/mode", mode: 0644, data: "mode"},
+		{path: "usr/bin/hi", link: "hello"},
+		{path: "usr/bin/bad-link", link: "other"},
+		{path: "usr/lib/", mode: 0755},
+		{path: "usr/share/doc/hello/copyright", mode: 0644},
+		{path: "etc/hello.conf", mode: 0644, data: "hello"},
+		{path: "etc/mutable.conf", mode: 0644, data: "mutated"},
+		{path: "etc/changed.conf", mode: 0644, data: "changed"},
+		{path: "etc/not-a-file/", mode: 0755},
+		{path: "var/lib/hello/", mode: os.ModeSticky | 0777},
+		{path: "var/lib/chisel/manifest.wall", mode: 0644},
+		{path: "tmp/kept", mode: 0644},
+		{path: "tmp/shared", mode: 0644},
+	})
+	slices := []*chisel.Slice{{
+		Name:    "hello_bins",
+		Package: "hello",
+		Mutate:  `content.write("/etc/mutable.conf", "mutated")`,
+		Contents: []string{
+			"/etc/changed.conf", "/etc/hello.conf", "/etc/mutable.conf", "/etc/not-a-file",
+			"/tmp/kept", "/tmp/removed", "/tmp/shared", "/usr/bin/bad-link", "/usr/bin/hello",
+			"/usr/bin/hi", "/usr/bin/missing", "/usr/bin/mode", "/usr/lib/", "/usr/lib/*.so",
+			"/usr/share/doc/hello/*", "/var/lib/chisel/**", "/var/lib/hello/",
+		},
+		Paths: map[string]*chisel.PathInfo{
+			"/etc/changed.conf":      {Kind: chisel.TextPath, Info: "hello"},
+			"/etc/hello.conf":        {Kind: chisel.TextPath, Info: "hello"},
+			"/etc/mutable.conf":      {Kind: chisel.TextPath, Info: "hello", Mutable: true},
+			"/etc/not-a-file":        {Kind: chisel.CopyPath},
+			"/tmp/kept":              {Kind: chisel.CopyPath, Until: "mutate"},
+			"/tmp/removed":           {Kind: chisel.CopyPath, Until: "mutate"},
+			"/tmp/shared":            {Kind: chisel.CopyPath, Until: "mutate"},
+			"/usr/bin/bad-link":      {Kind: chisel.SymlinkPath, Info: "hello"},
+			"/usr/bin/hello":         {Kind: chisel.CopyPath, Info: "/usr/bin/hello.real", Mode: 0755},
+			"/usr/bin/hi":            {Kind: chisel.SymlinkPath, Info: "hello"},
+			"/usr/bin/missing":       {Kind: chisel.CopyPath, Arch: []string{"amd64"}},
+			"/usr/bin/mode":          {Kind: chisel.CopyPath, Mode: 0755},
+			"/usr/lib/":              {Kind: chisel.CopyPath},
+			"/usr/lib/*.so":          {Kind: chisel.GlobPath},
+			"/usr/share/doc/hello/*": {Kind: chisel.GlobPath},
+			"/var/lib/chisel/**":     {Kind: chisel.GeneratePath, Info: "manifest"},
+			"/var/lib/hello/":        {Kind: chisel.DirPath, Mode: 01777},
+		},
+	}}
+	installed := []*chisel.Slice{{
+		Name:     "base_files",
+		Package:  "base",
+		Contents: []string{"/tmp/shared"},
+		Paths:    map[string]*chisel.PathInfo{"/tmp/shared": {Kind: chisel.CopyPath}},
+	}}
+
+	problems, err := rootfs.CheckContents(root, slices, &rootfs.ContentsOptions{Arch: "amd64", Installed: installed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, p := range problems {
+		have = append(have, p.String())
+	}
+	want := []string{
+		"hello_bins: /etc/changed.conf: text: want \"hello\", have \"changed\"",
+		"hello_bins: /etc/not-a-file: type: want no directory, have directory",
+		"hello_bins: /tmp/kept: unremoved: path is until mutate but was not removed",
+		"hello_bins: /usr/bin/bad-link: link: want target \"hello\", have \"other\"",
+		"hello_bins: /usr/bin/missing: missing: path does not exist",
+		"hello_bins: /usr/bin/mode: mode: want 0755, have 0644",
+		"hello_bins: /usr/lib/*.so: missing: no path matches",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have problems:\n%q\nwant:\n%q", have, want)
+	}
+
+	// Paths of other arches are not checked.
+	problems, err = rootfs.CheckContents(root, slices, &rootfs.ContentsOptions{Arch: "arm64", Installed: installed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != len(want)-1 {
+		t.Fatalf("have %d problem(s) on arm64, want %d", len(problems), len(want)-1)
+	}
+}
+
+func TestCheckContentsPackages(t *testing.T) {
+	root := makeRoot(t, []rootEntry{
+		{path: "usr/bin/hello", mode: 0755, data: "hello"},
+		{path: "usr/bin/mode", mode: 0644, data: "mode"},
+		{path: "usr/bin/link", link: "other"},
+		{path: "usr/bin/type", mode: 0755, data: "type"},
+		{path: "usr/bin/unpacked", mode: 0755, data: "unpacked"},
+		{path: "etc/hello.conf", mode: 0600, data: "extra"},
+		{path: "etc/mutable.conf", mode: 0644, data: "changed"},
+		{path: "tmp/removed", mode: 0644},
+		{path: "tmp/kept", mode: 0644},
+		{path: "var/cache/hello/kept", mode: 0644},
+	})
+	slices := []*chisel.Slice{{
+		Name:    "hello_bins",
+		Package: "hello",
+		Contents: []string{
+			"/etc/hello.conf", "/etc/mutable.conf", "/tmp/*", "/usr/bin/hello", "/usr/bin/link",
+			"/usr/bin/mode", "/usr/bin/type", "/usr/bin/unpacked", "/var/cache/hello/",
+		},
+		Paths: map[string]*chisel.PathInfo{
+			"/etc/hello.conf":   {Kind: chisel.CopyPath, Prefer: "hello-extra"},
+			"/etc/mutable.conf": {Kind: chisel.TextPath, Info: "hello", Mutable: true},
+			"/tmp/*":            {Kind: chisel.GlobPath, Until: "mutate"},
+			"/usr/bin/hello":    {Kind: chisel.CopyPath, Info: "/usr/bin/hello.real"},
+			"/usr/bin/link":     {Kind: chisel.CopyPath},
+			"/usr/bin/mode":     {Kind: chisel.CopyPath},
+			"/usr/bin/type":     {Kind: chisel.CopyPath},
+			"/usr/bin/unpacked": {Kind: chisel.CopyPath},
+			"/var/cache/hello/": {Kind: chisel.CopyPath, Until: "mutate"},
+		},
+	}}
+	installed := []*chisel.Slice{{
+		Name:     "hello-extra_config",
+		Package:  "hello-extra",
+		Contents: []string{"/etc/hello.conf", "/tmp/kept", "/var/cache/hello/kept"},
+		Paths: map[string]*chisel.PathInfo{
+			"/etc/hello.conf":       {Kind: chisel.CopyPath},
+			"/tmp/kept":             {Kind: chisel.CopyPath},
+			"/var/cache/hello/kept": {Kind: chisel.CopyPath},
+		},
+	}}
+	packages := map[string]map[string]*rootfs.PackageEntry{
+		"hello": {
+			"/etc/hello.conf":     {Mode: 0644},
+			"/usr/bin/hello.real": {Mode: 0755},
+			"/usr/bin/link":       {Mode: fs.ModeSymlink | 0777, Link: "hello"},
+			"/usr/bin/mode":       {Mode: 0755},
+			"/usr/bin/type":       {Mode: fs.ModeSymlink | 0777, Link: "hello"},
+			"/var/cache/hello/":   {Mode: fs.ModeDir | 0755},
+		},
+	}
+
+	problems, err := rootfs.CheckContents(root, slices, &rootfs.ContentsOptions{Installed: installed, Packages: packages})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, p := range problems {
+		have = append(have, p.String())
+	}
+	// No mutation script ran, so the mutable paths hold their text.
+	want := []string{
+		"hello_bins: /etc/mutable.conf: text: want \"hello\", have \"changed\"",
+		"hello_bins: /tmp/removed: unremoved: path matches /tmp/*, which is until mutate, but was not removed",
+		"hello_bins: /usr/bin/link: link: want target \"hello\" as /usr/bin/link in package hello, have \"other\"",
+		"hello_bins: /usr/bin/mode: mode: want 0755, have 0644",
+		"hello_bins: /usr/bin/type: type: want symlink as /usr/bin/type in package hello, have file",
+		"hello_bins: /usr/bin/unpacked: missing: source /usr/bin/unpacked is not in package hello",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have problems:\n%q\nwant:\n%q", have, want)
+	}
+}
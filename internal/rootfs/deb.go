@@ -0,0 +1,63 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// A PackageEntry is the entry of a path in the data of a package.
+type PackageEntry struct {
+	// Type and permission bits.
+	Mode fs.FileMode
+	// Target of symlinks.
+	Link string
+}
+
+// ReadDeb returns the name of the package of the deb at path and the
+// entries of its data by absolute path, with a trailing slash for
+// directories. It requires dpkg-deb, which reads debs of any compression.
+func ReadDeb(debPath string) (string, map[string]*PackageEntry, error) {
+	if _, err := exec.LookPath("dpkg-deb"); err != nil {
+		return "", nil, fmt.Errorf("cannot find dpkg-deb, install dpkg: %w", err)
+	}
+	out, err := exec.Command("dpkg-deb", "--field", debPath, "Package").Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot read package of %s: %w", debPath, err)
+	}
+	name := strings.TrimSpace(string(out))
+	data, err := exec.Command("dpkg-deb", "--fsys-tarfile", debPath).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot read data of %s: %w", debPath, err)
+	}
+	entries := make(map[string]*PackageEntry)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot read data of %s: %w", debPath, err)
+		}
+		p := path.Clean("/" + strings.TrimPrefix(hdr.Name, "./"))
+		if p == "/" {
+			continue
+		}
+		mode := hdr.FileInfo().Mode()
+		if mode.IsDir() {
+			p += "/"
+		}
+		e := &PackageEntry{Mode: mode}
+		if hdr.Typeflag == tar.TypeSymlink {
+			e.Link = hdr.Linkname
+		}
+		entries[p] = e
+	}
+	return name, entries, nil
+}
@@ -0,0 +1,53 @@
+package rootfs_test
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/fixtures"
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+func TestReadDeb(t *testing.T) {
+	if _, err := exec.LookPath("dpkg-deb"); err != nil {
+		t.Skip("dpkg-deb not installed")
+	}
+	data, err := fixtures.Deb(&fixtures.Package{
+		Name:    "hello",
+		Version: "1.0",
+		Arch:    "amd64",
+		Files:   map[string]string{"/usr/bin/hello": "hello", "/usr/share/doc/hello/copyright": "copyright"},
+		Links:   map[string]string{"/usr/bin/hi": "hello"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "hello.deb")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	name, entries, err := rootfs.ReadDeb(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "hello" {
+		t.Fatalf("have package %q, want hello", name)
+	}
+	want := map[string]*rootfs.PackageEntry{
+		"/usr/":                          {Mode: fs.ModeDir | 0755},
+		"/usr/bin/":                      {Mode: fs.ModeDir | 0755},
+		"/usr/bin/hello":                 {Mode: 0755},
+		"/usr/bin/hi":                    {Mode: fs.ModeSymlink | 0777, Link: "hello"},
+		"/usr/share/":                    {Mode: fs.ModeDir | 0755},
+		"/usr/share/doc/":                {Mode: fs.ModeDir | 0755},
+		"/usr/share/doc/hello/":          {Mode: fs.ModeDir | 0755},
+		"/usr/share/doc/hello/copyright": {Mode: 0644},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("have %d entries, want %d", len(entries), len(want))
+	}
+}
@@ -0,0 +1,136 @@
+package rootfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// An Entry describes one path of a tree by what makes its content: type,
+// permissions, symlink target and file digest, but not ownership or
+// timestamps.
+type Entry struct {
+	Path string `json:"path"`
+	// Type is "dir", "file", "symlink" or "special".
+	Type   string `json:"type"`
+	Mode   string `json:"mode,omitempty"`
+	Link   string `json:"link,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+func (e *Entry) String() string {
+	switch e.Type {
+	case "dir":
+		return fmt.Sprintf("d %s %s", e.Path, e.Mode)
+	case "symlink":
+		return fmt.Sprintf("l %s %s", e.Path, e.Link)
+	case "file":
+		return fmt.Sprintf("f %s %s %s", e.Path, e.Mode, e.SHA256)
+	default:
+		return fmt.Sprintf("s %s %s", e.Path, e.Mode)
+	}
+}
+
+// List returns the entries of the tree under root, in lexical order. Paths
+// are relative to root.
+func List(root string) ([]*Entry, error) {
+	var entries []*Entry
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		e := &Entry{Path: filepath.ToSlash(rel)}
+		mode := info.Mode()
+		switch {
+		case mode.IsDir():
+			e.Type = "dir"
+			e.Mode = fmt.Sprintf("%04o", unixPerm(mode))
+		case mode&fs.ModeSymlink != 0:
+			e.Type = "symlink"
+			if e.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			e.Type = "file"
+			e.Mode = fmt.Sprintf("%04o", unixPerm(mode))
+			if e.SHA256, err = fileDigest(path); err != nil {
+				return err
+			}
+		default:
+			e.Type = "special"
+			e.Mode = mode.String()
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Digest returns a digest of the whole tree under root. It covers the path,
+// type, permissions, symlink target and content of every entry, in lexical
+// order, but not the ownership or timestamps. Two roots with the same
+// content thus have the same digest.
+func Digest(root string) (string, error) {
+	entries, err := List(root)
+	if err != nil {
+		return "", err
+	}
+	return DigestEntries(entries), nil
+}
+
+// DigestEntries returns the digest of a tree from its entries, as Digest.
+func DigestEntries(entries []*Entry) string {
+	h := sha256.New()
+	for _, e := range entries {
+		io.WriteString(h, e.String()+"\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// A Difference is a path whose entries differ between two trees. One of the
+// entries is nil if the path is in one tree only.
+type Difference struct {
+	Path string `json:"path"`
+	A    *Entry `json:"a"`
+	B    *Entry `json:"b"`
+}
+
+// Diff returns the differences between the entries of two trees, sorted by
+// path.
+func Diff(a, b []*Entry) []*Difference {
+	byPath := make(map[string]*Difference)
+	for _, e := range a {
+		byPath[e.Path] = &Difference{Path: e.Path, A: e}
+	}
+	for _, e := range b {
+		if d, ok := byPath[e.Path]; ok {
+			d.B = e
+		} else {
+			byPath[e.Path] = &Difference{Path: e.Path, B: e}
+		}
+	}
+	var diffs []*Difference
+	for _, d := range byPath {
+		if d.A == nil || d.B == nil || *d.A != *d.B {
+			diffs = append(diffs, d)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
@@ -0,0 +1,105 @@
+package rootfs_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+var digestEntries = []rootEntry{
+	{path: "usr/bin/", mode: 0755},
+	{path: "usr/bin/hello", mode: 0755, data: "hello"},
+	{path: "usr/bin/hi", link: "hello"},
+	{path: "etc/hello.conf", mode: 0644, data: "conf"},
+}
+
+func TestDigest(t *testing.T) {
+	root1 := makeRoot(t, digestEntries)
+	root2 := makeRoot(t, digestEntries)
+
+	// Timestamps must not matter.
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(root2, "etc/hello.conf"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	d1, err := rootfs.Digest(root1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, err := rootfs.Digest(root2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d1 != d2 {
+		t.Fatalf("digests of identical roots differ: %s != %s", d1, d2)
+	}
+
+	changes := []func(root string) error{
+		func(root string) error {
+			return os.WriteFile(filepath.Join(root, "etc/hello.conf"), []byte("other"), 0644)
+		},
+		func(root string) error { return os.Chmod(filepath.Join(root, "usr/bin/hello"), 0700) },
+		func(root string) error { return os.Remove(filepath.Join(root, "usr/bin/hi")) },
+	}
+	for i, change := range changes {
+		root := makeRoot(t, digestEntries)
+		if err := change(root); err != nil {
+			t.Fatal(err)
+		}
+		d, err := rootfs.Digest(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d == d1 {
+			t.Fatalf("change %d did not change the digest", i)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	root1 := makeRoot(t, digestEntries)
+	root2 := makeRoot(t, digestEntries)
+	if err := os.WriteFile(filepath.Join(root2, "etc/hello.conf"), []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root2, "usr/bin/hi")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root2, "usr/bin/new"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := rootfs.List(root1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := rootfs.List(root2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err := rootfs.Digest(root1); err != nil || d != rootfs.DigestEntries(a) {
+		t.Fatalf("have digest %s (%v), want %s", d, err, rootfs.DigestEntries(a))
+	}
+
+	var have []string
+	for _, d := range rootfs.Diff(a, b) {
+		have = append(have, fmt.Sprintf("%s %v %v", d.Path, d.A != nil, d.B != nil))
+	}
+	want := []string{
+		"etc/hello.conf true true",
+		"usr/bin/hi true false",
+		"usr/bin/new false true",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have differences %v, want %v", have, want)
+	}
+	if diffs := rootfs.Diff(a, a); len(diffs) != 0 {
+		t.Fatalf("have %d differences between identical trees", len(diffs))
+	}
+}
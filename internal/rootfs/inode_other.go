@@ -0,0 +1,11 @@
+//go:build !unix
+
+package rootfs
+
+import (
+	"io/fs"
+)
+
+func inode(info fs.FileInfo) (ino uint64, nlink uint64, ok bool) {
+	return 0, 0, false
+}
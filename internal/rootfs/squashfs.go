@@ -0,0 +1,56 @@
+package rootfs
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+type SquashfsOptions struct {
+	// Modification time of all entries and of the filesystem itself. The
+	// zero value means the Unix epoch.
+	ModTime time.Time
+	// Compression algorithm, as known to mksquashfs. Empty means the
+	// mksquashfs default.
+	Compression string
+}
+
+// squashfsArgs returns the mksquashfs arguments to write a reproducible image
+// of root into path.
+func squashfsArgs(path, root string, opts *SquashfsOptions) []string {
+	mtime := strconv.FormatInt(max(opts.ModTime.Unix(), 0), 10)
+	args := []string{
+		root, path,
+		"-noappend",
+		"-no-xattrs",
+		"-all-root",
+		"-mkfs-time", mtime,
+		"-all-time", mtime,
+		// Keep the output independent of the number of CPUs.
+		"-processors", "1",
+	}
+	if opts.Compression != "" {
+		args = append(args, "-comp", opts.Compression)
+	}
+	return args
+}
+
+// WriteSquashfs writes the tree under root as a squashfs image at path, in a
+// reproducible way: all files are owned by root and share the same
+// modification time. It requires mksquashfs.
+func WriteSquashfs(path, root string, opts *SquashfsOptions) error {
+	if opts == nil {
+		opts = &SquashfsOptions{}
+	}
+	if _, err := exec.LookPath("mksquashfs"); err != nil {
+		return fmt.Errorf("cannot find mksquashfs, install squashfs-tools")
+	}
+	cmd := exec.Command("mksquashfs", squashfsArgs(path, root, opts)...)
+	cmd.Stderr = os.Stderr
+	if out, err := cmd.Output(); err != nil {
+		return fmt.Errorf("mksquashfs failed: %w\n%s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package rootfs_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+func TestWriteSquashfs(t *testing.T) {
+	if _, err := exec.LookPath("mksquashfs"); err != nil {
+		t.Skip("mksquashfs not installed")
+	}
+	dir := t.TempDir()
+	var images [][]byte
+	for i := 0; i < 2; i++ {
+		path := filepath.Join(dir, "root.squashfs")
+		if err := rootfs.WriteSquashfs(path, makeRoot(t, digestEntries), nil); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		images = append(images, data)
+	}
+	if !bytes.Equal(images[0], images[1]) {
+		t.Fatal("squashfs images of identical roots differ")
+	}
+}
@@ -0,0 +1,99 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type TarOptions struct {
+	// Modification time of all entries. The zero value means the Unix
+	// epoch.
+	ModTime time.Time
+}
+
+// WriteTar writes the tree under root as a tar archive, in a reproducible
+// way: entries are sorted, owned by root and share the same modification
+// time. Paths are relative to root, directories end with "/".
+func WriteTar(w io.Writer, root string, opts *TarOptions) error {
+	if opts == nil {
+		opts = &TarOptions{}
+	}
+	mtime := opts.ModTime
+	if mtime.IsZero() {
+		mtime = time.Unix(0, 0)
+	}
+	mtime = mtime.UTC()
+
+	tw := tar.NewWriter(w)
+	// Hard links are kept as such, to the first path seen with the inode.
+	inodes := make(map[uint64]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Mode = int64(unixPerm(info.Mode()))
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
+		hdr.ModTime, hdr.AccessTime, hdr.ChangeTime = mtime, time.Time{}, time.Time{}
+		hdr.Format = tar.FormatPAX
+		hdr.PAXRecords = nil
+
+		if info.Mode().IsRegular() {
+			if ino, nlink, ok := inode(info); ok && nlink > 1 {
+				if first, ok := inodes[ino]; ok {
+					hdr.Typeflag = tar.TypeLink
+					hdr.Linkname = first
+					hdr.Size = 0
+				} else {
+					inodes[ino] = hdr.Name
+				}
+			}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(tw, f); err != nil {
+			return fmt.Errorf("cannot write %s to archive: %w", rel, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,88 @@
+package rootfs_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+func TestWriteTar(t *testing.T) {
+	root1 := makeRoot(t, digestEntries)
+	root2 := makeRoot(t, digestEntries)
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(root2, "usr/bin/hello"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(root1, "usr/bin/hello"), filepath.Join(root1, "usr/bin/hello2")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf1, buf2 bytes.Buffer
+	if err := rootfs.WriteTar(&buf1, root1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := rootfs.WriteTar(&buf2, root2, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		name, link string
+		typ        byte
+		mode       int64
+		data       string
+	}
+	read := func(data []byte) []entry {
+		var entries []entry
+		tr := tar.NewReader(bytes.NewReader(data))
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Uid != 0 || hdr.Gid != 0 || !hdr.ModTime.Equal(time.Unix(0, 0)) {
+				t.Fatalf("entry %s is not normalized: %+v", hdr.Name, hdr)
+			}
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry{hdr.Name, hdr.Linkname, hdr.Typeflag, hdr.Mode, string(content)})
+		}
+		return entries
+	}
+
+	want := []entry{
+		{"etc/", "", tar.TypeDir, 0755, ""},
+		{"etc/hello.conf", "", tar.TypeReg, 0644, "conf"},
+		{"usr/", "", tar.TypeDir, 0755, ""},
+		{"usr/bin/", "", tar.TypeDir, 0755, ""},
+		{"usr/bin/hello", "", tar.TypeReg, 0755, "hello"},
+		{"usr/bin/hello2", "usr/bin/hello", tar.TypeLink, 0755, ""},
+		{"usr/bin/hi", "hello", tar.TypeSymlink, 0777, ""},
+	}
+	if have := read(buf1.Bytes()); !reflect.DeepEqual(have, want) {
+		t.Fatalf("have entries:\n%v\nwant:\n%v", have, want)
+	}
+
+	// Without the hard link, both archives must be byte-identical.
+	if err := os.Remove(filepath.Join(root1, "usr/bin/hello2")); err != nil {
+		t.Fatal(err)
+	}
+	buf1.Reset()
+	if err := rootfs.WriteTar(&buf1, root1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+		t.Fatal("archives of identical roots differ")
+	}
+}
@@ -0,0 +1,195 @@
+// Package rootfs inspects the root directories chisel installs slices into.
+package rootfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+)
+
+type ProblemKind string
+
+const (
+	Missing  ProblemKind = "missing"
+	Unlisted ProblemKind = "unlisted"
+	BadType  ProblemKind = "type"
+	BadMode  ProblemKind = "mode"
+	BadLink  ProblemKind = "link"
+	BadHash  ProblemKind = "digest"
+)
+
+// A Problem is a difference between a root and its manifest, or the
+// contents of its slices.
+type Problem struct {
+	// Slice declaring the path, if checking the contents.
+	Slice   string      `json:"slice,omitempty"`
+	Path    string      `json:"path"`
+	Kind    ProblemKind `json:"kind"`
+	Message string      `json:"message"`
+}
+
+func (p *Problem) String() string {
+	if p.Slice != "" {
+		return fmt.Sprintf("%s: %s: %s: %s", p.Slice, p.Path, p.Kind, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Path, p.Kind, p.Message)
+}
+
+// Verify checks that every path listed in the manifest exists in the root
+// with the recorded type, mode, link target and digest, and that the root has
+// no unlisted paths. Directories leading to listed paths are allowed even if
+// not listed themselves, as chisel creates them on demand.
+func Verify(root string, m *manifest.Manifest) ([]*Problem, error) {
+	var problems []*Problem
+	report := func(p string, kind ProblemKind, format string, args ...any) {
+		problems = append(problems, &Problem{Path: p, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	listed := make(map[string]bool)
+	parents := make(map[string]bool)
+	for _, entry := range m.Paths {
+		p := strings.TrimSuffix(entry.Path, "/")
+		listed[p] = true
+		for dir := path.Dir(p); dir != "/" && dir != "."; dir = path.Dir(dir) {
+			parents[dir] = true
+		}
+
+		fpath := filepath.Join(root, p)
+		info, err := os.Lstat(fpath)
+		if errors.Is(err, os.ErrNotExist) {
+			report(entry.Path, Missing, "path does not exist")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		isDir := strings.HasSuffix(entry.Path, "/")
+		switch {
+		case entry.Link != "":
+			if info.Mode()&fs.ModeSymlink == 0 {
+				report(entry.Path, BadType, "want symlink, have %s", typeName(info.Mode()))
+				continue
+			}
+			target, err := os.Readlink(fpath)
+			if err != nil {
+				return nil, err
+			}
+			if target != entry.Link {
+				report(entry.Path, BadLink, "want target %q, have %q", entry.Link, target)
+			}
+			continue // The mode of symlinks is meaningless.
+		case isDir:
+			if !info.IsDir() {
+				report(entry.Path, BadType, "want directory, have %s", typeName(info.Mode()))
+				continue
+			}
+		default:
+			if !info.Mode().IsRegular() {
+				report(entry.Path, BadType, "want file, have %s", typeName(info.Mode()))
+				continue
+			}
+			if want := entry.Digest(); want != "" {
+				have, err := fileDigest(fpath)
+				if err != nil {
+					return nil, err
+				}
+				if have != want {
+					report(entry.Path, BadHash, "want %s, have %s", want, have)
+				}
+			}
+		}
+		if entry.Mode != "" {
+			want, err := strconv.ParseUint(entry.Mode, 8, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mode %q for %s in manifest", entry.Mode, entry.Path)
+			}
+			if have := unixPerm(info.Mode()); have != uint32(want) {
+				report(entry.Path, BadMode, "want %04o, have %04o", want, have)
+			}
+		}
+	}
+
+	err := filepath.WalkDir(root, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, fpath)
+		if err != nil || rel == "." {
+			return err
+		}
+		p := "/" + filepath.ToSlash(rel)
+		if listed[p] || (d.IsDir() && parents[p]) {
+			return nil
+		}
+		if d.IsDir() {
+			p += "/"
+		}
+		report(p, Unlisted, "path is not in the manifest")
+		if d.IsDir() {
+			return fs.SkipDir // Everything below is unlisted as well.
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Path < problems[j].Path
+	})
+	return problems, nil
+}
+
+// unixPerm returns the permission bits of a mode along with the setuid,
+// setgid and sticky bits, as found in chisel manifests.
+func unixPerm(mode fs.FileMode) uint32 {
+	perm := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&fs.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return perm
+}
+
+func typeName(mode fs.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return "file"
+	case mode.IsDir():
+		return "directory"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	default:
+		return "special file"
+	}
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
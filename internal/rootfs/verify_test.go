@@ -0,0 +1,101 @@
+package rootfs_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// makeRoot creates a root from a list of entries. Paths ending in "/" are
+// directories, entries with a link are symlinks and the others are files.
+type rootEntry struct {
+	path string
+	mode os.FileMode
+	data string
+	link string
+}
+
+func makeRoot(t *testing.T, entries []rootEntry) string {
+	root := t.TempDir()
+	for _, e := range entries {
+		p := filepath.Join(root, e.path)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		switch {
+		case e.link != "":
+			err = os.Symlink(e.link, p)
+		case e.path[len(e.path)-1] == '/':
+			if err = os.MkdirAll(p, e.mode); err == nil {
+				err = os.Chmod(p, e.mode)
+			}
+		default:
+			if err = os.WriteFile(p, []byte(e.data), e.mode); err == nil {
+				err = os.Chmod(p, e.mode)
+			}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestVerify(t *testing.T) {
+	root := makeRoot(t, []rootEntry{
+		{path: "usr/bin/", mode: 0755},
+		{path: "usr/bin/hello", mode: 0755, data: "hello"},
+		{path: "usr/bin/hi", link: "hello"},
+		{path: "usr/bin/bad-link", link: "other"},
+		{path: "etc/hello.conf", mode: 0600, data: "changed"},
+		{path: "etc/mode", mode: 0644, data: "mode"},
+		{path: "etc/not-a-dir", mode: 0644},
+		{path: "opt/extra/file", mode: 0644},
+		{path: "usr/share/extra", mode: 0644},
+	})
+	m := &manifest.Manifest{Paths: []*manifest.Path{
+		{Path: "/etc/hello.conf", Mode: "0600", SHA256: digest("original"), FinalSHA256: digest("changed")},
+		{Path: "/etc/missing", Mode: "0644", SHA256: digest("")},
+		{Path: "/etc/mode", Mode: "0755", SHA256: digest("mode")},
+		{Path: "/etc/not-a-dir/", Mode: "0755"},
+		{Path: "/usr/bin/", Mode: "0755"},
+		{Path: "/usr/bin/bad-link", Mode: "0777", Link: "hello"},
+		{Path: "/usr/bin/hello", Mode: "0755", SHA256: digest("hi")},
+		{Path: "/usr/bin/hi", Mode: "0777", Link: "hello"},
+		{Path: "/usr/share/doc/", Mode: "0755"},
+	}}
+
+	problems, err := rootfs.Verify(root, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, p := range problems {
+		have = append(have, p.String())
+	}
+	want := []string{
+		"/etc/missing: missing: path does not exist",
+		"/etc/mode: mode: want 0755, have 0644",
+		"/etc/not-a-dir/: type: want directory, have file",
+		"/opt/: unlisted: path is not in the manifest",
+		"/usr/bin/bad-link: link: want target \"hello\", have \"other\"",
+		"/usr/bin/hello: digest: want " + digest("hi") + ", have " + digest("hello"),
+		"/usr/share/doc/: missing: path does not exist",
+		"/usr/share/extra: unlisted: path is not in the manifest",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have problems:\n%q\nwant:\n%q", have, want)
+	}
+}
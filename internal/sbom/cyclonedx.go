@@ -0,0 +1,134 @@
+package sbom
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/copyright"
+)
+
+// CycloneDXDocument is a CycloneDX 1.5 BOM, as serialized in JSON.
+// See https://cyclonedx.org/docs/1.5/json/.
+type CycloneDXDocument struct {
+	BOMFormat    string              `json:"bomFormat"`
+	SpecVersion  string              `json:"specVersion"`
+	SerialNumber string              `json:"serialNumber"`
+	Version      int                 `json:"version"`
+	Metadata     CycloneDXMetadata   `json:"metadata"`
+	Components   []*CycloneDXComp    `json:"components"`
+	Dependencies []*CycloneDXDepends `json:"dependencies,omitempty"`
+}
+
+type CycloneDXMetadata struct {
+	Timestamp string         `json:"timestamp"`
+	Tools     CycloneDXTools `json:"tools"`
+	Component *CycloneDXComp `json:"component,omitempty"`
+}
+
+type CycloneDXTools struct {
+	Components []*CycloneDXComp `json:"components"`
+}
+
+type CycloneDXComp struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Hashes     []*CycloneDXHash    `json:"hashes,omitempty"`
+	Licenses   []*CycloneDXLicense `json:"licenses,omitempty"`
+	Components []*CycloneDXComp    `json:"components,omitempty"`
+}
+
+type CycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// A CycloneDXLicense has either a license or an expression.
+type CycloneDXLicense struct {
+	License    *CycloneDXLicenseID `json:"license,omitempty"`
+	Expression string              `json:"expression,omitempty"`
+}
+
+type CycloneDXLicenseID struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type CycloneDXDepends struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// CycloneDX generates a CycloneDX BOM with one library component per deb
+// package, each with its regular files as nested file components.
+func CycloneDX(in *Input) *CycloneDXDocument {
+	d := digest(in)
+	root := &CycloneDXComp{
+		Type:   "container",
+		BOMRef: "root",
+		Name:   in.Name,
+	}
+	doc := &CycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: fmt.Sprintf("urn:uuid:%s-%s-5%s-a%s-%s", d[0:8], d[8:12], d[13:16], d[17:20], d[20:32]),
+		Version:      1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: in.Created.UTC().Format(time.RFC3339),
+			Tools: CycloneDXTools{
+				Components: []*CycloneDXComp{{Type: "application", Name: Tool}},
+			},
+			Component: root,
+		},
+	}
+
+	pkgs := slices.Clone(in.Manifest.Packages)
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	files := packageFiles(in.Manifest)
+	rootDeps := &CycloneDXDepends{Ref: root.BOMRef, DependsOn: []string{}}
+	for _, p := range pkgs {
+		purl := PURL(p)
+		comp := &CycloneDXComp{
+			Type:     "library",
+			BOMRef:   purl,
+			Name:     p.Name,
+			Version:  p.Version,
+			PURL:     purl,
+			Licenses: cycloneDXLicenses(in.Copyrights[p.Name]),
+		}
+		if p.Digest != "" {
+			comp.Hashes = []*CycloneDXHash{{Alg: "SHA-256", Content: p.Digest}}
+		}
+		for _, f := range files[p.Name] {
+			comp.Components = append(comp.Components, &CycloneDXComp{
+				Type:   "file",
+				Name:   f.Path,
+				Hashes: []*CycloneDXHash{{Alg: "SHA-256", Content: f.Digest()}},
+			})
+		}
+		doc.Components = append(doc.Components, comp)
+		rootDeps.DependsOn = append(rootDeps.DependsOn, purl)
+	}
+	doc.Dependencies = []*CycloneDXDepends{rootDeps}
+	return doc
+}
+
+func cycloneDXLicenses(c *copyright.Copyright) []*CycloneDXLicense {
+	if c == nil {
+		return nil
+	}
+	var licenses []*CycloneDXLicense
+	for _, l := range c.Licenses {
+		id, ok := copyright.SPDX(l)
+		lic := &CycloneDXLicenseID{ID: id}
+		if !ok {
+			lic = &CycloneDXLicenseID{Name: l}
+		}
+		licenses = append(licenses, &CycloneDXLicense{License: lic})
+	}
+	return licenses
+}
@@ -0,0 +1,51 @@
+package sbom_test
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/sbom"
+)
+
+func TestCycloneDX(t *testing.T) {
+	doc := sbom.CycloneDX(sampleInput)
+	if doc.BOMFormat != "CycloneDX" || doc.SpecVersion != "1.5" {
+		t.Fatalf("bad document header: %+v", doc)
+	}
+	uuid := regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-a[0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(doc.SerialNumber) {
+		t.Fatalf("invalid serial number %q", doc.SerialNumber)
+	}
+
+	var comps []string
+	for _, c := range doc.Components {
+		comps = append(comps, c.Type+" "+c.PURL)
+		for _, f := range c.Components {
+			comps = append(comps, "  "+f.Type+" "+f.Name+" "+f.Hashes[0].Content)
+		}
+	}
+	wantComps := []string{
+		"library pkg:deb/ubuntu/hello@1%3A2.10-3?arch=amd64",
+		"  file /etc/hello.conf 3333",
+		"  file /usr/bin/hello 1111",
+		"library pkg:deb/ubuntu/libc6@2.39-0ubuntu8?arch=amd64",
+		"  file /usr/lib/libc.so.6 4444",
+	}
+	if !reflect.DeepEqual(comps, wantComps) {
+		t.Fatalf("have components %q, want %q", comps, wantComps)
+	}
+
+	licenses := doc.Components[0].Licenses
+	if len(licenses) != 2 || licenses[0].License.ID != "GPL-3.0-or-later" || licenses[1].License.Name != "Custom" {
+		t.Fatalf("bad licenses for hello: %+v, %+v", licenses[0].License, licenses[1].License)
+	}
+	if doc.Components[1].Licenses != nil {
+		t.Fatalf("have licenses for libc6, want none")
+	}
+
+	deps := doc.Dependencies[0]
+	if deps.Ref != "root" || len(deps.DependsOn) != 2 {
+		t.Fatalf("bad dependencies: %+v", deps)
+	}
+}
@@ -0,0 +1,112 @@
+// Package sbom generates software bills of materials for chisel roots, from
+// the manifest chisel wrote into them.
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/copyright"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+)
+
+// Tool is used as the creator of the documents.
+const Tool = "sdf"
+
+type Input struct {
+	// Name of the document, usually the name of the image or the root.
+	Name     string
+	Manifest *manifest.Manifest
+	// Parsed copyright files per package name. Packages without one have
+	// no declared license.
+	Copyrights map[string]*copyright.Copyright
+	Created    time.Time
+}
+
+// PURL returns the package URL of a deb package from the Ubuntu archive.
+func PURL(p *manifest.Package) string {
+	u := "pkg:deb/ubuntu/" + url.PathEscape(p.Name)
+	if p.Version != "" {
+		u += "@" + strings.NewReplacer(":", "%3A", "+", "%2B").Replace(p.Version)
+	}
+	if p.Arch != "" {
+		u += "?arch=" + url.QueryEscape(p.Arch)
+	}
+	return u
+}
+
+// packageFiles returns the regular files of each package, sorted by path.
+// Files that are shared by slices of different packages are attributed to
+// each of them.
+func packageFiles(m *manifest.Manifest) map[string][]*manifest.Path {
+	files := make(map[string][]*manifest.Path)
+	for _, p := range m.Paths {
+		if p.Digest() == "" {
+			continue // Directories, symlinks and generated files.
+		}
+		seen := make(map[string]bool)
+		for _, s := range p.Slices {
+			pkg, _, err := chisel.Parse(s)
+			if err != nil || seen[pkg] {
+				continue
+			}
+			seen[pkg] = true
+			files[pkg] = append(files[pkg], p)
+		}
+	}
+	for _, f := range files {
+		sort.Slice(f, func(i, j int) bool { return f[i].Path < f[j].Path })
+	}
+	return files
+}
+
+// digest returns a stable digest of the input, used to derive unique but
+// reproducible document identifiers.
+func digest(in *Input) string {
+	h := sha256.New()
+	fmt.Fprintln(h, in.Name)
+	for _, p := range in.Manifest.Packages {
+		fmt.Fprintln(h, p.Name, p.Version, p.Arch, p.Digest)
+	}
+	for _, p := range in.Manifest.Paths {
+		fmt.Fprintln(h, p.Path, p.Mode, p.Digest(), p.Link, strings.Join(p.Slices, ","))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// spdxID turns s into a valid SPDX element identifier.
+func spdxID(prefix, s string) string {
+	var b strings.Builder
+	b.WriteString("SPDXRef-")
+	b.WriteString(prefix)
+	b.WriteString("-")
+	for _, r := range s {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// licenseExpression joins the licenses of a package into an SPDX license
+// expression. Each license of a DEP-5 file applies to some of the files, so
+// all of them apply to the package as a whole.
+func licenseExpression(c *copyright.Copyright) string {
+	if c == nil || len(c.Licenses) == 0 {
+		return "NOASSERTION"
+	}
+	var ids []string
+	for _, l := range c.Licenses {
+		id, _ := copyright.SPDX(l)
+		ids = append(ids, id)
+	}
+	return strings.Join(ids, " AND ")
+}
@@ -0,0 +1,168 @@
+package sbom
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/copyright"
+)
+
+// SPDXDocument is an SPDX 2.3 document, as serialized in JSON.
+// See https://spdx.github.io/spdx-spec/v2.3/.
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	Packages          []*SPDXPackage     `json:"packages"`
+	Files             []*SPDXFile        `json:"files,omitempty"`
+	Relationships     []*SPDXRelation    `json:"relationships"`
+	ExtractedLicenses []*SPDXLicenseInfo `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type SPDXPackage struct {
+	SPDXID           string             `json:"SPDXID"`
+	Name             string             `json:"name"`
+	VersionInfo      string             `json:"versionInfo,omitempty"`
+	Supplier         string             `json:"supplier"`
+	DownloadLocation string             `json:"downloadLocation"`
+	FilesAnalyzed    bool               `json:"filesAnalyzed"`
+	LicenseConcluded string             `json:"licenseConcluded"`
+	LicenseDeclared  string             `json:"licenseDeclared"`
+	CopyrightText    string             `json:"copyrightText"`
+	Checksums        []*SPDXChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []*SPDXExternalRef `json:"externalRefs,omitempty"`
+}
+
+type SPDXFile struct {
+	SPDXID           string          `json:"SPDXID"`
+	FileName         string          `json:"fileName"`
+	Checksums        []*SPDXChecksum `json:"checksums"`
+	LicenseConcluded string          `json:"licenseConcluded"`
+	CopyrightText    string          `json:"copyrightText"`
+}
+
+type SPDXChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+type SPDXExternalRef struct {
+	Category string `json:"referenceCategory"`
+	Type     string `json:"referenceType"`
+	Locator  string `json:"referenceLocator"`
+}
+
+type SPDXRelation struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+type SPDXLicenseInfo struct {
+	LicenseID     string `json:"licenseId"`
+	Name          string `json:"name"`
+	ExtractedText string `json:"extractedText"`
+}
+
+const noAssertion = "NOASSERTION"
+
+// SPDX generates an SPDX document with one package per deb package and one
+// file per regular file in the root.
+func SPDX(in *Input) *SPDXDocument {
+	doc := &SPDXDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              in.Name,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/%s-%s", in.Name, digest(in)),
+		CreationInfo: SPDXCreationInfo{
+			Created:  in.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + Tool},
+		},
+	}
+
+	pkgs := slices.Clone(in.Manifest.Packages)
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	files := packageFiles(in.Manifest)
+	fileIDs := make(map[string]string)
+	extracted := make(map[string]string)
+	for _, p := range pkgs {
+		c := in.Copyrights[p.Name]
+		pkg := &SPDXPackage{
+			SPDXID:           spdxID("Package", p.Name),
+			Name:             p.Name,
+			VersionInfo:      p.Version,
+			Supplier:         "Organization: Ubuntu",
+			DownloadLocation: noAssertion,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  licenseExpression(c),
+			CopyrightText:    noAssertion,
+			ExternalRefs: []*SPDXExternalRef{{
+				Category: "PACKAGE-MANAGER",
+				Type:     "purl",
+				Locator:  PURL(p),
+			}},
+		}
+		if p.Digest != "" {
+			pkg.Checksums = []*SPDXChecksum{{Algorithm: "SHA256", Value: p.Digest}}
+		}
+		if c != nil {
+			for _, l := range c.Licenses {
+				if id, ok := copyright.SPDX(l); !ok || strings.HasPrefix(id, "LicenseRef-") {
+					extracted[id] = l
+				}
+			}
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, &SPDXRelation{
+			Element: doc.SPDXID,
+			Type:    "DESCRIBES",
+			Related: pkg.SPDXID,
+		})
+
+		for _, f := range files[p.Name] {
+			id, ok := fileIDs[f.Path]
+			if !ok {
+				id = spdxID("File", fmt.Sprintf("%d", len(fileIDs)+1))
+				fileIDs[f.Path] = id
+				doc.Files = append(doc.Files, &SPDXFile{
+					SPDXID:           id,
+					FileName:         "." + f.Path,
+					Checksums:        []*SPDXChecksum{{Algorithm: "SHA256", Value: f.Digest()}},
+					LicenseConcluded: noAssertion,
+					CopyrightText:    noAssertion,
+				})
+			}
+			doc.Relationships = append(doc.Relationships, &SPDXRelation{
+				Element: pkg.SPDXID,
+				Type:    "CONTAINS",
+				Related: id,
+			})
+		}
+	}
+
+	var ids []string
+	for id := range extracted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		doc.ExtractedLicenses = append(doc.ExtractedLicenses, &SPDXLicenseInfo{
+			LicenseID:     id,
+			Name:          extracted[id],
+			ExtractedText: "See the copyright file of the package.",
+		})
+	}
+	return doc
+}
@@ -0,0 +1,107 @@
+package sbom_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/copyright"
+	"github.com/rebornplusplus/chisel-tools/internal/manifest"
+	"github.com/rebornplusplus/chisel-tools/internal/sbom"
+)
+
+var sampleInput = &sbom.Input{
+	Name: "hello",
+	Manifest: &manifest.Manifest{
+		Packages: []*manifest.Package{{
+			Name:    "libc6",
+			Version: "2.39-0ubuntu8",
+			Digest:  "c6c6",
+			Arch:    "amd64",
+		}, {
+			Name:    "hello",
+			Version: "1:2.10-3",
+			Digest:  "abcd",
+			Arch:    "amd64",
+		}},
+		Paths: []*manifest.Path{{
+			Path:   "/usr/bin/",
+			Mode:   "0755",
+			Slices: []string{"hello_bins"},
+		}, {
+			Path:   "/usr/bin/hello",
+			Mode:   "0755",
+			Slices: []string{"hello_bins"},
+			SHA256: "1111",
+		}, {
+			Path:        "/etc/hello.conf",
+			Mode:        "0644",
+			Slices:      []string{"hello_config"},
+			SHA256:      "2222",
+			FinalSHA256: "3333",
+		}, {
+			Path:   "/usr/lib/libc.so.6",
+			Mode:   "0755",
+			Slices: []string{"libc6_libs"},
+			SHA256: "4444",
+		}},
+	},
+	Copyrights: map[string]*copyright.Copyright{
+		"hello": {Machine: true, Licenses: []string{"GPL-3+", "Custom"}},
+	},
+	Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestSPDX(t *testing.T) {
+	doc := sbom.SPDX(sampleInput)
+	if doc.SPDXVersion != "SPDX-2.3" || doc.CreationInfo.Created != "2024-01-02T03:04:05Z" {
+		t.Fatalf("bad document header: %+v", doc)
+	}
+	if doc2 := sbom.SPDX(sampleInput); doc2.DocumentNamespace != doc.DocumentNamespace {
+		t.Fatalf("namespace is not stable: %s != %s", doc.DocumentNamespace, doc2.DocumentNamespace)
+	}
+
+	var pkgs []string
+	for _, p := range doc.Packages {
+		pkgs = append(pkgs, p.Name+" "+p.LicenseDeclared+" "+p.ExternalRefs[0].Locator)
+	}
+	wantPkgs := []string{
+		"hello GPL-3.0-or-later AND LicenseRef-Custom pkg:deb/ubuntu/hello@1%3A2.10-3?arch=amd64",
+		"libc6 NOASSERTION pkg:deb/ubuntu/libc6@2.39-0ubuntu8?arch=amd64",
+	}
+	if !reflect.DeepEqual(pkgs, wantPkgs) {
+		t.Fatalf("have packages %q, want %q", pkgs, wantPkgs)
+	}
+
+	var files []string
+	for _, f := range doc.Files {
+		files = append(files, f.SPDXID+" "+f.FileName+" "+f.Checksums[0].Value)
+	}
+	wantFiles := []string{
+		"SPDXRef-File-1 ./etc/hello.conf 3333",
+		"SPDXRef-File-2 ./usr/bin/hello 1111",
+		"SPDXRef-File-3 ./usr/lib/libc.so.6 4444",
+	}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Fatalf("have files %q, want %q", files, wantFiles)
+	}
+
+	var rels []string
+	for _, r := range doc.Relationships {
+		rels = append(rels, r.Element+" "+r.Type+" "+r.Related)
+	}
+	wantRels := []string{
+		"SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-hello",
+		"SPDXRef-Package-hello CONTAINS SPDXRef-File-1",
+		"SPDXRef-Package-hello CONTAINS SPDXRef-File-2",
+		"SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-libc6",
+		"SPDXRef-Package-libc6 CONTAINS SPDXRef-File-3",
+	}
+	if !reflect.DeepEqual(rels, wantRels) {
+		t.Fatalf("have relationships %q, want %q", rels, wantRels)
+	}
+
+	if len(doc.ExtractedLicenses) != 1 || doc.ExtractedLicenses[0].LicenseID != "LicenseRef-Custom" {
+		t.Fatalf("have extracted licenses %+v, want LicenseRef-Custom", doc.ExtractedLicenses)
+	}
+}
@@ -0,0 +1,338 @@
+// Package sdfconfig reads the configuration files of sdf, which give default
+// values to the command flags.
+//
+// The user configuration is in sdf/config.yaml under the user configuration
+// directory, usually ~/.config, and the repository one is .sdf.yaml in the
+// current directory or a parent of it up to the root of the git repository.
+// The repository configuration takes precedence. For example:
+//
+//	# Flags of every command that has them.
+//	release: ../chisel-releases
+//	arch: arm64
+//	# Flags of one command.
+//	commands:
+//	  install:
+//	    workers: 16
+//	# Named sets of values, applied on top of the rest with --profile.
+//	profiles:
+//	  quick:
+//	    commands:
+//	      install:
+//	        combine: true
+//	  full-matrix:
+//	    commands:
+//	      matrix:
+//	        chisel-version: [v1.0.0, v1.1.0, v1.2.0]
+//
+// Flags are given by their long name, and list values set repeatable flags.
+// Relative paths are relative to the directory of the file they are in, see
+// [Values.Dir].
+package sdfconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The name of the repository configuration file.
+const RepoFileName = ".sdf.yaml"
+
+// A Value is the value of a flag, with one item per occurrence for
+// repeatable flags.
+type Value []string
+
+func (v *Value) UnmarshalYAML(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		*v = Value{n.Value}
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := n.Decode(&items); err != nil {
+			return err
+		}
+		*v = items
+		return nil
+	default:
+		return fmt.Errorf("line %d: flag value must be a scalar or a list", n.Line)
+	}
+}
+
+// Values are flag values, for all commands and per command.
+type Values struct {
+	Flags    map[string]Value
+	Commands map[string]map[string]Value
+
+	// Directories of the files the values were read from, by flag and by
+	// command and flag.
+	dirs    map[string]string
+	cmdDirs map[string]map[string]string
+}
+
+func (v *Values) UnmarshalYAML(n *yaml.Node) error {
+	return v.decode(n, nil)
+}
+
+// decode fills the values from the mapping node, calling other for the keys
+// that are not flag names if it is not nil.
+func (v *Values) decode(n *yaml.Node, other func(key string, value *yaml.Node) (bool, error)) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: want a map of flag values", n.Line)
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		key, value := n.Content[i].Value, n.Content[i+1]
+		if other != nil {
+			ok, err := other(key, value)
+			if err != nil {
+				return err
+			} else if ok {
+				continue
+			}
+		}
+		if key == "commands" {
+			if err := value.Decode(&v.Commands); err != nil {
+				return err
+			}
+			continue
+		}
+		var fv Value
+		if err := value.Decode(&fv); err != nil {
+			return err
+		}
+		if v.Flags == nil {
+			v.Flags = make(map[string]Value)
+		}
+		v.Flags[key] = fv
+	}
+	return nil
+}
+
+type File struct {
+	Path     string
+	Values   Values
+	Profiles map[string]*Values
+}
+
+// Parse the content of a configuration file.
+func Parse(data []byte) (*File, error) {
+	var n yaml.Node
+	if err := yaml.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	f := &File{}
+	if len(n.Content) == 0 {
+		return f, nil
+	}
+	err := f.Values.decode(n.Content[0], func(key string, value *yaml.Node) (bool, error) {
+		if key != "profiles" {
+			return false, nil
+		}
+		return true, value.Decode(&f.Profiles)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ReadFile reads the configuration file at path.
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	f.Path = path
+	dir := filepath.Dir(path)
+	f.Values.setDir(dir)
+	for _, p := range f.Profiles {
+		if p != nil {
+			p.setDir(dir)
+		}
+	}
+	return f, nil
+}
+
+// setDir records dir as the directory of the file all the values were read
+// from.
+func (v *Values) setDir(dir string) {
+	v.dirs = make(map[string]string)
+	for name := range v.Flags {
+		v.dirs[name] = dir
+	}
+	v.cmdDirs = make(map[string]map[string]string)
+	for cmd, flags := range v.Commands {
+		v.cmdDirs[cmd] = make(map[string]string)
+		for name := range flags {
+			v.cmdDirs[cmd][name] = dir
+		}
+	}
+}
+
+// UserPath returns the path of the user configuration file.
+func UserPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sdf", "config.yaml"), nil
+}
+
+// RepoPath returns the path of the repository configuration file for dir, or
+// an empty string if there is none. It is searched in dir and its parents up
+// to the root of the git repository.
+func RepoPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		p := filepath.Join(dir, RepoFileName)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the user configuration file and the repository one for dir, in
+// that order. Missing files are skipped.
+func Load(dir string) ([]*File, error) {
+	var paths []string
+	if p, err := UserPath(); err == nil {
+		paths = append(paths, p)
+	}
+	p, err := RepoPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	if p != "" {
+		paths = append(paths, p)
+	}
+	var files []*File
+	for _, p := range paths {
+		f, err := ReadFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Resolve merges the values of the files, the later ones taking precedence,
+// and then the values of the profile in every file that defines it. The
+// profile must be defined in one of the files at least, unless it is empty.
+func Resolve(files []*File, profile string) (*Values, error) {
+	v := &Values{}
+	for _, f := range files {
+		v.merge(&f.Values)
+	}
+	if profile == "" {
+		return v, nil
+	}
+	found := false
+	for _, f := range files {
+		if p, ok := f.Profiles[profile]; ok {
+			found = true
+			if p != nil {
+				v.merge(p)
+			}
+		}
+	}
+	if !found {
+		names := Profiles(files)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("profile %q not defined, the configuration has no profiles", profile)
+		}
+		return nil, fmt.Errorf("profile %q not defined, want one of: %s", profile, strings.Join(names, ", "))
+	}
+	return v, nil
+}
+
+func (v *Values) merge(o *Values) {
+	if v.dirs == nil {
+		v.dirs = make(map[string]string)
+		v.cmdDirs = make(map[string]map[string]string)
+	}
+	for name, value := range o.Flags {
+		if v.Flags == nil {
+			v.Flags = make(map[string]Value)
+		}
+		v.Flags[name] = value
+		v.dirs[name] = o.dirs[name]
+	}
+	for cmd, flags := range o.Commands {
+		if v.Commands == nil {
+			v.Commands = make(map[string]map[string]Value)
+		}
+		if v.Commands[cmd] == nil {
+			v.Commands[cmd] = make(map[string]Value)
+			v.cmdDirs[cmd] = make(map[string]string)
+		}
+		for name, value := range flags {
+			v.Commands[cmd][name] = value
+			v.cmdDirs[cmd][name] = o.cmdDirs[cmd][name]
+		}
+	}
+}
+
+// For returns the values of the flags of the command, with the values for
+// that command taking precedence over those for all commands.
+func (v *Values) For(command string) map[string]Value {
+	values := make(map[string]Value)
+	for name, value := range v.Flags {
+		values[name] = value
+	}
+	for name, value := range v.Commands[command] {
+		values[name] = value
+	}
+	return values
+}
+
+// Dir returns the directory of the configuration file the value of the flag
+// for the command is from, as returned by [Values.For], or an empty string if
+// it was not read from a file.
+func (v *Values) Dir(command, flag string) string {
+	if _, ok := v.Commands[command][flag]; ok {
+		return v.cmdDirs[command][flag]
+	}
+	return v.dirs[flag]
+}
+
+// Profiles returns the names of the profiles defined in the files, sorted.
+func Profiles(files []*File) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, f := range files {
+		for name := range f.Profiles {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
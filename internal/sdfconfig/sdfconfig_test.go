@@ -0,0 +1,190 @@
+package sdfconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/sdfconfig"
+)
+
+const userConfig = `
+release: ../chisel-releases
+arch: arm64
+commands:
+  install:
+    workers: 16
+    keep: [base-files_base]
+profiles:
+  quick:
+    commands:
+      install:
+        combine: true
+`
+
+const repoConfig = `
+arch: amd64
+profiles:
+  quick:
+    arch: riscv64
+  full-matrix:
+    commands:
+      matrix:
+        chisel-version: [v1.0.0, v1.1.0]
+commands:
+  install:
+    workers: 4
+`
+
+var resolveTests = []struct {
+	summary string
+	profile string
+	command string
+	values  map[string]sdfconfig.Value
+	err     string
+}{{
+	summary: "Repository values take precedence",
+	command: "install",
+	values: map[string]sdfconfig.Value{
+		"release": {"../chisel-releases"},
+		"arch":    {"amd64"},
+		"workers": {"4"},
+		"keep":    {"base-files_base"},
+	},
+}, {
+	summary: "Flags of other commands are left out",
+	command: "matrix",
+	values: map[string]sdfconfig.Value{
+		"release": {"../chisel-releases"},
+		"arch":    {"amd64"},
+	},
+}, {
+	summary: "Profile defined in both files",
+	profile: "quick",
+	command: "install",
+	values: map[string]sdfconfig.Value{
+		"release": {"../chisel-releases"},
+		"arch":    {"riscv64"},
+		"workers": {"4"},
+		"keep":    {"base-files_base"},
+		"combine": {"true"},
+	},
+}, {
+	summary: "Profile with repeated flags",
+	profile: "full-matrix",
+	command: "matrix",
+	values: map[string]sdfconfig.Value{
+		"release":        {"../chisel-releases"},
+		"arch":           {"amd64"},
+		"chisel-version": {"v1.0.0", "v1.1.0"},
+	},
+}, {
+	summary: "Undefined profile",
+	profile: "slow",
+	err:     `profile "slow" not defined, want one of: full-matrix, quick`,
+}}
+
+func TestResolve(t *testing.T) {
+	user, err := sdfconfig.Parse([]byte(userConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := sdfconfig.Parse([]byte(repoConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range resolveTests {
+		t.Logf("Summary: %s", test.summary)
+		v, err := sdfconfig.Resolve([]*sdfconfig.File{user, repo}, test.profile)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("have error %v, want %s", err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := v.For(test.command); !reflect.DeepEqual(have, test.values) {
+			t.Fatalf("have %v, want %v", have, test.values)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := sdfconfig.Parse([]byte("arch: {amd64: true}\n"))
+	if err == nil || err.Error() != "line 1: flag value must be a scalar or a list" {
+		t.Fatalf("have error %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	repo := t.TempDir()
+	sub := filepath.Join(repo, "slices", "sub")
+	for _, dir := range []string{filepath.Join(home, "sdf"), filepath.Join(repo, ".git"), sub} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(home, "sdf", "config.yaml"), []byte(userConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Outside of the repository, only the user file is read.
+	files, err := sdfconfig.Load(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != filepath.Join(home, "sdf", "config.yaml") {
+		t.Fatalf("have files %v, want the user file", files)
+	}
+	if err := os.WriteFile(filepath.Join(repo, sdfconfig.RepoFileName), []byte(repoConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err = sdfconfig.Load(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[1].Path != filepath.Join(repo, sdfconfig.RepoFileName) {
+		t.Fatalf("have files %v, want the user and repository files", files)
+	}
+}
+
+func TestResolveDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	repo := t.TempDir()
+	for _, dir := range []string{filepath.Join(home, "sdf"), filepath.Join(repo, ".git")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(home, "sdf", "config.yaml"), []byte(userConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, sdfconfig.RepoFileName), []byte(repoConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := sdfconfig.Load(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := sdfconfig.Resolve(files, "quick")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userDir := filepath.Join(home, "sdf")
+	for _, test := range []struct{ command, flag, dir string }{
+		{"install", "release", userDir},
+		{"install", "arch", repo},
+		{"install", "workers", repo},
+		{"install", "combine", userDir},
+		{"install", "none", ""},
+	} {
+		if dir := v.Dir(test.command, test.flag); dir != test.dir {
+			t.Fatalf("have directory %q for %s of %s, want %q", dir, test.flag, test.command, test.dir)
+		}
+	}
+}
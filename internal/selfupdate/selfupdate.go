@@ -0,0 +1,289 @@
+// Package selfupdate downloads the released sdf binaries and replaces the
+// running one with them.
+//
+// Every release publishes, for each platform, the archive
+// sdf_<version>_<os>_<arch>.tar.gz holding the binary, along with
+// checksums.txt listing the SHA-256 checksums of the archives and
+// checksums.txt.sig, the base64 encoded signature of checksums.txt.
+//
+// The release builds have the public key of the releases built in, see
+// [ReleaseKey].
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/attest"
+)
+
+const (
+	DefaultAPIURL = "https://api.github.com"
+	DefaultRepo   = "rebornplusplus/chisel-tools"
+)
+
+const (
+	checksumsAsset = "checksums.txt"
+	signatureAsset = checksumsAsset + ".sig"
+)
+
+// ReleaseKey is the public key the checksums of the releases are signed
+// with, the base64 of its DER encoding as in a PEM block. It is set for the
+// release builds with:
+//
+//	-ldflags "-X github.com/rebornplusplus/chisel-tools/internal/selfupdate.ReleaseKey=<key>"
+var ReleaseKey string
+
+// BuiltinKey returns the release key built in, nil if there is none.
+func BuiltinKey() (crypto.PublicKey, error) {
+	if ReleaseKey == "" {
+		return nil, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(ReleaseKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release key: %w", err)
+	}
+	block := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	key, err := attest.ParsePublicKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release key: %w", err)
+	}
+	return key, nil
+}
+
+// The client of the updaters without one. The binaries are small, so that
+// a download taking longer is one that stalled.
+var defaultClient = &http.Client{Timeout: 5 * time.Minute}
+
+type Updater struct {
+	// Base URL of the GitHub API, DefaultAPIURL if empty.
+	APIURL string
+	// Repository the releases are published in, DefaultRepo if empty.
+	Repo string
+	// Token to authenticate to the API with, to raise its rate limit.
+	Token string
+	// Public key checksums.txt must be signed with. Downloads fail without
+	// one, unless SkipSignature is set.
+	Key           crypto.PublicKey
+	SkipSignature bool
+	// Client to make the requests with, one timing out after 5 minutes if
+	// nil.
+	Client *http.Client
+}
+
+type Release struct {
+	Version string   `json:"tag_name"`
+	Assets  []*Asset `json:"assets"`
+}
+
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// AssetName returns the name of the release asset of the version for the host.
+func AssetName(version string) string {
+	return fmt.Sprintf("sdf_%s_%s_%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+}
+
+func (r *Release) asset(name string) (*Asset, error) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("release %s has no asset %s", r.Version, name)
+}
+
+// Release returns the release of the version, the latest one if empty.
+func (u *Updater) Release(version string) (*Release, error) {
+	base := u.APIURL
+	if base == "" {
+		base = DefaultAPIURL
+	}
+	repo := u.Repo
+	if repo == "" {
+		repo = DefaultRepo
+	}
+	url := strings.TrimSuffix(base, "/") + "/repos/" + repo + "/releases/"
+	if version == "" {
+		url += "latest"
+	} else {
+		if !strings.HasPrefix(version, "v") {
+			version = "v" + version
+		}
+		url += "tags/" + version
+	}
+	data, err := u.get(url, true)
+	if err != nil {
+		return nil, err
+	}
+	r := &Release{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("cannot parse release at %s: %w", url, err)
+	}
+	if r.Version == "" {
+		return nil, fmt.Errorf("release at %s has no tag", url)
+	}
+	return r, nil
+}
+
+// Download returns the sdf binary of the release for the host. The archive
+// is checked against its checksum in checksums.txt, and checksums.txt
+// against its signature unless the updater skips it.
+func (u *Updater) Download(r *Release) ([]byte, error) {
+	asset, err := r.asset(AssetName(r.Version))
+	if err != nil {
+		return nil, err
+	}
+	sums, err := r.asset(checksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+	checksums, err := u.get(sums.URL, false)
+	if err != nil {
+		return nil, err
+	}
+	if u.Key == nil && !u.SkipSignature {
+		return nil, fmt.Errorf("no key to check the signature of %s of release %s with", checksumsAsset, r.Version)
+	}
+	if !u.SkipSignature {
+		sigAsset, err := r.asset(signatureAsset)
+		if err != nil {
+			return nil, err
+		}
+		data, err := u.get(sigAsset.URL, false)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature of %s: %w", checksumsAsset, err)
+		}
+		if !attest.VerifySignature(u.Key, checksums, sig) {
+			return nil, fmt.Errorf("invalid signature of %s of release %s", checksumsAsset, r.Version)
+		}
+	}
+	want, err := checksum(checksums, asset.Name)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := u.get(asset.URL, false)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(archive)
+	if hex.EncodeToString(h[:]) != want {
+		return nil, fmt.Errorf("checksum mismatch for %s", asset.Name)
+	}
+	data, err := extract(archive)
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract %s: %w", asset.Name, err)
+	}
+	return data, nil
+}
+
+// checksum returns the checksum of the file in the output of sha256sum.
+func checksum(checksums []byte, name string) (string, error) {
+	for _, line := range strings.Split(string(checksums), "\n") {
+		fields := strings.Fields(line)
+		// Binary mode prefixes the name with an asterisk.
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no checksum of %s in %s", name, checksumsAsset)
+}
+
+func (u *Updater) get(url string, api bool) ([]byte, error) {
+	client := u.Client
+	if client == nil {
+		client = defaultClient
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if api {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if u.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+u.Token)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extract returns the sdf binary from the release archive.
+func extract(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no sdf binary in archive")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == "sdf" {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// Replace replaces the binary at path, following symlinks, with data. The
+// new binary is written next to it and renamed over it, so that the binary
+// is never partially written, even while it runs.
+func Replace(path string, data []byte) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sdf-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
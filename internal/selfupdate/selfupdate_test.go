@@ -0,0 +1,214 @@
+package selfupdate_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/selfupdate"
+)
+
+func releaseArchive(t *testing.T, bin string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range map[string]string{"LICENSE": "GPL", "sdf": bin} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(data))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// releaseServer serves the API and the assets of the releases, whose files
+// are given by name.
+func releaseServer(t *testing.T, releases map[string]map[string][]byte) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release := func(version string) {
+			files, ok := releases[version]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			rel := &selfupdate.Release{Version: version}
+			for name := range files {
+				rel.Assets = append(rel.Assets, &selfupdate.Asset{Name: name, URL: srv.URL + "/download/" + version + "/" + name})
+			}
+			json.NewEncoder(w).Encode(rel)
+		}
+		switch {
+		case r.URL.Path == "/repos/"+selfupdate.DefaultRepo+"/releases/latest":
+			release("v1.1.0")
+		case strings.HasPrefix(r.URL.Path, "/repos/"+selfupdate.DefaultRepo+"/releases/tags/"):
+			release(filepath.Base(r.URL.Path))
+		case strings.HasPrefix(r.URL.Path, "/download/"):
+			version, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/download/"), "/")
+			data, ok := releases[version][name]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownload(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := func(version string, archive []byte, sign bool) map[string][]byte {
+		sum := sha256.Sum256(archive)
+		checksums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), selfupdate.AssetName(version))
+		files := map[string][]byte{
+			selfupdate.AssetName(version): archive,
+			"checksums.txt":               []byte(checksums),
+		}
+		if sign {
+			sig := ed25519.Sign(key, []byte(checksums))
+			files["checksums.txt.sig"] = []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+		}
+		return files
+	}
+	tampered := release("v0.9.0", releaseArchive(t, "old"), true)
+	tampered[selfupdate.AssetName("v0.9.0")] = releaseArchive(t, "evil")
+	forged := release("v0.8.0", releaseArchive(t, "old"), true)
+	forged["checksums.txt"] = append(forged["checksums.txt"], "\n"...)
+	srv := releaseServer(t, map[string]map[string][]byte{
+		"v1.1.0": release("v1.1.0", releaseArchive(t, "new"), true),
+		"v1.0.0": release("v1.0.0", releaseArchive(t, "unsigned"), false),
+		"v0.9.0": tampered,
+		"v0.8.0": forged,
+	})
+
+	u := &selfupdate.Updater{APIURL: srv.URL, Key: pub}
+	r, err := u.Release("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "v1.1.0" {
+		t.Fatalf("have latest version %s, want v1.1.0", r.Version)
+	}
+	bin, err := u.Download(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bin) != "new" {
+		t.Fatalf("have binary %q, want %q", bin, "new")
+	}
+
+	for _, test := range []struct {
+		version string
+		key     bool
+		skip    bool
+		err     string
+	}{
+		{"1.0.0", true, false, "release v1.0.0 has no asset checksums.txt.sig"},
+		{"v1.0.0", false, false, "no key to check the signature of checksums.txt of release v1.0.0 with"},
+		{"v1.0.0", false, true, ""},
+		{"v0.9.0", true, false, "checksum mismatch for " + selfupdate.AssetName("v0.9.0")},
+		{"v0.9.0", false, true, "checksum mismatch for " + selfupdate.AssetName("v0.9.0")},
+		{"v0.8.0", true, false, "invalid signature of checksums.txt of release v0.8.0"},
+	} {
+		u := &selfupdate.Updater{APIURL: srv.URL, SkipSignature: test.skip}
+		if test.key {
+			u.Key = pub
+		}
+		r, err := u.Release(test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = u.Download(r)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Fatalf("have error %v for %s, want %q", err, test.version, test.err)
+		}
+	}
+
+	if _, err := u.Release("v0.1.0"); err == nil {
+		t.Fatal("have no error for a missing release")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "sdf")
+	if err := os.WriteFile(bin, []byte("old"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(bin, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := selfupdate.Replace(link, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Fatalf("have binary %q, want %q", data, "new")
+	}
+	if info, err := os.Stat(bin); err != nil || info.Mode().Perm() != 0750 {
+		t.Fatalf("have binary mode %v (%v), want 0750", info.Mode(), err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("have link replaced: %v (%v)", info.Mode(), err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("have %d entries, want the binary and the link", len(entries))
+	}
+}
+
+func TestBuiltinKey(t *testing.T) {
+	defer func(key string) { selfupdate.ReleaseKey = key }(selfupdate.ReleaseKey)
+
+	selfupdate.ReleaseKey = ""
+	if key, err := selfupdate.BuiltinKey(); key != nil || err != nil {
+		t.Fatalf("have key %v, error %v, want neither", key, err)
+	}
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selfupdate.ReleaseKey = base64.StdEncoding.EncodeToString(der)
+	key, err := selfupdate.BuiltinKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(key) {
+		t.Fatalf("have key %v, want %v", key, pub)
+	}
+	selfupdate.ReleaseKey = "not a key"
+	if _, err := selfupdate.BuiltinKey(); err == nil || !strings.HasPrefix(err.Error(), "invalid release key: ") {
+		t.Fatalf("have error %v, want an invalid release key", err)
+	}
+}
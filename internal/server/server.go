@@ -0,0 +1,220 @@
+// Package server serves a read-only JSON API over a chisel release.
+//
+// Every request works on the current snapshot of a [chisel.Store], so that
+// the release can be reloaded in the background while it is being served.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/lint"
+)
+
+type Server struct {
+	store *chisel.Store
+	mux   *http.ServeMux
+}
+
+// New returns a server for the release in the store.
+func New(store *chisel.Store) *Server {
+	s := &Server{store: store, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /{$}", s.release)
+	s.mux.HandleFunc("GET /slices", s.slices)
+	s.mux.HandleFunc("GET /slices/{name}", s.slice)
+	s.mux.HandleFunc("GET /slices/{name}/deps", s.deps)
+	s.mux.HandleFunc("GET /slices/{name}/rdeps", s.rdeps)
+	s.mux.HandleFunc("GET /find", s.find)
+	s.mux.HandleFunc("GET /lint", s.lint)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Release is the response of "GET /".
+type Release struct {
+	Path     string   `json:"path"`
+	Format   string   `json:"format"`
+	Archives []string `json:"archives"`
+	Packages int      `json:"packages"`
+	Slices   int      `json:"slices"`
+}
+
+// Slice is a slice as it appears in the responses.
+type Slice struct {
+	Name      string   `json:"name"`
+	Package   string   `json:"package"`
+	Essential []string `json:"essential"`
+	Contents  []string `json:"contents"`
+	File      string   `json:"file,omitempty"`
+}
+
+// Match is an entry in the response of "GET /find".
+type Match struct {
+	Slice string `json:"slice"`
+	Path  string `json:"path"`
+}
+
+// Error is the response of failed requests.
+type Error struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) release(w http.ResponseWriter, req *http.Request) {
+	r := s.store.Snapshot()
+	resp := &Release{
+		Path:     r.Path,
+		Format:   r.Config.Format,
+		Archives: []string{},
+		Slices:   len(r.Slices),
+	}
+	for name := range r.Config.Archives {
+		resp.Archives = append(resp.Archives, name)
+	}
+	sort.Strings(resp.Archives)
+	pkgs := make(map[string]bool)
+	for _, sl := range r.Slices {
+		pkgs[sl.Package] = true
+	}
+	resp.Packages = len(pkgs)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) slices(w http.ResponseWriter, req *http.Request) {
+	r := s.store.Snapshot()
+	pkg := req.URL.Query().Get("package")
+	resp := []*Slice{}
+	for _, sl := range r.Slices {
+		if pkg == "" || sl.Package == pkg {
+			resp = append(resp, newSlice(r, sl))
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) slice(w http.ResponseWriter, req *http.Request) {
+	r := s.store.Snapshot()
+	sl := lookup(w, r, req.PathValue("name"))
+	if sl == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, newSlice(r, sl))
+}
+
+// deps responds with the slices chisel installs along with the slice, that
+// is, the closure of its essential slices, sorted by name.
+func (s *Server) deps(w http.ResponseWriter, req *http.Request) {
+	r := s.store.Snapshot()
+	sl := lookup(w, r, req.PathValue("name"))
+	if sl == nil {
+		return
+	}
+	resp := r.Deps(sl.Name)
+	if resp == nil {
+		resp = []string{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// rdeps responds with the slices that install the slice along with them,
+// sorted by name.
+func (s *Server) rdeps(w http.ResponseWriter, req *http.Request) {
+	r := s.store.Snapshot()
+	sl := lookup(w, r, req.PathValue("name"))
+	if sl == nil {
+		return
+	}
+	resp := r.RDeps(sl.Name)
+	if resp == nil {
+		resp = []string{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// find responds with the slices whose contents include the path given in
+// the "path" parameter.
+func (s *Server) find(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Query().Get("path")
+	if !strings.HasPrefix(path, "/") {
+		writeJSON(w, http.StatusBadRequest, &Error{Error: fmt.Sprintf("invalid path %q, want an absolute path", path)})
+		return
+	}
+	r := s.store.Snapshot()
+	resp := []*Match{}
+	for _, sl := range r.Slices {
+		for _, p := range sl.Contents {
+			if chisel.MatchPath(p, path) {
+				resp = append(resp, &Match{Slice: sl.Name, Path: p})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) lint(w http.ResponseWriter, req *http.Request) {
+	r := s.store.Snapshot()
+	var checks []*lint.Check
+	for _, name := range req.URL.Query()["check"] {
+		c := lint.FindCheck(name)
+		if c == nil {
+			writeJSON(w, http.StatusBadRequest, &Error{Error: fmt.Sprintf("unknown check %q", name)})
+			return
+		}
+		checks = append(checks, c)
+	}
+	resp := []*lint.Issue{}
+	for _, i := range lint.Run(r, checks...) {
+		issue := *i
+		issue.File = relPath(r, i.File)
+		resp = append(resp, &issue)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// lookup returns the slice with the name, or responds with an error and
+// returns nil if there is none.
+func lookup(w http.ResponseWriter, r *chisel.Release, name string) *chisel.Slice {
+	if _, _, err := chisel.Parse(name); err != nil {
+		writeJSON(w, http.StatusBadRequest, &Error{Error: err.Error()})
+		return nil
+	}
+	sl := r.Slice(name)
+	if sl == nil {
+		writeJSON(w, http.StatusNotFound, &Error{Error: fmt.Sprintf("slice %s not found", name)})
+	}
+	return sl
+}
+
+func newSlice(r *chisel.Release, sl *chisel.Slice) *Slice {
+	return &Slice{
+		Name:      sl.Name,
+		Package:   sl.Package,
+		Essential: append([]string{}, sl.Essential...),
+		Contents:  append([]string{}, sl.Contents...),
+		File:      relPath(r, sl.File),
+	}
+}
+
+// relPath returns the path relative to the release directory, so that the
+// responses do not depend on where the release is checked out.
+func relPath(r *chisel.Release, path string) string {
+	if rel, err := filepath.Rel(r.Path, path); err == nil && path != "" {
+		return rel
+	}
+	return path
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,217 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/server"
+)
+
+var releaseFiles = map[string]string{
+	"chisel.yaml": `
+format: v1
+archives:
+  ubuntu:
+    suites: [noble]
+    components: [main]
+`,
+	"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    essential: [foo_config, bar_libs]
+    contents:
+      /usr/bin/foo:
+  config:
+    contents:
+      /etc/foo/**:
+`,
+	"slices/bar.yaml": `
+package: bar
+slices:
+  libs:
+    essential: [baz_libs]
+    contents:
+      /usr/lib/*/libbar.so.*:
+`,
+}
+
+var serverTests = []struct {
+	summary string
+	path    string
+	status  int
+	body    string
+}{{
+	summary: "Release",
+	path:    "/",
+	status:  http.StatusOK,
+	body:    `{"path": "$DIR", "format": "v1", "archives": ["ubuntu"], "packages": 2, "slices": 3}`,
+}, {
+	summary: "All slices",
+	path:    "/slices",
+	status:  http.StatusOK,
+	body: `[
+		{"name": "bar_libs", "package": "bar", "essential": ["baz_libs"], "contents": ["/usr/lib/*/libbar.so.*"], "file": "slices/bar.yaml"},
+		{"name": "foo_bins", "package": "foo", "essential": ["foo_config", "bar_libs"], "contents": ["/usr/bin/foo"], "file": "slices/foo.yaml"},
+		{"name": "foo_config", "package": "foo", "essential": [], "contents": ["/etc/foo/**"], "file": "slices/foo.yaml"}
+	]`,
+}, {
+	summary: "Slices of a package",
+	path:    "/slices?package=bar",
+	status:  http.StatusOK,
+	body:    `[{"name": "bar_libs", "package": "bar", "essential": ["baz_libs"], "contents": ["/usr/lib/*/libbar.so.*"], "file": "slices/bar.yaml"}]`,
+}, {
+	summary: "Slices of an unknown package",
+	path:    "/slices?package=none",
+	status:  http.StatusOK,
+	body:    `[]`,
+}, {
+	summary: "Slice",
+	path:    "/slices/foo_config",
+	status:  http.StatusOK,
+	body:    `{"name": "foo_config", "package": "foo", "essential": [], "contents": ["/etc/foo/**"], "file": "slices/foo.yaml"}`,
+}, {
+	summary: "Slice not found",
+	path:    "/slices/foo_none",
+	status:  http.StatusNotFound,
+	body:    `{"error": "slice foo_none not found"}`,
+}, {
+	summary: "Invalid slice name",
+	path:    "/slices/foo",
+	status:  http.StatusBadRequest,
+	body:    `{"error": "invalid slice name: foo"}`,
+}, {
+	summary: "Dependencies include undefined slices",
+	path:    "/slices/foo_bins/deps",
+	status:  http.StatusOK,
+	body:    `["bar_libs", "baz_libs", "foo_config"]`,
+}, {
+	summary: "Reverse dependencies",
+	path:    "/slices/bar_libs/rdeps",
+	status:  http.StatusOK,
+	body:    `["foo_bins"]`,
+}, {
+	summary: "No reverse dependencies",
+	path:    "/slices/foo_bins/rdeps",
+	status:  http.StatusOK,
+	body:    `[]`,
+}, {
+	summary: "Find a path matching a glob",
+	path:    "/find?path=/usr/lib/x86_64-linux-gnu/libbar.so.1",
+	status:  http.StatusOK,
+	body:    `[{"slice": "bar_libs", "path": "/usr/lib/*/libbar.so.*"}]`,
+}, {
+	summary: "Find a path in no slice",
+	path:    "/find?path=/usr/bin/bar",
+	status:  http.StatusOK,
+	body:    `[]`,
+}, {
+	summary: "Find a relative path",
+	path:    "/find?path=usr/bin/foo",
+	status:  http.StatusBadRequest,
+	body:    `{"error": "invalid path \"usr/bin/foo\", want an absolute path"}`,
+}, {
+	summary: "Lint",
+	path:    "/lint",
+	status:  http.StatusOK,
+	body:    `[{"check": "undefined-essential", "file": "slices/bar.yaml", "slice": "bar_libs", "message": "essential slice baz_libs is of undefined package baz"}]`,
+}, {
+	summary: "Lint with selected checks",
+	path:    "/lint?check=path",
+	status:  http.StatusOK,
+	body:    `[]`,
+}, {
+	summary: "Lint with an unknown check",
+	path:    "/lint?check=none",
+	status:  http.StatusBadRequest,
+	body:    `{"error": "unknown check \"none\""}`,
+}, {
+	summary: "Unknown endpoint",
+	path:    "/none",
+	status:  http.StatusNotFound,
+}}
+
+func writeRelease(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func get(t *testing.T, srv *server.Server, path string) (int, any) {
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	if rec.Code == http.StatusNotFound && !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		return rec.Code, nil
+	}
+	var body any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response %q: %v", rec.Body, err)
+	}
+	return rec.Code, body
+}
+
+func TestServer(t *testing.T) {
+	dir := writeRelease(t, releaseFiles)
+	store, err := chisel.NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(store)
+	for _, test := range serverTests {
+		t.Logf("Summary: %s", test.summary)
+		status, body := get(t, srv, test.path)
+		if status != test.status {
+			t.Fatalf("have status %d, want %d", status, test.status)
+		}
+		var want any
+		if test.body != "" {
+			data := strings.ReplaceAll(test.body, "$DIR", dir)
+			if err := json.Unmarshal([]byte(data), &want); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(body, want) {
+			t.Fatalf("have %v, want %v", body, want)
+		}
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	dir := writeRelease(t, releaseFiles)
+	store, err := chisel.NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(store)
+
+	path := filepath.Join(dir, "slices/baz.yaml")
+	if err := os.WriteFile(path, []byte("package: baz\nslices:\n  libs: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Apply([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+	_, body := get(t, srv, "/lint")
+	if want := []any{}; !reflect.DeepEqual(body, want) {
+		t.Fatalf("have %v, want %v", body, want)
+	}
+	_, body = get(t, srv, "/slices/baz_libs/rdeps")
+	if want := []any{"bar_libs", "foo_bins"}; !reflect.DeepEqual(body, want) {
+		t.Fatalf("have %v, want %v", body, want)
+	}
+}
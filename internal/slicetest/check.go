@@ -0,0 +1,149 @@
+package slicetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A Failure is a check of a test that did not pass.
+type Failure struct {
+	// The path or command that was checked.
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+func (f *Failure) String() string {
+	return f.Subject + ": " + f.Message
+}
+
+// A Runner runs a command of a test against the root and returns its
+// standard output.
+type Runner func(ctx context.Context, root, command string) ([]byte, error)
+
+// HostRunner runs the command with the shell of the host, in the root
+// directory and with $ROOT set to it.
+func HostRunner(ctx context.Context, root, command string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "ROOT="+root)
+	return output(cmd)
+}
+
+// output runs cmd and returns its standard output, with the standard error
+// in the error on failure.
+func output(cmd *exec.Cmd) ([]byte, error) {
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%w: %s", err, msg)
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+// Check runs the test against the root, with the commands run by run.
+func Check(ctx context.Context, root string, t *Test, run Runner) []*Failure {
+	var failures []*Failure
+	fail := func(subject, format string, args ...any) {
+		failures = append(failures, &Failure{Subject: subject, Message: fmt.Sprintf(format, args...)})
+	}
+
+	paths := make([]string, 0, len(t.Paths))
+	for p := range t.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if msg := checkPath(root, p, t.Paths[p]); msg != "" {
+			fail(p, "%s", msg)
+		}
+	}
+
+	for _, c := range t.Commands {
+		out, err := run(ctx, root, c.Run)
+		if err != nil {
+			fail(c.Run, "%s", err)
+			continue
+		}
+		out = bytes.TrimSpace(out)
+		if c.stdout != nil && !c.stdout.Match(out) {
+			fail(c.Run, "output %q does not match %q", out, c.Stdout)
+		}
+	}
+	return failures
+}
+
+// checkPath returns why the path does not match the check, or "" if it does.
+func checkPath(root, p string, c *PathCheck) string {
+	full := filepath.Join(root, p)
+	info, err := os.Lstat(full)
+	if c.Missing {
+		if err == nil {
+			return "exists but should be missing"
+		}
+		return ""
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return "is missing"
+	}
+	if err != nil {
+		return err.Error()
+	}
+
+	typ := "file"
+	switch {
+	case info.IsDir():
+		typ = "dir"
+	case info.Mode()&fs.ModeSymlink != 0:
+		typ = "symlink"
+	case !info.Mode().IsRegular():
+		typ = "special"
+	}
+	want := c.Type
+	if want == "" {
+		switch {
+		case strings.HasSuffix(p, "/"):
+			want = "dir"
+		case c.Link != "":
+			want = "symlink"
+		case c.content != nil:
+			want = "file"
+		}
+	}
+	if want != "" && typ != want {
+		return fmt.Sprintf("is a %s, want a %s", typ, want)
+	}
+	if c.Mode != "" && info.Mode().Perm() != c.mode {
+		return fmt.Sprintf("has mode %04o, want %s", info.Mode().Perm(), c.Mode)
+	}
+	if c.Link != "" {
+		target, err := os.Readlink(full)
+		if err != nil {
+			return err.Error()
+		}
+		if target != c.Link {
+			return fmt.Sprintf("links to %s, want %s", target, c.Link)
+		}
+	}
+	if c.content != nil {
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return err.Error()
+		}
+		if !c.content.Match(data) {
+			return fmt.Sprintf("content does not match %q", c.Content)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package slicetest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+const checkSpec = `
+package: hello
+tests:
+  pass:
+    slices: [hello_bins]
+    paths:
+      /usr/bin/: {}
+      /usr/bin/hello: {type: file, mode: "0755"}
+      /usr/bin/hi: {link: hello}
+      /etc/hello.conf: {content: "^greeting="}
+      /usr/share/doc/: {missing: true}
+    commands:
+      - test -x usr/bin/hello
+      - run: cat "$ROOT/etc/hello.conf"
+        stdout: hi$
+  fail:
+    slices: [hello_bins]
+    paths:
+      /usr/bin/hello: {mode: "0644"}
+      /usr/bin/hi: {link: other}
+      /usr/bin/bye: {}
+      /etc/hello.conf: {content: "^farewell="}
+      /etc/: {missing: true}
+      /etc/hello.conf/: {}
+    commands:
+      - exit 3
+      - run: echo bye
+        stdout: hi
+`
+
+func makeRoot(t *testing.T) string {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "usr/bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "usr/bin/hello"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("hello", filepath.Join(root, "usr/bin/hi")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc/hello.conf"), []byte("greeting=hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestCheck(t *testing.T) {
+	spec, err := slicetest.Parse([]byte(checkSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := makeRoot(t)
+
+	if failures := slicetest.Check(context.Background(), root, spec.Tests["pass"], slicetest.HostRunner); len(failures) != 0 {
+		t.Fatalf("have failures %v", failures)
+	}
+
+	var have []string
+	for _, f := range slicetest.Check(context.Background(), root, spec.Tests["fail"], slicetest.HostRunner) {
+		have = append(have, f.String())
+	}
+	want := []string{
+		"/etc/: exists but should be missing",
+		`/etc/hello.conf: content does not match "^farewell="`,
+		"/etc/hello.conf/: is a file, want a dir",
+		"/usr/bin/bye: is missing",
+		"/usr/bin/hello: has mode 0755, want 0644",
+		"/usr/bin/hi: links to hello, want other",
+		"exit 3: exit status 3",
+		`echo bye: output "bye" does not match "hi"`,
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have failures:\n%q\nwant:\n%q", have, want)
+	}
+}
@@ -0,0 +1,135 @@
+package slicetest
+
+import (
+	"debug/elf"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Directories the dynamic loader searches by default, as patterns.
+var libraryDirs = []string{
+	"/lib", "/usr/lib", "/lib64", "/usr/lib64",
+	"/lib/*-linux-gnu*", "/usr/lib/*-linux-gnu*",
+}
+
+// MissingLibraries returns the dynamic loader and the shared libraries that
+// the ELF program at path in the root needs but are not in the root. The
+// dependencies of the libraries are not followed, see [LibraryClosure].
+func MissingLibraries(root, prog string) ([]string, error) {
+	_, missing, err := elfDeps(root, prog)
+	return missing, err
+}
+
+// LibraryClosure follows the dependencies of the ELF program at path in the
+// root, and of its libraries, and returns the missing ones mapped to the
+// path in the root of the first file that needs them.
+func LibraryClosure(root, prog string) (map[string]string, error) {
+	missing := make(map[string]string)
+	seen := map[string]bool{prog: true}
+	todo := []string{prog}
+	for len(todo) > 0 {
+		p := todo[0]
+		todo = todo[1:]
+		found, lost, err := elfDeps(root, p)
+		if err != nil {
+			if p == prog {
+				return nil, err
+			}
+			// Not an ELF library, such as a linker script.
+			continue
+		}
+		for _, lib := range lost {
+			if _, ok := missing[lib]; !ok {
+				missing[lib] = p
+			}
+		}
+		for _, lib := range found {
+			if !seen[lib] {
+				seen[lib] = true
+				todo = append(todo, lib)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// isELF tells if the file at the host path starts with the ELF magic.
+func isELF(p string) bool {
+	f, err := elf.Open(p)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// elfDeps returns the dynamic loader and the shared libraries that the ELF
+// file at path in the root needs, split in the paths in the root of those
+// found and the names of those missing.
+func elfDeps(root, prog string) (found, missing []string, err error) {
+	p, err := resolve(root, prog)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := elf.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	for _, ph := range f.Progs {
+		if ph.Type != elf.PT_INTERP {
+			continue
+		}
+		data := make([]byte, ph.Filesz)
+		if _, err := ph.ReadAt(data, 0); err != nil {
+			return nil, nil, err
+		}
+		interp := strings.TrimRight(string(data), "\x00")
+		if _, err := resolve(root, interp); err != nil {
+			missing = append(missing, interp)
+		} else {
+			found = append(found, interp)
+		}
+	}
+
+	libs, err := f.ImportedLibraries()
+	if err != nil {
+		return nil, nil, err
+	}
+	var dirs []string
+	for _, tag := range []elf.DynTag{elf.DT_RUNPATH, elf.DT_RPATH} {
+		paths, _ := f.DynString(tag)
+		for _, list := range paths {
+			for _, dir := range strings.Split(list, ":") {
+				dirs = append(dirs, strings.ReplaceAll(dir, "$ORIGIN", path.Dir(prog)))
+			}
+		}
+	}
+	for _, pattern := range libraryDirs {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, m := range matches {
+			rel, _ := filepath.Rel(root, m)
+			dirs = append(dirs, "/"+filepath.ToSlash(rel))
+		}
+	}
+	for _, lib := range libs {
+		ok := false
+		for _, dir := range dirs {
+			p := path.Join(dir, lib)
+			if _, err := resolve(root, p); err == nil {
+				found = append(found, p)
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			missing = append(missing, lib)
+		}
+	}
+	return found, missing, nil
+}
@@ -0,0 +1,113 @@
+package slicetest_test
+
+import (
+	"debug/elf"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+// elfRoot returns a root with a copy of a dynamically linked program of the
+// host at /usr/bin/prog, along with its loader and libraries.
+func elfRoot(t *testing.T) (root, interp string, libs []string) {
+	host, err := exec.LookPath("ls")
+	if err != nil {
+		t.Skip("ls not found")
+	}
+	f, err := elf.Open(host)
+	if err != nil {
+		t.Skipf("ls is not an ELF program: %v", err)
+	}
+	defer f.Close()
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_INTERP {
+			data := make([]byte, p.Filesz)
+			p.ReadAt(data, 0)
+			interp = strings.TrimRight(string(data), "\x00")
+		}
+	}
+	if libs, err = f.ImportedLibraries(); err != nil || interp == "" || len(libs) == 0 {
+		t.Skip("ls is not dynamically linked")
+	}
+	data, err := os.ReadFile(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "usr/bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "usr/bin/prog"), data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root, interp, libs
+}
+
+func TestMissingLibraries(t *testing.T) {
+	root, interp, libs := elfRoot(t)
+	missing, err := slicetest.MissingLibraries(root, "/usr/bin/prog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]string{interp}, libs...); !reflect.DeepEqual(missing, want) {
+		t.Fatalf("have missing %v, want %v", missing, want)
+	}
+
+	// Install the libraries in /usr/lib/<triplet> with /lib a symlink to
+	// usr/lib, and the loader behind an absolute symlink.
+	libDir := filepath.Join(root, "usr/lib/test-linux-gnu")
+	if err := os.MkdirAll(libDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("usr/lib", filepath.Join(root, "lib")); err != nil {
+		t.Fatal(err)
+	}
+	for _, lib := range libs {
+		if err := os.WriteFile(filepath.Join(libDir, lib), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(libDir, "loader"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, filepath.Dir(interp)), 0755); err != nil && !os.IsExist(err) {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(root, interp))
+	if err := os.Symlink("/usr/lib/test-linux-gnu/loader", filepath.Join(root, interp)); err != nil {
+		t.Fatal(err)
+	}
+	missing, err = slicetest.MissingLibraries(root, "/usr/bin/prog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("have missing %v, want none", missing)
+	}
+}
+
+func TestLibraryClosure(t *testing.T) {
+	root, interp, libs := elfRoot(t)
+	missing, err := slicetest.LibraryClosure(root, "/usr/bin/prog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{interp: "/usr/bin/prog"}
+	for _, lib := range libs {
+		want[lib] = "/usr/bin/prog"
+	}
+	if !reflect.DeepEqual(missing, want) {
+		t.Fatalf("have missing %v, want %v", missing, want)
+	}
+
+	// The host has everything its programs need.
+	host, _ := exec.LookPath("ls")
+	if missing, err := slicetest.LibraryClosure("/", host); err != nil || len(missing) != 0 {
+		t.Fatalf("have missing %v (%v) on the host", missing, err)
+	}
+}
@@ -0,0 +1,9 @@
+package slicetest
+
+import "time"
+
+func FakePollInterval(d time.Duration) (restore func()) {
+	old := pollInterval
+	pollInterval = d
+	return func() { pollInterval = old }
+}
@@ -0,0 +1,66 @@
+package slicetest
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+// Golden returns the content of the golden file of a root, with one line per
+// entry as formatted by [rootfs.Entry.String].
+func Golden(entries []*rootfs.Entry) []byte {
+	var b bytes.Buffer
+	for _, e := range entries {
+		b.WriteString(e.String())
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// CompareGolden returns the lines of the golden file want that are not in
+// have, prefixed with "-", and the lines of have that are not in want,
+// prefixed with "+". The lines are sorted by path.
+func CompareGolden(have, want []byte) []string {
+	haveLines := lineSet(have)
+	wantLines := lineSet(want)
+	var diff []string
+	for l := range wantLines {
+		if !haveLines[l] {
+			diff = append(diff, "-"+l)
+		}
+	}
+	for l := range haveLines {
+		if !wantLines[l] {
+			diff = append(diff, "+"+l)
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		pi, pj := linePath(diff[i][1:]), linePath(diff[j][1:])
+		if pi != pj {
+			return pi < pj
+		}
+		return diff[i] < diff[j]
+	})
+	return diff
+}
+
+func lineSet(data []byte) map[string]bool {
+	lines := make(map[string]bool)
+	for _, l := range strings.Split(string(data), "\n") {
+		if l != "" {
+			lines[l] = true
+		}
+	}
+	return lines
+}
+
+// linePath returns the path of a golden line, the second field.
+func linePath(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return line
+	}
+	return fields[1]
+}
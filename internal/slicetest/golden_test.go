@@ -0,0 +1,41 @@
+package slicetest_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+func TestGolden(t *testing.T) {
+	entries := []*rootfs.Entry{
+		{Path: "usr/bin", Type: "dir", Mode: "0755"},
+		{Path: "usr/bin/hello", Type: "file", Mode: "0755", SHA256: "aaaa"},
+		{Path: "usr/bin/hi", Type: "symlink", Link: "hello"},
+	}
+	golden := slicetest.Golden(entries)
+	want := "d usr/bin 0755\nf usr/bin/hello 0755 aaaa\nl usr/bin/hi hello\n"
+	if string(golden) != want {
+		t.Fatalf("have golden %q, want %q", golden, want)
+	}
+	if diff := slicetest.CompareGolden(golden, []byte(want)); len(diff) != 0 {
+		t.Fatalf("have differences %v with itself", diff)
+	}
+
+	changed := slicetest.Golden([]*rootfs.Entry{
+		{Path: "usr/bin", Type: "dir", Mode: "0755"},
+		{Path: "usr/bin/hello", Type: "file", Mode: "0755", SHA256: "bbbb"},
+		{Path: "usr/bin/new", Type: "file", Mode: "0644", SHA256: "cccc"},
+	})
+	diff := slicetest.CompareGolden(changed, golden)
+	wantDiff := []string{
+		"+f usr/bin/hello 0755 bbbb",
+		"-f usr/bin/hello 0755 aaaa",
+		"-l usr/bin/hi hello",
+		"+f usr/bin/new 0644 cccc",
+	}
+	if !reflect.DeepEqual(diff, wantDiff) {
+		t.Fatalf("have differences %q, want %q", diff, wantDiff)
+	}
+}
@@ -0,0 +1,256 @@
+package slicetest
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A ProbeRef is a built-in probe used by a test, with its arguments.
+type ProbeRef struct {
+	Name string   `yaml:"name"`
+	Args []string `yaml:"args"`
+}
+
+// Probes may be given by name only.
+func (p *ProbeRef) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&p.Name)
+	}
+	type plain ProbeRef
+	return n.Decode((*plain)(p))
+}
+
+func (p *ProbeRef) String() string {
+	if len(p.Args) == 0 {
+		return p.Name
+	}
+	return p.Name + " " + strings.Join(p.Args, " ")
+}
+
+type probe func(ctx context.Context, root string, exec Executor, args []string) ([]string, error)
+
+// The built-in probes, returning the problems found if any:
+//
+//   - elf: the ELF programs and libraries in the given paths, or in the
+//     whole root, have their interpreter and libraries.
+//   - python-import: python3 in the root imports the given modules.
+//   - java-classpath: the Class-Path entries of the jars in the given paths,
+//     or in the whole root, exist.
+//   - ca-certificates: the CA certificates bundle, by default the one
+//     ca-certificates generates, holds valid certificates.
+var probes = map[string]probe{
+	"elf":             probeELF,
+	"python-import":   probePythonImport,
+	"java-classpath":  probeJavaClasspath,
+	"ca-certificates": probeCACertificates,
+}
+
+// Probe runs the built-in probes of the test against the root. Probes that
+// run programs do it inside the root with exec.
+func Probe(ctx context.Context, root string, t *Test, exec Executor) []*Failure {
+	var failures []*Failure
+	for _, ref := range t.Probes {
+		problems, err := probes[ref.Name](ctx, root, exec, ref.Args)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		for _, p := range problems {
+			failures = append(failures, &Failure{Subject: "probe " + ref.String(), Message: p})
+		}
+	}
+	return failures
+}
+
+// findFiles returns the paths in the root of the regular files under the
+// given paths, or in the whole root, for which match is true.
+func findFiles(root string, paths []string, match func(hostPath string) bool) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+	var found []string
+	for _, p := range paths {
+		start, err := resolve(root, p)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.WalkDir(start, func(hp string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() && match(hp) {
+				rel, _ := filepath.Rel(root, hp)
+				found = append(found, "/"+filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+func probeELF(ctx context.Context, root string, exec Executor, args []string) ([]string, error) {
+	files, err := findFiles(root, args, isELF)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, f := range files {
+		missing, err := LibraryClosure(root, f)
+		if err != nil {
+			return nil, err
+		}
+		var libs []string
+		for lib := range missing {
+			libs = append(libs, lib)
+		}
+		sort.Strings(libs)
+		for _, lib := range libs {
+			if by := missing[lib]; by != f {
+				problems = append(problems, fmt.Sprintf("%s needs %s, through %s", f, lib, by))
+			} else {
+				problems = append(problems, fmt.Sprintf("%s needs %s", f, lib))
+			}
+		}
+	}
+	return problems, nil
+}
+
+func probePythonImport(ctx context.Context, root string, exec Executor, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no modules to import")
+	}
+	var problems []string
+	for _, module := range args {
+		if _, err := exec(ctx, root, []string{"python3", "-c", "import " + module}); err != nil {
+			problems = append(problems, fmt.Sprintf("cannot import %s: %s", module, err))
+		}
+	}
+	return problems, nil
+}
+
+func probeJavaClasspath(ctx context.Context, root string, exec Executor, args []string) ([]string, error) {
+	jars, err := findFiles(root, args, func(hp string) bool {
+		return strings.HasSuffix(hp, ".jar")
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(jars) == 0 {
+		return []string{"no jars found"}, nil
+	}
+	var problems []string
+	for _, jar := range jars {
+		hp, err := resolve(root, jar)
+		if err != nil {
+			return nil, err
+		}
+		classPath, err := jarClassPath(hp)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", jar, err))
+			continue
+		}
+		for _, entry := range classPath {
+			// Entries are URLs relative to the jar.
+			p := entry
+			if !path.IsAbs(p) {
+				p = path.Join(path.Dir(jar), entry)
+			}
+			if _, err := resolve(root, p); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: Class-Path entry %s is missing", jar, entry))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// jarClassPath returns the Class-Path entries of the jar manifest.
+func jarClassPath(hostPath string) ([]string, error) {
+	z, err := zip.OpenReader(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	f, err := z.Open("META-INF/MANIFEST.MF")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Manifest lines are wrapped at 72 bytes, with continuations starting
+	// with a space.
+	var value string
+	inClassPath := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.HasPrefix(line, " "):
+			if inClassPath {
+				value += line[1:]
+			}
+		case strings.HasPrefix(line, "Class-Path:"):
+			inClassPath = true
+			value = strings.TrimPrefix(line, "Class-Path:")
+		default:
+			inClassPath = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return strings.Fields(value), nil
+}
+
+// The bundle ca-certificates generates in /etc/ssl/certs.
+const caBundle = "/etc/ssl/certs/ca-certificates.crt"
+
+func probeCACertificates(ctx context.Context, root string, exec Executor, args []string) ([]string, error) {
+	bundle := caBundle
+	if len(args) > 0 {
+		bundle = args[0]
+	}
+	hp, err := resolve(root, bundle)
+	if err != nil {
+		return []string{fmt.Sprintf("%s is missing", bundle)}, nil
+	}
+	data, err := os.ReadFile(hp)
+	if err != nil {
+		return nil, err
+	}
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return []string{fmt.Sprintf("%s has an invalid certificate: %s", bundle, err)}, nil
+		}
+		count++
+	}
+	if count == 0 {
+		return []string{fmt.Sprintf("%s has no certificates", bundle)}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,183 @@
+package slicetest_test
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+func testCertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test CA"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func testJar(t *testing.T, manifest string) []byte {
+	var buf strings.Builder
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(manifest))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return []byte(buf.String())
+}
+
+var probeTests = []struct {
+	summary string
+	probes  string
+	files   func(t *testing.T) map[string][]byte
+	// Modules python3 fails to import.
+	broken   []string
+	calls    [][]string
+	failures []string
+}{{
+	summary: "CA certificates",
+	probes:  "[ca-certificates]",
+	files: func(t *testing.T) map[string][]byte {
+		return map[string][]byte{"etc/ssl/certs/ca-certificates.crt": testCertificate(t)}
+	},
+}, {
+	summary: "Missing CA certificates",
+	probes:  "[ca-certificates]",
+	failures: []string{
+		"probe ca-certificates: /etc/ssl/certs/ca-certificates.crt is missing",
+	},
+}, {
+	summary: "Empty CA certificates bundle in another path",
+	probes:  "[{name: ca-certificates, args: [/etc/pki/tls/cert.pem]}]",
+	files: func(t *testing.T) map[string][]byte {
+		return map[string][]byte{"etc/pki/tls/cert.pem": nil}
+	},
+	failures: []string{
+		"probe ca-certificates /etc/pki/tls/cert.pem: /etc/pki/tls/cert.pem has no certificates",
+	},
+}, {
+	summary: "Python imports",
+	probes:  "[{name: python-import, args: [ssl, json]}]",
+	broken:  []string{"ssl"},
+	calls:   [][]string{{"python3", "-c", "import ssl"}, {"python3", "-c", "import json"}},
+	failures: []string{
+		"probe python-import ssl json: cannot import ssl: No module named ssl",
+	},
+}, {
+	summary: "Java class path",
+	probes:  "[java-classpath]",
+	files: func(t *testing.T) map[string][]byte {
+		return map[string][]byte{
+			"usr/share/java/app.jar": testJar(t, "Manifest-Version: 1.0\r\n"+
+				"Class-Path: lib/present.jar lib/miss\r\n ing.jar /usr/share/java/a\r\n bs.jar\r\n"+
+				"Main-Class: App\r\n"),
+			"usr/share/java/lib/present.jar": testJar(t, "Manifest-Version: 1.0\r\n"),
+		}
+	},
+	failures: []string{
+		"probe java-classpath: /usr/share/java/app.jar: Class-Path entry lib/missing.jar is missing",
+		"probe java-classpath: /usr/share/java/app.jar: Class-Path entry /usr/share/java/abs.jar is missing",
+	},
+}, {
+	summary: "No jars",
+	probes:  "[{name: java-classpath, args: [/usr]}]",
+	files: func(t *testing.T) map[string][]byte {
+		return map[string][]byte{"usr/share/doc/README": []byte("hello")}
+	},
+	failures: []string{
+		"probe java-classpath /usr: no jars found",
+	},
+}}
+
+func TestProbe(t *testing.T) {
+	for _, test := range probeTests {
+		t.Logf("Summary: %s", test.summary)
+		spec, err := slicetest.Parse([]byte("package: hello\ntests:\n  probe:\n    slices: [hello_bins]\n    probes: " + test.probes + "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := t.TempDir()
+		if test.files != nil {
+			for p, data := range test.files(t) {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(root, p)), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(root, p), data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		var calls [][]string
+		exec := func(ctx context.Context, root string, args []string) ([]byte, error) {
+			calls = append(calls, args)
+			for _, m := range test.broken {
+				if args[2] == "import "+m {
+					return nil, fmt.Errorf("No module named %s", m)
+				}
+			}
+			return nil, nil
+		}
+		var failures []string
+		for _, f := range slicetest.Probe(context.Background(), root, spec.Tests["probe"], exec) {
+			failures = append(failures, f.String())
+		}
+		if !reflect.DeepEqual(calls, test.calls) {
+			t.Errorf("have calls %q, want %q", calls, test.calls)
+		}
+		if !reflect.DeepEqual(failures, test.failures) {
+			t.Errorf("have failures %q, want %q", failures, test.failures)
+		}
+	}
+}
+
+func TestProbeELF(t *testing.T) {
+	root, interp, libs := elfRoot(t)
+	spec, err := slicetest.Parse([]byte("package: hello\ntests:\n  probe:\n    slices: [hello_bins]\n    probes: [elf]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	failures := slicetest.Probe(context.Background(), root, spec.Tests["probe"], nil)
+	if len(failures) != len(libs)+1 {
+		t.Fatalf("have failures %v, want %s and %q missing", failures, interp, libs)
+	}
+	for _, f := range failures {
+		if f.Subject != "probe elf" || !strings.HasPrefix(f.Message, "/usr/bin/prog needs ") {
+			t.Fatalf("have failure %v", f)
+		}
+	}
+}
+
+func TestParseUnknownProbe(t *testing.T) {
+	_, err := slicetest.Parse([]byte("package: hello\ntests:\n  probe:\n    slices: [hello_bins]\n    probes: [nope]\n"))
+	if err == nil || err.Error() != `test probe: unknown probe "nope"` {
+		t.Fatalf("have error %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package slicetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/rootfs"
+)
+
+// A Machine is a root booted with systemd.
+type Machine interface {
+	// Exec runs a command in the machine and returns its standard output.
+	Exec(ctx context.Context, args ...string) ([]byte, error)
+	// Stop the machine and release its resources.
+	Stop() error
+}
+
+// A Booter boots the root with its /sbin/init, which must be systemd.
+type Booter func(ctx context.Context, root string) (Machine, error)
+
+// How long to wait between checks of the state of a unit.
+var pollInterval = time.Second
+
+// Services boots the root and checks that the units of the test reach the
+// active state within the timeout.
+func Services(ctx context.Context, root string, t *Test, boot Booter, timeout time.Duration) []*Failure {
+	if len(t.Services) == 0 {
+		return nil
+	}
+	m, err := boot(ctx, root)
+	if err != nil {
+		return []*Failure{{Subject: "boot", Message: err.Error()}}
+	}
+	defer m.Stop()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var failures []*Failure
+	for _, unit := range t.Services {
+		if msg := waitActive(ctx, m, unit); msg != "" {
+			failures = append(failures, &Failure{Subject: unit, Message: msg})
+		}
+	}
+	return failures
+}
+
+// waitActive returns why the unit did not become active, or "" if it did.
+func waitActive(ctx context.Context, m Machine, unit string) string {
+	state := "unknown"
+	for {
+		// "systemctl is-active" exits with non-zero status unless
+		// active, but still prints the state.
+		out, _ := m.Exec(ctx, "systemctl", "is-active", unit)
+		if s := strings.TrimSpace(string(out)); s != "" {
+			state = s
+		}
+		switch state {
+		case "active":
+			return ""
+		case "failed":
+			return "failed to start" + unitStatus(m, unit)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Sprintf("still %s after the timeout", state) + unitStatus(m, unit)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// unitStatus returns the status of the unit to show with a failure.
+func unitStatus(m Machine, unit string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, _ := m.Exec(ctx, "systemctl", "status", "--no-pager", "--lines=10", unit)
+	if s := strings.TrimSpace(string(out)); s != "" {
+		return "\n" + s
+	}
+	return ""
+}
+
+// container is a machine running in a podman or docker container.
+type container struct {
+	engine string
+	id     string
+	image  string // Image to remove on stop, if any.
+}
+
+func (c *container) Exec(ctx context.Context, args ...string) ([]byte, error) {
+	return output(exec.CommandContext(ctx, c.engine, append([]string{"exec", c.id}, args...)...))
+}
+
+func (c *container) Stop() error {
+	_, err := output(exec.Command(c.engine, "rm", "--force", c.id))
+	if c.image != "" {
+		if _, e := output(exec.Command(c.engine, "rmi", "--force", c.image)); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// PodmanBooter boots the root directory in a podman container, which sets
+// up what systemd needs to run in it.
+func PodmanBooter(ctx context.Context, root string) (Machine, error) {
+	out, err := output(exec.CommandContext(ctx, "podman", "run", "--detach",
+		"--systemd=always", "--rootfs", root, "/sbin/init"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot boot root with podman: %w", err)
+	}
+	return &container{engine: "podman", id: strings.TrimSpace(string(out))}, nil
+}
+
+// DockerBooter imports the root as an image and boots it in a privileged
+// docker container, with the mounts systemd needs.
+func DockerBooter(ctx context.Context, root string) (Machine, error) {
+	var tarball bytes.Buffer
+	if err := rootfs.WriteTar(&tarball, root, nil); err != nil {
+		return nil, err
+	}
+	image := fmt.Sprintf("sdf-test:%d-%d", os.Getpid(), time.Now().UnixNano())
+	cmd := exec.CommandContext(ctx, "docker", "import", "-", image)
+	cmd.Stdin = &tarball
+	if _, err := output(cmd); err != nil {
+		return nil, fmt.Errorf("cannot import root into docker: %w", err)
+	}
+	out, err := output(exec.CommandContext(ctx, "docker", "run", "--detach", "--privileged",
+		"--cgroupns=host", "--volume=/sys/fs/cgroup:/sys/fs/cgroup:rw",
+		"--tmpfs=/run", "--tmpfs=/run/lock", "--tmpfs=/tmp",
+		image, "/sbin/init"))
+	if err != nil {
+		exec.Command("docker", "rmi", "--force", image).Run()
+		return nil, fmt.Errorf("cannot boot root with docker: %w", err)
+	}
+	return &container{engine: "docker", id: strings.TrimSpace(string(out)), image: image}, nil
+}
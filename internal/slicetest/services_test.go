@@ -0,0 +1,82 @@
+package slicetest_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+// fakeMachine reports the states of the units in order, one per check, and
+// then stays in the last one.
+type fakeMachine struct {
+	states  map[string][]string
+	stopped bool
+}
+
+func (m *fakeMachine) Exec(ctx context.Context, args ...string) ([]byte, error) {
+	unit := args[len(args)-1]
+	switch args[1] {
+	case "is-active":
+		states := m.states[unit]
+		state := states[0]
+		if len(states) > 1 {
+			m.states[unit] = states[1:]
+		}
+		if state != "active" {
+			return []byte(state + "\n"), fmt.Errorf("exit status 3")
+		}
+		return []byte(state + "\n"), nil
+	case "status":
+		return []byte("status of " + unit), nil
+	}
+	return nil, fmt.Errorf("unexpected command %v", args)
+}
+
+func (m *fakeMachine) Stop() error {
+	m.stopped = true
+	return nil
+}
+
+func TestServices(t *testing.T) {
+	defer slicetest.FakePollInterval(time.Millisecond)()
+
+	m := &fakeMachine{states: map[string][]string{
+		"slow.service":   {"inactive", "activating", "active"},
+		"broken.service": {"activating", "failed"},
+		"stuck.service":  {"activating"},
+	}}
+	var booted string
+	boot := func(ctx context.Context, root string) (slicetest.Machine, error) {
+		booted = root
+		return m, nil
+	}
+	test := &slicetest.Test{Services: []string{"slow.service", "broken.service", "stuck.service"}}
+	failures := slicetest.Services(context.Background(), "/root", test, boot, 50*time.Millisecond)
+	if booted != "/root" || !m.stopped {
+		t.Fatalf("have booted %q and stopped %v", booted, m.stopped)
+	}
+	var have []string
+	for _, f := range failures {
+		have = append(have, f.String())
+	}
+	want := []string{
+		"broken.service: failed to start\nstatus of broken.service",
+		"stuck.service: still activating after the timeout\nstatus of stuck.service",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("have failures %q, want %q", have, want)
+	}
+
+	failing := func(ctx context.Context, root string) (slicetest.Machine, error) {
+		return nil, fmt.Errorf("no init")
+	}
+	failures = slicetest.Services(context.Background(), "/root", test, failing, time.Second)
+	if len(failures) != 1 || !strings.Contains(failures[0].String(), "no init") {
+		t.Fatalf("have failures %v, want a boot failure", failures)
+	}
+}
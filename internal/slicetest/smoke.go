@@ -0,0 +1,162 @@
+package slicetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A SmokeCommand is a program of the root, run inside of it to check that it
+// starts. It must exit with status zero.
+type SmokeCommand struct {
+	Args []string `yaml:"args"`
+	// Regular expression the standard output, trimmed of surrounding space,
+	// must match.
+	Stdout string `yaml:"stdout"`
+
+	stdout *regexp.Regexp
+}
+
+// Smoke commands may be given as a plain string, split on spaces, or as a
+// list of arguments.
+func (c *SmokeCommand) UnmarshalYAML(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		var s string
+		if err := n.Decode(&s); err != nil {
+			return err
+		}
+		c.Args = strings.Fields(s)
+		return nil
+	case yaml.SequenceNode:
+		return n.Decode(&c.Args)
+	}
+	type plain SmokeCommand
+	return n.Decode((*plain)(c))
+}
+
+func (c *SmokeCommand) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Smoke runs the smoke commands of the test inside the root, with exec.
+func Smoke(ctx context.Context, root string, t *Test, exec Executor) []*Failure {
+	var failures []*Failure
+	for _, c := range t.Smoke {
+		out, err := exec(ctx, root, c.Args)
+		if err != nil {
+			failures = append(failures, &Failure{Subject: c.String(), Message: err.Error()})
+			continue
+		}
+		out = bytes.TrimSpace(out)
+		if c.stdout != nil && !c.stdout.Match(out) {
+			failures = append(failures, &Failure{
+				Subject: c.String(),
+				Message: fmt.Sprintf("output %q does not match %q", out, c.Stdout),
+			})
+		}
+	}
+	return failures
+}
+
+// An Executor runs the program of a smoke command inside the root and
+// returns its standard output.
+type Executor func(ctx context.Context, root string, args []string) ([]byte, error)
+
+// The PATH smoke commands run with.
+const smokePath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// ChrootExecutor runs the program with chroot, which needs privileges.
+func ChrootExecutor(ctx context.Context, root string, args []string) ([]byte, error) {
+	return execute(ctx, root, []string{"chroot", root}, args)
+}
+
+// UnshareExecutor runs the program with chroot in new user and PID
+// namespaces, as root of the user namespace, which needs no privileges.
+func UnshareExecutor(ctx context.Context, root string, args []string) ([]byte, error) {
+	return execute(ctx, root, []string{"unshare", "--map-root-user", "--fork", "--pid", "chroot", root}, args)
+}
+
+func execute(ctx context.Context, root string, wrapper, args []string) ([]byte, error) {
+	argv := append(wrapper, args...)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = []string{"PATH=" + smokePath, "HOME=/", "LANG=C.UTF-8"}
+	out, err := output(cmd)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 127 {
+		// The program did not start. Tell why if it is because of missing
+		// libraries, rather than "No such file or directory".
+		if prog := lookPath(root, args[0]); prog != "" {
+			if missing, e := MissingLibraries(root, prog); e == nil && len(missing) > 0 {
+				return out, fmt.Errorf("cannot start %s: missing %s", args[0], strings.Join(missing, ", "))
+			}
+		}
+	}
+	return out, err
+}
+
+// lookPath returns the path in the root of the program, as found in the
+// PATH of smoke commands, or "" if there is none.
+func lookPath(root, prog string) string {
+	if strings.Contains(prog, "/") {
+		return prog
+	}
+	for _, dir := range filepath.SplitList(smokePath) {
+		p := path.Join(dir, prog)
+		if _, err := resolve(root, p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// resolve returns the host path of the path in the root, following symlinks
+// as if root was "/".
+func resolve(root, p string) (string, error) {
+	const maxLinks = 40
+	links := 0
+	resolved := "/"
+	rest := strings.Split(strings.Trim(path.Clean("/"+p), "/"), "/")
+	for len(rest) > 0 {
+		name := rest[0]
+		rest = rest[1:]
+		if name == "" || name == "." {
+			continue
+		}
+		if name == ".." {
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, name)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > maxLinks {
+			return "", fmt.Errorf("too many levels of symbolic links: %s", p)
+		}
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		rest = append(strings.Split(strings.Trim(target, "/"), "/"), rest...)
+	}
+	return filepath.Join(root, resolved), nil
+}
@@ -0,0 +1,52 @@
+package slicetest_test
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+func TestSmoke(t *testing.T) {
+	spec, err := slicetest.Parse([]byte(`
+package: hello
+tests:
+  smoke:
+    slices: [hello_bins]
+    smoke:
+      - hello --version
+      - [hello, "two words"]
+      - args: [hello, --help]
+        stdout: ^usage
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls [][]string
+	exec := func(ctx context.Context, root string, args []string) ([]byte, error) {
+		calls = append(calls, args)
+		return []byte("hello 1.0\n"), nil
+	}
+	failures := slicetest.Smoke(context.Background(), "/root", spec.Tests["smoke"], exec)
+	want := [][]string{{"hello", "--version"}, {"hello", "two words"}, {"hello", "--help"}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("have calls %q, want %q", calls, want)
+	}
+	if len(failures) != 1 || failures[0].String() != `hello --help: output "hello 1.0" does not match "^usage"` {
+		t.Fatalf("have failures %v", failures)
+	}
+}
+
+func TestUnshareExecutor(t *testing.T) {
+	if err := exec.Command("unshare", "--map-root-user", "true").Run(); err != nil {
+		t.Skip("cannot create user namespaces")
+	}
+	root, interp, _ := elfRoot(t)
+	_, err := slicetest.UnshareExecutor(context.Background(), root, []string{"prog"})
+	if err == nil || !strings.HasPrefix(err.Error(), "cannot start prog: missing "+interp) {
+		t.Fatalf("have error %v, want missing %s", err, interp)
+	}
+}
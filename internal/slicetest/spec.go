@@ -0,0 +1,206 @@
+// Package slicetest runs declarative functional tests against roots that
+// chisel installed slices into.
+//
+// Tests are defined per package, in YAML files usually kept at
+// tests/<package>.yaml in the release:
+//
+//	package: hello
+//	tests:
+//	  bins:
+//	    slices: [hello_bins]
+//	    paths:
+//	      /usr/bin/hello: {type: file, mode: "0755"}
+//	      /usr/bin/hi: {link: hello}
+//	      /etc/hello.conf: {content: "^greeting="}
+//	      /usr/share/doc/: {missing: true}
+//	    commands:
+//	      - test -x usr/bin/hello
+//	      - run: cat etc/hello.conf
+//	        stdout: greeting
+//	    smoke:
+//	      - hello --version
+//	    services:
+//	      - hello.service
+//	    probes:
+//	      - elf
+//	      - name: python-import
+//	        args: [ssl, json]
+//
+// Commands run on the host, in the root directory. Smoke commands run inside
+// of the root, to check that the sliced programs start. Services are checked
+// by booting the root with systemd in a container.
+package slicetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Spec struct {
+	Package string           `yaml:"package"`
+	Tests   map[string]*Test `yaml:"tests"`
+}
+
+type Test struct {
+	// Name is set from the key of the test in the spec.
+	Name     string                `yaml:"-"`
+	Slices   []string              `yaml:"slices"`
+	Paths    map[string]*PathCheck `yaml:"paths"`
+	Commands []*Command            `yaml:"commands"`
+	Smoke    []*SmokeCommand       `yaml:"smoke"`
+	// Systemd units that must become active when the root boots.
+	Services []string `yaml:"services"`
+	// Built-in probes to run, see [Probe].
+	Probes []*ProbeRef `yaml:"probes"`
+}
+
+// A PathCheck is the expected state of a path in the root. Directories end
+// with "/".
+type PathCheck struct {
+	// One of "file", "dir" or "symlink", if set.
+	Type string `yaml:"type"`
+	// Permissions in octal, e.g. "0755".
+	Mode string `yaml:"mode"`
+	// Target of the symlink.
+	Link string `yaml:"link"`
+	// Regular expression the file content must match.
+	Content string `yaml:"content"`
+	// The path must not exist.
+	Missing bool `yaml:"missing"`
+
+	content *regexp.Regexp
+	mode    os.FileMode
+}
+
+// A Command is run in the root and must exit with status zero.
+type Command struct {
+	Run string `yaml:"run"`
+	// Regular expression the standard output, trimmed of surrounding space,
+	// must match.
+	Stdout string `yaml:"stdout"`
+
+	stdout *regexp.Regexp
+}
+
+// Commands may be given as plain strings.
+func (c *Command) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		return n.Decode(&c.Run)
+	}
+	type plain Command
+	return n.Decode((*plain)(c))
+}
+
+// Parse a test spec file.
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	if spec.Package == "" {
+		return nil, fmt.Errorf("no 'package' specified")
+	}
+	for name, t := range spec.Tests {
+		t.Name = name
+		if len(t.Slices) == 0 {
+			return nil, fmt.Errorf("test %s has no 'slices'", name)
+		}
+		for p, c := range t.Paths {
+			if err := c.compile(); err != nil {
+				return nil, fmt.Errorf("test %s: path %s: %w", name, p, err)
+			}
+		}
+		for i, c := range t.Commands {
+			if c.Run == "" {
+				return nil, fmt.Errorf("test %s: command %d has no 'run'", name, i+1)
+			}
+			if c.Stdout != "" {
+				re, err := regexp.Compile(c.Stdout)
+				if err != nil {
+					return nil, fmt.Errorf("test %s: command %d: invalid stdout: %w", name, i+1, err)
+				}
+				c.stdout = re
+			}
+		}
+		for i, c := range t.Smoke {
+			if len(c.Args) == 0 {
+				return nil, fmt.Errorf("test %s: smoke command %d has no 'args'", name, i+1)
+			}
+			if c.Stdout != "" {
+				re, err := regexp.Compile(c.Stdout)
+				if err != nil {
+					return nil, fmt.Errorf("test %s: smoke command %d: invalid stdout: %w", name, i+1, err)
+				}
+				c.stdout = re
+			}
+		}
+		for _, p := range t.Probes {
+			if _, ok := probes[p.Name]; !ok {
+				return nil, fmt.Errorf("test %s: unknown probe %q", name, p.Name)
+			}
+		}
+	}
+	return &spec, nil
+}
+
+func (c *PathCheck) compile() error {
+	switch c.Type {
+	case "", "file", "dir", "symlink":
+	default:
+		return fmt.Errorf("invalid type %q", c.Type)
+	}
+	if c.Mode != "" {
+		mode, err := strconv.ParseUint(c.Mode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid mode %q", c.Mode)
+		}
+		c.mode = os.FileMode(mode)
+	}
+	if c.Content != "" {
+		re, err := regexp.Compile(c.Content)
+		if err != nil {
+			return fmt.Errorf("invalid content: %w", err)
+		}
+		c.content = re
+	}
+	return nil
+}
+
+// ReadFile reads the test spec file at path.
+func ReadFile(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse test spec %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// SpecFiles returns the test spec files of a release, sorted by path.
+func SpecFiles(release string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(release, "tests", "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// SortedTests returns the tests of the spec sorted by name.
+func (s *Spec) SortedTests() []*Test {
+	tests := make([]*Test, 0, len(s.Tests))
+	for _, t := range s.Tests {
+		tests = append(tests, t)
+	}
+	sort.Slice(tests, func(i, j int) bool { return tests[i].Name < tests[j].Name })
+	return tests
+}
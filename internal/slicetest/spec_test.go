@@ -0,0 +1,80 @@
+package slicetest_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/slicetest"
+)
+
+const sampleSpec = `
+package: hello
+tests:
+  bins:
+    slices: [hello_bins]
+    paths:
+      /usr/bin/hello: {type: file, mode: "0755"}
+      /usr/bin/hi: {link: hello}
+    commands:
+      - test -x usr/bin/hello
+      - run: cat etc/hello.conf
+        stdout: greeting
+  copyright:
+    slices: [hello_copyright]
+`
+
+var parseTests = []struct {
+	summary string
+	data    string
+	err     string
+}{{
+	summary: "No package",
+	data:    "tests: {}",
+	err:     "no 'package' specified",
+}, {
+	summary: "No slices",
+	data:    "package: hello\ntests:\n  bins: {}",
+	err:     "test bins has no 'slices'",
+}, {
+	summary: "Invalid mode",
+	data:    "package: hello\ntests:\n  bins:\n    slices: [hello_bins]\n    paths:\n      /usr/bin/hello: {mode: rwx}",
+	err:     `test bins: path /usr/bin/hello: invalid mode "rwx"`,
+}, {
+	summary: "Invalid content",
+	data:    "package: hello\ntests:\n  bins:\n    slices: [hello_bins]\n    paths:\n      /etc/hello.conf: {content: \"(\"}",
+	err:     "test bins: path /etc/hello.conf: invalid content",
+}, {
+	summary: "Command without run",
+	data:    "package: hello\ntests:\n  bins:\n    slices: [hello_bins]\n    commands:\n      - stdout: x",
+	err:     "test bins: command 1 has no 'run'",
+}}
+
+func TestParse(t *testing.T) {
+	spec, err := slicetest.Parse([]byte(sampleSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, test := range spec.SortedTests() {
+		names = append(names, test.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"bins", "copyright"}) {
+		t.Fatalf("have tests %v", names)
+	}
+	bins := spec.Tests["bins"]
+	if len(bins.Commands) != 2 || bins.Commands[0].Run != "test -x usr/bin/hello" || bins.Commands[1].Stdout != "greeting" {
+		t.Fatalf("bad commands: %+v, %+v", bins.Commands[0], bins.Commands[1])
+	}
+	if bins.Paths["/usr/bin/hi"].Link != "hello" {
+		t.Fatalf("bad paths: %+v", bins.Paths)
+	}
+
+	for _, tc := range parseTests {
+		t.Logf("Summary: %s", tc.summary)
+		_, err := slicetest.Parse([]byte(tc.data))
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Fatalf("have error %v, want %q", err, tc.err)
+		}
+	}
+}
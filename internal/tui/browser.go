@@ -0,0 +1,423 @@
+// Package tui implements an interactive terminal browser for chisel
+// releases, and the line editor of interactive prompts.
+//
+// The [Browser] and the [LineEditor] hold the state of the interface and
+// are driven by key presses, independently of any terminal, while
+// [Terminal] draws them and reads the keys.
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+)
+
+// An Action is something the browser asks its caller to do in response to a
+// key press.
+type Action int
+
+const (
+	None Action = iota
+	Quit
+	// Cut the slice returned by [Browser.Selected].
+	Cut
+	// Read the release again and pass it to [Browser.SetRelease].
+	Reload
+	// Run the line returned by [LineEditor.Accept].
+	Submit
+	// The line was abandoned, a new one is being edited.
+	Cancel
+)
+
+type paneKind int
+
+const (
+	packagesPane paneKind = iota
+	slicesPane
+	contentsPane
+	depsPane
+	outputPane
+)
+
+// A pane is one level of the navigation, the browser shows the last one.
+type pane struct {
+	kind   paneKind
+	pkg    string
+	slice  string
+	title  string   // Of output panes.
+	text   []string // Of output panes.
+	cursor int
+	offset int
+	filter string
+}
+
+// An entry is a line of a pane, with the package or slice it refers to, if
+// any.
+type entry struct {
+	label string
+	name  string
+}
+
+type Browser struct {
+	release   *chisel.Release
+	panes     []*pane
+	status    string
+	filtering bool
+	height    int // Of the list in the last rendering.
+}
+
+// NewBrowser returns a browser showing the packages of the release.
+func NewBrowser(r *chisel.Release) *Browser {
+	return &Browser{
+		release: r,
+		panes:   []*pane{{kind: packagesPane}},
+		height:  20,
+	}
+}
+
+// SetRelease replaces the browsed release, keeping the navigation where
+// possible.
+func (b *Browser) SetRelease(r *chisel.Release) {
+	b.release = r
+	for i, p := range b.panes {
+		if (p.kind == contentsPane || p.kind == depsPane) && r.Slice(p.slice) == nil {
+			b.panes = b.panes[:i]
+			break
+		}
+	}
+	if len(b.panes) == 0 {
+		b.panes = []*pane{{kind: packagesPane}}
+	}
+	for _, p := range b.panes {
+		p.cursor = min(p.cursor, max(len(b.entries(p))-1, 0))
+	}
+}
+
+// SetStatus shows the message at the bottom of the screen until the next key
+// press.
+func (b *Browser) SetStatus(format string, args ...any) {
+	b.status = fmt.Sprintf(format, args...)
+}
+
+// ShowOutput opens a pane with the text, like the output of a failed command.
+func (b *Browser) ShowOutput(title, text string) {
+	b.panes = append(b.panes, &pane{
+		kind:  outputPane,
+		title: title,
+		text:  strings.Split(strings.TrimRight(text, "\n"), "\n"),
+	})
+}
+
+// Selected returns the slice under the cursor, or the slice shown, or an
+// empty string if there is none.
+func (b *Browser) Selected() string {
+	p := b.pane()
+	switch p.kind {
+	case slicesPane:
+		if e := b.current(p); e != nil {
+			return e.name
+		}
+	case contentsPane, depsPane:
+		return p.slice
+	}
+	return ""
+}
+
+func (b *Browser) pane() *pane {
+	return b.panes[len(b.panes)-1]
+}
+
+// current returns the entry under the cursor, or nil if the pane is empty.
+func (b *Browser) current(p *pane) *entry {
+	entries := b.entries(p)
+	if p.cursor < len(entries) {
+		return &entries[p.cursor]
+	}
+	return nil
+}
+
+// Handle updates the browser for the key press and returns what the caller
+// should do about it.
+func (b *Browser) Handle(k Key) Action {
+	b.status = ""
+	p := b.pane()
+	if b.filtering {
+		switch k {
+		case KeyEnter:
+			b.filtering = false
+		case KeyEscape:
+			b.filtering = false
+			p.filter = ""
+		case KeyBackspace:
+			if p.filter != "" {
+				_, n := utf8.DecodeLastRuneInString(p.filter)
+				p.filter = p.filter[:len(p.filter)-n]
+			}
+		case KeyCtrlC:
+			return Quit
+		default:
+			if utf8.RuneCountInString(string(k)) == 1 {
+				p.filter += string(k)
+			}
+		}
+		p.cursor, p.offset = 0, 0
+		return None
+	}
+
+	n := len(b.entries(p))
+	switch k {
+	case "q", KeyCtrlC:
+		return Quit
+	case KeyUp, "k":
+		p.cursor = max(p.cursor-1, 0)
+	case KeyDown, "j":
+		p.cursor = max(min(p.cursor+1, n-1), 0)
+	case KeyPageUp:
+		p.cursor = max(p.cursor-b.height, 0)
+	case KeyPageDown:
+		p.cursor = max(min(p.cursor+b.height, n-1), 0)
+	case KeyHome, "g":
+		p.cursor = 0
+	case KeyEnd, "G":
+		p.cursor = max(n-1, 0)
+	case KeyEnter, KeyRight, "l":
+		b.open(p)
+	case KeyEscape, KeyLeft, KeyBackspace, "h":
+		if p.filter != "" && k == KeyEscape {
+			p.filter = ""
+			p.cursor, p.offset = 0, 0
+		} else if len(b.panes) > 1 {
+			b.panes = b.panes[:len(b.panes)-1]
+		}
+	case KeyTab:
+		switch p.kind {
+		case contentsPane:
+			b.replace(&pane{kind: depsPane, pkg: p.pkg, slice: p.slice})
+		case depsPane:
+			b.replace(&pane{kind: contentsPane, pkg: p.pkg, slice: p.slice})
+		}
+	case "/":
+		if p.kind != outputPane {
+			b.filtering = true
+		}
+	case "c":
+		if b.Selected() == "" {
+			b.status = "Select a slice to cut"
+			return None
+		}
+		return Cut
+	case "r":
+		return Reload
+	}
+	return None
+}
+
+func (b *Browser) open(p *pane) {
+	e := b.current(p)
+	if e == nil || e.name == "" {
+		return
+	}
+	switch p.kind {
+	case packagesPane:
+		b.panes = append(b.panes, &pane{kind: slicesPane, pkg: e.name})
+	case slicesPane, depsPane:
+		s := b.release.Slice(e.name)
+		if s == nil {
+			b.status = fmt.Sprintf("Slice %s is not defined", e.name)
+			return
+		}
+		if p.kind == depsPane && e.name == p.slice {
+			return
+		}
+		b.panes = append(b.panes, &pane{kind: contentsPane, pkg: s.Package, slice: s.Name})
+	}
+}
+
+func (b *Browser) replace(p *pane) {
+	b.panes[len(b.panes)-1] = p
+}
+
+// entries returns the lines of the pane, filtered.
+func (b *Browser) entries(p *pane) []entry {
+	entries := b.allEntries(p)
+	if p.filter == "" {
+		return entries
+	}
+	var filtered []entry
+	for _, e := range entries {
+		if strings.Contains(e.label, p.filter) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+func (b *Browser) allEntries(p *pane) []entry {
+	var entries []entry
+	switch p.kind {
+	case packagesPane:
+		count := make(map[string]int)
+		for _, s := range b.release.Slices {
+			count[s.Package]++
+		}
+		var pkgs []string
+		for pkg := range count {
+			pkgs = append(pkgs, pkg)
+		}
+		sort.Strings(pkgs)
+		for _, pkg := range pkgs {
+			entries = append(entries, entry{label: fmt.Sprintf("%s (%d)", pkg, count[pkg]), name: pkg})
+		}
+	case slicesPane:
+		for _, s := range b.release.Slices {
+			if s.Package == p.pkg {
+				entries = append(entries, entry{label: s.Name, name: s.Name})
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	case contentsPane:
+		if s := b.release.Slice(p.slice); s != nil {
+			for _, path := range s.Contents {
+				entries = append(entries, entry{label: path})
+			}
+		}
+	case depsPane:
+		entries = depsTree(b.release, p.slice)
+	case outputPane:
+		for _, line := range p.text {
+			entries = append(entries, entry{label: line})
+		}
+	}
+	return entries
+}
+
+// depsTree returns the tree of the essential slices of the slice, one entry
+// per line. Slices are expanded the first time they appear only.
+func depsTree(r *chisel.Release, name string) []entry {
+	entries := []entry{{label: name, name: name}}
+	seen := map[string]bool{name: true}
+	var walk func(s *chisel.Slice, prefix string)
+	walk = func(s *chisel.Slice, prefix string) {
+		for i, e := range s.Essential {
+			branch, indent := "├─ ", "│  "
+			if i == len(s.Essential)-1 {
+				branch, indent = "└─ ", "   "
+			}
+			label := prefix + branch + e
+			dep := r.Slice(e)
+			switch {
+			case dep == nil:
+				label += " (undefined)"
+			case seen[e]:
+				label += " (see above)"
+			}
+			entries = append(entries, entry{label: label, name: e})
+			if dep != nil && !seen[e] {
+				seen[e] = true
+				walk(dep, prefix+indent)
+			}
+		}
+	}
+	if s := r.Slice(name); s != nil {
+		walk(s, "")
+	}
+	return entries
+}
+
+func (b *Browser) title(p *pane) string {
+	switch p.kind {
+	case packagesPane:
+		return "Packages in " + b.release.Path
+	case slicesPane:
+		return "Slices of " + p.pkg
+	case contentsPane, depsPane:
+		what := "Contents"
+		if p.kind == depsPane {
+			what = "Dependencies"
+		}
+		title := fmt.Sprintf("%s of %s", what, p.slice)
+		if s := b.release.Slice(p.slice); s != nil && s.File != "" {
+			file, err := filepath.Rel(b.release.Path, s.File)
+			if err != nil {
+				file = s.File
+			}
+			title += " (" + file + ")"
+		}
+		return title
+	}
+	return p.title
+}
+
+func (b *Browser) help(p *pane) string {
+	switch p.kind {
+	case packagesPane:
+		return "enter: slices  /: filter  r: reload  q: quit"
+	case slicesPane:
+		return "enter: contents  c: cut  /: filter  esc: back  q: quit"
+	case contentsPane:
+		return "tab: dependencies  c: cut  /: filter  esc: back  q: quit"
+	case depsPane:
+		return "tab: contents  enter: open  c: cut  /: filter  esc: back  q: quit"
+	}
+	return "esc: back  q: quit"
+}
+
+// Render returns the lines of the screen for the given size.
+func (b *Browser) Render(width, height int) []string {
+	p := b.pane()
+	entries := b.entries(p)
+	b.height = max(height-2, 1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+b.height {
+		p.offset = p.cursor - b.height + 1
+	}
+
+	title := b.title(p)
+	if p.filter != "" {
+		title += fmt.Sprintf(" [/%s]", p.filter)
+	}
+	lines := []string{title}
+	for i := p.offset; i < len(entries) && i < p.offset+b.height; i++ {
+		marker := "  "
+		if i == p.cursor && p.kind != outputPane {
+			marker = "> "
+		}
+		lines = append(lines, marker+entries[i].label)
+	}
+	if len(entries) == 0 {
+		lines = append(lines, "  (empty)")
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	switch {
+	case b.filtering:
+		lines = append(lines, "/"+p.filter)
+	case b.status != "":
+		lines = append(lines, b.status)
+	default:
+		lines = append(lines, b.help(p))
+	}
+	for i, line := range lines {
+		lines[i] = truncate(line, width)
+	}
+	return lines[:min(len(lines), height)]
+}
+
+// truncate cuts the line to the width, in runes.
+func truncate(line string, width int) string {
+	if utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	runes := []rune(line)
+	if width <= 1 {
+		return string(runes[:max(width, 0)])
+	}
+	return string(runes[:width-1]) + "…"
+}
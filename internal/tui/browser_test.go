@@ -0,0 +1,278 @@
+package tui_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/chisel"
+	"github.com/rebornplusplus/chisel-tools/internal/tui"
+)
+
+var releaseFiles = map[string]string{
+	"chisel.yaml": `
+format: v1
+archives:
+  ubuntu:
+    suites: [noble]
+    components: [main]
+`,
+	"slices/foo.yaml": `
+package: foo
+slices:
+  bins:
+    essential: [foo_config, bar_libs, baz_libs]
+    contents:
+      /usr/bin/foo:
+  config:
+    essential: [bar_libs]
+    contents:
+      /etc/foo/**:
+`,
+	"slices/bar.yaml": `
+package: bar
+slices:
+  libs:
+    contents:
+      /usr/lib/libbar.so.1:
+`,
+}
+
+func readRelease(t *testing.T, files map[string]string) *chisel.Release {
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := chisel.ReadRelease(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+var browserTests = []struct {
+	summary  string
+	keys     []tui.Key
+	action   tui.Action
+	selected string
+	screen   []string
+}{{
+	summary: "Packages",
+	screen: []string{
+		"Packages in $DIR",
+		"> bar (1)",
+		"  foo (2)",
+		"",
+		"",
+		"enter: slices  /: filter  r: reload  q: quit",
+	},
+}, {
+	summary:  "Slices of a package",
+	keys:     []tui.Key{"j", tui.KeyEnter, tui.KeyDown},
+	selected: "foo_config",
+	screen: []string{
+		"Slices of foo",
+		"  foo_bins",
+		"> foo_config",
+		"",
+		"",
+		"enter: contents  c: cut  /: filter  esc: back  q: quit",
+	},
+}, {
+	summary:  "Contents of a slice",
+	keys:     []tui.Key{tui.KeyHome, "j", tui.KeyRight, tui.KeyEnter},
+	selected: "foo_bins",
+	screen: []string{
+		"Contents of foo_bins (slices/foo.yaml)",
+		"> /usr/bin/foo",
+		"",
+		"",
+		"",
+		"tab: dependencies  c: cut  /: filter  esc: back  q: quit",
+	},
+}, {
+	summary:  "Dependency tree",
+	keys:     []tui.Key{"j", tui.KeyEnter, tui.KeyEnter, tui.KeyTab, tui.KeyEnd},
+	selected: "foo_bins",
+	screen: []string{
+		"Dependencies of foo_bins (slices/foo.yaml)",
+		"  ├─ foo_config",
+		"  │  └─ bar_libs",
+		"  ├─ bar_libs (see above)",
+		"> └─ baz_libs (undefined)",
+		"tab: contents  enter: open  c: cut  /: filter  esc: back  q: quit",
+	},
+}, {
+	summary:  "Open a dependency",
+	keys:     []tui.Key{"j", tui.KeyEnter, tui.KeyEnter, tui.KeyTab, "j", "j", tui.KeyEnter},
+	selected: "bar_libs",
+	screen: []string{
+		"Contents of bar_libs (slices/bar.yaml)",
+		"> /usr/lib/libbar.so.1",
+		"",
+		"",
+		"",
+		"tab: dependencies  c: cut  /: filter  esc: back  q: quit",
+	},
+}, {
+	summary:  "Undefined dependency",
+	keys:     []tui.Key{"j", tui.KeyEnter, tui.KeyEnter, tui.KeyTab, tui.KeyEnd, tui.KeyEnter},
+	selected: "foo_bins",
+	screen: []string{
+		"Dependencies of foo_bins (slices/foo.yaml)",
+		"  ├─ foo_config",
+		"  │  └─ bar_libs",
+		"  ├─ bar_libs (see above)",
+		"> └─ baz_libs (undefined)",
+		"Slice baz_libs is not defined",
+	},
+}, {
+	summary: "Back",
+	keys:    []tui.Key{"j", tui.KeyEnter, tui.KeyEnter, tui.KeyEscape, "h"},
+	screen: []string{
+		"Packages in $DIR",
+		"  bar (1)",
+		"> foo (2)",
+		"",
+		"",
+		"enter: slices  /: filter  r: reload  q: quit",
+	},
+}, {
+	summary: "Filter",
+	keys:    []tui.Key{"/", "f", "x", tui.KeyBackspace},
+	screen: []string{
+		"Packages in $DIR [/f]",
+		"> foo (2)",
+		"",
+		"",
+		"",
+		"/f",
+	},
+}, {
+	summary:  "Filter applied",
+	keys:     []tui.Key{"/", "f", tui.KeyEnter, tui.KeyEnter, "/", "c", tui.KeyEnter},
+	selected: "foo_config",
+	screen: []string{
+		"Slices of foo [/c]",
+		"> foo_config",
+		"",
+		"",
+		"",
+		"enter: contents  c: cut  /: filter  esc: back  q: quit",
+	},
+}, {
+	summary: "Filter cleared",
+	keys:    []tui.Key{"/", "f", tui.KeyEnter, tui.KeyEscape},
+	screen: []string{
+		"Packages in $DIR",
+		"> bar (1)",
+		"  foo (2)",
+		"",
+		"",
+		"enter: slices  /: filter  r: reload  q: quit",
+	},
+}, {
+	summary: "Cut without a slice",
+	keys:    []tui.Key{"c"},
+	screen: []string{
+		"Packages in $DIR",
+		"> bar (1)",
+		"  foo (2)",
+		"",
+		"",
+		"Select a slice to cut",
+	},
+}, {
+	summary:  "Cut",
+	keys:     []tui.Key{tui.KeyEnter, "c"},
+	action:   tui.Cut,
+	selected: "bar_libs",
+}, {
+	summary: "Reload",
+	keys:    []tui.Key{"r"},
+	action:  tui.Reload,
+}, {
+	summary:  "Quit",
+	keys:     []tui.Key{tui.KeyEnter, "q"},
+	action:   tui.Quit,
+	selected: "bar_libs",
+}}
+
+func TestBrowser(t *testing.T) {
+	r := readRelease(t, releaseFiles)
+	for _, test := range browserTests {
+		t.Logf("Summary: %s", test.summary)
+		b := tui.NewBrowser(r)
+		action := tui.None
+		for _, k := range test.keys {
+			action = b.Handle(k)
+		}
+		if action != test.action {
+			t.Fatalf("have action %v, want %v", action, test.action)
+		}
+		if selected := b.Selected(); selected != test.selected {
+			t.Fatalf("have selected %q, want %q", selected, test.selected)
+		}
+		if test.screen == nil {
+			continue
+		}
+		var want []string
+		for _, line := range test.screen {
+			want = append(want, strings.ReplaceAll(line, "$DIR", r.Path))
+		}
+		screen := b.Render(80, 6)
+		if !reflect.DeepEqual(screen, want) {
+			t.Fatalf("have screen:\n%s\nwant:\n%s", strings.Join(screen, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestBrowserScroll(t *testing.T) {
+	r := readRelease(t, releaseFiles)
+	b := tui.NewBrowser(r)
+	b.Handle(tui.KeyEnd)
+	screen := b.Render(12, 3)
+	want := []string{"Packages in…", "> foo (2)", "enter: slic…"}
+	if !reflect.DeepEqual(screen, want) {
+		t.Fatalf("have %q, want %q", screen, want)
+	}
+}
+
+func TestBrowserOutput(t *testing.T) {
+	r := readRelease(t, releaseFiles)
+	b := tui.NewBrowser(r)
+	b.ShowOutput("Cut failed", "error: foo\nmore\n")
+	want := []string{"Cut failed", "  error: foo", "  more", "esc: back  q: quit"}
+	if screen := b.Render(80, 4); !reflect.DeepEqual(screen, want) {
+		t.Fatalf("have %q, want %q", screen, want)
+	}
+	b.Handle(tui.KeyEscape)
+	if screen := b.Render(80, 4); screen[0] != "Packages in "+r.Path {
+		t.Fatalf("have %q, want the packages", screen)
+	}
+}
+
+func TestBrowserSetRelease(t *testing.T) {
+	r := readRelease(t, releaseFiles)
+	b := tui.NewBrowser(r)
+	for _, k := range []tui.Key{"j", tui.KeyEnter, tui.KeyEnter} {
+		b.Handle(k)
+	}
+	files := map[string]string{}
+	for name, data := range releaseFiles {
+		files[name] = data
+	}
+	files["slices/foo.yaml"] = "package: foo\nslices:\n  config: {}\n"
+	b.SetRelease(readRelease(t, files))
+	if selected := b.Selected(); selected != "foo_config" {
+		t.Fatalf("have selected %q, want %q", selected, "foo_config")
+	}
+}
@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Key is a key press, named after the key for special keys, like "up" or
+// "enter", and the typed character otherwise.
+type Key string
+
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyHome      Key = "home"
+	KeyEnd       Key = "end"
+	KeyPageUp    Key = "pgup"
+	KeyPageDown  Key = "pgdown"
+	KeyEnter     Key = "enter"
+	KeyEscape    Key = "esc"
+	KeyBackspace Key = "backspace"
+	KeyTab       Key = "tab"
+	KeyCtrlC     Key = "ctrl-c"
+	KeyCtrlD     Key = "ctrl-d"
+)
+
+// Escape sequences sent by terminals for special keys, without the leading
+// escape character. Both the normal and the application cursor keys modes
+// are recognized.
+var sequences = map[string]Key{
+	"[A":  KeyUp,
+	"[B":  KeyDown,
+	"[C":  KeyRight,
+	"[D":  KeyLeft,
+	"OA":  KeyUp,
+	"OB":  KeyDown,
+	"OC":  KeyRight,
+	"OD":  KeyLeft,
+	"[H":  KeyHome,
+	"[F":  KeyEnd,
+	"OH":  KeyHome,
+	"OF":  KeyEnd,
+	"[1~": KeyHome,
+	"[4~": KeyEnd,
+	"[5~": KeyPageUp,
+	"[6~": KeyPageDown,
+}
+
+// ParseKeys returns the keys in the input read from a terminal in raw mode.
+// An escape sequence arrives within a single read, so a lone escape
+// character at the end of the input is the escape key. Unknown sequences and
+// control characters are dropped.
+func ParseKeys(input []byte) []Key {
+	var keys []Key
+	for len(input) > 0 {
+		switch c := input[0]; {
+		case c == 0x1b:
+			n, key := parseSequence(input[1:])
+			if key != "" {
+				keys = append(keys, key)
+			}
+			input = input[1+n:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, KeyEnter)
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, KeyBackspace)
+		case c == '\t':
+			keys = append(keys, KeyTab)
+		case c == 0x03:
+			keys = append(keys, KeyCtrlC)
+		case c == 0x04:
+			keys = append(keys, KeyCtrlD)
+		case c < 0x20:
+		default:
+			r, n := utf8.DecodeRune(input)
+			if r != utf8.RuneError && unicode.IsPrint(r) {
+				keys = append(keys, Key(string(r)))
+			}
+			input = input[n:]
+			continue
+		}
+		input = input[1:]
+	}
+	return keys
+}
+
+// parseSequence parses the escape sequence at the start of the input, which
+// follows an escape character. It returns the length of the sequence and its
+// key, which is empty if the sequence is unknown.
+func parseSequence(input []byte) (int, Key) {
+	if len(input) == 0 || (input[0] != '[' && input[0] != 'O') {
+		return 0, KeyEscape
+	}
+	// A sequence ends with its first letter or tilde after the
+	// introducer.
+	for i := 1; i < len(input); i++ {
+		if c := input[i]; c == '~' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+			return i + 1, sequences[string(input[:i+1])]
+		}
+	}
+	return len(input), ""
+}
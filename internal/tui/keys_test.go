@@ -0,0 +1,52 @@
+package tui_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/tui"
+)
+
+var parseKeysTests = []struct {
+	summary string
+	input   string
+	keys    []tui.Key
+}{{
+	summary: "Characters",
+	input:   "jké",
+	keys:    []tui.Key{"j", "k", "é"},
+}, {
+	summary: "Control characters",
+	input:   "\r\n\t\x7f\x03\x04\x01",
+	keys:    []tui.Key{tui.KeyEnter, tui.KeyEnter, tui.KeyTab, tui.KeyBackspace, tui.KeyCtrlC, tui.KeyCtrlD},
+}, {
+	summary: "Cursor keys in both modes",
+	input:   "\x1b[A\x1b[B\x1bOC\x1bOD",
+	keys:    []tui.Key{tui.KeyUp, tui.KeyDown, tui.KeyRight, tui.KeyLeft},
+}, {
+	summary: "Page keys",
+	input:   "\x1b[5~\x1b[6~\x1b[H\x1b[4~",
+	keys:    []tui.Key{tui.KeyPageUp, tui.KeyPageDown, tui.KeyHome, tui.KeyEnd},
+}, {
+	summary: "Lone escape",
+	input:   "\x1b",
+	keys:    []tui.Key{tui.KeyEscape},
+}, {
+	summary: "Escape followed by a character",
+	input:   "\x1bq",
+	keys:    []tui.Key{tui.KeyEscape, "q"},
+}, {
+	summary: "Unknown sequences are dropped",
+	input:   "\x1b[1;5Aj\x1b[",
+	keys:    []tui.Key{"j"},
+}}
+
+func TestParseKeys(t *testing.T) {
+	for _, test := range parseKeysTests {
+		t.Logf("Summary: %s", test.summary)
+		keys := tui.ParseKeys([]byte(test.input))
+		if !reflect.DeepEqual(keys, test.keys) {
+			t.Fatalf("have %q, want %q", keys, test.keys)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// A LineEditor holds the line typed at a prompt, with the history of the
+// lines entered before and the completion of the word before the cursor.
+type LineEditor struct {
+	// Complete returns the candidates for the last of the words before the
+	// cursor, which is the one being typed and may be empty. The
+	// candidates not starting with it are ignored.
+	Complete func(words []string) []string
+
+	line    []rune
+	cursor  int
+	history []string
+	recall  int    // Index of the line recalled from the history.
+	draft   []rune // Line being typed before recalling the history.
+	choices []string
+}
+
+// NewLineEditor returns an editor with an empty line and no history.
+func NewLineEditor(complete func(words []string) []string) *LineEditor {
+	return &LineEditor{Complete: complete}
+}
+
+// Handle updates the line for the key press and returns what the caller
+// should do about it: [Submit] on enter, [Cancel] on ctrl-c, [Quit] on
+// ctrl-d with an empty line and [None] otherwise.
+func (e *LineEditor) Handle(k Key) Action {
+	e.choices = nil
+	switch k {
+	case KeyEnter:
+		return Submit
+	case KeyCtrlC:
+		e.reset()
+		return Cancel
+	case KeyCtrlD:
+		if len(e.line) == 0 {
+			return Quit
+		}
+	case KeyLeft:
+		e.cursor = max(e.cursor-1, 0)
+	case KeyRight:
+		e.cursor = min(e.cursor+1, len(e.line))
+	case KeyHome:
+		e.cursor = 0
+	case KeyEnd:
+		e.cursor = len(e.line)
+	case KeyBackspace:
+		if e.cursor > 0 {
+			e.line = slices.Delete(e.line, e.cursor-1, e.cursor)
+			e.cursor--
+		}
+	case KeyUp:
+		if e.recall > 0 {
+			if e.recall == len(e.history) {
+				e.draft = e.line
+			}
+			e.recall--
+			e.setLine([]rune(e.history[e.recall]))
+		}
+	case KeyDown:
+		if e.recall < len(e.history) {
+			e.recall++
+			if e.recall == len(e.history) {
+				e.setLine(e.draft)
+			} else {
+				e.setLine([]rune(e.history[e.recall]))
+			}
+		}
+	case KeyTab:
+		e.complete()
+	default:
+		// Other special keys have names longer than a character.
+		if r := []rune(string(k)); len(r) == 1 {
+			e.insert(string(k))
+		}
+	}
+	return None
+}
+
+// Accept returns the line and starts a new one, adding the line to the
+// history unless it is blank or the same as the last one.
+func (e *LineEditor) Accept() string {
+	line := string(e.line)
+	if strings.TrimSpace(line) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+		e.history = append(e.history, line)
+	}
+	e.reset()
+	return line
+}
+
+// Render returns the line and the position of the cursor in it, in runes.
+func (e *LineEditor) Render() (line string, cursor int) {
+	return string(e.line), e.cursor
+}
+
+// Choices returns the candidates of the last completion, if it could not
+// choose between them.
+func (e *LineEditor) Choices() []string {
+	return e.choices
+}
+
+func (e *LineEditor) reset() {
+	e.line = nil
+	e.cursor = 0
+	e.draft = nil
+	e.recall = len(e.history)
+}
+
+func (e *LineEditor) setLine(line []rune) {
+	e.line = slices.Clone(line)
+	e.cursor = len(e.line)
+}
+
+func (e *LineEditor) insert(s string) {
+	r := []rune(s)
+	e.line = slices.Insert(e.line, e.cursor, r...)
+	e.cursor += len(r)
+}
+
+// complete completes the word before the cursor with the longest prefix
+// common to its candidates. A unique candidate is followed by a space
+// unless it ends with a slash, like a directory, and the candidates are
+// shown if none of them can be chosen.
+func (e *LineEditor) complete() {
+	if e.Complete == nil {
+		return
+	}
+	before := string(e.line[:e.cursor])
+	words := strings.Fields(before)
+	if len(words) == 0 || strings.HasSuffix(before, " ") {
+		words = append(words, "")
+	}
+	word := words[len(words)-1]
+	var candidates []string
+	for _, c := range e.Complete(words) {
+		if strings.HasPrefix(c, word) {
+			candidates = append(candidates, c)
+		}
+	}
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+	switch len(candidates) {
+	case 0:
+	case 1:
+		e.insert(strings.TrimPrefix(candidates[0], word))
+		if !strings.HasSuffix(candidates[0], "/") {
+			e.insert(" ")
+		}
+	default:
+		prefix := candidates[0]
+		for _, c := range candidates[1:] {
+			for !strings.HasPrefix(c, prefix) {
+				prefix = prefix[:len(prefix)-1]
+			}
+		}
+		for !utf8.ValidString(prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+		if len(prefix) > len(word) {
+			e.insert(strings.TrimPrefix(prefix, word))
+		} else {
+			e.choices = candidates
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package tui_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rebornplusplus/chisel-tools/internal/tui"
+)
+
+// typing returns the keys typing the text.
+func typing(text string) []tui.Key {
+	var keys []tui.Key
+	for _, r := range text {
+		keys = append(keys, tui.Key(string(r)))
+	}
+	return keys
+}
+
+func join(keys ...[]tui.Key) []tui.Key {
+	var all []tui.Key
+	for _, k := range keys {
+		all = append(all, k...)
+	}
+	return all
+}
+
+func completeWords(words []string) []string {
+	if len(words) == 1 {
+		return []string{"deps", "rdeps", "info", "find"}
+	}
+	switch words[0] {
+	case "deps", "info":
+		return []string{"foo_bins", "foo_config", "bar_libs"}
+	case "find":
+		return []string{"/usr/", "/etc/"}
+	}
+	return nil
+}
+
+var lineEditorTests = []struct {
+	summary string
+	history []string
+	keys    []tui.Key
+	action  tui.Action
+	line    string
+	cursor  int
+	choices []string
+}{{
+	summary: "Typing",
+	keys:    typing("deps foo"),
+	line:    "deps foo",
+	cursor:  8,
+}, {
+	summary: "Editing in the middle",
+	keys:    join(typing("dps"), []tui.Key{tui.KeyLeft, tui.KeyLeft, "e", tui.KeyEnd, tui.KeyBackspace, "s"}),
+	line:    "deps",
+	cursor:  4,
+}, {
+	summary: "Moving out of the line",
+	keys:    join(typing("ab"), []tui.Key{tui.KeyRight, tui.KeyHome, tui.KeyLeft, tui.KeyBackspace}),
+	line:    "ab",
+	cursor:  0,
+}, {
+	summary: "Enter",
+	keys:    join(typing("info"), []tui.Key{tui.KeyEnter}),
+	action:  tui.Submit,
+	line:    "info",
+	cursor:  4,
+}, {
+	summary: "Ctrl-c abandons the line",
+	keys:    join(typing("info"), []tui.Key{tui.KeyCtrlC}),
+	action:  tui.Cancel,
+}, {
+	summary: "Ctrl-d quits on an empty line only",
+	keys:    []tui.Key{tui.KeyCtrlD},
+	action:  tui.Quit,
+}, {
+	summary: "Ctrl-d on a line",
+	keys:    join(typing("a"), []tui.Key{tui.KeyCtrlD}),
+	line:    "a",
+	cursor:  1,
+}, {
+	summary: "Unique completion",
+	keys:    join(typing("rd"), []tui.Key{tui.KeyTab}),
+	line:    "rdeps ",
+	cursor:  6,
+}, {
+	summary: "Completion of the common prefix",
+	keys:    join(typing("deps foo"), []tui.Key{tui.KeyTab}),
+	line:    "deps foo_",
+	cursor:  9,
+}, {
+	summary: "Ambiguous completion shows the choices",
+	keys:    join(typing("deps foo_"), []tui.Key{tui.KeyTab}),
+	line:    "deps foo_",
+	cursor:  9,
+	choices: []string{"foo_bins", "foo_config"},
+}, {
+	summary: "Choices are cleared on the next key",
+	keys:    join(typing("deps foo_"), []tui.Key{tui.KeyTab, "b"}),
+	line:    "deps foo_b",
+	cursor:  10,
+}, {
+	summary: "Completion of a new word",
+	keys:    join(typing("info "), []tui.Key{tui.KeyTab}),
+	line:    "info ",
+	cursor:  5,
+	choices: []string{"bar_libs", "foo_bins", "foo_config"},
+}, {
+	summary: "Completion of a directory",
+	keys:    join(typing("find /u"), []tui.Key{tui.KeyTab}),
+	line:    "find /usr/",
+	cursor:  10,
+}, {
+	summary: "Completion before the cursor",
+	keys:    join(typing("de bar"), []tui.Key{tui.KeyHome, tui.KeyRight, tui.KeyRight, tui.KeyTab}),
+	line:    "deps  bar",
+	cursor:  5,
+}, {
+	summary: "No completion",
+	keys:    join(typing("deps x"), []tui.Key{tui.KeyTab}),
+	line:    "deps x",
+	cursor:  6,
+}, {
+	summary: "History",
+	history: []string{"deps foo_bins", "info bar_libs"},
+	keys:    []tui.Key{tui.KeyUp, tui.KeyUp, tui.KeyUp},
+	line:    "deps foo_bins",
+	cursor:  13,
+}, {
+	summary: "Back from the history to the line being typed",
+	history: []string{"deps foo_bins"},
+	keys:    join(typing("in"), []tui.Key{tui.KeyUp, tui.KeyDown, tui.KeyDown}),
+	line:    "in",
+	cursor:  2,
+}, {
+	summary: "Editing a line of the history",
+	history: []string{"deps foo_bins"},
+	keys:    []tui.Key{tui.KeyUp, tui.KeyBackspace, tui.KeyBackspace, tui.KeyBackspace, tui.KeyBackspace},
+	line:    "deps foo_",
+	cursor:  9,
+}}
+
+func TestLineEditor(t *testing.T) {
+	for _, test := range lineEditorTests {
+		t.Logf("Summary: %s", test.summary)
+		e := tui.NewLineEditor(completeWords)
+		for _, line := range test.history {
+			for _, k := range join(typing(line), []tui.Key{tui.KeyEnter}) {
+				e.Handle(k)
+			}
+			e.Accept()
+		}
+		action := tui.None
+		for _, k := range test.keys {
+			action = e.Handle(k)
+		}
+		if action != test.action {
+			t.Fatalf("have action %v, want %v", action, test.action)
+		}
+		line, cursor := e.Render()
+		if line != test.line || cursor != test.cursor {
+			t.Fatalf("have line %q with the cursor at %d, want %q at %d", line, cursor, test.line, test.cursor)
+		}
+		if choices := e.Choices(); !reflect.DeepEqual(choices, test.choices) {
+			t.Fatalf("have choices %q, want %q", choices, test.choices)
+		}
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	e := tui.NewLineEditor(nil)
+	var accepted []string
+	for _, line := range []string{"a", "a", " ", "b"} {
+		for _, k := range join(typing(line), []tui.Key{tui.KeyEnter}) {
+			e.Handle(k)
+		}
+		accepted = append(accepted, e.Accept())
+	}
+	if want := []string{"a", "a", " ", "b"}; !reflect.DeepEqual(accepted, want) {
+		t.Fatalf("have accepted %q, want %q", accepted, want)
+	}
+	// Blank and repeated lines are not in the history.
+	var recalled []string
+	for range 3 {
+		e.Handle(tui.KeyUp)
+		line, _ := e.Render()
+		recalled = append(recalled, line)
+	}
+	if want := []string{"b", "a", "a"}; !reflect.DeepEqual(recalled, want) {
+		t.Fatalf("have recalled %q, want %q", recalled, want)
+	}
+}
@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Terminal draws full screens of text and reads key presses. It uses the
+// alternate screen, so that the contents of the terminal are back once the
+// browser is stopped.
+type Terminal struct {
+	in, out *os.File
+	restore func() error
+}
+
+// Open returns a terminal reading from in and writing to out, which must be
+// terminals.
+func Open(in, out *os.File) (*Terminal, error) {
+	if err := checkTerminal(in); err != nil {
+		return nil, err
+	}
+	return &Terminal{in: in, out: out}, nil
+}
+
+// IsTerminal returns whether the file is a terminal.
+func IsTerminal(f *os.File) bool {
+	return checkTerminal(f) == nil
+}
+
+// Start puts the terminal in raw mode and switches to the alternate screen.
+func (t *Terminal) Start() error {
+	restore, err := makeRaw(t.in)
+	if err != nil {
+		return err
+	}
+	t.restore = restore
+	_, err = t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	return err
+}
+
+// Stop restores the terminal as it was before [Terminal.Start].
+func (t *Terminal) Stop() error {
+	if t.restore == nil {
+		return nil
+	}
+	t.out.WriteString("\x1b[?25h\x1b[?1049l")
+	err := t.restore()
+	t.restore = nil
+	return err
+}
+
+// Size returns the width and height of the terminal.
+func (t *Terminal) Size() (width, height int) {
+	width, height, err := size(t.out)
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Draw replaces the screen with the lines.
+func (t *Terminal) Draw(lines []string) error {
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(strings.ReplaceAll(line, "\x1b", ""))
+		buf.WriteString("\x1b[K")
+	}
+	buf.WriteString("\x1b[J")
+	_, err := t.out.Write(buf.Bytes())
+	return err
+}
+
+// ReadKeys waits for key presses and returns them.
+func (t *Terminal) ReadKeys() ([]Key, error) {
+	buf := make([]byte, 256)
+	n, err := t.in.Read(buf)
+	return ParseKeys(buf[:n]), err
+}
+
+// ReadLine shows the prompt and returns the line edited with the editor,
+// once entered. Unlike [Terminal.Start], it leaves the screen as it is, the
+// terminal is only in raw mode while the line is edited. It returns io.EOF
+// on ctrl-d with an empty line.
+func (t *Terminal) ReadLine(prompt string, e *LineEditor) (string, error) {
+	restore, err := makeRaw(t.in)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	prompt = strings.ReplaceAll(prompt, "\x1b", "")
+	draw := func() error {
+		line, cursor := e.Render()
+		// Redraw the line and move the cursor back from its start.
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "\r%s%s\x1b[K\r", prompt, line)
+		if n := utf8.RuneCountInString(prompt) + cursor; n > 0 {
+			fmt.Fprintf(&buf, "\x1b[%dC", n)
+		}
+		_, err := t.out.Write(buf.Bytes())
+		return err
+	}
+	for {
+		if err := draw(); err != nil {
+			return "", err
+		}
+		keys, err := t.ReadKeys()
+		if err != nil {
+			return "", err
+		}
+		for _, k := range keys {
+			switch e.Handle(k) {
+			case Submit:
+				// Show the line as entered, keys may have changed it
+				// since the last drawing.
+				draw()
+				t.out.WriteString("\r\n")
+				return e.Accept(), nil
+			case Cancel:
+				t.out.WriteString("^C\r\n")
+			case Quit:
+				t.out.WriteString("\r\n")
+				return "", io.EOF
+			}
+			if choices := e.Choices(); len(choices) > 0 {
+				t.out.WriteString("\r\n" + strings.ReplaceAll(strings.Join(choices, "  "), "\x1b", "") + "\r\n")
+			}
+		}
+	}
+}
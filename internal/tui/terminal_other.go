@@ -0,0 +1,22 @@
+//go:build !linux
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("terminal interface not supported on this platform")
+
+func checkTerminal(f *os.File) error {
+	return errUnsupported
+}
+
+func makeRaw(f *os.File) (restore func() error, err error) {
+	return nil, errUnsupported
+}
+
+func size(f *os.File) (width, height int, err error) {
+	return 0, 0, errUnsupported
+}
@@ -0,0 +1,183 @@
+// Package vex generates OpenVEX documents stating whether the vulnerable
+// packages found in a root are exploitable, given the files that are
+// actually installed from them. See https://openvex.dev.
+package vex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const Context = "https://openvex.dev/ns/v0.2.0"
+
+type Status string
+
+const (
+	NotAffected Status = "not_affected"
+	Affected    Status = "affected"
+)
+
+// The justification for not affected products, from the OpenVEX spec.
+const VulnerableCodeNotPresent = "vulnerable_code_not_present"
+
+type Document struct {
+	Context    string       `json:"@context"`
+	ID         string       `json:"@id"`
+	Author     string       `json:"author"`
+	Timestamp  string       `json:"timestamp"`
+	Version    int          `json:"version"`
+	Tooling    string       `json:"tooling,omitempty"`
+	Statements []*Statement `json:"statements"`
+}
+
+type Statement struct {
+	Vulnerability   Vulnerability `json:"vulnerability"`
+	Products        []*Product    `json:"products"`
+	Status          Status        `json:"status"`
+	Justification   string        `json:"justification,omitempty"`
+	ImpactStatement string        `json:"impact_statement,omitempty"`
+	ActionStatement string        `json:"action_statement,omitempty"`
+}
+
+type Vulnerability struct {
+	Name string `json:"name"`
+}
+
+type Product struct {
+	ID            string       `json:"@id"`
+	Subcomponents []*Component `json:"subcomponents,omitempty"`
+}
+
+type Component struct {
+	ID string `json:"@id"`
+}
+
+// A Finding is a vulnerable package in a root.
+type Finding struct {
+	CVE string
+	// Package URL of the vulnerable package.
+	PURL    string
+	Package string
+	Fixed   string
+	// Paths installed from the package, directories ending with "/".
+	Installed []string
+	// Patterns, as in path.Match, of the files holding the vulnerable code.
+	// If empty, the package is assumed to be affected unless it only
+	// installs data files.
+	Files []string
+}
+
+type Input struct {
+	Author string
+	// Product ID, such as the image reference. If empty, the package URLs
+	// are the products.
+	Product  string
+	Created  time.Time
+	Findings []*Finding
+}
+
+// Path prefixes holding no code that could be vulnerable.
+var dataPrefixes = []string{
+	"/usr/share/doc/",
+	"/usr/share/doc-base/",
+	"/usr/share/info/",
+	"/usr/share/lintian/",
+	"/usr/share/locale/",
+	"/usr/share/man/",
+}
+
+func isData(p string) bool {
+	if strings.HasSuffix(p, "/") {
+		return true
+	}
+	for _, prefix := range dataPrefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// status decides if the finding is exploitable in the root.
+func status(f *Finding) *Statement {
+	s := &Statement{Vulnerability: Vulnerability{Name: f.CVE}}
+	if len(f.Files) > 0 {
+		for _, p := range f.Installed {
+			for _, pattern := range f.Files {
+				if ok, _ := path.Match(pattern, p); ok {
+					s.Status = Affected
+					s.ActionStatement = fmt.Sprintf("%s is installed, upgrade %s to %s", p, f.Package, f.Fixed)
+					return s
+				}
+			}
+		}
+		s.Status = NotAffected
+		s.Justification = VulnerableCodeNotPresent
+		s.ImpactStatement = fmt.Sprintf("None of the vulnerable files of %s (%s) are included in the installed slices", f.Package, strings.Join(f.Files, ", "))
+		return s
+	}
+	for _, p := range f.Installed {
+		if !isData(p) {
+			s.Status = Affected
+			s.ActionStatement = fmt.Sprintf("Upgrade %s to %s", f.Package, f.Fixed)
+			return s
+		}
+	}
+	s.Status = NotAffected
+	s.Justification = VulnerableCodeNotPresent
+	s.ImpactStatement = fmt.Sprintf("The installed slices of %s only include documentation and data files", f.Package)
+	return s
+}
+
+// Generate returns a document with one statement per CVE and package.
+func Generate(in *Input) *Document {
+	var statements []*Statement
+	for _, f := range in.Findings {
+		s := status(f)
+		if in.Product != "" {
+			s.Products = []*Product{{
+				ID:            in.Product,
+				Subcomponents: []*Component{{ID: f.PURL}},
+			}}
+		} else {
+			s.Products = []*Product{{ID: f.PURL}}
+		}
+		statements = append(statements, s)
+	}
+	sort.SliceStable(statements, func(i, j int) bool {
+		a, b := statements[i], statements[j]
+		if a.Vulnerability.Name != b.Vulnerability.Name {
+			return a.Vulnerability.Name < b.Vulnerability.Name
+		}
+		return productID(a) < productID(b)
+	})
+
+	// The ID is derived from the statements, so that the same findings in
+	// the same root yield the same document.
+	h := sha256.New()
+	for _, s := range statements {
+		fmt.Fprintf(h, "%s %s %s\n", s.Vulnerability.Name, productID(s), s.Status)
+	}
+	return &Document{
+		Context:    Context,
+		ID:         "https://openvex.dev/docs/public/vex-" + hex.EncodeToString(h.Sum(nil)),
+		Author:     in.Author,
+		Timestamp:  in.Created.UTC().Format(time.RFC3339),
+		Version:    1,
+		Tooling:    "sdf",
+		Statements: statements,
+	}
+}
+
+func productID(s *Statement) string {
+	p := s.Products[0]
+	if len(p.Subcomponents) > 0 {
+		return p.Subcomponents[0].ID
+	}
+	return p.ID
+}
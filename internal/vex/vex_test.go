@@ -0,0 +1,97 @@
+package vex_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/vex"
+)
+
+var generateTests = []struct {
+	summary string
+	finding *vex.Finding
+	status  vex.Status
+}{{
+	summary: "Library installed",
+	finding: &vex.Finding{
+		Installed: []string{"/usr/lib/", "/usr/lib/libfoo.so.1", "/usr/share/doc/foo/copyright"},
+	},
+	status: vex.Affected,
+}, {
+	summary: "Only the copyright file installed",
+	finding: &vex.Finding{
+		Installed: []string{"/usr/share/doc/foo/", "/usr/share/doc/foo/copyright"},
+	},
+	status: vex.NotAffected,
+}, {
+	summary: "Vulnerable file installed",
+	finding: &vex.Finding{
+		Installed: []string{"/usr/bin/foo", "/usr/lib/libfoo.so.1"},
+		Files:     []string{"/usr/lib/libfoo.so.*"},
+	},
+	status: vex.Affected,
+}, {
+	summary: "Vulnerable file not installed",
+	finding: &vex.Finding{
+		Installed: []string{"/usr/lib/libfoo.so.1"},
+		Files:     []string{"/usr/bin/foo-helper"},
+	},
+	status: vex.NotAffected,
+}}
+
+func TestGenerate(t *testing.T) {
+	for _, tc := range generateTests {
+		t.Logf("Summary: %s", tc.summary)
+		tc.finding.CVE = "CVE-2024-0001"
+		tc.finding.PURL = "pkg:deb/ubuntu/foo@1.0?arch=amd64"
+		tc.finding.Package = "foo"
+		tc.finding.Fixed = "1.1"
+		doc := vex.Generate(&vex.Input{
+			Author:   "test",
+			Product:  "ghcr.io/org/foo:latest",
+			Created:  time.Unix(0, 0),
+			Findings: []*vex.Finding{tc.finding},
+		})
+		if len(doc.Statements) != 1 {
+			t.Fatalf("have %d statements, want 1", len(doc.Statements))
+		}
+		s := doc.Statements[0]
+		if s.Status != tc.status {
+			t.Fatalf("have status %s, want %s", s.Status, tc.status)
+		}
+		if (s.Status == vex.NotAffected) != (s.Justification == vex.VulnerableCodeNotPresent) {
+			t.Fatalf("have justification %q for status %s", s.Justification, s.Status)
+		}
+		want := []*vex.Product{{
+			ID:            "ghcr.io/org/foo:latest",
+			Subcomponents: []*vex.Component{{ID: tc.finding.PURL}},
+		}}
+		if !reflect.DeepEqual(s.Products, want) {
+			t.Fatalf("have products %+v, want %+v", s.Products, want)
+		}
+	}
+}
+
+func TestGenerateDocument(t *testing.T) {
+	findings := []*vex.Finding{
+		{CVE: "CVE-2024-0002", PURL: "pkg:deb/ubuntu/b", Installed: []string{"/usr/bin/b"}},
+		{CVE: "CVE-2024-0001", PURL: "pkg:deb/ubuntu/a", Installed: []string{"/usr/bin/a"}},
+	}
+	in := &vex.Input{Author: "test", Created: time.Unix(0, 0), Findings: findings}
+	doc := vex.Generate(in)
+	if doc.Context != vex.Context || doc.Timestamp != "1970-01-01T00:00:00Z" || doc.Version != 1 {
+		t.Fatalf("bad document: %+v", doc)
+	}
+	var names []string
+	for _, s := range doc.Statements {
+		names = append(names, s.Vulnerability.Name+" "+s.Products[0].ID)
+	}
+	want := []string{"CVE-2024-0001 pkg:deb/ubuntu/a", "CVE-2024-0002 pkg:deb/ubuntu/b"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("have statements %v, want %v", names, want)
+	}
+	if again := vex.Generate(in); again.ID != doc.ID {
+		t.Fatalf("have ID %s, want %s", again.ID, doc.ID)
+	}
+}
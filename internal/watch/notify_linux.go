@@ -0,0 +1,213 @@
+//go:build linux
+
+package watch
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const notifyMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_ATTRIB |
+	unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ONLYDIR
+
+// A notifier gathers the files of a directory tree that inotify notifies
+// as changed. Directories created in the tree, or moved into it, are watched
+// as they are, and their files are changed.
+type notifier struct {
+	dir  string
+	fd   int
+	file *os.File // Of fd, so that reading it can be stopped.
+	done chan struct{}
+
+	mu      sync.Mutex
+	dirs    map[int]string  // Watched directories, by watch descriptor.
+	files   map[string]bool // Files of the tree, to find those of the directories removed.
+	changed map[string]bool
+	err     error
+}
+
+func newNotifier(dir string) (*notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	n := &notifier{
+		dir:     dir,
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		done:    make(chan struct{}),
+		dirs:    make(map[int]string),
+		files:   make(map[string]bool),
+		changed: make(map[string]bool),
+	}
+	if err := n.add(dir, false); err != nil {
+		n.file.Close()
+		return nil, err
+	}
+	go n.read()
+	return n, nil
+}
+
+// changes returns the files notified as changed since the previous call,
+// sorted.
+func (n *notifier) changes() ([]string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return nil, n.err
+	}
+	var changed []string
+	for p := range n.changed {
+		changed = append(changed, p)
+	}
+	clear(n.changed)
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func (n *notifier) close() {
+	n.file.Close()
+	<-n.done
+}
+
+// add watches the directories of the tree in dir and records its files,
+// as changed too if changed is true. Directories that cannot be watched,
+// such as when inotify runs out of watches, fail the notifier, as their
+// changes would be missed.
+func (n *notifier) add(dir string, changed bool) error {
+	return walk(dir, func(path string) error {
+		wd, err := unix.InotifyAddWatch(n.fd, path, notifyMask)
+		if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENOTDIR) {
+			// Removed meanwhile, which is notified.
+			return filepath.SkipDir
+		} else if err != nil {
+			return &fs.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+		}
+		n.dirs[wd] = path
+		return nil
+	}, func(path string, d fs.DirEntry) error {
+		n.files[path] = true
+		if changed {
+			n.changed[path] = true
+		}
+		return nil
+	})
+}
+
+// remove stops watching the directory at path, once removed or moved out
+// of the tree, and its files are changed.
+func (n *notifier) remove(path string) {
+	prefix := path + string(filepath.Separator)
+	for p := range n.files {
+		if strings.HasPrefix(p, prefix) {
+			delete(n.files, p)
+			n.changed[p] = true
+		}
+	}
+	for wd, dir := range n.dirs {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			unix.InotifyRmWatch(n.fd, uint32(wd))
+			delete(n.dirs, wd)
+		}
+	}
+}
+
+// rescan watches the tree again once inotify dropped events, and every
+// file of the tree is changed, as any could be.
+func (n *notifier) rescan() error {
+	for wd := range n.dirs {
+		unix.InotifyRmWatch(n.fd, uint32(wd))
+	}
+	old := n.files
+	n.dirs = make(map[int]string)
+	n.files = make(map[string]bool)
+	if err := n.add(n.dir, true); err != nil {
+		return err
+	}
+	for p := range old {
+		if !n.files[p] {
+			n.changed[p] = true
+		}
+	}
+	return nil
+}
+
+func (n *notifier) read() {
+	defer close(n.done)
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		size, err := n.file.Read(buf)
+		if errors.Is(err, os.ErrClosed) {
+			return
+		}
+		n.mu.Lock()
+		if err == nil {
+			err = n.handle(buf[:size])
+		}
+		if err != nil {
+			n.err = err
+			n.mu.Unlock()
+			return
+		}
+		n.mu.Unlock()
+	}
+}
+
+// handle records the changes of the events in buf.
+func (n *notifier) handle(buf []byte) error {
+	for len(buf) >= unix.SizeofInotifyEvent {
+		e := (*unix.InotifyEvent)(unsafe.Pointer(&buf[0]))
+		end := unix.SizeofInotifyEvent + int(e.Len)
+		name := string(bytes.TrimRight(buf[unix.SizeofInotifyEvent:end], "\x00"))
+		buf = buf[end:]
+
+		if e.Mask&unix.IN_Q_OVERFLOW != 0 {
+			if err := n.rescan(); err != nil {
+				return err
+			}
+			continue
+		}
+		dir, ok := n.dirs[int(e.Wd)]
+		if !ok {
+			continue
+		}
+		if e.Mask&unix.IN_IGNORED != 0 {
+			delete(n.dirs, int(e.Wd))
+			if dir == n.dir {
+				return &fs.PathError{Op: "watch", Path: dir, Err: fs.ErrNotExist}
+			}
+			continue
+		}
+		if name == "" || strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		removed := e.Mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0
+		switch {
+		case e.Mask&unix.IN_ISDIR != 0 && removed:
+			n.remove(path)
+		case e.Mask&unix.IN_ISDIR != 0:
+			if e.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+				if err := n.add(path, true); err != nil {
+					return err
+				}
+			}
+		case removed:
+			delete(n.files, path)
+			n.changed[path] = true
+		default:
+			n.files[path] = true
+			n.changed[path] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package watch
+
+import (
+	"errors"
+)
+
+type notifier struct{}
+
+func newNotifier(dir string) (*notifier, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (n *notifier) changes() ([]string, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (n *notifier) close() {}
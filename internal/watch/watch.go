@@ -0,0 +1,153 @@
+// Package watch reports the files that change in a directory tree.
+//
+// On Linux, the changes are notified by inotify, with the directories
+// created in the tree watched as they are. Elsewhere, or if inotify cannot
+// watch the tree to start with, the tree is polled instead, which is cheap
+// for a release. Note that inotify does not see the changes made to network
+// file systems by other machines.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type stamp struct {
+	modTime time.Time
+	size    int64
+	mode    fs.FileMode
+}
+
+// A Watcher tracks the files of a directory tree. Hidden files and
+// directories, such as .git or editor swap files, are ignored.
+type Watcher struct {
+	dir   string
+	files map[string]stamp
+}
+
+// New returns a watcher of the tree in dir, in its current state.
+func New(dir string) (*Watcher, error) {
+	w := &Watcher{dir: dir}
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	return w, nil
+}
+
+func (w *Watcher) scan() (map[string]stamp, error) {
+	files := make(map[string]stamp)
+	err := walk(w.dir, nil, func(path string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		files[path] = stamp{modTime: info.ModTime(), size: info.Size(), mode: info.Mode()}
+		return nil
+	})
+	return files, err
+}
+
+// walk calls dirFn, if not nil, and fileFn with the directories and the
+// files of the tree in dir, leaving out the hidden ones.
+func walk(dir string, dirFn func(path string) error, fileFn func(path string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files removed while walking are found removed by the
+			// next poll, or by this one if they were known.
+			if path != dir && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return fileFn(path, d)
+		}
+		if dirFn != nil {
+			return dirFn(path)
+		}
+		return nil
+	})
+}
+
+// Poll returns the files created, modified or removed since the previous
+// poll, sorted.
+func (w *Watcher) Poll() ([]string, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for p, s := range files {
+		if old, ok := w.files[p]; !ok || old != s {
+			changed = append(changed, p)
+		}
+	}
+	for p := range w.files {
+		if _, ok := files[p]; !ok {
+			changed = append(changed, p)
+		}
+	}
+	w.files = files
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Watch checks the changes of the tree every interval and calls fn with the
+// files that changed, until the context is done. Changes are batched until a
+// check finds no more of them, so that a burst of writes, like an editor
+// saving or a git checkout, results in a single call.
+func Watch(ctx context.Context, dir string, interval time.Duration, fn func(changed []string)) error {
+	var poll func() ([]string, error)
+	if n, err := newNotifier(dir); err == nil {
+		defer n.close()
+		poll = n.changes
+	} else {
+		w, err := New(dir)
+		if err != nil {
+			return err
+		}
+		poll = w.Poll
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	pending := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		changed, err := poll()
+		if err != nil {
+			return err
+		}
+		for _, p := range changed {
+			pending[p] = true
+		}
+		if len(changed) > 0 || len(pending) == 0 {
+			continue
+		}
+		var batch []string
+		for p := range pending {
+			batch = append(batch, p)
+		}
+		sort.Strings(batch)
+		clear(pending)
+		fn(batch)
+	}
+}
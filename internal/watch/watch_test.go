@@ -0,0 +1,153 @@
+package watch_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rebornplusplus/chisel-tools/internal/watch"
+)
+
+func writeFile(t *testing.T, p, content string) {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "chisel.yaml"), "format: v1\n")
+	writeFile(t, filepath.Join(dir, "slices/a.yaml"), "package: a\n")
+	writeFile(t, filepath.Join(dir, "slices/b.yaml"), "package: b\n")
+	w, err := watch.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := w.Poll()
+	if err != nil || len(changed) != 0 {
+		t.Fatalf("have changes %v, %v, want none", changed, err)
+	}
+
+	writeFile(t, filepath.Join(dir, "slices/a.yaml"), "package: a\nslices: {}\n")
+	writeFile(t, filepath.Join(dir, "slices/c.yaml"), "package: c\n")
+	writeFile(t, filepath.Join(dir, ".git/index"), "ignored")
+	writeFile(t, filepath.Join(dir, "slices/.a.yaml.swp"), "ignored")
+	if err := os.Remove(filepath.Join(dir, "slices/b.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	changed, err = w.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "slices/a.yaml"),
+		filepath.Join(dir, "slices/b.yaml"),
+		filepath.Join(dir, "slices/c.yaml"),
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("have changes %q, want %q", changed, want)
+	}
+	if changed, _ := w.Poll(); len(changed) != 0 {
+		t.Fatalf("have changes %v again", changed)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	batches := make(chan []string)
+	go watch.Watch(ctx, dir, 10*time.Millisecond, func(changed []string) {
+		batches <- changed
+	})
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "a.yaml"), "a")
+	writeFile(t, filepath.Join(dir, "b.yaml"), "b")
+	select {
+	case changed := <-batches:
+		want := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+		if !reflect.DeepEqual(changed, want) {
+			t.Fatalf("have changes %q, want %q", changed, want)
+		}
+	case <-ctx.Done():
+		t.Fatal("no changes reported")
+	}
+}
+
+func TestWatchDirectories(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(dir, "slices/a.yaml"), "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	batches := make(chan []string)
+	go watch.Watch(ctx, dir, 10*time.Millisecond, func(changed []string) {
+		batches <- changed
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	steps := []struct {
+		summary string
+		change  func()
+		want    []string
+	}{{
+		summary: "Directories created, and their files",
+		change: func() {
+			writeFile(t, filepath.Join(dir, "slices/new/b.yaml"), "b")
+			writeFile(t, filepath.Join(dir, ".git/index"), "ignored")
+		},
+		want: []string{filepath.Join(dir, "slices/new/b.yaml")},
+	}, {
+		summary: "Files of the directories created",
+		change: func() {
+			writeFile(t, filepath.Join(dir, "slices/new/c.yaml"), "c")
+		},
+		want: []string{filepath.Join(dir, "slices/new/c.yaml")},
+	}, {
+		summary: "Directories moved out of the tree",
+		change: func() {
+			if err := os.Rename(filepath.Join(dir, "slices/new"), filepath.Join(other, "new")); err != nil {
+				t.Fatal(err)
+			}
+		},
+		want: []string{filepath.Join(dir, "slices/new/b.yaml"), filepath.Join(dir, "slices/new/c.yaml")},
+	}, {
+		summary: "Directories moved into the tree",
+		change: func() {
+			writeFile(t, filepath.Join(other, "new/d.yaml"), "d")
+			if err := os.Rename(filepath.Join(other, "new"), filepath.Join(dir, "moved")); err != nil {
+				t.Fatal(err)
+			}
+		},
+		want: []string{
+			filepath.Join(dir, "moved/b.yaml"),
+			filepath.Join(dir, "moved/c.yaml"),
+			filepath.Join(dir, "moved/d.yaml"),
+		},
+	}, {
+		summary: "Directories removed",
+		change: func() {
+			if err := os.RemoveAll(filepath.Join(dir, "slices")); err != nil {
+				t.Fatal(err)
+			}
+		},
+		want: []string{filepath.Join(dir, "slices/a.yaml")},
+	}}
+	for _, step := range steps {
+		t.Logf("Summary: %s", step.summary)
+		step.change()
+		select {
+		case changed := <-batches:
+			if !reflect.DeepEqual(changed, step.want) {
+				t.Fatalf("have changes %q, want %q", changed, step.want)
+			}
+		case <-ctx.Done():
+			t.Fatal("no changes reported")
+		}
+	}
+}